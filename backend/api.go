@@ -2,11 +2,13 @@ package main
 
 import (
 	"compress/gzip"
+	"context"
 	"encoding/json"
 	"net/http"
 
 	"log"
 	"strings"
+	"time"
 
 	"github.com/alice-lg/alice-lg/backend/api"
 
@@ -41,8 +43,18 @@ func endpoint(wrapped apiEndpoint) httprouter.Handle {
 		req *http.Request,
 		params httprouter.Params) {
 
+		// Requests to the sources are canceled when the
+		// client went away or the request timed out
+		ctx := req.Context()
+		if timeout := AliceConfig.Server.RequestTimeout; timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(
+				ctx, time.Duration(timeout)*time.Second)
+			defer cancel()
+		}
+
 		// Get result from handler
-		result, err := wrapped(req, params)
+		result, err := wrapped(req.WithContext(ctx), params)
 		if err != nil {
 			// Get affected rs id
 			rsId, paramErr := validateSourceId(params.ByName("id"))
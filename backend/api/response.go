@@ -49,6 +49,9 @@ type ConfigResponse struct {
 	LookupColumnsOrder []string          `json:"lookup_columns_order"`
 
 	PrefixLookupEnabled bool `json:"prefix_lookup_enabled"`
+
+	// The views supported by each route server
+	Capabilities map[string]SourceCapabilities `json:"capabilities"`
 }
 
 type Noexport struct {
@@ -88,6 +91,26 @@ type Status struct {
 	RouterId     string    `json:"router_id"`
 	Version      string    `json:"version"`
 	Backend      string    `json:"backend"`
+
+	// Statistics of the requests to the source, if available
+	Requests *RequestStats `json:"requests,omitempty"`
+
+	// The endpoint of the source in use, if redundant
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Available features of the source, e.g. birdwatcher modules
+	Capabilities map[string]bool `json:"capabilities,omitempty"`
+}
+
+// Request statistics
+type RequestStats struct {
+	Requests     int           `json:"requests"`
+	Failures     int           `json:"failures"`
+	Retries      int           `json:"retries"`
+	LatencyLast  time.Duration `json:"latency_last"`
+	LatencyAvg   time.Duration `json:"latency_avg"`
+	LatencyMax   time.Duration `json:"latency_max"`
+	LatencyTotal time.Duration `json:"-"`
 }
 
 type StatusResponse struct {
@@ -95,6 +118,35 @@ type StatusResponse struct {
 	Status Status    `json:"status"`
 }
 
+// The views supported by a source
+type SourceCapabilities struct {
+	RoutesReceived    bool `json:"routes_received"`
+	RoutesFiltered    bool `json:"routes_filtered"`
+	RoutesNotExported bool `json:"routes_not_exported"`
+	Status            bool `json:"status"`
+	LiveLookup        bool `json:"live_lookup"`
+	NeighboursStatus  bool `json:"neighbours_status"`
+
+	// The version of the backend, if known
+	Version string `json:"version,omitempty"`
+}
+
+// The health of a source, tracked by a circuit breaker
+type SourceHealth struct {
+	// The state of the circuit: closed, open or half_open
+	State string `json:"state"`
+
+	// Consecutive failures of requests
+	Failures    int       `json:"failures"`
+	LastError   string    `json:"last_error,omitempty"`
+	LastErrorAt time.Time `json:"last_error_at"`
+
+	// Requests fail fast until the source is probed again
+	RetryAt time.Time `json:"retry_at"`
+
+	Requests RequestStats `json:"requests"`
+}
+
 // Routeservers
 type Routeserver struct {
 	Id         string   `json:"id"`
@@ -102,6 +154,9 @@ type Routeserver struct {
 	Group      string   `json:"group"`
 	Blackholes []string `json:"blackholes"`
 
+	Capabilities *SourceCapabilities `json:"capabilities,omitempty"`
+	Health       *SourceHealth       `json:"health,omitempty"`
+
 	Order int `json:"-"`
 }
 
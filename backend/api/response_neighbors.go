@@ -68,6 +68,10 @@ func (self *Neighbour) MatchLargeCommunity(_community Community) bool {
 	return true // Ignore
 }
 
+func (self *Neighbour) MatchFamily(_family string) bool {
+	return true // Ignore
+}
+
 func (self *Neighbour) MatchName(name string) bool {
 	name = strings.ToLower(name)
 	neighName := strings.ToLower(self.Description)
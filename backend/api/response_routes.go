@@ -1,6 +1,7 @@
 package api
 
 import (
+	"strings"
 	"time"
 )
 
@@ -18,9 +19,34 @@ type Route struct {
 	Type      []string      `json:"type"` // [BGP, unicast, univ]
 	Primary   bool          `json:"primary"`
 
+	// Address family and NLRI details of
+	// non unicast routes, e.g. VPN or FlowSpec
+	Family             string         `json:"family,omitempty"`
+	RouteDistinguisher string         `json:"route_distinguisher,omitempty"`
+	Labels             []int          `json:"labels,omitempty"`
+	FlowSpec           []FlowSpecRule `json:"flowspec,omitempty"`
+
 	Details Details `json:"details"`
 }
 
+// A FlowSpecRule is a match component of a FlowSpec NLRI
+type FlowSpecRule struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// Get the address family of the route. Sources only
+// providing unicast routes do not set the family.
+func (self *Route) AddressFamily() string {
+	if self.Family != "" {
+		return self.Family
+	}
+	if strings.Contains(self.Network, ":") {
+		return "ipv6-unicast"
+	}
+	return "ipv4-unicast"
+}
+
 // Implement Filterable interface for routes
 func (self *Route) MatchSourceId(id string) bool {
 	return true // A route has no source info so we exclude this filter
@@ -43,6 +69,10 @@ func (self *Route) MatchLargeCommunity(community Community) bool {
 	return self.Bgp.HasLargeCommunity(community)
 }
 
+func (self *Route) MatchFamily(family string) bool {
+	return self.AddressFamily() == family
+}
+
 type Routes []*Route
 
 // Implement sorting interface for routes
@@ -141,6 +171,10 @@ func (self *LookupRoute) MatchLargeCommunity(community Community) bool {
 	return self.Bgp.HasLargeCommunity(community)
 }
 
+func (self *LookupRoute) MatchFamily(family string) bool {
+	return true // Lookup routes have no family
+}
+
 // Implement sorting interface for lookup routes
 func (routes LookupRoutes) Len() int {
 	return len(routes)
@@ -13,6 +13,7 @@ const (
 	SEARCH_KEY_COMMUNITIES       = "communities"
 	SEARCH_KEY_EXT_COMMUNITIES   = "ext_communities"
 	SEARCH_KEY_LARGE_COMMUNITIES = "large_communities"
+	SEARCH_KEY_FAMILIES          = "families"
 )
 
 /*
@@ -28,6 +29,7 @@ type Filterable interface {
 	MatchCommunity(community Community) bool
 	MatchExtCommunity(community ExtCommunity) bool
 	MatchLargeCommunity(community Community) bool
+	MatchFamily(family string) bool
 }
 
 type FilterValue interface{}
@@ -214,6 +216,14 @@ func searchFilterMatchLargeCommunity(route Filterable, value interface{}) bool {
 	return route.MatchLargeCommunity(community)
 }
 
+func searchFilterMatchFamily(route Filterable, value interface{}) bool {
+	family, ok := value.(string)
+	if !ok {
+		return false
+	}
+	return route.MatchFamily(family)
+}
+
 func selectCmpFuncByKey(key string) SearchFilterComparator {
 	var cmp SearchFilterComparator
 	switch key {
@@ -232,6 +242,9 @@ func selectCmpFuncByKey(key string) SearchFilterComparator {
 	case SEARCH_KEY_LARGE_COMMUNITIES:
 		cmp = searchFilterMatchLargeCommunity
 		break
+	case SEARCH_KEY_FAMILIES:
+		cmp = searchFilterMatchFamily
+		break
 	default:
 		cmp = nil
 	}
@@ -314,6 +327,11 @@ func NewSearchFilters() *SearchFilters {
 			Filters:    []*SearchFilter{},
 			filtersIdx: make(map[string]int),
 		},
+		&SearchFilterGroup{
+			Key:        SEARCH_KEY_FAMILIES,
+			Filters:    []*SearchFilter{},
+			filtersIdx: make(map[string]int),
+		},
 	}
 
 	return groups
@@ -333,6 +351,8 @@ func (self *SearchFilters) GetGroupByKey(key string) *SearchFilterGroup {
 		return (*self)[3]
 	case SEARCH_KEY_LARGE_COMMUNITIES:
 		return (*self)[4]
+	case SEARCH_KEY_FAMILIES:
+		return (*self)[5]
 	}
 	return nil
 }
@@ -380,7 +400,7 @@ func (self *SearchFilters) UpdateFromLookupRoute(route *LookupRoute) {
 }
 
 // This is the same as above, but only the communities
-// are considered.
+// and the family are considered.
 func (self *SearchFilters) UpdateFromRoute(route *Route) {
 
 	// Add communities
@@ -405,6 +425,14 @@ func (self *SearchFilters) UpdateFromRoute(route *Route) {
 			Value: c,
 		})
 	}
+
+	// Add the family, if provided by the source
+	if route.Family != "" {
+		self.GetGroupByKey(SEARCH_KEY_FAMILIES).AddFilter(&SearchFilter{
+			Name:  route.Family,
+			Value: route.Family,
+		})
+	}
 }
 
 /*
@@ -464,6 +492,14 @@ func FiltersFromQuery(query url.Values) (*SearchFilters, error) {
 			}
 			queryFilters.GetGroupByKey(SEARCH_KEY_LARGE_COMMUNITIES).AddFilters(filters)
 			break
+
+		case SEARCH_KEY_FAMILIES:
+			filters, err := parseQueryValueList(parseStringValue, value)
+			if err != nil {
+				return nil, err
+			}
+			queryFilters.GetGroupByKey(SEARCH_KEY_FAMILIES).AddFilters(filters)
+			break
 		}
 	}
 
@@ -500,6 +536,11 @@ func (self *SearchFilters) MatchRoute(route Filterable) bool {
 		return false
 	}
 
+	families := self.GetGroupByKey(SEARCH_KEY_FAMILIES)
+	if !families.MatchAny(route) {
+		return false
+	}
+
 	return true
 }
 
@@ -352,6 +352,57 @@ func TestSearchFilterExcludeRoute(t *testing.T) {
 	}
 }
 
+func TestSearchFilterFamilies(t *testing.T) {
+	routes := Routes{
+		&Route{Id: "route_01", Network: "10.0.0.0/8"},
+		&Route{Id: "route_02", Network: "2001:db8::/32"},
+		&Route{
+			Id:      "route_03",
+			Network: "[destination: 10.0.0.0/24]",
+			Family:  "ipv4-flowspec",
+		},
+	}
+
+	match := func(query string) []string {
+		values, _ := url.ParseQuery(query)
+		filters, err := FiltersFromQuery(values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := []string{}
+		for _, r := range routes {
+			if filters.MatchRoute(r) {
+				ids = append(ids, r.Id)
+			}
+		}
+		return ids
+	}
+
+	if ids := match("families=ipv4-flowspec"); len(ids) != 1 || ids[0] != "route_03" {
+		t.Error("Expected only the flowspec route, got:", ids)
+	}
+	if ids := match("families=ipv6-unicast,ipv4-flowspec"); len(ids) != 2 {
+		t.Error("Expected 2 routes, got:", ids)
+	}
+	// Unicast routes without a family
+	if ids := match("families=ipv4-unicast"); len(ids) != 1 || ids[0] != "route_01" {
+		t.Error("Expected only the ipv4 route, got:", ids)
+	}
+	if ids := match(""); len(ids) != 3 {
+		t.Error("Expected all routes without a family filter, got:", ids)
+	}
+
+	// Only families provided by the source are available
+	available := NewSearchFilters()
+	for _, r := range routes {
+		available.UpdateFromRoute(r)
+	}
+	families := available.GetGroupByKey(SEARCH_KEY_FAMILIES).Filters
+	if len(families) != 1 || families[0].Value != "ipv4-flowspec" {
+		t.Error("Unexpected families available:", families)
+	}
+}
+
 // Communities should match all aswell
 func testSearchFilterCommunities(route Filterable, t *testing.T) {
 	query := "communities=23:42,111:11"
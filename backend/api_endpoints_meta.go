@@ -2,6 +2,7 @@ package main
 
 import (
 	"github.com/alice-lg/alice-lg/backend/api"
+	"github.com/alice-lg/alice-lg/backend/sources"
 	"github.com/julienschmidt/httprouter"
 
 	"net/http"
@@ -15,7 +16,7 @@ func apiStatusShow(_req *http.Request, _params httprouter.Params) (api.Response,
 }
 
 // Handle status
-func apiStatus(_req *http.Request, params httprouter.Params) (api.Response, error) {
+func apiStatus(req *http.Request, params httprouter.Params) (api.Response, error) {
 	rsId, err := validateSourceId(params.ByName("id"))
 	if err != nil {
 		return nil, err
@@ -25,8 +26,12 @@ func apiStatus(_req *http.Request, params httprouter.Params) (api.Response, erro
 	if source == nil {
 		return nil, SOURCE_NOT_FOUND_ERROR
 	}
+	err = validateCapability(source.Capabilities().Status, "status")
+	if err != nil {
+		return nil, err
+	}
 
-	result, err := source.Status()
+	result, err := sources.WithContext(source).StatusContext(req.Context())
 	if err != nil {
 		apiLogSourceError("status", rsId, err)
 	}
@@ -55,6 +60,13 @@ func apiConfigShow(_req *http.Request, _params httprouter.Params) (api.Response,
 		LookupColumns:          AliceConfig.Ui.LookupColumns,
 		LookupColumnsOrder:     AliceConfig.Ui.LookupColumnsOrder,
 		PrefixLookupEnabled:    AliceConfig.Server.EnablePrefixLookup,
+		Capabilities:           make(map[string]api.SourceCapabilities),
+	}
+	for _, sourceConfig := range AliceConfig.Sources {
+		capabilities := sourceConfig.getCapabilities()
+		if capabilities != nil {
+			result.Capabilities[sourceConfig.Id] = *capabilities
+		}
 	}
 	return result, nil
 }
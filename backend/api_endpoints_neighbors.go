@@ -2,6 +2,7 @@ package main
 
 import (
 	"github.com/alice-lg/alice-lg/backend/api"
+	"github.com/alice-lg/alice-lg/backend/sources"
 	"github.com/julienschmidt/httprouter"
 
 	"net/http"
@@ -10,7 +11,7 @@ import (
 
 // Handle get neighbors on routeserver
 func apiNeighborsList(
-	_req *http.Request,
+	req *http.Request,
 	params httprouter.Params,
 ) (api.Response, error) {
 	rsId, err := validateSourceId(params.ByName("id"))
@@ -24,7 +25,7 @@ func apiNeighborsList(
 	// to RS query if store is not ready yet
 	sourceStatus := AliceNeighboursStore.SourceStatus(rsId)
 	if sourceStatus.State == STATE_READY {
-		neighbors := AliceNeighboursStore.GetNeighborsAt(rsId)
+		neighbors := AliceNeighboursStore.GetNeighborsAt(req.Context(), rsId)
 		// Make response
 		neighborsResponse = &api.NeighboursResponse{
 			Api: api.ApiStatus{
@@ -45,7 +46,15 @@ func apiNeighborsList(
 			return nil, SOURCE_NOT_FOUND_ERROR
 		}
 
-		neighborsResponse, err = source.Neighbours()
+		neighborsResponse, err = sources.WithContext(source).
+			NeighboursContext(req.Context())
+
+		// Some details of the neighbors might be missing,
+		// e.g. the routes filtered by a pipe
+		if _, ok := err.(*sources.PartialError); ok && neighborsResponse != nil {
+			apiLogSourceError("neighbors", rsId, err)
+			err = nil
+		}
 		if err != nil {
 			apiLogSourceError("neighbors", rsId, err)
 			return nil, err
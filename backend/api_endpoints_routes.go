@@ -2,6 +2,7 @@ package main
 
 import (
 	"github.com/alice-lg/alice-lg/backend/api"
+	"github.com/alice-lg/alice-lg/backend/sources"
 	"github.com/julienschmidt/httprouter"
 
 	"net/http"
@@ -9,7 +10,7 @@ import (
 )
 
 // Handle routes
-func apiRoutesList(_req *http.Request, params httprouter.Params) (api.Response, error) {
+func apiRoutesList(req *http.Request, params httprouter.Params) (api.Response, error) {
 	rsId, err := validateSourceId(params.ByName("id"))
 	if err != nil {
 		return nil, err
@@ -21,7 +22,8 @@ func apiRoutesList(_req *http.Request, params httprouter.Params) (api.Response,
 		return nil, SOURCE_NOT_FOUND_ERROR
 	}
 
-	result, err := source.Routes(neighborId)
+	result, err := sources.WithContext(source).
+		RoutesContext(req.Context(), neighborId)
 	if err != nil {
 		apiLogSourceError("routes", rsId, neighborId, err)
 	}
@@ -47,8 +49,13 @@ func apiRoutesListReceived(
 	if source == nil {
 		return nil, SOURCE_NOT_FOUND_ERROR
 	}
+	err = validateCapability(source.Capabilities().RoutesReceived, "routes_received")
+	if err != nil {
+		return nil, err
+	}
 
-	result, err := source.RoutesReceived(neighborId)
+	result, err := sources.WithContext(source).
+		RoutesReceivedContext(req.Context(), neighborId)
 	if err != nil {
 		apiLogSourceError("routes_received", rsId, neighborId, err)
 		return nil, err
@@ -120,8 +127,13 @@ func apiRoutesListFiltered(
 	if source == nil {
 		return nil, SOURCE_NOT_FOUND_ERROR
 	}
+	err = validateCapability(source.Capabilities().RoutesFiltered, "routes_filtered")
+	if err != nil {
+		return nil, err
+	}
 
-	result, err := source.RoutesFiltered(neighborId)
+	result, err := sources.WithContext(source).
+		RoutesFilteredContext(req.Context(), neighborId)
 	if err != nil {
 		apiLogSourceError("routes_filtered", rsId, neighborId, err)
 		return nil, err
@@ -193,8 +205,13 @@ func apiRoutesListNotExported(
 	if source == nil {
 		return nil, SOURCE_NOT_FOUND_ERROR
 	}
+	err = validateCapability(source.Capabilities().RoutesNotExported, "routes_not_exported")
+	if err != nil {
+		return nil, err
+	}
 
-	result, err := source.RoutesNotExported(neighborId)
+	result, err := sources.WithContext(source).
+		RoutesNotExportedContext(req.Context(), neighborId)
 	if err != nil {
 		apiLogSourceError("routes_not_exported", rsId, neighborId, err)
 		return nil, err
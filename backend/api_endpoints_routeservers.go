@@ -21,6 +21,9 @@ func apiRouteserversList(_req *http.Request, _params httprouter.Params) (api.Res
 			Group:      source.Group,
 			Blackholes: source.Blackholes,
 			Order:      source.Order,
+
+			Capabilities: source.getCapabilities(),
+			Health:       source.getHealth(),
 		})
 	}
 
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alice-lg/alice-lg/backend/api"
+	"github.com/alice-lg/alice-lg/backend/sources"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// A source without not exported routes
+type capabilitiesTestSource struct {
+	sources.Source
+}
+
+func (self *capabilitiesTestSource) Capabilities() api.SourceCapabilities {
+	return api.SourceCapabilities{
+		RoutesReceived:   true,
+		RoutesFiltered:   true,
+		Status:           true,
+		NeighboursStatus: true,
+	}
+}
+
+func testCapabilitiesConfig() *Config {
+	return &Config{
+		Sources: []*SourceConfig{
+			&SourceConfig{
+				Id:       "rs1",
+				Name:     "rs1.example.net",
+				instance: &capabilitiesTestSource{},
+			},
+		},
+	}
+}
+
+func TestApiRouteserversListCapabilities(t *testing.T) {
+	AliceConfig = testCapabilitiesConfig()
+
+	result, err := apiRouteserversList(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	routeservers := result.(api.RouteserversResponse).Routeservers
+	if len(routeservers) != 1 || routeservers[0].Capabilities == nil {
+		t.Fatal("Expected capabilities of the route server, got:", routeservers)
+	}
+	capabilities := routeservers[0].Capabilities
+	if !capabilities.RoutesReceived || capabilities.RoutesNotExported {
+		t.Error("Unexpected capabilities:", capabilities)
+	}
+
+	result, err = apiConfigShow(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := result.(api.ConfigResponse)
+	if config.Capabilities["rs1"] != *capabilities {
+		t.Error("Expected the capabilities in the config, got:",
+			config.Capabilities)
+	}
+}
+
+func TestApiRoutesNotSupported(t *testing.T) {
+	AliceConfig = testCapabilitiesConfig()
+
+	req := httptest.NewRequest("GET",
+		"/api/v1/routeservers/rs1/neighbors/n1/routes/not-exported", nil)
+	params := httprouter.Params{
+		{Key: "id", Value: "rs1"},
+		{Key: "neighborId", Value: "n1"},
+	}
+
+	_, err := apiRoutesListNotExported(req, params)
+	if _, ok := err.(*sources.UnsupportedError); !ok {
+		t.Fatal("Expected unsupported error, got:", err)
+	}
+
+	response, _ := apiErrorResponse("rs1", err)
+	if response.Tag != NOT_SUPPORTED_TAG {
+		t.Error("Expected tag", NOT_SUPPORTED_TAG, "got:", response.Tag)
+	}
+}
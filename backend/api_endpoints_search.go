@@ -2,8 +2,10 @@ package main
 
 import (
 	"github.com/alice-lg/alice-lg/backend/api"
+	"github.com/alice-lg/alice-lg/backend/sources"
 	"github.com/julienschmidt/httprouter"
 
+	"context"
 	"net/http"
 	"sort"
 	"time"
@@ -45,6 +47,7 @@ func apiLookupPrefixGlobal(
 	var routes api.LookupRoutes
 	if lookupPrefix {
 		routes = AliceRoutesStore.LookupPrefix(q)
+		routes = append(routes, apiLookupPrefixLive(req.Context(), q)...)
 
 	} else {
 		neighbours := AliceNeighboursStore.LookupNeighbours(q)
@@ -135,6 +138,46 @@ func apiLookupPrefixGlobal(
 	return response, nil
 }
 
+// Lookup the prefix on all sources answering
+// prefix lookups directly. Partial prefixes are
+// only looked up in the routes store.
+func apiLookupPrefixLive(ctx context.Context, prefix string) api.LookupRoutes {
+	if !IsPrefixOrAddress(prefix) {
+		return nil
+	}
+
+	results := make(chan api.LookupRoutes)
+	count := 0
+
+	for _, sourceConfig := range AliceConfig.Sources {
+		if !sourceConfig.hasLiveLookup() {
+			continue
+		}
+		source, ok := sources.WithLookupContext(sourceConfig.getInstance())
+		if !ok {
+			continue
+		}
+
+		count++
+		go func(sourceId string, source sources.PrefixLookupContextSource) {
+			response, err := source.LookupPrefixContext(ctx, prefix)
+			if err != nil {
+				apiLogSourceError("lookup_prefix", sourceId, prefix, err)
+				results <- nil
+				return
+			}
+			results <- response.Routes
+		}(sourceConfig.Id, source)
+	}
+
+	routes := api.LookupRoutes{}
+	for i := 0; i < count; i++ {
+		routes = append(routes, <-results...)
+	}
+
+	return routes
+}
+
 func apiLookupNeighborsGlobal(
 	req *http.Request,
 	params httprouter.Params,
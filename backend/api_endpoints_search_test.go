@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alice-lg/alice-lg/backend/api"
+	"github.com/alice-lg/alice-lg/backend/sources"
+)
+
+// A source answering prefix lookups, failing
+// like GoBGP for partial prefixes.
+type lookupTestSource struct {
+	sources.Source
+	lookups int
+}
+
+func (self *lookupTestSource) LookupPrefixContext(
+	ctx context.Context,
+	prefix string,
+) (*api.RoutesLookupResponse, error) {
+	self.lookups++
+	if !IsPrefixOrAddress(prefix) {
+		return nil, &sources.InvalidRequestError{
+			Param: "prefix",
+			Value: prefix,
+		}
+	}
+	return &api.RoutesLookupResponse{
+		Routes: api.LookupRoutes{
+			&api.LookupRoute{
+				Neighbour: &api.Neighbour{Asn: 31078},
+				Network:   prefix,
+				State:     "imported",
+			},
+		},
+	}, nil
+}
+
+func TestApiLookupPrefixPartialLive(t *testing.T) {
+	startTestNeighboursStore()
+	neighbour := &api.Neighbour{
+		Id:            "ID163_AS31078",
+		Asn:           31078,
+		RouteServerId: "rs1",
+	}
+	AliceNeighboursStore.neighboursMap["rs1"][neighbour.Id] = neighbour
+
+	store := makeTestRoutesStore()
+	store.configMap["rs1"].LiveLookup = true
+	AliceRoutesStore = store
+
+	source := &lookupTestSource{}
+	AliceConfig = &Config{
+		Sources: []*SourceConfig{
+			&SourceConfig{
+				Id:         "rs1",
+				LiveLookup: true,
+				instance:   source,
+			},
+		},
+	}
+
+	// A partial prefix is served by the store
+	req := httptest.NewRequest("GET", "/api/v1/lookup/prefix?q=193.200.", nil)
+	result, err := apiLookupPrefixGlobal(req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := result.(api.PaginatedRoutesLookupResponse)
+	if len(response.Imported.Routes) != 1 ||
+		response.Imported.Routes[0].Network != "193.200.230.0/24" {
+		t.Error("Expected the imported route of the store, got:",
+			response.Imported.Routes)
+	}
+	if source.lookups != 0 {
+		t.Error("Expected no live lookup, got:", source.lookups)
+	}
+
+	// A complete prefix is looked up live
+	req = httptest.NewRequest("GET", "/api/v1/lookup/prefix?q=193.200.230.0/24", nil)
+	result, err = apiLookupPrefixGlobal(req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response = result.(api.PaginatedRoutesLookupResponse)
+	if len(response.Imported.Routes) != 1 || source.lookups != 1 {
+		t.Error("Expected the live lookup result, got:",
+			response.Imported.Routes)
+	}
+}
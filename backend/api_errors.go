@@ -6,11 +6,17 @@ package main
 // to internal IP addresses.
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/url"
 	"strings"
 
 	"github.com/alice-lg/alice-lg/backend/api"
+	"github.com/alice-lg/alice-lg/backend/sources"
+
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
 )
 
 type ResourceNotFoundError struct{}
@@ -26,18 +32,31 @@ const (
 	CONNECTION_REFUSED_TAG = "CONNECTION_REFUSED"
 	CONNECTION_TIMEOUT_TAG = "CONNECTION_TIMEOUT"
 	RESOURCE_NOT_FOUND_TAG = "NOT_FOUND"
+	INVALID_RESPONSE_TAG   = "INVALID_RESPONSE"
+	NOT_SUPPORTED_TAG      = "NOT_SUPPORTED"
+	UNAVAILABLE_TAG        = "SOURCE_UNAVAILABLE"
+	INVALID_REQUEST_TAG    = "INVALID_REQUEST"
 )
 
 const (
 	GENERIC_ERROR_CODE      = 42
 	CONNECTION_REFUSED_CODE = 100
 	CONNECTION_TIMEOUT_CODE = 101
+	INVALID_RESPONSE_CODE   = 102
+	NOT_SUPPORTED_CODE      = 103
+	UNAVAILABLE_CODE        = 104
+	INVALID_REQUEST_CODE    = 105
 	RESOURCE_NOT_FOUND_CODE = 404
 )
 
 const (
 	ERROR_STATUS              = http.StatusInternalServerError
 	RESOURCE_NOT_FOUND_STATUS = http.StatusNotFound
+	INVALID_RESPONSE_STATUS   = http.StatusBadGateway
+	NOT_SUPPORTED_STATUS      = http.StatusNotImplemented
+	TIMEOUT_STATUS            = http.StatusGatewayTimeout
+	UNAVAILABLE_STATUS        = http.StatusServiceUnavailable
+	INVALID_REQUEST_STATUS    = http.StatusBadRequest
 )
 
 func apiErrorResponse(routeserverId string, err error) (api.ErrorResponse, int) {
@@ -46,20 +65,60 @@ func apiErrorResponse(routeserverId string, err error) (api.ErrorResponse, int)
 	tag := GENERIC_ERROR_TAG
 	status := ERROR_STATUS
 
-	switch e := err.(type) {
-	case *ResourceNotFoundError:
+	// The request timed out, either the request_timeout of
+	// the api or the timeout of the request to the source.
+	if errors.Is(err, context.DeadlineExceeded) ||
+		grpcstatus.Code(err) == codes.DeadlineExceeded {
+		tag = CONNECTION_TIMEOUT_TAG
+		code = CONNECTION_TIMEOUT_CODE
+		status = TIMEOUT_STATUS
+		message = "The request timed out"
+	}
+
+	// Errors might be wrapped by the source, e.g.
+	// when a request is retried.
+	var (
+		notFoundErr    *ResourceNotFoundError
+		sourceNotFound *sources.NotFoundError
+		responseErr    *sources.InvalidResponseError
+		unsupportedErr *sources.UnsupportedError
+		circuitErr     *sources.CircuitOpenError
+		invalidErr     *sources.InvalidRequestError
+		urlErr         *url.Error
+	)
+	switch {
+	case errors.As(err, &notFoundErr), errors.As(err, &sourceNotFound):
 		tag = RESOURCE_NOT_FOUND_TAG
 		code = RESOURCE_NOT_FOUND_CODE
 		status = RESOURCE_NOT_FOUND_STATUS
-	case *url.Error:
+	case errors.As(err, &responseErr):
+		tag = INVALID_RESPONSE_TAG
+		code = INVALID_RESPONSE_CODE
+		status = INVALID_RESPONSE_STATUS
+	case errors.As(err, &unsupportedErr):
+		tag = NOT_SUPPORTED_TAG
+		code = NOT_SUPPORTED_CODE
+		status = NOT_SUPPORTED_STATUS
+	case errors.As(err, &invalidErr):
+		tag = INVALID_REQUEST_TAG
+		code = INVALID_REQUEST_CODE
+		status = INVALID_REQUEST_STATUS
+	case errors.As(err, &circuitErr):
+		tag = UNAVAILABLE_TAG
+		code = UNAVAILABLE_CODE
+		status = UNAVAILABLE_STATUS
+	case errors.As(err, &urlErr):
+		// The url is not part of the message
 		if strings.Contains(message, "connection refused") {
 			tag = CONNECTION_REFUSED_TAG
 			code = CONNECTION_REFUSED_CODE
 			message = "Connection refused while dialing the API"
-		} else if e.Timeout() {
+		} else if urlErr.Timeout() {
 			tag = CONNECTION_TIMEOUT_TAG
 			code = CONNECTION_TIMEOUT_CODE
 			message = "Connection timed out when connecting to the backend API"
+		} else {
+			message = "Request to the backend API failed"
 		}
 	}
 
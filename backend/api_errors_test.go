@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alice-lg/alice-lg/backend/sources"
+	"github.com/alice-lg/alice-lg/backend/sources/birdwatcher"
+	"github.com/alice-lg/alice-lg/backend/sources/gobgp"
+)
+
+func TestApiErrorResponse(t *testing.T) {
+	response, status := apiErrorResponse("rs1", fmt.Errorf("something failed"))
+	if response.Tag != GENERIC_ERROR_TAG || status != ERROR_STATUS {
+		t.Error("Expected generic error, got:", response.Tag, status)
+	}
+
+	response, status = apiErrorResponse("rs1", SOURCE_NOT_FOUND_ERROR)
+	if response.Tag != RESOURCE_NOT_FOUND_TAG || status != http.StatusNotFound {
+		t.Error("Expected not found error, got:", response.Tag, status)
+	}
+
+	response, status = apiErrorResponse("rs1", &sources.NotFoundError{
+		Resource: "neighbor",
+		Id:       "R192_42",
+	})
+	if response.Tag != RESOURCE_NOT_FOUND_TAG || status != http.StatusNotFound {
+		t.Error("Expected not found error, got:", response.Tag, status)
+	}
+}
+
+func TestApiErrorResponseConnectionRefused(t *testing.T) {
+	client := birdwatcher.NewClient(birdwatcher.Config{
+		Api:     "http://127.0.0.1:1",
+		Retries: 0,
+	})
+	_, err := client.GetJson("/status")
+	if err == nil {
+		t.Fatal("Expected an error for an unreachable birdwatcher")
+	}
+
+	response, _ := apiErrorResponse("rs1", err)
+	if response.Tag != CONNECTION_REFUSED_TAG {
+		t.Error("Expected tag", CONNECTION_REFUSED_TAG, "got:", response.Tag)
+	}
+	if strings.Contains(response.Message, "127.0.0.1") {
+		t.Error("Expected the url to be stripped:", response.Message)
+	}
+}
+
+func TestApiErrorResponseInvalidResponse(t *testing.T) {
+	err := &sources.InvalidResponseError{
+		Backend: "birdwatcher",
+		Field:   "protocols.ID103.routes.imported",
+		Reason:  "expected number, got string",
+	}
+	response, status := apiErrorResponse("rs1", err)
+	if response.Tag != INVALID_RESPONSE_TAG {
+		t.Error("Expected tag", INVALID_RESPONSE_TAG, "got:", response.Tag)
+	}
+	if response.Code != INVALID_RESPONSE_CODE {
+		t.Error("Expected code", INVALID_RESPONSE_CODE, "got:", response.Code)
+	}
+	if status != http.StatusBadGateway {
+		t.Error("Expected status 502, got:", status)
+	}
+	if response.Message != err.Error() {
+		t.Error("Expected the field in the message, got:", response.Message)
+	}
+	if response.RouteserverId != "rs1" {
+		t.Error("Unexpected routeserver id:", response.RouteserverId)
+	}
+}
+
+func TestApiErrorResponseNotSupported(t *testing.T) {
+	err := &sources.UnsupportedError{
+		Feature: "routes_noexport",
+		Reason:  "module not enabled in birdwatcher",
+	}
+	response, status := apiErrorResponse("rs1", err)
+	if response.Tag != NOT_SUPPORTED_TAG {
+		t.Error("Expected tag", NOT_SUPPORTED_TAG, "got:", response.Tag)
+	}
+	if response.Code != NOT_SUPPORTED_CODE {
+		t.Error("Expected code", NOT_SUPPORTED_CODE, "got:", response.Code)
+	}
+	if status != http.StatusNotImplemented {
+		t.Error("Expected status 501, got:", status)
+	}
+}
+
+func TestApiErrorResponseInvalidRequest(t *testing.T) {
+	err := &sources.InvalidRequestError{
+		Param:  "prefix",
+		Value:  "10.0",
+		Reason: "not an address or prefix",
+	}
+	response, status := apiErrorResponse("rs1", err)
+	if response.Tag != INVALID_REQUEST_TAG {
+		t.Error("Expected tag", INVALID_REQUEST_TAG, "got:", response.Tag)
+	}
+	if status != http.StatusBadRequest {
+		t.Error("Expected status 400, got:", status)
+	}
+}
+
+func TestApiErrorResponseCircuitOpen(t *testing.T) {
+	err := &sources.CircuitOpenError{
+		LastError: fmt.Errorf("connection refused"),
+	}
+	response, status := apiErrorResponse("rs1", err)
+	if response.Tag != UNAVAILABLE_TAG {
+		t.Error("Expected tag", UNAVAILABLE_TAG, "got:", response.Tag)
+	}
+	if status != http.StatusServiceUnavailable {
+		t.Error("Expected status 503, got:", status)
+	}
+}
+
+func TestApiErrorResponseTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			<-req.Context().Done()
+		}))
+	defer server.Close()
+	// The modules probe of the source is hanging as well
+	defer server.CloseClientConnections()
+
+	source := birdwatcher.NewBirdwatcher(birdwatcher.Config{
+		Api:  server.URL,
+		Type: "single_table",
+	})
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err := source.StatusContext(ctx)
+	if err == nil {
+		t.Fatal("Expected the request to time out")
+	}
+	response, status := apiErrorResponse("rs1", err)
+	if response.Tag != CONNECTION_TIMEOUT_TAG {
+		t.Error("Expected tag", CONNECTION_TIMEOUT_TAG, "got:", response.Tag)
+	}
+	if status != http.StatusGatewayTimeout {
+		t.Error("Expected status 504, got:", status)
+	}
+}
+
+func TestApiErrorResponseTimeoutGoBGP(t *testing.T) {
+	// A daemon accepting connections, but never responding
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	source := gobgp.NewGoBGP(gobgp.Config{
+		Id:             "rs1",
+		Host:           listener.Addr().String(),
+		Insecure:       true,
+		RequestTimeout: 10,
+	})
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	_, err = source.StatusContext(ctx)
+	if err == nil {
+		t.Fatal("Expected the request to time out")
+	}
+	response, status := apiErrorResponse("rs1", err)
+	if response.Tag != CONNECTION_TIMEOUT_TAG {
+		t.Error("Expected tag", CONNECTION_TIMEOUT_TAG, "got:",
+			response.Tag, err)
+	}
+	if status != http.StatusGatewayTimeout {
+		t.Error("Expected status 504, got:", status)
+	}
+}
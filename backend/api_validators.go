@@ -5,6 +5,8 @@ import (
 	"strconv"
 
 	"net/http"
+
+	"github.com/alice-lg/alice-lg/backend/sources"
 )
 
 // Helper: Validate source Id
@@ -15,6 +17,14 @@ func validateSourceId(id string) (string, error) {
 	return id, nil
 }
 
+// Helper: Validate that a view is supported by the source
+func validateCapability(supported bool, view string) error {
+	if !supported {
+		return &sources.UnsupportedError{Feature: view}
+	}
+	return nil
+}
+
 // Helper: Validate query string
 func validateQueryString(req *http.Request, key string) (string, error) {
 	query := req.URL.Query()
@@ -0,0 +1,16 @@
+package main
+
+// The source backends compiled into Alice. Backends
+// register themselves when imported, additional
+// backends are added with a blank import.
+
+import (
+	_ "github.com/alice-lg/alice-lg/backend/sources/alice"
+	_ "github.com/alice-lg/alice-lg/backend/sources/bird"
+	_ "github.com/alice-lg/alice-lg/backend/sources/birdwatcher"
+	_ "github.com/alice-lg/alice-lg/backend/sources/bmp"
+	_ "github.com/alice-lg/alice-lg/backend/sources/frr"
+	_ "github.com/alice-lg/alice-lg/backend/sources/gobgp"
+	_ "github.com/alice-lg/alice-lg/backend/sources/mrt"
+	_ "github.com/alice-lg/alice-lg/backend/sources/openbgpd"
+)
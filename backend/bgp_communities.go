@@ -61,6 +61,15 @@ func MakeWellKnownBgpCommunities() BgpCommunities {
 			"1048323": "no export subconfed",
 			"1048324": "nopeer",
 		},
+
+		// RFC 8097 origin validation state extended communities
+		"ov": BgpCommunities{
+			"0": BgpCommunities{
+				"valid":     "RPKI valid",
+				"not-found": "RPKI not found",
+				"invalid":   "RPKI invalid",
+			},
+		},
 	}
 
 	return c
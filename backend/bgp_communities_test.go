@@ -28,6 +28,18 @@ func TestCommunityLookup(t *testing.T) {
 	}
 }
 
+func TestExtCommunityLookup(t *testing.T) {
+	c := MakeWellKnownBgpCommunities()
+
+	label, err := c.Lookup("ov:0:invalid")
+	if err != nil {
+		t.Error(err)
+	}
+	if label != "RPKI invalid" {
+		t.Error("Label should have been: RPKI invalid, got:", label)
+	}
+}
+
 func TestSetCommunity(t *testing.T) {
 	c := MakeWellKnownBgpCommunities()
 
@@ -5,18 +5,15 @@ import (
 	"log"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/alice-lg/alice-lg/backend/api"
 	"github.com/alice-lg/alice-lg/backend/sources"
-	"github.com/alice-lg/alice-lg/backend/sources/birdwatcher"
-	"github.com/alice-lg/alice-lg/backend/sources/gobgp"
 
 	"github.com/go-ini/ini"
 )
 
-const SOURCE_UNKNOWN = 0
-const SOURCE_BIRDWATCHER = 1
-const SOURCE_GOBGP = 2
-
 type ServerConfig struct {
 	Listen                         string `ini:"listen_http"`
 	EnablePrefixLookup             bool   `ini:"enable_prefix_lookup"`
@@ -24,6 +21,9 @@ type ServerConfig struct {
 	RoutesStoreRefreshInterval     int    `ini:"routes_store_refresh_interval"`
 	Asn                            int    `ini:"asn"`
 	EnableNeighborsStatusRefresh   bool   `ini:"enable_neighbors_status_refresh"`
+	RequestTimeout                 int    `ini:"request_timeout"`
+	CircuitBreakerFailures         int    `ini:"circuit_breaker_failures"`
+	CircuitBreakerCoolDown         int    `ini:"circuit_breaker_cooldown"`
 }
 
 type HousekeepingConfig struct {
@@ -95,13 +95,19 @@ type SourceConfig struct {
 	// Blackhole IPs
 	Blackholes []string
 
-	// Source configurations
-	Type        int
-	Birdwatcher birdwatcher.Config
-	GoBGP       gobgp.Config
+	// The backend, e.g. birdwatcher, and its configuration
+	Backend string
+	Config  interface{}
+
+	// Prefix lookups are answered by the source
+	LiveLookup bool
+
+	// The circuit breaker of the source
+	Health sources.HealthConfig
 
-	// Source instance
-	instance sources.Source
+	// Source instance, made on the first request
+	instance     sources.Source
+	instanceLock sync.Mutex
 }
 
 type Config struct {
@@ -149,16 +155,11 @@ func isSourceBase(section *ini.Section) bool {
 	return len(strings.Split(section.Name(), ".")) == 2
 }
 
-// Get backend configuration type
-func getBackendType(section *ini.Section) int {
+// Get the backend name of a config section,
+// e.g. birdwatcher for [source.rs1.birdwatcher]
+func getBackendName(section *ini.Section) string {
 	name := section.Name()
-	if strings.HasSuffix(name, "birdwatcher") {
-		return SOURCE_BIRDWATCHER
-	} else if strings.HasSuffix(name, "gobgp") {
-		return SOURCE_GOBGP
-	}
-
-	return SOURCE_UNKNOWN
+	return name[strings.LastIndex(name, ".")+1:]
 }
 
 // Get UI config: Routes Columns Default
@@ -558,7 +559,7 @@ func getUiConfig(config *ini.File) (UiConfig, error) {
 }
 
 func getSources(config *ini.File) ([]*SourceConfig, error) {
-	sources := []*SourceConfig{}
+	sourceConfigs := []*SourceConfig{}
 
 	order := 0
 	sourceSections := config.ChildSections("source")
@@ -575,92 +576,53 @@ func getSources(config *ini.File) ([]*SourceConfig, error) {
 		sourceConfigSections := section.ChildSections()
 		if len(sourceConfigSections) == 0 {
 			// This source has no configured backend
-			return sources, fmt.Errorf("%s has no backend configuration", section.Name())
+			return sourceConfigs, fmt.Errorf("%s has no backend configuration", section.Name())
 		}
 
 		if len(sourceConfigSections) > 1 {
 			// The source is ambiguous
-			return sources, fmt.Errorf("%s has ambigous backends", section.Name())
+			return sourceConfigs, fmt.Errorf("%s has ambigous backends", section.Name())
 		}
 
 		// Configure backend
 		backendConfig := sourceConfigSections[0]
-		backendType := getBackendType(backendConfig)
-
-		if backendType == SOURCE_UNKNOWN {
-			return sources, fmt.Errorf("%s has an unsupported backend", section.Name())
+		backend, err := sources.GetBackend(getBackendName(backendConfig))
+		if err != nil {
+			return sourceConfigs, fmt.Errorf("%s: %s", section.Name(), err)
 		}
 
 		// Make config
-		sourceName := section.Key("name").MustString("Unknown Source")
-		sourceGroup := section.Key("group").MustString("")
-		sourceBlackholes := TrimmedStringList(
-			section.Key("blackholes").MustString(""))
-
-		config := &SourceConfig{
-			Id:         sourceId,
-			Order:      order,
-			Name:       sourceName,
-			Group:      sourceGroup,
-			Blackholes: sourceBlackholes,
-			Type:       backendType,
+		base := sources.Definition{
+			Id:    sourceId,
+			Name:  section.Key("name").MustString("Unknown Source"),
+			Group: section.Key("group").MustString(""),
+			Blackholes: TrimmedStringList(
+				section.Key("blackholes").MustString("")),
 		}
 
-		// Set backend
-		switch backendType {
-		case SOURCE_BIRDWATCHER:
-			sourceType := backendConfig.Key("type").MustString("")
-			peerTablePrefix := backendConfig.Key("peer_table_prefix").MustString("T")
-			pipeProtocolPrefix := backendConfig.Key("pipe_protocol_prefix").MustString("M")
-
-			if sourceType != "single_table" &&
-				sourceType != "multi_table" {
-				log.Fatal("Configuration error (birdwatcher source) unknown birdwatcher type:", sourceType)
-			}
-
-			log.Println("Adding birdwatcher source of type", sourceType,
-				"with peer_table_prefix", peerTablePrefix,
-				"and pipe_protocol_prefix", pipeProtocolPrefix)
-
-			c := birdwatcher.Config{
-				Id:   config.Id,
-				Name: config.Name,
-
-				Timezone:        "UTC",
-				ServerTime:      "2006-01-02T15:04:05.999999999Z07:00",
-				ServerTimeShort: "2006-01-02",
-				ServerTimeExt:   "Mon, 02 Jan 2006 15:04:05 -0700",
-
-				Type:               sourceType,
-				PeerTablePrefix:    peerTablePrefix,
-				PipeProtocolPrefix: pipeProtocolPrefix,
-			}
-
-			backendConfig.MapTo(&c)
-			config.Birdwatcher = c
-
-		case SOURCE_GOBGP:
-			c := gobgp.Config{
-				Id:   config.Id,
-				Name: config.Name,
-			}
-
-			backendConfig.MapTo(&c)
-			// Update defaults:
-			//  - processing_timeout
-			if c.ProcessingTimeout == 0 {
-				c.ProcessingTimeout = 300
-			}
-
-			config.GoBGP = c
+		// A backend might provide multiple sources,
+		// e.g. the VRFs of a GoBGP daemon.
+		definitions, err := backend.Configure(base, backendConfig)
+		if err != nil {
+			return sourceConfigs, fmt.Errorf("%s: %s", backendConfig.Name(), err)
 		}
 
-		// Add to list of sources
-		sources = append(sources, config)
-		order++
+		for _, definition := range definitions {
+			sourceConfigs = append(sourceConfigs, &SourceConfig{
+				Id:         definition.Id,
+				Order:      order,
+				Name:       definition.Name,
+				Group:      definition.Group,
+				Blackholes: definition.Blackholes,
+				Backend:    backend.Name,
+				Config:     definition.Config,
+				LiveLookup: definition.LiveLookup,
+			})
+			order++
+		}
 	}
 
-	return sources, nil
+	return sourceConfigs, nil
 }
 
 // Try to load configfiles as specified in the files
@@ -692,17 +654,27 @@ func loadConfig(file string) (*Config, error) {
 	}
 
 	// Map sections
-	server := ServerConfig{}
+	server := ServerConfig{
+		CircuitBreakerFailures: sources.DEFAULT_HEALTH_FAILURES,
+		CircuitBreakerCoolDown: sources.DEFAULT_HEALTH_COOLDOWN,
+	}
 	parsedConfig.Section("server").MapTo(&server)
 
 	housekeeping := HousekeepingConfig{}
 	parsedConfig.Section("housekeeping").MapTo(&housekeeping)
 
 	// Get all sources
-	sources, err := getSources(parsedConfig)
+	sourceConfigs, err := getSources(parsedConfig)
 	if err != nil {
 		return nil, err
 	}
+	for _, source := range sourceConfigs {
+		source.Health = sources.HealthConfig{
+			Failures: server.CircuitBreakerFailures,
+			CoolDown: time.Duration(
+				server.CircuitBreakerCoolDown) * time.Second,
+		}
+	}
 
 	// Get UI configurations
 	ui, err := getUiConfig(parsedConfig)
@@ -714,7 +686,7 @@ func loadConfig(file string) (*Config, error) {
 		Server:       server,
 		Housekeeping: housekeeping,
 		Ui:           ui,
-		Sources:      sources,
+		Sources:      sourceConfigs,
 		File:         file,
 	}
 
@@ -723,22 +695,52 @@ func loadConfig(file string) (*Config, error) {
 
 // Get source instance from config
 func (self *SourceConfig) getInstance() sources.Source {
+	self.instanceLock.Lock()
+	defer self.instanceLock.Unlock()
+
 	if self.instance != nil {
 		return self.instance
 	}
 
-	var instance sources.Source
-	switch self.Type {
-	case SOURCE_BIRDWATCHER:
-		instance = birdwatcher.NewBirdwatcher(self.Birdwatcher)
-	case SOURCE_GOBGP:
-		instance = gobgp.NewGoBGP(self.GoBGP)
+	backend, err := sources.GetBackend(self.Backend)
+	if err != nil {
+		log.Println("Could not make source", self.Id, ":", err)
+		return nil
 	}
 
+	instance := sources.NewHealthSource(
+		self.Id, backend.New(self.Config), self.Health)
 	self.instance = instance
 	return instance
 }
 
+// Get the views supported by the source,
+// nil if the source could not be made.
+func (self *SourceConfig) getCapabilities() *api.SourceCapabilities {
+	instance := self.getInstance()
+	if instance == nil {
+		return nil
+	}
+	capabilities := instance.Capabilities()
+	return &capabilities
+}
+
+// Get the health of the source, nil if not tracked
+func (self *SourceConfig) getHealth() *api.SourceHealth {
+	source, ok := self.getInstance().(*sources.HealthSource)
+	if !ok {
+		return nil
+	}
+	health := source.Health()
+	return &health
+}
+
+// Check if prefix lookups are answered by the
+// source itself instead of the routes store.
+func (self *SourceConfig) hasLiveLookup() bool {
+	return self.LiveLookup
+}
+
 // Get configuration file with fallbacks
 func getConfigFile(filename string) (string, error) {
 	// Check if requested file is present
@@ -1,10 +1,22 @@
 package main
 
 import (
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/go-ini/ini"
+
+	"github.com/alice-lg/alice-lg/backend/sources"
+	"github.com/alice-lg/alice-lg/backend/sources/alice"
+	"github.com/alice-lg/alice-lg/backend/sources/bird"
 	"github.com/alice-lg/alice-lg/backend/sources/birdwatcher"
+	"github.com/alice-lg/alice-lg/backend/sources/bmp"
+	"github.com/alice-lg/alice-lg/backend/sources/frr"
 	"github.com/alice-lg/alice-lg/backend/sources/gobgp"
+	"github.com/alice-lg/alice-lg/backend/sources/mrt"
+	"github.com/alice-lg/alice-lg/backend/sources/openbgpd"
 )
 
 // Test configuration loading and parsing
@@ -53,27 +65,88 @@ func TestSourceConfig(t *testing.T) {
 	rs1 := config.Sources[0] // Birdwatcher v4
 	rs2 := config.Sources[1] // Birdwatcher v6
 	rs3 := config.Sources[2] // GoBGP
+	rs4 := config.Sources[3] // OpenBGPD
+	rs5 := config.Sources[4] // FRR
+	rs6 := config.Sources[5] // BIRD
+	rs7 := config.Sources[6] // BMP
+	rs8 := config.Sources[7] // MRT
+	rs9 := config.Sources[8] // Alice
+	rs10 := config.Sources[9]
+
+	// Get the backend configs
+	rs1Config, _ := rs1.Config.(birdwatcher.Config)
+	rs2Config, _ := rs2.Config.(birdwatcher.Config)
+	rs3Config, _ := rs3.Config.(gobgp.Config)
+	rs4Config, _ := rs4.Config.(openbgpd.Config)
+	rs5Config, _ := rs5.Config.(frr.Config)
+	rs6Config, _ := rs6.Config.(bird.Config)
+	rs7Config, _ := rs7.Config.(bmp.Config)
+	rs8Config, _ := rs8.Config.(mrt.Config)
+	rs9Config, _ := rs9.Config.(alice.Config)
 
 	nilBirdwatcherConfig := birdwatcher.Config{}
-	if rs1.Birdwatcher == nilBirdwatcherConfig {
+	if rs1Config == nilBirdwatcherConfig {
 		t.Errorf(
 			"Example routeserver %s should have been identified as a birdwatcher source but was not",
 			rs1.Name,
 		)
 	}
-	if rs2.Birdwatcher == nilBirdwatcherConfig {
+	if rs2Config == nilBirdwatcherConfig {
 		t.Errorf(
 			"Example routeserver %s should have been identified as a birdwatcher source but was not",
 			rs2.Name,
 		)
 	}
-	nilGoBGPConfig := gobgp.Config{}
-	if rs3.GoBGP == nilGoBGPConfig {
+	if rs3Config.Host == "" {
 		t.Errorf(
 			"Example routeserver %s should have been identified as a gobgp source but was not",
 			rs3.Name,
 		)
 	}
+	if len(rs3Config.Families) != len(gobgp.DEFAULT_FAMILIES) {
+		t.Error("Expected default families for", rs3.Name, ":", rs3Config.Families)
+	}
+	nilOpenBGPDConfig := openbgpd.Config{}
+	if rs4Config == nilOpenBGPDConfig {
+		t.Errorf(
+			"Example routeserver %s should have been identified as an openbgpd source but was not",
+			rs4.Name,
+		)
+	}
+	nilFRRConfig := frr.Config{}
+	if rs5Config == nilFRRConfig {
+		t.Errorf(
+			"Example routeserver %s should have been identified as a frr source but was not",
+			rs5.Name,
+		)
+	}
+	if rs6Config.Socket == "" || len(rs6Config.MasterTables) != 2 {
+		t.Errorf(
+			"Example routeserver %s should have been identified as a bird source but was not",
+			rs6.Name,
+		)
+	}
+	if rs7.Backend != "bmp" || rs7Config.Listen != ":11019" {
+		t.Errorf(
+			"Example routeserver %s should have been identified as a bmp source but was not",
+			rs7.Name,
+		)
+	}
+	if rs8.Backend != "mrt" || rs8Config.File == "" {
+		t.Errorf(
+			"Example routeserver %s should have been identified as a mrt source but was not",
+			rs8.Name,
+		)
+	}
+	if rs9.Backend != "alice" || rs9.Id != "ams-rs1" || rs9Config.RemoteId != "rs1" {
+		t.Errorf(
+			"Example routeserver %s should have been identified as a federated source but was not",
+			rs9.Name,
+		)
+	}
+	if rs10.Id != "ams-rs2" || rs10.Group != "AMS" || rs10.Order != 9 {
+		t.Error("Unexpected federated source:", rs10.Id, rs10.Group, rs10.Order)
+	}
 }
 
 func TestSourceConfigDefaultsOverride(t *testing.T) {
@@ -88,11 +161,15 @@ func TestSourceConfigDefaultsOverride(t *testing.T) {
 	rs2 := config.Sources[1] // Birdwatcher v6
 	rs3 := config.Sources[2] // GoBGP
 
+	rs1Config := rs1.Config.(birdwatcher.Config)
+	rs2Config := rs2.Config.(birdwatcher.Config)
+	rs3Config := rs3.Config.(gobgp.Config)
+
 	// Source 1 should be on default time
 	// Source 2 should have an override
 	// For now it should be sufficient to test if
 	// the serverTime(rs1) != serverTime(rs2)
-	if rs1.Birdwatcher.ServerTime == rs2.Birdwatcher.ServerTime {
+	if rs1Config.ServerTime == rs2Config.ServerTime {
 		t.Error("Server times should be different between",
 			"source 1 and 2 in example configuration",
 			"(alice.example.conf)")
@@ -100,20 +177,43 @@ func TestSourceConfigDefaultsOverride(t *testing.T) {
 
 	// Check presence of timezone, default: UTC (rs1)
 	// override: Europe/Bruessels (rs2)
-	if rs1.Birdwatcher.Timezone != "UTC" {
+	if rs1Config.Timezone != "UTC" {
 		t.Error("Expected RS1 Timezone to be default: UTC")
 	}
 
-	if rs2.Birdwatcher.Timezone != "Europe/Brussels" {
-		t.Error("Expected 'Europe/Brussels', got", rs2.Birdwatcher.Timezone)
+	if rs2Config.Timezone != "Europe/Brussels" {
+		t.Error("Expected 'Europe/Brussels', got", rs2Config.Timezone)
 	}
 
-	if rs3.GoBGP.ProcessingTimeout != 300 {
+	// Check client defaults
+	if rs1Config.StatusTimeout != birdwatcher.DEFAULT_STATUS_TIMEOUT ||
+		rs1Config.DumpTimeout != birdwatcher.DEFAULT_DUMP_TIMEOUT ||
+		rs1Config.Retries != birdwatcher.DEFAULT_RETRIES ||
+		rs1Config.Concurrency != birdwatcher.DEFAULT_CONCURRENCY ||
+		rs1Config.TableTimeout != birdwatcher.DEFAULT_TABLE_TIMEOUT ||
+		rs1Config.ProbeInterval != birdwatcher.DEFAULT_PROBE_INTERVAL {
+		t.Error("Unexpected birdwatcher client config:", rs1Config)
+	}
+
+	if rs3Config.ProcessingTimeout != 300 {
 		t.Error(
 			"Expected GoBGP example to set 300s 'processing_timeout', got",
-			rs3.GoBGP.ProcessingTimeout,
+			rs3Config.ProcessingTimeout,
 		)
 	}
+
+	if rs3Config.RequestTimeout != 10 || rs3Config.KeepaliveTimeout != 20 {
+		t.Error(
+			"Expected GoBGP example to use the default timeouts, got",
+			rs3Config.RequestTimeout, rs3Config.KeepaliveTimeout,
+		)
+	}
+	if rs3Config.KeepaliveInterval != 0 {
+		t.Error("Expected keepalives to be disabled by default")
+	}
+	if rs3Config.CacheTtl != gobgp.DEFAULT_CACHE_TTL {
+		t.Error("Expected the default cache ttl, got:", rs3Config.CacheTtl)
+	}
 }
 
 func TestRejectAndNoexportReasons(t *testing.T) {
@@ -171,6 +271,21 @@ func TestOwnASN(t *testing.T) {
 	if config.Server.Asn != 9033 {
 		t.Error("Expected a set server asn")
 	}
+	if config.Server.RequestTimeout != 60 {
+		t.Error("Expected a request timeout of 60s, got:",
+			config.Server.RequestTimeout)
+	}
+	if config.Server.CircuitBreakerFailures != 5 ||
+		config.Server.CircuitBreakerCoolDown != 30 {
+		t.Error("Unexpected circuit breaker config:",
+			config.Server.CircuitBreakerFailures,
+			config.Server.CircuitBreakerCoolDown)
+	}
+
+	health := config.Sources[0].Health
+	if health.Failures != 5 || health.CoolDown != 30*time.Second {
+		t.Error("Unexpected source health config:", health)
+	}
 }
 
 func TestRpkiConfig(t *testing.T) {
@@ -221,3 +336,129 @@ func TestRejectCandidatesConfig(t *testing.T) {
 		t.Error("expected 23:42:46 to be a 'reject-candidate'")
 	}
 }
+
+func TestGoBGPVrfSources(t *testing.T) {
+	config, err := ini.Load([]byte(`
+[source.rs2]
+name = rs2.example.com
+[source.rs2.gobgp]
+host = rs2.example.com:50051
+vrfs = red, blue
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sources, err := getSources(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sources) != 3 {
+		t.Fatal("Expected the global view and 2 vrfs, got:", len(sources))
+	}
+
+	global := sources[0].Config.(gobgp.Config)
+	if sources[0].Id != "rs2" || global.Vrf != "" {
+		t.Error("Unexpected global view:", sources[0].Id, global.Vrf)
+	}
+
+	red := sources[1]
+	redConfig := red.Config.(gobgp.Config)
+	if red.Id != "rs2-red" || redConfig.Id != "rs2-red" ||
+		redConfig.Vrf != "red" || red.Order != 1 {
+		t.Error("Unexpected vrf source:", red.Id, redConfig.Vrf, red.Order)
+	}
+	if red.Name != "rs2.example.com (red)" {
+		t.Error("Unexpected vrf source name:", red.Name)
+	}
+	if redConfig.Host != "rs2.example.com:50051" {
+		t.Error("Expected vrf source to use the daemon of the global view")
+	}
+}
+
+func TestRedundantEndpoints(t *testing.T) {
+	config, err := ini.Load([]byte(`
+[source.rs1]
+name = rs1.example.com
+[source.rs1.birdwatcher]
+api = http://rs1a.example.com:29184, http://rs1b.example.com:29184
+endpoint_selection = round_robin
+type = multi_table
+
+[source.rs2]
+name = rs2.example.com
+[source.rs2.gobgp]
+host = rs2a.example.com:50051, rs2b.example.com:50051
+endpoint_selection = random
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = getSources(config)
+	if err == nil || !strings.Contains(err.Error(), "endpoint_selection") {
+		t.Error("Expected an error for the unknown selection, got:", err)
+	}
+
+	config.Section("source.rs2.gobgp").Key("endpoint_selection").SetValue("priority")
+	sourceConfigs, err := getSources(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs1Config := sourceConfigs[0].Config.(birdwatcher.Config)
+	if rs1Config.EndpointSelection != "round_robin" {
+		t.Error("Unexpected endpoint selection:", rs1Config.EndpointSelection)
+	}
+}
+
+func TestUnknownBackend(t *testing.T) {
+	config, err := ini.Load([]byte(`
+[source.rs1]
+name = rs1.example.com
+[source.rs1.quagga]
+api = http://rs1.example.com
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = getSources(config)
+	if err == nil {
+		t.Fatal("Expected an error for the unknown backend")
+	}
+	if !strings.Contains(err.Error(), "birdwatcher, bmp") {
+		t.Error("Expected the available backends in the error, got:", err)
+	}
+}
+
+func TestGetInstanceConcurrent(t *testing.T) {
+	made := int32(0)
+	sources.Register(&sources.Backend{
+		Name: "instance-test",
+		Configure: func(base sources.Definition, section *ini.Section) ([]*sources.Definition, error) {
+			return []*sources.Definition{&base}, nil
+		},
+		New: func(config interface{}) sources.Source {
+			atomic.AddInt32(&made, 1)
+			return &capabilitiesTestSource{}
+		},
+	})
+
+	source := &SourceConfig{Id: "rs1", Backend: "instance-test"}
+	instances := make(chan sources.Source, 10)
+	for i := 0; i < 10; i++ {
+		go func() {
+			instances <- source.getInstance()
+		}()
+	}
+
+	first := <-instances
+	for i := 1; i < 10; i++ {
+		if instance := <-instances; instance != first {
+			t.Error("Expected the same instance")
+		}
+	}
+	if made != 1 {
+		t.Error("Expected a single instance, made:", made)
+	}
+}
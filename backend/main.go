@@ -5,6 +5,8 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/alice-lg/alice-lg/backend/sources"
+
 	"github.com/julienschmidt/httprouter"
 )
 
@@ -34,6 +36,15 @@ func main() {
 
 	log.Println("Using configuration:", AliceConfig.File)
 
+	// Start sources collecting their state in the
+	// background, like the BMP station.
+	for _, source := range AliceConfig.Sources {
+		backend, err := sources.GetBackend(source.Backend)
+		if err == nil && backend.Eager {
+			source.getInstance()
+		}
+	}
+
 	// Setup local routes store
 	AliceRoutesStore = NewRoutesStore(AliceConfig)
 
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"regexp"
 	"strconv"
@@ -8,6 +9,7 @@ import (
 	"time"
 
 	"github.com/alice-lg/alice-lg/backend/api"
+	"github.com/alice-lg/alice-lg/backend/sources"
 )
 
 var REGEX_MATCH_ASLOOKUP = regexp.MustCompile(`(?i)^AS(\d+)`)
@@ -69,8 +71,10 @@ func (self *NeighboursStore) Start() {
 }
 
 func (self *NeighboursStore) init() {
+	ctx := context.Background()
+
 	// Perform initial update
-	self.update()
+	self.update(ctx)
 
 	// Initial logging
 	self.Stats().Log()
@@ -78,7 +82,7 @@ func (self *NeighboursStore) init() {
 	// Periodically update store
 	for {
 		time.Sleep(self.refreshInterval)
-		self.update()
+		self.update(ctx)
 	}
 }
 
@@ -97,7 +101,7 @@ func (self *NeighboursStore) SourceState(sourceId string) int {
 }
 
 // Update all neighbors
-func (self *NeighboursStore) update() {
+func (self *NeighboursStore) update(ctx context.Context) {
 	successCount := 0
 	errorCount := 0
 	t0 := time.Now()
@@ -117,7 +121,21 @@ func (self *NeighboursStore) update() {
 		sourceConfig := self.configMap[sourceId]
 		source := sourceConfig.getInstance()
 
-		neighboursRes, err := source.Neighbours()
+		neighboursRes, err := sources.WithContext(source).NeighboursContext(ctx)
+
+		// Keep the neighbours of a partial result, the
+		// failed parts are reported in the status
+		var failed map[string]error
+		if partial, ok := err.(*sources.PartialError); ok && neighboursRes != nil {
+			log.Println(
+				"Refreshing the neighbors store for:",
+				sourceConfig.Name, "(", sourceConfig.Id, ")",
+				"is incomplete:", err,
+			)
+			failed = partial.Failed
+			err = nil
+		}
+
 		if err != nil {
 			log.Println(
 				"Refreshing the neighbors store failed for:",
@@ -153,6 +171,7 @@ func (self *NeighboursStore) update() {
 		self.statusMap[sourceId] = StoreStatus{
 			LastRefresh: time.Now(),
 			State:       STATE_READY,
+			Failed:      failed,
 		}
 		self.lastRefresh = time.Now().UTC()
 		self.Unlock()
@@ -166,7 +185,10 @@ func (self *NeighboursStore) update() {
 	)
 }
 
-func (self *NeighboursStore) GetNeighborsAt(sourceId string) api.Neighbours {
+func (self *NeighboursStore) GetNeighborsAt(
+	ctx context.Context,
+	sourceId string,
+) api.Neighbours {
 	self.RLock()
 	neighborsIdx := self.neighboursMap[sourceId]
 	self.RUnlock()
@@ -176,7 +198,14 @@ func (self *NeighboursStore) GetNeighborsAt(sourceId string) api.Neighbours {
 		sourceConfig := self.configMap[sourceId]
 		source := sourceConfig.getInstance()
 
-		neighborsStatusData, err := source.NeighboursStatus()
+		// Sources without neighbor status fall back to the store
+		var neighborsStatusData *api.NeighboursStatusResponse
+		err := validateCapability(
+			source.Capabilities().NeighboursStatus, "neighbours_status")
+		if err == nil {
+			neighborsStatusData, err = sources.WithContext(source).
+				NeighboursStatusContext(ctx)
+		}
 		if err == nil {
 			neighborsStatus = make(map[string]api.NeighbourStatus, len(neighborsStatusData.Neighbours))
 
@@ -314,6 +343,14 @@ func (self *NeighboursStore) Stats() NeighboursStoreStats {
 			Neighbours: len(neighbours),
 			UpdatedAt:  status.LastRefresh,
 		}
+
+		if len(status.Failed) > 0 {
+			serverStats.FailedTables = make(map[string]string)
+			for table, err := range status.Failed {
+				serverStats.FailedTables[table] = err.Error()
+			}
+		}
+
 		rsStats = append(rsStats, serverStats)
 	}
 	self.RUnlock()
@@ -3,6 +3,8 @@ package main
 import (
 	"github.com/alice-lg/alice-lg/backend/api"
 
+	"context"
+	"fmt"
 	"sort"
 	"testing"
 )
@@ -90,6 +92,29 @@ func TestGetSourceState(t *testing.T) {
 	}
 }
 
+func TestNeighboursStoreStatsFailedTables(t *testing.T) {
+	store := makeTestNeighboursStore()
+	delete(store.neighboursMap, "rs2")
+	store.configMap = map[string]*SourceConfig{
+		"rs1": &SourceConfig{Id: "rs1", Name: "rs1.test"},
+	}
+	store.statusMap["rs1"] = StoreStatus{
+		State: STATE_READY,
+		Failed: map[string]error{
+			"T65003": fmt.Errorf("unexpected response: 500"),
+		},
+	}
+
+	stats := store.Stats()
+	rs := stats.RouteServers[0]
+	if rs.State != "READY" || rs.Neighbours != 3 {
+		t.Error("expected 3 neighbours, READY, got:", rs)
+	}
+	if rs.FailedTables["T65003"] != "unexpected response: 500" {
+		t.Error("expected failed table T65003, got:", rs.FailedTables)
+	}
+}
+
 func TestGetNeighbourAt(t *testing.T) {
 	store := makeTestNeighboursStore()
 
@@ -101,7 +126,7 @@ func TestGetNeighbourAt(t *testing.T) {
 
 func TestGetNeighbors(t *testing.T) {
 	store := makeTestNeighboursStore()
-	neighbors := store.GetNeighborsAt("rs2")
+	neighbors := store.GetNeighborsAt(context.Background(), "rs2")
 
 	if len(neighbors) != 2 {
 		t.Error("Expected 2 neighbors, got:", len(neighbors))
@@ -114,7 +139,7 @@ func TestGetNeighbors(t *testing.T) {
 			neighbors[0])
 	}
 
-	neighbors = store.GetNeighborsAt("rs3")
+	neighbors = store.GetNeighborsAt(context.Background(), "rs3")
 	if len(neighbors) != 0 {
 		t.Error("Unknown source should have yielded zero results")
 	}
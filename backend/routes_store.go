@@ -1,12 +1,14 @@
 package main
 
 import (
+	"context"
 	"log"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/alice-lg/alice-lg/backend/api"
+	"github.com/alice-lg/alice-lg/backend/sources"
 )
 
 type RoutesStore struct {
@@ -62,8 +64,10 @@ func (self *RoutesStore) Start() {
 
 // Service initialization
 func (self *RoutesStore) init() {
+	ctx := context.Background()
+
 	// Initial refresh
-	self.update()
+	self.update(ctx)
 
 	// Initial stats
 	self.Stats().Log()
@@ -71,12 +75,12 @@ func (self *RoutesStore) init() {
 	// Periodically update store
 	for {
 		time.Sleep(self.refreshInterval)
-		self.update()
+		self.update(ctx)
 	}
 }
 
 // Update all routes
-func (self *RoutesStore) update() {
+func (self *RoutesStore) update(ctx context.Context) {
 	successCount := 0
 	errorCount := 0
 	t0 := time.Now()
@@ -97,7 +101,21 @@ func (self *RoutesStore) update() {
 		}
 		self.Unlock()
 
-		routes, err := source.AllRoutes()
+		routes, err := sources.WithContext(source).AllRoutesContext(ctx)
+
+		// Keep the routes of a partial result, the
+		// failed parts are reported in the status
+		var failed map[string]error
+		if partial, ok := err.(*sources.PartialError); ok && routes != nil {
+			log.Println(
+				"Refreshing the routes store for:", sourceConfig.Name,
+				"(", sourceConfig.Id, ")",
+				"is incomplete:", err,
+			)
+			failed = partial.Failed
+			err = nil
+		}
+
 		if err != nil {
 			log.Println(
 				"Refreshing the routes store failed for:", sourceConfig.Name,
@@ -125,6 +143,7 @@ func (self *RoutesStore) update() {
 		self.statusMap[sourceId] = StoreStatus{
 			LastRefresh: time.Now(),
 			State:       STATE_READY,
+			Failed:      failed,
 		}
 		self.lastRefresh = time.Now().UTC()
 		self.Unlock()
@@ -166,6 +185,13 @@ func (self *RoutesStore) Stats() RoutesStoreStats {
 			UpdatedAt: status.LastRefresh,
 		}
 
+		if len(status.Failed) > 0 {
+			serverStats.FailedTables = make(map[string]string)
+			for table, err := range status.Failed {
+				serverStats.FailedTables[table] = err.Error()
+			}
+		}
+
 		rsStats = append(rsStats, serverStats)
 	}
 	self.RUnlock()
@@ -301,6 +327,16 @@ func (self *RoutesStore) LookupPrefixAt(
 	sourceId string,
 	prefix string,
 ) chan api.LookupRoutes {
+	return self.lookupPrefixAt(sourceId, prefix, true)
+}
+
+// Single RS lookup, the imported routes are
+// skipped if they are looked up live.
+func (self *RoutesStore) lookupPrefixAt(
+	sourceId string,
+	prefix string,
+	withImported bool,
+) chan api.LookupRoutes {
 
 	response := make(chan api.LookupRoutes)
 
@@ -315,14 +351,17 @@ func (self *RoutesStore) LookupPrefixAt(
 			routes.Filtered,
 			prefix,
 			"filtered")
-		imported := filterRoutesByPrefix(
-			config,
-			routes.Imported,
-			prefix,
-			"imported")
 
 		var result api.LookupRoutes
-		result = append(filtered, imported...)
+		result = filtered
+		if withImported {
+			imported := filterRoutesByPrefix(
+				config,
+				routes.Imported,
+				prefix,
+				"imported")
+			result = append(result, imported...)
+		}
 
 		response <- result
 	}()
@@ -340,7 +379,12 @@ func (self *RoutesStore) LookupPrefix(prefix string) api.LookupRoutes {
 	// Dispatch
 	self.RLock()
 	for sourceId, _ := range self.routesMap {
-		res := self.LookupPrefixAt(sourceId, prefix)
+		// Sources with a live lookup are queried directly for
+		// the accepted routes of complete prefixes, the filtered
+		// routes are not part of their RIB.
+		withImported := !self.configMap[sourceId].hasLiveLookup() ||
+			!IsPrefixOrAddress(prefix)
+		res := self.lookupPrefixAt(sourceId, prefix, withImported)
 		responses = append(responses, res)
 	}
 	self.RUnlock()
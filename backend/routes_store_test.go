@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"strings"
@@ -79,11 +80,11 @@ func makeTestRoutesStore() *RoutesStore {
 
 	configMap := map[string]*SourceConfig{
 		"rs1": &SourceConfig{
-			Id:   "rs1",
-			Name: "rs1.test",
-			Type: SOURCE_BIRDWATCHER,
+			Id:      "rs1",
+			Name:    "rs1.test",
+			Backend: "birdwatcher",
 
-			Birdwatcher: birdwatcher.Config{
+			Config: birdwatcher.Config{
 				Api:             "http://localhost:2342",
 				Timezone:        "UTC",
 				ServerTime:      "2006-01-02T15:04:05",
@@ -124,6 +125,25 @@ func TestRoutesStoreStats(t *testing.T) {
 	}
 }
 
+func TestRoutesStoreStatsFailedTables(t *testing.T) {
+	store := makeTestRoutesStore()
+	store.statusMap["rs1"] = StoreStatus{
+		State: STATE_READY,
+		Failed: map[string]error{
+			"T65003": fmt.Errorf("unexpected response: 500"),
+		},
+	}
+
+	stats := store.Stats()
+	rs := stats.RouteServers[0]
+	if rs.State != "READY" {
+		t.Error("expected state READY, got:", rs.State)
+	}
+	if rs.FailedTables["T65003"] != "unexpected response: 500" {
+		t.Error("expected failed table T65003, got:", rs.FailedTables)
+	}
+}
+
 func TestLookupPrefixAt(t *testing.T) {
 	startTestNeighboursStore()
 	store := makeTestRoutesStore()
@@ -168,6 +188,42 @@ func TestLookupPrefix(t *testing.T) {
 	}
 }
 
+func TestLookupPrefixLiveLookup(t *testing.T) {
+	startTestNeighboursStore()
+	store := makeTestRoutesStore()
+	query := "42.23.0.0/16"
+
+	filtered := 0
+	for _, route := range store.LookupPrefix(query) {
+		if route.State == "filtered" {
+			filtered++
+		}
+	}
+	if filtered == 0 {
+		t.Fatal("Expected filtered routes in the lookup results")
+	}
+
+	// The accepted routes are looked up live, the
+	// filtered routes are still served by the store.
+	store.configMap["rs1"].LiveLookup = true
+	results := store.LookupPrefix(query)
+	if len(results) != filtered {
+		t.Error("Expected", filtered, "filtered routes, got:", len(results))
+	}
+	for _, route := range results {
+		if route.State != "filtered" {
+			t.Error("Unexpected route state:", route.State)
+		}
+	}
+
+	// Partial prefixes are not looked up live,
+	// the accepted routes are served by the store.
+	results = store.LookupPrefix("193.200.")
+	if len(results) != 1 || results[0].State != "imported" {
+		t.Error("Expected the imported route of the store, got:", results)
+	}
+}
+
 func TestLookupNeighboursPrefixesAt(t *testing.T) {
 	startTestNeighboursStore()
 	store := makeTestRoutesStore()
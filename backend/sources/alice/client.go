@@ -0,0 +1,137 @@
+package alice
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/alice-lg/alice-lg/backend/api"
+)
+
+const (
+	API_PREFIX = "/api/v1"
+)
+
+// A RemoteError is an error response of the remote instance
+type RemoteError struct {
+	api.ErrorResponse
+}
+
+func (self *RemoteError) Error() string {
+	return fmt.Sprintf(
+		"remote %s: %s", strings.ToLower(self.Tag), self.Message)
+}
+
+type Client struct {
+	Api  string
+	http *http.Client
+}
+
+func NewClient(api string, timeout time.Duration) *Client {
+	return &Client{
+		Api: strings.TrimSuffix(api, "/"),
+		http: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+// Get the path of an endpoint for a route server
+func routeserverEndpoint(rsId string, endpoint string) string {
+	return "/routeservers/" + url.PathEscape(rsId) + endpoint
+}
+
+// Get the path of an endpoint for a neighbor
+func neighborEndpoint(rsId, neighborId string, endpoint string) string {
+	return routeserverEndpoint(rsId,
+		"/neighbors/"+url.PathEscape(neighborId)+endpoint)
+}
+
+// Request an endpoint of the remote api
+// and decode the response into result.
+func (self *Client) GetJson(endpoint string, result interface{}) error {
+	return self.GetJsonContext(context.Background(), endpoint, result)
+}
+
+// Request an endpoint, the request is canceled with the context
+func (self *Client) GetJsonContext(
+	ctx context.Context,
+	endpoint string,
+	result interface{},
+) error {
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodGet, self.Api+API_PREFIX+endpoint, nil)
+	if err != nil {
+		return err
+	}
+	res, err := self.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	decoder := json.NewDecoder(res.Body)
+	if res.StatusCode != http.StatusOK {
+		remoteErr := &RemoteError{}
+		if err := decoder.Decode(&remoteErr.ErrorResponse); err != nil {
+			return fmt.Errorf("remote api responded with: %s", res.Status)
+		}
+		return remoteErr
+	}
+
+	return decoder.Decode(result)
+}
+
+// Get the list of route servers of the remote instance
+func (self *Client) Routeservers() (api.Routeservers, error) {
+	response := &api.RouteserversResponse{}
+	if err := self.GetJson("/routeservers", response); err != nil {
+		return nil, err
+	}
+	return response.Routeservers, nil
+}
+
+/*
+Get all pages of a paginated routes endpoint. The routes
+of all pages are merged into the first response.
+*/
+func (self *Client) GetRoutesPages(endpoint string) (*api.RoutesResponse, error) {
+	return self.GetRoutesPagesContext(context.Background(), endpoint)
+}
+
+// Get all pages, the requests are canceled with the context
+func (self *Client) GetRoutesPagesContext(
+	ctx context.Context,
+	endpoint string,
+) (*api.RoutesResponse, error) {
+	var result *api.RoutesResponse
+	for page := 0; ; page++ {
+		response := &struct {
+			api.RoutesResponse
+			Pagination api.Pagination `json:"pagination"`
+		}{}
+		err := self.GetJsonContext(ctx,
+			fmt.Sprintf("%s?page=%d", endpoint, page), response)
+		if err != nil {
+			return nil, err
+		}
+
+		if result == nil {
+			result = &response.RoutesResponse
+		} else {
+			result.Imported = append(result.Imported, response.Imported...)
+			result.Filtered = append(result.Filtered, response.Filtered...)
+			result.NotExported = append(result.NotExported, response.NotExported...)
+		}
+
+		if page+1 >= response.Pagination.TotalPages {
+			break
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,109 @@
+package alice
+
+import (
+	"github.com/alice-lg/alice-lg/backend/api"
+	"github.com/alice-lg/alice-lg/backend/sources"
+
+	"github.com/go-ini/ini"
+
+	"fmt"
+	"log"
+)
+
+type Config struct {
+	Id   string
+	Name string
+
+	// Api is the base url of the remote Alice instance,
+	// e.g. https://lg.example.com
+	Api string `ini:"api"`
+
+	// Routeservers are the ids of the remote route servers to
+	// federate, named by their id. If empty, all route servers
+	// are federated, with the name, group and blackholes
+	// discovered when the configuration is loaded.
+	Routeservers []string `ini:"routeservers"`
+
+	// Timeout in seconds for requests to the remote instance
+	Timeout int `ini:"timeout"`
+
+	// RemoteId is the id of the route server
+	// on the remote instance.
+	RemoteId string
+}
+
+func init() {
+	sources.Register(&sources.Backend{
+		Name:      "alice",
+		Configure: configure,
+		New: func(config interface{}) sources.Source {
+			return NewAlice(config.(Config))
+		},
+	})
+}
+
+/*
+Make the sources for the route servers of a remote Alice
+instance. The id of a source is derived from the id of the
+section and the remote id, e.g. [source.ams.alice] with the
+remote route server rs1 becomes ams-rs1.
+*/
+func configure(
+	base sources.Definition,
+	section *ini.Section,
+) ([]*sources.Definition, error) {
+	c := Config{}
+	section.MapTo(&c)
+	if c.Api == "" {
+		return nil, fmt.Errorf("no api configured")
+	}
+
+	// The configured route servers do not depend on
+	// the remote instance being reachable on startup.
+	routeservers := configuredRouteservers(c.Routeservers)
+	if len(routeservers) == 0 {
+		log.Println("Discovering route servers of", c.Api)
+		discovered, err := Discover(c)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"could not discover route servers, "+
+					"configure the routeservers to federate: %s", err)
+		}
+		routeservers = discovered
+	}
+
+	definitions := make([]*sources.Definition, 0, len(routeservers))
+	for _, rs := range routeservers {
+		definition := base
+		definition.Id = base.Id + "-" + rs.Id
+		definition.Name = rs.Name
+		if definition.Group == "" {
+			definition.Group = rs.Group
+		}
+		if len(definition.Blackholes) == 0 {
+			definition.Blackholes = rs.Blackholes
+		}
+
+		remote := c
+		remote.Id = definition.Id
+		remote.Name = definition.Name
+		remote.RemoteId = rs.Id
+		definition.Config = remote
+
+		definitions = append(definitions, &definition)
+	}
+
+	return definitions, nil
+}
+
+// Make the configured route servers, named by their id
+func configuredRouteservers(ids []string) api.Routeservers {
+	routeservers := make(api.Routeservers, 0, len(ids))
+	for _, id := range ids {
+		routeservers = append(routeservers, api.Routeserver{
+			Id:   id,
+			Name: id,
+		})
+	}
+	return routeservers
+}
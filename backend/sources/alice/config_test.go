@@ -0,0 +1,93 @@
+package alice
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-ini/ini"
+
+	"github.com/alice-lg/alice-lg/backend/sources"
+)
+
+func testSection(t *testing.T, config string) *ini.Section {
+	file, err := ini.Load([]byte(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return file.Section("source.ams.alice")
+}
+
+func TestConfigureDiscover(t *testing.T) {
+	remote := testRemote(t)
+	defer remote.Close()
+
+	section := testSection(t, `
+[source.ams.alice]
+api = `+remote.URL+`/
+`)
+	definitions, err := configure(sources.Definition{Id: "ams"}, section)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(definitions) != 2 {
+		t.Fatal("Expected 2 definitions, got:", len(definitions))
+	}
+
+	rs1 := definitions[0]
+	if rs1.Id != "ams-rs1" || rs1.Name != "rs1.ams.example.com" ||
+		rs1.Group != "AMS" || len(rs1.Blackholes) != 1 {
+		t.Error("Unexpected definition:", rs1)
+	}
+	if rs1.Config.(Config).RemoteId != "rs1" {
+		t.Error("Unexpected remote id:", rs1.Config)
+	}
+}
+
+func TestConfigureRouteservers(t *testing.T) {
+	requests := 0
+	remote := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			requests++
+		}))
+	defer remote.Close()
+
+	section := testSection(t, `
+[source.ams.alice]
+api = `+remote.URL+`/
+routeservers = rs1, rs3
+`)
+	definitions, err := configure(sources.Definition{
+		Id:    "ams",
+		Group: "AMS",
+	}, section)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(definitions) != 2 {
+		t.Fatal("Expected 2 definitions, got:", len(definitions))
+	}
+
+	// The route servers are named by their id
+	rs3 := definitions[1]
+	if rs3.Id != "ams-rs3" || rs3.Name != "rs3" || rs3.Group != "AMS" {
+		t.Error("Unexpected definition:", rs3)
+	}
+
+	// The remote instance is not queried on startup
+	if requests != 0 {
+		t.Error("Expected no discovery, got requests:", requests)
+	}
+}
+
+func TestConfigureUnreachable(t *testing.T) {
+	section := testSection(t, `
+[source.ams.alice]
+api = http://127.0.0.1:1/
+timeout = 1
+`)
+	_, err := configure(sources.Definition{Id: "ams"}, section)
+	if err == nil {
+		t.Error("Expected an error without route servers to federate")
+	}
+}
@@ -0,0 +1,42 @@
+package alice
+
+import (
+	"context"
+
+	"github.com/alice-lg/alice-lg/backend/api"
+)
+
+// Requests without a context are made with
+// the background context.
+
+func (self *Alice) Status() (*api.StatusResponse, error) {
+	return self.StatusContext(context.Background())
+}
+
+func (self *Alice) Neighbours() (*api.NeighboursResponse, error) {
+	return self.NeighboursContext(context.Background())
+}
+
+func (self *Alice) NeighboursStatus() (*api.NeighboursStatusResponse, error) {
+	return self.NeighboursStatusContext(context.Background())
+}
+
+func (self *Alice) Routes(neighborId string) (*api.RoutesResponse, error) {
+	return self.RoutesContext(context.Background(), neighborId)
+}
+
+func (self *Alice) RoutesReceived(neighborId string) (*api.RoutesResponse, error) {
+	return self.RoutesReceivedContext(context.Background(), neighborId)
+}
+
+func (self *Alice) RoutesFiltered(neighborId string) (*api.RoutesResponse, error) {
+	return self.RoutesFilteredContext(context.Background(), neighborId)
+}
+
+func (self *Alice) RoutesNotExported(neighborId string) (*api.RoutesResponse, error) {
+	return self.RoutesNotExportedContext(context.Background(), neighborId)
+}
+
+func (self *Alice) AllRoutes() (*api.RoutesResponse, error) {
+	return self.AllRoutesContext(context.Background())
+}
@@ -0,0 +1,222 @@
+package alice
+
+import (
+	"context"
+	"time"
+
+	"github.com/alice-lg/alice-lg/backend/api"
+	"github.com/alice-lg/alice-lg/backend/sources"
+)
+
+const (
+	DEFAULT_TIMEOUT = 30
+
+	ENDPOINT_STATUS              = "/status"
+	ENDPOINT_NEIGHBORS           = "/neighbors"
+	ENDPOINT_ROUTES              = "/routes"
+	ENDPOINT_ROUTES_RECEIVED     = "/routes/received"
+	ENDPOINT_ROUTES_FILTERED     = "/routes/filtered"
+	ENDPOINT_ROUTES_NOT_EXPORTED = "/routes/not-exported"
+)
+
+/*
+Alice is a source federating a route server of another
+Alice instance. The remote instance caches the responses
+of its route servers, so the api status of the remote
+responses is passed through.
+*/
+type Alice struct {
+	config Config
+	client *Client
+}
+
+func NewAlice(config Config) *Alice {
+	if config.Timeout == 0 {
+		config.Timeout = DEFAULT_TIMEOUT
+	}
+
+	client := NewClient(
+		config.Api, time.Duration(config.Timeout)*time.Second)
+
+	return &Alice{
+		config: config,
+		client: client,
+	}
+}
+
+// Discover the route servers of a remote instance
+func Discover(config Config) (api.Routeservers, error) {
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = DEFAULT_TIMEOUT
+	}
+	client := NewClient(config.Api, time.Duration(timeout)*time.Second)
+
+	return client.Routeservers()
+}
+
+// The remote instance handles caching
+func (self *Alice) ExpireCaches() int {
+	return 0
+}
+
+// The views of the remote route server are
+// forwarded, lookups use the routes store.
+func (self *Alice) Capabilities() api.SourceCapabilities {
+	return api.SourceCapabilities{
+		RoutesReceived:    true,
+		RoutesFiltered:    true,
+		RoutesNotExported: true,
+		Status:            true,
+		LiveLookup:        false,
+		NeighboursStatus:  true,
+	}
+}
+
+func (self *Alice) StatusContext(ctx context.Context) (*api.StatusResponse, error) {
+	response := &api.StatusResponse{}
+	err := self.client.GetJsonContext(ctx,
+		routeserverEndpoint(self.config.RemoteId, ENDPOINT_STATUS),
+		response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (self *Alice) NeighboursContext(ctx context.Context) (*api.NeighboursResponse, error) {
+	response := &api.NeighboursResponse{}
+	err := self.client.GetJsonContext(ctx,
+		routeserverEndpoint(self.config.RemoteId, ENDPOINT_NEIGHBORS),
+		response)
+	if err != nil {
+		return nil, err
+	}
+
+	// The neighbors belong to the local source
+	for _, neighbor := range response.Neighbours {
+		neighbor.RouteServerId = self.config.Id
+	}
+
+	return response, nil
+}
+
+// The remote api does not expose the neighbors status,
+// so it is derived from the neighbors.
+func (self *Alice) NeighboursStatusContext(ctx context.Context) (*api.NeighboursStatusResponse, error) {
+	neighbors, err := self.NeighboursContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := make(api.NeighboursStatus, 0, len(neighbors.Neighbours))
+	for _, neighbor := range neighbors.Neighbours {
+		status = append(status, &api.NeighbourStatus{
+			Id:    neighbor.Id,
+			State: neighbor.State,
+			Since: neighbor.Uptime,
+		})
+	}
+
+	response := &api.NeighboursStatusResponse{
+		Api:        neighbors.Api,
+		Neighbours: status,
+	}
+
+	return response, nil
+}
+
+// Get filtered, accepted and not exported routes
+func (self *Alice) RoutesContext(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
+	response := &api.RoutesResponse{}
+	err := self.client.GetJsonContext(ctx,
+		neighborEndpoint(self.config.RemoteId, neighborId, ENDPOINT_ROUTES),
+		response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// Get all received routes
+func (self *Alice) RoutesReceivedContext(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
+	return self.client.GetRoutesPagesContext(ctx,
+		neighborEndpoint(self.config.RemoteId, neighborId, ENDPOINT_ROUTES_RECEIVED))
+}
+
+// Get all filtered routes
+func (self *Alice) RoutesFilteredContext(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
+	return self.client.GetRoutesPagesContext(ctx,
+		neighborEndpoint(self.config.RemoteId, neighborId, ENDPOINT_ROUTES_FILTERED))
+}
+
+// Get all not exported routes
+func (self *Alice) RoutesNotExportedContext(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
+	return self.client.GetRoutesPagesContext(ctx,
+		neighborEndpoint(self.config.RemoteId, neighborId, ENDPOINT_ROUTES_NOT_EXPORTED))
+}
+
+/*
+AllRoutes collects the received and filtered routes of all
+established neighbors, as the remote api does not provide
+the routes of a route server at once.
+The routes of the other neighbors are kept, when requesting
+the routes of a neighbor fails.
+*/
+func (self *Alice) AllRoutesContext(ctx context.Context) (*api.RoutesResponse, error) {
+	neighbors, err := self.NeighboursContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &api.RoutesResponse{
+		Api:      neighbors.Api,
+		Imported: api.Routes{},
+		Filtered: api.Routes{},
+	}
+
+	failed := map[string]error{}
+	for _, neighbor := range neighbors.Neighbours {
+		if neighbor.State != "up" {
+			continue
+		}
+
+		imported, filtered, err := self.neighborRoutes(ctx, neighbor.Id)
+		if err != nil {
+			// The request was canceled, the result is incomplete
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			failed[neighbor.Id] = err
+			continue
+		}
+
+		response.Imported = append(response.Imported, imported...)
+		response.Filtered = append(response.Filtered, filtered...)
+	}
+
+	// Report the failed neighbors along with the result
+	if len(failed) > 0 {
+		return response, &sources.PartialError{Failed: failed}
+	}
+
+	return response, nil
+}
+
+// Get the received and filtered routes of a neighbor
+func (self *Alice) neighborRoutes(
+	ctx context.Context,
+	neighborId string,
+) (api.Routes, api.Routes, error) {
+	received, err := self.RoutesReceivedContext(ctx, neighborId)
+	if err != nil {
+		return nil, nil, err
+	}
+	filtered, err := self.RoutesFilteredContext(ctx, neighborId)
+	if err != nil {
+		return nil, nil, err
+	}
+	return received.Imported, filtered.Filtered, nil
+}
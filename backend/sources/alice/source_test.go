@@ -0,0 +1,236 @@
+package alice
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alice-lg/alice-lg/backend/sources"
+)
+
+// Make a route for a paginated routes response
+func testRoute(network string) string {
+	return fmt.Sprintf(`{
+		"id": "%s", "neighbour_id": "R192_1", "network": "%s",
+		"gateway": "192.0.2.1", "age": 60000000000,
+		"bgp": {"as_path": [65001], "communities": [[65001, 42]]}
+	}`, network, network)
+}
+
+func testRoutesPage(key string, page, pages int, routes ...string) string {
+	list := ""
+	for i, r := range routes {
+		if i > 0 {
+			list += ","
+		}
+		list += r
+	}
+	return fmt.Sprintf(`{
+		"api": {"version": "4.2.0", "result_from_cache": true},
+		"%s": [%s],
+		"pagination": {"page": %d, "page_size": 1, "total_pages": %d}
+	}`, key, list, page, pages)
+}
+
+// Start a remote alice instance
+func testRemote(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	serveFile := func(path, filename string) {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			data, err := ioutil.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.Write(data)
+		})
+	}
+
+	serveFile("/api/v1/routeservers", "testdata/routeservers.json")
+	serveFile("/api/v1/routeservers/rs1/neighbors", "testdata/neighbors.json")
+
+	mux.HandleFunc("/api/v1/routeservers/rs1/neighbors/R192_1/routes/received",
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Query().Get("page") {
+			case "0":
+				fmt.Fprint(w, testRoutesPage("imported", 0, 2, testRoute("10.1.0.0/24")))
+			case "1":
+				fmt.Fprint(w, testRoutesPage("imported", 1, 2, testRoute("10.2.0.0/24")))
+			default:
+				t.Error("Unexpected page requested:", r.URL)
+			}
+		})
+	mux.HandleFunc("/api/v1/routeservers/rs1/neighbors/R192_1/routes/filtered",
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, testRoutesPage("filtered", 0, 1, testRoute("10.3.0.0/24")))
+		})
+	mux.HandleFunc("/api/v1/routeservers/rs1/status",
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"code": 100, "tag": "CONNECTION_REFUSED",
+				"message": "Connection refused while dialing the API",
+				"routeserver_id": "rs1"}`)
+		})
+
+	return httptest.NewServer(mux)
+}
+
+func TestDiscover(t *testing.T) {
+	remote := testRemote(t)
+	defer remote.Close()
+
+	routeservers, err := Discover(Config{Api: remote.URL + "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routeservers) != 2 {
+		t.Fatal("Expected 2 routeservers, got:", len(routeservers))
+	}
+	if routeservers[0].Name != "rs1.ams.example.com" ||
+		routeservers[0].Blackholes[0] != "192.0.2.66" {
+		t.Error("Unexpected routeserver:", routeservers[0])
+	}
+}
+
+func TestNeighbours(t *testing.T) {
+	remote := testRemote(t)
+	defer remote.Close()
+
+	source := NewAlice(Config{Id: "ams-rs1", Api: remote.URL, RemoteId: "rs1"})
+	neighbors, err := source.Neighbours()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(neighbors.Neighbours) != 2 {
+		t.Fatal("Expected 2 neighbors, got:", len(neighbors.Neighbours))
+	}
+
+	// The remote cache status is preserved
+	if !neighbors.Api.ResultFromCache || neighbors.Api.CacheStatus.OrigTtl != 300 {
+		t.Error("Unexpected api status:", neighbors.Api)
+	}
+
+	n := neighbors.Neighbours[0]
+	if n.RouteServerId != "ams-rs1" {
+		t.Error("Expected neighbor to belong to the local source, got:", n.RouteServerId)
+	}
+	if n.Uptime.Hours() != 1 || n.RoutesFiltered != 1 {
+		t.Error("Unexpected neighbor:", n)
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	remote := testRemote(t)
+	defer remote.Close()
+
+	source := NewAlice(Config{Id: "ams-rs1", Api: remote.URL, RemoteId: "rs1"})
+	received, err := source.RoutesReceived("R192_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(received.Imported) != 2 {
+		t.Fatal("Expected routes of all pages, got:", len(received.Imported))
+	}
+	if received.Imported[1].Network != "10.2.0.0/24" ||
+		received.Imported[1].Bgp.Communities[0][1] != 42 {
+		t.Error("Unexpected route:", received.Imported[1])
+	}
+
+	// All routes only include established neighbors
+	all, err := source.AllRoutes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all.Imported) != 2 || len(all.Filtered) != 1 {
+		t.Error("Unexpected routes:", len(all.Imported), len(all.Filtered))
+	}
+}
+
+func TestAllRoutesPartial(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/routeservers/rs1/neighbors",
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"api": {}, "neighbours": [
+				{"id": "R192_1", "state": "up"},
+				{"id": "R192_2", "state": "up"}
+			]}`)
+		})
+	mux.HandleFunc("/api/v1/routeservers/rs1/neighbors/R192_1/routes/received",
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, testRoutesPage("imported", 0, 1, testRoute("10.1.0.0/24")))
+		})
+	mux.HandleFunc("/api/v1/routeservers/rs1/neighbors/R192_1/routes/filtered",
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, testRoutesPage("filtered", 0, 1, testRoute("10.3.0.0/24")))
+		})
+	mux.HandleFunc("/api/v1/routeservers/rs1/neighbors/R192_2/routes/received",
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"code": 101, "tag": "CONNECTION_TIMEOUT",
+				"message": "The request timed out",
+				"routeserver_id": "rs1"}`)
+		})
+	remote := httptest.NewServer(mux)
+	defer remote.Close()
+
+	// The routes of the other neighbors are kept
+	source := NewAlice(Config{Id: "ams-rs1", Api: remote.URL, RemoteId: "rs1"})
+	all, err := source.AllRoutes()
+	partial, ok := err.(*sources.PartialError)
+	if !ok {
+		t.Fatal("Expected a partial error, got:", err)
+	}
+	if len(partial.Failed) != 1 || partial.Failed["R192_2"] == nil {
+		t.Error("Unexpected failed neighbors:", partial.Failed)
+	}
+	if all == nil || len(all.Imported) != 1 || len(all.Filtered) != 1 {
+		t.Error("Expected the routes of R192_1, got:", all)
+	}
+}
+
+func TestRemoteError(t *testing.T) {
+	remote := testRemote(t)
+	defer remote.Close()
+
+	source := NewAlice(Config{Id: "ams-rs1", Api: remote.URL, RemoteId: "rs1"})
+	_, err := source.Status()
+	remoteErr, ok := err.(*RemoteError)
+	if !ok {
+		t.Fatal("Expected a remote error, got:", err)
+	}
+	if remoteErr.Code != 100 {
+		t.Error("Unexpected error code:", remoteErr.Code)
+	}
+	t.Log(err)
+}
+
+func TestStatusCanceled(t *testing.T) {
+	canceled := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			<-req.Context().Done()
+			close(canceled)
+		}))
+	defer server.Close()
+
+	source := NewAlice(Config{Api: server.URL, RemoteId: "rs1"})
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+
+	if _, err := source.StatusContext(ctx); err == nil {
+		t.Error("Expected the request to be canceled")
+	}
+
+	// The request to the remote is canceled as well
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Error("Expected the remote request to be canceled")
+	}
+}
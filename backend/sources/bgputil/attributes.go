@@ -0,0 +1,79 @@
+package bgputil
+
+// Helpers for decoding native BGP path attributes,
+// as received through BMP or read from MRT dumps.
+
+import (
+	"github.com/alice-lg/alice-lg/backend/api"
+
+	"github.com/osrg/gobgp/pkg/packet/bgp"
+)
+
+// Get the origin as string
+func DecodeOrigin(origin uint8) string {
+	switch origin {
+	case bgp.BGP_ORIGIN_ATTR_TYPE_IGP:
+		return "IGP"
+	case bgp.BGP_ORIGIN_ATTR_TYPE_EGP:
+		return "EGP"
+	case bgp.BGP_ORIGIN_ATTR_TYPE_INCOMPLETE:
+		return "Incomplete"
+	}
+	return "unknown"
+}
+
+// Decode path attributes into the bgp info of a route.
+// The next hop is taken from the NEXT_HOP attribute or
+// from the MP_REACH_NLRI attribute.
+func DecodePathAttributes(attrs []bgp.PathAttributeInterface) api.BgpInfo {
+	info := api.BgpInfo{
+		Origin:           "unknown",
+		AsPath:           []int{},
+		Communities:      api.Communities{},
+		LargeCommunities: api.Communities{},
+		ExtCommunities:   api.ExtCommunities{},
+	}
+
+	for _, attr := range attrs {
+		switch a := attr.(type) {
+		case *bgp.PathAttributeOrigin:
+			info.Origin = DecodeOrigin(a.Value)
+		case *bgp.PathAttributeNextHop:
+			info.NextHop = a.Value.String()
+		case *bgp.PathAttributeMpReachNLRI:
+			if info.NextHop == "" && a.Nexthop != nil {
+				info.NextHop = a.Nexthop.String()
+			}
+		case *bgp.PathAttributeMultiExitDisc:
+			info.Med = int(a.Value)
+		case *bgp.PathAttributeLocalPref:
+			info.LocalPref = int(a.Value)
+		case *bgp.PathAttributeAsPath:
+			for _, segment := range a.Value {
+				for _, asn := range segment.GetAS() {
+					info.AsPath = append(info.AsPath, int(asn))
+				}
+			}
+		case *bgp.PathAttributeCommunities:
+			for _, community := range a.Value {
+				info.Communities = append(info.Communities, api.Community{
+					int((0xffff0000 & community) >> 16),
+					int(0xffff & community),
+				})
+			}
+		case *bgp.PathAttributeLargeCommunities:
+			for _, community := range a.Values {
+				info.LargeCommunities = append(info.LargeCommunities, api.Community{
+					int(community.ASN),
+					int(community.LocalData1),
+					int(community.LocalData2),
+				})
+			}
+		case *bgp.PathAttributeExtendedCommunities:
+			info.ExtCommunities = append(info.ExtCommunities,
+				DecodeExtCommunities(a.Value)...)
+		}
+	}
+
+	return info
+}
@@ -0,0 +1,115 @@
+package bgputil
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/alice-lg/alice-lg/backend/api"
+
+	"github.com/osrg/gobgp/pkg/packet/bgp"
+)
+
+/*
+Extended communities are represented like the communities
+from birdwatcher: A tuple of a type tag, the global and the
+local administrator, e.g. (rt, 64500, 23).
+
+Route targets and origins use the tags of bird, as
+do communities of unknown types, which are encoded as
+(generic, 0x<high 32 bit>, 0x<low 32 bit>). Other types
+are tagged with their names:
+
+    (ov, 0, valid|not-found|invalid)  RFC 8097 validation state
+    (color, 0, <color>)
+    (encap, 0, <tunnel type>)
+    (redirect, <as|ip>, <local admin>)
+    (traffic-rate, <as>, <rate>)
+    (traffic-action, <terminal>, <sample>)
+    (traffic-remark, 0, <dscp>)
+
+The tuples are strings, like the values of search filters.
+*/
+
+func extCommunity(tag string, global, local interface{}) api.ExtCommunity {
+	return api.ExtCommunity{
+		tag,
+		fmt.Sprintf("%v", global),
+		fmt.Sprintf("%v", local),
+	}
+}
+
+// Get the tag for an address or AS specific community
+func subTypeTag(subType bgp.ExtendedCommunityAttrSubType) string {
+	switch subType {
+	case bgp.EC_SUBTYPE_ROUTE_TARGET:
+		return "rt"
+	case bgp.EC_SUBTYPE_ROUTE_ORIGIN:
+		return "ro"
+	}
+	return ""
+}
+
+func boolFlag(flag bool) int {
+	if flag {
+		return 1
+	}
+	return 0
+}
+
+// Encode a community as generic
+func genericExtCommunity(community bgp.ExtendedCommunityInterface) api.ExtCommunity {
+	buf, err := community.Serialize()
+	if err != nil || len(buf) != 8 {
+		return extCommunity("unknown", 0, 0)
+	}
+	return extCommunity("generic",
+		fmt.Sprintf("0x%08x", binary.BigEndian.Uint32(buf[0:4])),
+		fmt.Sprintf("0x%08x", binary.BigEndian.Uint32(buf[4:8])))
+}
+
+// Decode an extended community
+func DecodeExtCommunity(community bgp.ExtendedCommunityInterface) api.ExtCommunity {
+	switch c := community.(type) {
+	case *bgp.TwoOctetAsSpecificExtended:
+		if tag := subTypeTag(c.SubType); tag != "" {
+			return extCommunity(tag, c.AS, c.LocalAdmin)
+		}
+	case *bgp.FourOctetAsSpecificExtended:
+		if tag := subTypeTag(c.SubType); tag != "" {
+			return extCommunity(tag, c.AS, c.LocalAdmin)
+		}
+	case *bgp.IPv4AddressSpecificExtended:
+		if tag := subTypeTag(c.SubType); tag != "" {
+			return extCommunity(tag, c.IPv4, c.LocalAdmin)
+		}
+	case *bgp.ValidationExtended:
+		return extCommunity("ov", 0, c.State)
+	case *bgp.ColorExtended:
+		return extCommunity("color", 0, c.Color)
+	case *bgp.EncapExtended:
+		return extCommunity("encap", 0, uint16(c.TunnelType))
+	case *bgp.RedirectTwoOctetAsSpecificExtended:
+		return extCommunity("redirect", c.AS, c.LocalAdmin)
+	case *bgp.RedirectFourOctetAsSpecificExtended:
+		return extCommunity("redirect", c.AS, c.LocalAdmin)
+	case *bgp.RedirectIPv4AddressSpecificExtended:
+		return extCommunity("redirect", c.IPv4, c.LocalAdmin)
+	case *bgp.TrafficRateExtended:
+		return extCommunity("traffic-rate", c.AS, fmt.Sprintf("%.0f", c.Rate))
+	case *bgp.TrafficActionExtended:
+		return extCommunity("traffic-action",
+			boolFlag(c.Terminal), boolFlag(c.Sample))
+	case *bgp.TrafficRemarkExtended:
+		return extCommunity("traffic-remark", 0, c.DSCP)
+	}
+	return genericExtCommunity(community)
+}
+
+// Decode a list of extended communities
+func DecodeExtCommunities(communities []bgp.ExtendedCommunityInterface) api.ExtCommunities {
+	result := make(api.ExtCommunities, 0, len(communities))
+	for _, community := range communities {
+		result = append(result, DecodeExtCommunity(community))
+	}
+	return result
+}
@@ -0,0 +1,62 @@
+package bgputil
+
+import (
+	"testing"
+
+	"github.com/alice-lg/alice-lg/backend/api"
+
+	"github.com/osrg/gobgp/pkg/packet/bgp"
+)
+
+func TestDecodeExtCommunity(t *testing.T) {
+	tests := []struct {
+		community bgp.ExtendedCommunityInterface
+		expected  string
+	}{
+		{bgp.NewTwoOctetAsSpecificExtended(
+			bgp.EC_SUBTYPE_ROUTE_TARGET, 64500, 23, true), "rt:64500:23"},
+		{bgp.NewFourOctetAsSpecificExtended(
+			bgp.EC_SUBTYPE_ROUTE_ORIGIN, 4200000000, 42, true), "ro:4200000000:42"},
+		{bgp.NewIPv4AddressSpecificExtended(
+			bgp.EC_SUBTYPE_ROUTE_TARGET, "192.0.2.1", 100, true), "rt:192.0.2.1:100"},
+		{bgp.NewValidationExtended(bgp.VALIDATION_STATE_INVALID), "ov:0:invalid"},
+		{bgp.NewColorExtended(100), "color:0:100"},
+		{bgp.NewEncapExtended(bgp.TUNNEL_TYPE_VXLAN), "encap:0:8"},
+		{bgp.NewRedirectTwoOctetAsSpecificExtended(64500, 666), "redirect:64500:666"},
+		{bgp.NewTrafficRateExtended(64500, 1000000), "traffic-rate:64500:1000000"},
+		{bgp.NewTrafficActionExtended(true, false), "traffic-action:1:0"},
+		// Source AS is not known by name
+		{bgp.NewTwoOctetAsSpecificExtended(
+			bgp.EC_SUBTYPE_SOURCE_AS, 64500, 0, true), "generic:0x0009fbf4:0x00000000"},
+		{bgp.NewOpaqueExtended(false, []byte{0x42, 0, 0, 0, 0, 0, 1}),
+			"generic:0x43420000:0x00000001"},
+	}
+
+	for _, test := range tests {
+		community := DecodeExtCommunity(test.community)
+		if len(community) != 3 {
+			t.Error("Expected a tuple of tag, global and local admin:", community)
+		}
+		if community.String() != test.expected {
+			t.Error("Expected", test.expected, "got:", community.String())
+		}
+	}
+}
+
+func TestDecodeExtCommunitiesMatch(t *testing.T) {
+	info := DecodePathAttributes([]bgp.PathAttributeInterface{
+		bgp.NewPathAttributeExtendedCommunities([]bgp.ExtendedCommunityInterface{
+			bgp.NewTwoOctetAsSpecificExtended(
+				bgp.EC_SUBTYPE_ROUTE_TARGET, 64500, 23, true),
+			bgp.NewValidationExtended(bgp.VALIDATION_STATE_VALID),
+		}),
+	})
+
+	// Search filters are parsed into strings
+	if !info.HasExtCommunity(api.ExtCommunity{"rt", "64500", "23"}) {
+		t.Error("Expected route target to match:", info.ExtCommunities)
+	}
+	if !info.HasExtCommunity(api.ExtCommunity{"ov", "0", "valid"}) {
+		t.Error("Expected validation state to match:", info.ExtCommunities)
+	}
+}
@@ -0,0 +1,147 @@
+package bird
+
+// Client for the BIRD control socket
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Line of a reply from the control socket.
+// Continuation lines have the code of the
+// preceding line.
+type Line struct {
+	Code int
+	Text string
+}
+
+type Reply []Line
+
+type Client struct {
+	Socket string
+
+	timeout time.Duration
+}
+
+func NewClient(socket string, timeout time.Duration) *Client {
+	return &Client{
+		Socket:  socket,
+		timeout: timeout,
+	}
+}
+
+// Reply codes >= 8000 are errors: 8xxx are run-time
+// errors, 9xxx are parse errors.
+func isErrorCode(code int) bool {
+	return code >= 8000
+}
+
+// Read a reply from the control socket until the
+// final line is received. A final line has a space
+// after the reply code instead of a dash.
+func readReply(reader *bufio.Reader) (Reply, error) {
+	reply := Reply{}
+	code := 0
+	for {
+		text, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		text = strings.TrimRight(text, "\r\n")
+
+		// Continuation line
+		if strings.HasPrefix(text, " ") {
+			reply = append(reply, Line{Code: code, Text: text[1:]})
+			continue
+		}
+
+		if len(text) < 5 {
+			return nil, fmt.Errorf("Unexpected reply from bird: %s", text)
+		}
+
+		code, err = strconv.Atoi(text[:4])
+		if err != nil {
+			return nil, fmt.Errorf("Unexpected reply from bird: %s", text)
+		}
+
+		line := Line{Code: code, Text: text[5:]}
+		if isErrorCode(code) {
+			return nil, fmt.Errorf("bird: %s", line.Text)
+		}
+
+		final := text[4] == ' '
+		if !final || line.Text != "" {
+			reply = append(reply, line)
+		}
+		if final {
+			return reply, nil
+		}
+	}
+}
+
+// Query the control socket
+func (self *Client) Query(command string) (Reply, error) {
+	return self.QueryContext(context.Background(), command)
+}
+
+// Query the control socket, the query is
+// aborted when the context is done.
+func (self *Client) QueryContext(ctx context.Context, command string) (Reply, error) {
+	dialer := &net.Dialer{Timeout: self.timeout}
+	conn, err := dialer.DialContext(ctx, "unix", self.Socket)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// Use the earlier of the timeout and the deadline of the context
+	deadline := time.Time{}
+	if self.timeout > 0 {
+		deadline = time.Now().Add(self.timeout)
+	}
+	if ctxDeadline, ok := ctx.Deadline(); ok &&
+		(deadline.IsZero() || ctxDeadline.Before(deadline)) {
+		deadline = ctxDeadline
+	}
+	if !deadline.IsZero() {
+		conn.SetDeadline(deadline)
+	}
+
+	// Closing the connection unblocks reading the reply
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	reply, err := self.query(conn, command)
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return reply, err
+}
+
+// Send a command and read the reply
+func (self *Client) query(conn net.Conn, command string) (Reply, error) {
+	reader := bufio.NewReader(conn)
+
+	// Read the welcome message
+	if _, err := readReply(reader); err != nil {
+		return nil, err
+	}
+
+	if _, err := fmt.Fprintf(conn, "%s\n", command); err != nil {
+		return nil, err
+	}
+
+	return readReply(reader)
+}
@@ -0,0 +1,69 @@
+package bird
+
+import (
+	"github.com/alice-lg/alice-lg/backend/sources"
+
+	"github.com/go-ini/ini"
+
+	"fmt"
+)
+
+type Config struct {
+	Id   string
+	Name string
+
+	// Path to the BIRD control socket
+	Socket string `ini:"socket"`
+
+	// Timezone of the times in the BIRD output
+	Timezone string `ini:"timezone"`
+
+	Type               string `ini:"type"`
+	PeerTablePrefix    string `ini:"peer_table_prefix"`
+	PipeProtocolPrefix string `ini:"pipe_protocol_prefix"`
+
+	// The master table(s), for BIRD 2 this is
+	// usually: master4, master6
+	MasterTables []string `ini:"master_tables"`
+
+	// Timeout in seconds for queries
+	Timeout int `ini:"timeout"`
+
+	// CacheTtl is the time in seconds a response is
+	// considered to be valid.
+	CacheTtl int `ini:"cache_ttl"`
+}
+
+func init() {
+	sources.Register(&sources.Backend{
+		Name:      "bird",
+		Configure: configure,
+		New: func(config interface{}) sources.Source {
+			return NewBird(config.(Config))
+		},
+	})
+}
+
+// Parse the config section of a BIRD source
+func configure(
+	base sources.Definition,
+	section *ini.Section,
+) ([]*sources.Definition, error) {
+	c := Config{
+		Id:   base.Id,
+		Name: base.Name,
+
+		Timezone:           "UTC",
+		PeerTablePrefix:    "T",
+		PipeProtocolPrefix: "M",
+	}
+	section.MapTo(&c)
+
+	if c.Type != "single_table" &&
+		c.Type != "multi_table" {
+		return nil, fmt.Errorf("unknown bird type: %s", c.Type)
+	}
+
+	base.Config = c
+	return []*sources.Definition{&base}, nil
+}
@@ -0,0 +1,42 @@
+package bird
+
+import (
+	"context"
+
+	"github.com/alice-lg/alice-lg/backend/api"
+)
+
+// Requests without a context are made with
+// the background context.
+
+func (self *Bird) Status() (*api.StatusResponse, error) {
+	return self.StatusContext(context.Background())
+}
+
+func (self *Bird) Neighbours() (*api.NeighboursResponse, error) {
+	return self.NeighboursContext(context.Background())
+}
+
+func (self *Bird) NeighboursStatus() (*api.NeighboursStatusResponse, error) {
+	return self.NeighboursStatusContext(context.Background())
+}
+
+func (self *Bird) Routes(neighborId string) (*api.RoutesResponse, error) {
+	return self.RoutesContext(context.Background(), neighborId)
+}
+
+func (self *Bird) RoutesReceived(neighborId string) (*api.RoutesResponse, error) {
+	return self.RoutesReceivedContext(context.Background(), neighborId)
+}
+
+func (self *Bird) RoutesFiltered(neighborId string) (*api.RoutesResponse, error) {
+	return self.RoutesFilteredContext(context.Background(), neighborId)
+}
+
+func (self *Bird) RoutesNotExported(neighborId string) (*api.RoutesResponse, error) {
+	return self.RoutesNotExportedContext(context.Background(), neighborId)
+}
+
+func (self *Bird) AllRoutes() (*api.RoutesResponse, error) {
+	return self.AllRoutesContext(context.Background())
+}
@@ -0,0 +1,486 @@
+package bird
+
+// Parsers for the BIRD CLI output
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alice-lg/alice-lg/backend/api"
+)
+
+// BIRD reply codes
+const (
+	CODE_VERSION        = 1000
+	CODE_PROTOCOL       = 1002
+	CODE_PROTOCOL_INFO  = 1006
+	CODE_ROUTE          = 1007
+	CODE_ROUTE_DETAILS  = 1008
+	CODE_ROUTER_ID      = 1011
+	CODE_ROUTE_ATTRS    = 1012
+	CODE_STATUS_MESSAGE = 13
+)
+
+var (
+	REGEX_ROUTE_COUNTS = regexp.MustCompile(`(\d+) (imported|filtered|exported|preferred)`)
+	REGEX_TUPLE        = regexp.MustCompile(`\(([^)]*)\)`)
+	REGEX_ROUTE_SOURCE = regexp.MustCompile(`\[([^\]]+)\]`)
+	REGEX_ROUTE_PREF   = regexp.MustCompile(`\((\d+)(?:/(\d+))?\)`)
+	REGEX_VIA          = regexp.MustCompile(`via (\S+)(?: on (\S+))?`)
+	REGEX_TIME         = regexp.MustCompile(`^\d{2}:\d{2}:\d{2}`)
+)
+
+// Time formats used by BIRD
+var timeLayouts = []string{
+	"2006-01-02 15:04:05.999",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"15:04:05.999",
+	"15:04:05",
+}
+
+// Parse a server time in one of the known layouts.
+// Times without a date are assumed to be from today.
+func parseServerTime(value string, timezone string) time.Time {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	value = strings.TrimSpace(value)
+	for _, layout := range timeLayouts {
+		t, err := time.ParseInLocation(layout, value, loc)
+		if err != nil {
+			continue
+		}
+		if t.Year() == 0 {
+			now := time.Now().In(loc)
+			t = time.Date(
+				now.Year(), now.Month(), now.Day(),
+				t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+		}
+		return t.UTC()
+	}
+	return time.Time{}
+}
+
+// Parse the output of show status
+func parseStatus(reply Reply, config Config) api.Status {
+	status := api.Status{
+		Backend:  "bird",
+		Version:  "unknown",
+		Message:  "unknown",
+		RouterId: "unknown",
+	}
+
+	for _, line := range reply {
+		text := strings.TrimSpace(line.Text)
+		switch {
+		case line.Code == CODE_VERSION:
+			status.Version = strings.TrimPrefix(text, "BIRD ")
+		case line.Code == CODE_STATUS_MESSAGE:
+			status.Message = text
+		case strings.HasPrefix(text, "Router ID is "):
+			status.RouterId = strings.TrimPrefix(text, "Router ID is ")
+		case strings.HasPrefix(text, "Current server time is "):
+			status.ServerTime = parseServerTime(
+				strings.TrimPrefix(text, "Current server time is "),
+				config.Timezone)
+		case strings.HasPrefix(text, "Last reboot on "):
+			status.LastReboot = parseServerTime(
+				strings.TrimPrefix(text, "Last reboot on "),
+				config.Timezone)
+		case strings.HasPrefix(text, "Last reconfiguration on "):
+			status.LastReconfig = parseServerTime(
+				strings.TrimPrefix(text, "Last reconfiguration on "),
+				config.Timezone)
+		}
+	}
+
+	return status
+}
+
+// A Protocol as listed in show protocols
+type Protocol struct {
+	Name  string
+	Proto string
+	Table string
+	State string
+	Since time.Time
+	Info  string
+
+	Routes  map[string]int
+	Details map[string]interface{}
+}
+
+type Protocols map[string]*Protocol
+
+// Make a details key from a protocol info key,
+// e.g. "Neighbor address" -> "neighbor_address"
+func detailsKey(key string) string {
+	return strings.Replace(strings.ToLower(strings.TrimSpace(key)), " ", "_", -1)
+}
+
+// Parse the protocol summary line:
+//
+//	name proto table state since info
+//
+// The since column can contain a date and a time.
+func parseProtocolLine(text string, config Config) *Protocol {
+	fields := strings.Fields(text)
+	if len(fields) < 4 {
+		return nil
+	}
+
+	protocol := &Protocol{
+		Name:    fields[0],
+		Proto:   fields[1],
+		Table:   fields[2],
+		State:   fields[3],
+		Routes:  map[string]int{},
+		Details: map[string]interface{}{},
+	}
+
+	rest := fields[4:]
+	if len(rest) > 0 {
+		since := rest[0]
+		rest = rest[1:]
+		if len(rest) > 0 && REGEX_TIME.MatchString(rest[0]) {
+			since += " " + rest[0]
+			rest = rest[1:]
+		}
+		protocol.Since = parseServerTime(since, config.Timezone)
+	}
+	protocol.Info = strings.Join(rest, " ")
+
+	return protocol
+}
+
+// Parse the output of show protocols [all]
+func parseProtocols(reply Reply, config Config) Protocols {
+	protocols := Protocols{}
+
+	var protocol *Protocol
+	for _, line := range reply {
+		switch line.Code {
+		case CODE_PROTOCOL:
+			protocol = parseProtocolLine(line.Text, config)
+			if protocol != nil {
+				protocols[protocol.Name] = protocol
+			}
+		case CODE_PROTOCOL_INFO:
+			if protocol == nil {
+				continue
+			}
+			kv := strings.SplitN(line.Text, ":", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			key := detailsKey(kv[0])
+			value := strings.TrimSpace(kv[1])
+
+			switch key {
+			case "routes":
+				// Sum up the routes of all channels
+				for _, m := range REGEX_ROUTE_COUNTS.FindAllStringSubmatch(value, -1) {
+					count, _ := strconv.Atoi(m[1])
+					protocol.Routes[m[2]] += count
+				}
+			case "table":
+				// BIRD 2 has the table in the channel
+				if protocol.Table == "---" {
+					protocol.Table = value
+				}
+			}
+
+			// Keep the first value, e.g. the state
+			// of the protocol before the channel state.
+			if _, ok := protocol.Details[key]; !ok {
+				protocol.Details[key] = value
+			}
+		}
+	}
+
+	return protocols
+}
+
+// Get a protocol details value as string
+func (self *Protocol) detail(key string) string {
+	value, _ := self.Details[key].(string)
+	return value
+}
+
+// Check if the protocol is a BGP session
+func (self *Protocol) IsBgp() bool {
+	return self.Proto == "BGP"
+}
+
+// Check if the protocol is a pipe
+func (self *Protocol) IsPipe() bool {
+	return self.Proto == "Pipe"
+}
+
+// Make a neighbor from a BGP protocol
+func (self *Protocol) Neighbour(config Config) *api.Neighbour {
+	asn, _ := strconv.Atoi(self.detail("neighbor_as"))
+
+	details := make(map[string]interface{}, len(self.Details)+4)
+	for k, v := range self.Details {
+		details[k] = v
+	}
+	details["protocol"] = self.Name
+	details["bird_protocol"] = self.Proto
+	details["table"] = self.Table
+	details["info"] = self.Info
+
+	description := self.detail("description")
+	if description == "" {
+		description = "no description"
+	}
+
+	uptime := time.Duration(0)
+	if !self.Since.IsZero() {
+		uptime = time.Since(self.Since)
+	}
+
+	return &api.Neighbour{
+		Id: self.Name,
+
+		Address:     self.detail("neighbor_address"),
+		Asn:         asn,
+		State:       strings.ToLower(self.State),
+		Description: description,
+
+		RoutesReceived:  self.Routes["imported"] + self.Routes["filtered"],
+		RoutesAccepted:  self.Routes["imported"],
+		RoutesFiltered:  self.Routes["filtered"],
+		RoutesExported:  self.Routes["exported"],
+		RoutesPreferred: self.Routes["preferred"],
+
+		Uptime:    uptime,
+		LastError: self.detail("last_error"),
+
+		RouteServerId: config.Id,
+
+		Details: details,
+	}
+}
+
+// Parse a list of tuples like "(65000,1) (65001,23)"
+func parseTuples(value string) [][]string {
+	tuples := [][]string{}
+	for _, m := range REGEX_TUPLE.FindAllStringSubmatch(value, -1) {
+		tuple := []string{}
+		for _, v := range strings.Split(m[1], ",") {
+			tuple = append(tuple, strings.TrimSpace(v))
+		}
+		tuples = append(tuples, tuple)
+	}
+	return tuples
+}
+
+// Parse communities like "(65000,1) (65001,23)"
+func parseCommunities(value string) api.Communities {
+	communities := api.Communities{}
+	for _, tuple := range parseTuples(value) {
+		community := api.Community{}
+		for _, v := range tuple {
+			val, err := strconv.Atoi(v)
+			if err != nil {
+				community = nil
+				break
+			}
+			community = append(community, val)
+		}
+		if community != nil {
+			communities = append(communities, community)
+		}
+	}
+	return communities
+}
+
+// Parse extended communities like "(rt, 65000, 100)"
+func parseExtCommunities(value string) api.ExtCommunities {
+	communities := api.ExtCommunities{}
+	for _, tuple := range parseTuples(value) {
+		if len(tuple) != 3 {
+			continue
+		}
+		communities = append(communities, api.ExtCommunity{
+			tuple[0], tuple[1], tuple[2],
+		})
+	}
+	return communities
+}
+
+// Parse an as path
+func parseAsPath(value string) []int {
+	path := []int{}
+	value = strings.NewReplacer("{", " ", "}", " ").Replace(value)
+	for _, token := range strings.Fields(value) {
+		asn, err := strconv.Atoi(token)
+		if err != nil {
+			continue
+		}
+		path = append(path, asn)
+	}
+	return path
+}
+
+// Parse the route line:
+//
+//	network [via gw on iface] [proto since from x] * (pref) [AS123i]
+//
+// The network is omitted for further paths of a prefix.
+func parseRouteLine(text string, network string, config Config) *api.Route {
+	if !strings.HasPrefix(text, " ") && !strings.HasPrefix(text, "\t") {
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			return nil
+		}
+		network = fields[0]
+	}
+
+	route := &api.Route{
+		Id:        network,
+		Network:   network,
+		Interface: "unknown interface",
+		Gateway:   "unknown gateway",
+		Metric:    -1,
+		Type:      []string{},
+		Details: map[string]interface{}{
+			"network": network,
+		},
+	}
+
+	if m := REGEX_ROUTE_SOURCE.FindStringSubmatch(text); m != nil {
+		tokens := strings.Fields(m[1])
+		route.NeighbourId = tokens[0]
+		route.Details["from_protocol"] = tokens[0]
+
+		// The remaining tokens are the age and an optional
+		// "from <address>" for routes learned from another peer.
+		since := []string{}
+		for i := 1; i < len(tokens); i++ {
+			if tokens[i] == "from" && i+1 < len(tokens) {
+				route.Details["learnt_from"] = tokens[i+1]
+				break
+			}
+			since = append(since, tokens[i])
+		}
+		age := parseServerTime(strings.Join(since, " "), config.Timezone)
+		if !age.IsZero() {
+			route.Age = time.Since(age)
+		}
+
+		rest := text[strings.Index(text, m[0])+len(m[0]):]
+		route.Primary = strings.HasPrefix(strings.TrimSpace(rest), "*")
+	}
+
+	if m := REGEX_ROUTE_PREF.FindStringSubmatch(text); m != nil {
+		route.Metric, _ = strconv.Atoi(m[1])
+	}
+
+	// BIRD 1.x has the nexthop in the route line
+	parseVia(route, text)
+
+	return route
+}
+
+// Parse "via <gateway> on <interface>"
+func parseVia(route *api.Route, text string) {
+	m := REGEX_VIA.FindStringSubmatch(text)
+	if m == nil {
+		return
+	}
+	route.Gateway = m[1]
+	if m[2] != "" {
+		route.Interface = m[2]
+	}
+}
+
+// Parse a route attribute line, e.g. "BGP.origin: IGP"
+func parseRouteAttribute(route *api.Route, text string) {
+	kv := strings.SplitN(strings.TrimSpace(text), ":", 2)
+	if len(kv) != 2 {
+		return
+	}
+	key := strings.TrimSpace(kv[0])
+	value := strings.TrimSpace(kv[1])
+
+	switch key {
+	case "Type":
+		route.Type = strings.Fields(value)
+	case "BGP.origin":
+		route.Bgp.Origin = value
+	case "BGP.as_path":
+		route.Bgp.AsPath = parseAsPath(value)
+	case "BGP.next_hop":
+		// The next hop might be followed by a link local address
+		if hops := strings.Fields(value); len(hops) > 0 {
+			route.Bgp.NextHop = hops[0]
+		}
+	case "BGP.local_pref":
+		route.Bgp.LocalPref, _ = strconv.Atoi(value)
+	case "BGP.med":
+		route.Bgp.Med, _ = strconv.Atoi(value)
+	case "BGP.community":
+		route.Bgp.Communities = parseCommunities(value)
+	case "BGP.large_community":
+		route.Bgp.LargeCommunities = parseCommunities(value)
+	case "BGP.ext_community":
+		route.Bgp.ExtCommunities = parseExtCommunities(value)
+	default:
+		route.Details[key] = value
+	}
+}
+
+// Parse the output of show route ... all
+func parseRoutes(reply Reply, config Config) api.Routes {
+	routes := api.Routes{}
+
+	var route *api.Route
+	network := ""
+	for _, line := range reply {
+		switch line.Code {
+		case CODE_ROUTE:
+			// BIRD 2 prints the table name
+			if strings.HasPrefix(line.Text, "Table ") {
+				continue
+			}
+			route = parseRouteLine(line.Text, network, config)
+			if route == nil {
+				continue
+			}
+			network = route.Network
+			routes = append(routes, route)
+		case CODE_ROUTE_DETAILS:
+			if route != nil {
+				parseVia(route, line.Text)
+			}
+		case CODE_ROUTE_ATTRS:
+			if route != nil {
+				parseRouteAttribute(route, line.Text)
+			}
+		}
+	}
+
+	for _, route := range routes {
+		if route.Bgp.Communities == nil {
+			route.Bgp.Communities = api.Communities{}
+		}
+		if route.Bgp.LargeCommunities == nil {
+			route.Bgp.LargeCommunities = api.Communities{}
+		}
+		if route.Bgp.ExtCommunities == nil {
+			route.Bgp.ExtCommunities = api.ExtCommunities{}
+		}
+	}
+
+	// Sort routes for deterministic ordering
+	sort.Sort(routes)
+
+	return routes
+}
@@ -0,0 +1,147 @@
+package bird
+
+import (
+	"bufio"
+	"os"
+	"testing"
+
+	"github.com/alice-lg/alice-lg/backend/api"
+)
+
+// Load a recorded BIRD CLI transcript
+func loadTestReply(t *testing.T, filename string) Reply {
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	reply, err := readReply(bufio.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reply
+}
+
+func TestParseStatus(t *testing.T) {
+	reply := loadTestReply(t, "testdata/show_status.txt")
+	status := parseStatus(reply, Config{Timezone: "UTC"})
+
+	if status.Version != "2.0.7" {
+		t.Error("Unexpected version:", status.Version)
+	}
+	if status.RouterId != "192.0.2.254" {
+		t.Error("Unexpected router id:", status.RouterId)
+	}
+	if status.Message != "Daemon is up and running" {
+		t.Error("Unexpected message:", status.Message)
+	}
+	if status.LastReboot.Hour() != 9 {
+		t.Error("Unexpected last reboot:", status.LastReboot)
+	}
+}
+
+func TestParseProtocols(t *testing.T) {
+	reply := loadTestReply(t, "testdata/show_protocols_all.txt")
+	protocols := parseProtocols(reply, Config{Timezone: "UTC"})
+
+	if len(protocols) != 4 {
+		t.Fatal("Expected 4 protocols, got:", len(protocols))
+	}
+
+	p := protocols["pb_0001_as65001"]
+	if !p.IsBgp() {
+		t.Error("Expected a BGP protocol, got:", p.Proto)
+	}
+	if p.Table != "t_0001_as65001" {
+		t.Error("Unexpected table:", p.Table)
+	}
+	if p.Routes["imported"] != 3 || p.Routes["filtered"] != 1 {
+		t.Error("Unexpected routes:", p.Routes)
+	}
+
+	n := p.Neighbour(Config{Id: "rs1"})
+	if n.Address != "192.0.2.1" || n.Asn != 65001 {
+		t.Error("Unexpected neighbor:", n.Address, n.Asn)
+	}
+	if n.State != "up" || n.RoutesReceived != 4 {
+		t.Error("Unexpected neighbor state:", n.State, n.RoutesReceived)
+	}
+
+	n = protocols["pb_0002_as65002"].Neighbour(Config{Id: "rs1"})
+	if n.State != "start" || n.LastError != "Socket: Connection refused" {
+		t.Error("Unexpected neighbor state:", n.State, n.LastError)
+	}
+
+	if !protocols["m_0001_as65001"].IsPipe() {
+		t.Error("Expected pipe protocol")
+	}
+}
+
+func TestParseRoutes(t *testing.T) {
+	reply := loadTestReply(t, "testdata/show_route_protocol.txt")
+	routes := parseRoutes(reply, Config{Timezone: "UTC"})
+
+	if len(routes) != 3 {
+		t.Fatal("Expected 3 routes, got:", len(routes))
+	}
+
+	r := routes[0]
+	if r.Network != "198.51.100.0/24" || r.NeighbourId != "pb_0001_as65001" {
+		t.Error("Unexpected route:", r.Network, r.NeighbourId)
+	}
+	if r.Gateway != "192.0.2.1" || r.Interface != "eth0" {
+		t.Error("Unexpected gateway:", r.Gateway, r.Interface)
+	}
+	if !r.Primary || r.Metric != 100 {
+		t.Error("Unexpected primary / metric:", r.Primary, r.Metric)
+	}
+	if len(r.Bgp.AsPath) != 2 || r.Bgp.LocalPref != 100 {
+		t.Error("Unexpected bgp info:", r.Bgp)
+	}
+	if len(r.Bgp.Communities) != 2 || len(r.Bgp.LargeCommunities) != 1 {
+		t.Error("Unexpected communities:", r.Bgp.Communities, r.Bgp.LargeCommunities)
+	}
+	if len(r.Bgp.ExtCommunities) != 1 ||
+		r.Bgp.ExtCommunities[0].String() != "rt:65000:100" {
+		t.Error("Unexpected ext communities:", r.Bgp.ExtCommunities)
+	}
+
+	// Second path of a prefix
+	for _, r := range routes[1:] {
+		if r.Network != "203.0.113.0/24" {
+			t.Error("Unexpected network:", r.Network)
+		}
+		if r.Gateway == "192.0.2.3" {
+			if r.Primary || r.Details["learnt_from"] != "192.0.2.3" {
+				t.Error("Unexpected alternative path:", r)
+			}
+		}
+	}
+}
+
+func TestParseRouteAttributeEmpty(t *testing.T) {
+	route := &api.Route{Details: make(api.Details)}
+	parseRouteAttribute(route, "\tBGP.next_hop: ")
+	if route.Bgp.NextHop != "" {
+		t.Error("Unexpected next hop:", route.Bgp.NextHop)
+	}
+
+	parseRouteAttribute(route, "\tBGP.next_hop: 2001:db8::1 fe80::1")
+	if route.Bgp.NextHop != "2001:db8::1" {
+		t.Error("Unexpected next hop:", route.Bgp.NextHop)
+	}
+}
+
+func TestReadReplyError(t *testing.T) {
+	file, err := os.Open("testdata/no_such_protocol.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	_, err = readReply(bufio.NewReader(file))
+	if err == nil {
+		t.Error("Expected an error")
+	}
+}
@@ -0,0 +1,519 @@
+package bird
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alice-lg/alice-lg/backend/api"
+	"github.com/alice-lg/alice-lg/backend/caches"
+	"github.com/alice-lg/alice-lg/backend/sources"
+)
+
+const (
+	DEFAULT_TIMEOUT   = 30
+	DEFAULT_CACHE_TTL = 300
+)
+
+// Protocol and table names are passed to the
+// BIRD CLI and must be valid symbols.
+var REGEX_SYMBOL = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+
+type Bird struct {
+	config Config
+	client *Client
+
+	// Caches: Neighbors
+	neighborsCache *caches.NeighborsCache
+
+	// Caches: Routes
+	routesRequiredCache    *caches.RoutesCache
+	routesNotExportedCache *caches.RoutesCache
+
+	// Mutices:
+	routesFetchMutex *sources.LockMap
+}
+
+func NewBird(config Config) *Bird {
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = DEFAULT_TIMEOUT
+	}
+	if config.CacheTtl == 0 {
+		config.CacheTtl = DEFAULT_CACHE_TTL
+	}
+	if config.Timezone == "" {
+		config.Timezone = "UTC"
+	}
+	if len(config.MasterTables) == 0 {
+		config.MasterTables = []string{"master"}
+	}
+
+	client := NewClient(config.Socket, time.Duration(timeout)*time.Second)
+
+	// Cache settings:
+	// TODO: Maybe read from config file
+	neighborsCacheDisable := false
+
+	routesCacheDisabled := false
+	routesCacheMaxSize := 128
+
+	// Initialize caches
+	neighborsCache := caches.NewNeighborsCache(neighborsCacheDisable)
+	routesRequiredCache := caches.NewRoutesCache(
+		routesCacheDisabled, routesCacheMaxSize)
+	routesNotExportedCache := caches.NewRoutesCache(
+		routesCacheDisabled, routesCacheMaxSize)
+
+	return &Bird{
+		config: config,
+		client: client,
+
+		neighborsCache: neighborsCache,
+
+		routesRequiredCache:    routesRequiredCache,
+		routesNotExportedCache: routesNotExportedCache,
+
+		routesFetchMutex: sources.NewLockMap(),
+	}
+}
+
+// Make api status for responses. The ttl
+// is derived from the configured cache ttl.
+func (self *Bird) makeApiStatus() api.ApiStatus {
+	now := time.Now().UTC()
+	return api.ApiStatus{
+		Version:         "bird",
+		ResultFromCache: false,
+		Ttl:             now.Add(time.Duration(self.config.CacheTtl) * time.Second),
+		CacheStatus: api.CacheStatus{
+			CachedAt: now,
+			OrigTtl:  self.config.CacheTtl,
+		},
+	}
+}
+
+func (self *Bird) isMultiTable() bool {
+	return self.config.Type == "multi_table"
+}
+
+// Get the name of the pipe to the master table
+// for a peer table
+func (self *Bird) getMasterPipeName(table string) string {
+	ptPrefix := self.config.PeerTablePrefix
+	if strings.HasPrefix(table, ptPrefix) {
+		return self.config.PipeProtocolPrefix + table[len(ptPrefix):]
+	}
+	return ""
+}
+
+// Query the control socket
+func (self *Bird) query(ctx context.Context, command string, args ...interface{}) (Reply, error) {
+	return self.client.QueryContext(ctx, fmt.Sprintf(command, args...))
+}
+
+// Get all protocols
+func (self *Bird) fetchProtocols(ctx context.Context) (Protocols, error) {
+	reply, err := self.query(ctx, "show protocols all")
+	if err != nil {
+		return nil, err
+	}
+	return parseProtocols(reply, self.config), nil
+}
+
+// Get a single BGP protocol. The neighbor id is
+// the name of the protocol.
+func (self *Bird) fetchProtocol(ctx context.Context, neighborId string) (*Protocol, error) {
+	if !REGEX_SYMBOL.MatchString(neighborId) {
+		return nil, &sources.NotFoundError{Resource: "neighbor", Id: neighborId}
+	}
+	reply, err := self.query(ctx, "show protocols all %s", neighborId)
+	if err != nil {
+		return nil, err
+	}
+	protocol, ok := parseProtocols(reply, self.config)[neighborId]
+	if !ok || !protocol.IsBgp() {
+		return nil, &sources.NotFoundError{Resource: "neighbor", Id: neighborId}
+	}
+	return protocol, nil
+}
+
+func (self *Bird) ExpireCaches() int {
+	count := self.routesRequiredCache.Expire()
+	count += self.routesNotExportedCache.Expire()
+
+	return count
+}
+
+func (self *Bird) Capabilities() api.SourceCapabilities {
+	return api.SourceCapabilities{
+		RoutesReceived:    true,
+		RoutesFiltered:    true,
+		RoutesNotExported: true,
+		Status:            true,
+		LiveLookup:        false,
+		NeighboursStatus:  true,
+	}
+}
+
+func (self *Bird) StatusContext(ctx context.Context) (*api.StatusResponse, error) {
+	reply, err := self.query(ctx, "show status")
+	if err != nil {
+		return nil, err
+	}
+
+	response := &api.StatusResponse{
+		Api:    self.makeApiStatus(),
+		Status: parseStatus(reply, self.config),
+	}
+
+	return response, nil
+}
+
+// Count the routes filtered by the pipes in a multi table
+// setup. This is only possible for peer tables with
+// a single neighbor.
+func (self *Bird) pipeFilteredCounts(protocols Protocols) map[string]int {
+	tables := map[string][]*Protocol{}
+	for _, protocol := range protocols {
+		if protocol.IsBgp() {
+			tables[protocol.Table] = append(tables[protocol.Table], protocol)
+		}
+	}
+
+	filtered := map[string]int{}
+	for table, peers := range tables {
+		pipe, ok := protocols[self.getMasterPipeName(table)]
+		if !ok || len(peers) != 1 {
+			continue
+		}
+		peer := peers[0]
+		if !isProtocolUp(peer.State) {
+			continue
+		}
+		count := peer.Routes["imported"] - pipe.Routes["imported"]
+		if count > 0 {
+			filtered[peer.Name] = count
+		}
+	}
+
+	return filtered
+}
+
+// Get neighbors from protocols
+func (self *Bird) NeighboursContext(ctx context.Context) (*api.NeighboursResponse, error) {
+	// Check if we hit the cache
+	response := self.neighborsCache.Get()
+	if response != nil {
+		return response, nil
+	}
+
+	protocols, err := self.fetchProtocols(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pipeFiltered := map[string]int{}
+	if self.isMultiTable() {
+		pipeFiltered = self.pipeFilteredCounts(protocols)
+	}
+
+	neighbours := api.Neighbours{}
+	for _, protocol := range protocols {
+		if !protocol.IsBgp() {
+			continue
+		}
+		neighbour := protocol.Neighbour(self.config)
+		if count, ok := pipeFiltered[neighbour.Id]; ok {
+			neighbour.RoutesAccepted -= count
+			neighbour.RoutesFiltered += count
+		}
+		neighbours = append(neighbours, neighbour)
+	}
+	sort.Sort(neighbours)
+
+	response = &api.NeighboursResponse{
+		Api:        self.makeApiStatus(),
+		Neighbours: neighbours,
+	}
+
+	// Cache result
+	self.neighborsCache.Set(response)
+
+	return response, nil
+}
+
+// Get live neighbor status
+func (self *Bird) NeighboursStatusContext(ctx context.Context) (*api.NeighboursStatusResponse, error) {
+	reply, err := self.query(ctx, "show protocols")
+	if err != nil {
+		return nil, err
+	}
+
+	neighbours := api.NeighboursStatus{}
+	for _, protocol := range parseProtocols(reply, self.config) {
+		if !protocol.IsBgp() {
+			continue
+		}
+		since := time.Duration(0)
+		if !protocol.Since.IsZero() {
+			since = time.Since(protocol.Since)
+		}
+		neighbours = append(neighbours, &api.NeighbourStatus{
+			Id:    protocol.Name,
+			State: strings.ToLower(protocol.State),
+			Since: since,
+		})
+	}
+	sort.Sort(neighbours)
+
+	response := &api.NeighboursStatusResponse{
+		Api:        self.makeApiStatus(),
+		Neighbours: neighbours,
+	}
+
+	return response, nil
+}
+
+// Get the routes filtered by the pipe from a peer table
+// to the master table.
+func (self *Bird) fetchPipeFilteredRoutes(ctx context.Context, protocol *Protocol) (api.Routes, error) {
+	pipe := self.getMasterPipeName(protocol.Table)
+	if pipe == "" || !REGEX_SYMBOL.MatchString(protocol.Table) {
+		return api.Routes{}, nil
+	}
+
+	reply, err := self.query(ctx,
+		"show route table %s noexport %s protocol %s all",
+		protocol.Table, pipe, protocol.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseRoutes(reply, self.config), nil
+}
+
+// Get the routes received from the neighbor and split
+// them into imported and filtered routes.
+func (self *Bird) fetchRequiredRoutes(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
+	// Allow only one concurrent request for this neighbor
+	// to our backend server.
+	self.routesFetchMutex.Lock(neighborId)
+	defer self.routesFetchMutex.Unlock(neighborId)
+
+	// Check if we have a cache hit
+	response := self.routesRequiredCache.Get(neighborId)
+	if response != nil {
+		return response, nil
+	}
+
+	protocol, err := self.fetchProtocol(ctx, neighborId)
+	if err != nil {
+		return nil, err
+	}
+
+	tableSelector := ""
+	if self.isMultiTable() && REGEX_SYMBOL.MatchString(protocol.Table) {
+		tableSelector = "table " + protocol.Table + " "
+	}
+
+	reply, err := self.query(ctx, "show route %sprotocol %s all",
+		tableSelector, neighborId)
+	if err != nil {
+		return nil, err
+	}
+	imported := parseRoutes(reply, self.config)
+
+	reply, err = self.query(ctx, "show route %sfiltered protocol %s all",
+		tableSelector, neighborId)
+	if err != nil {
+		return nil, err
+	}
+	filtered := parseRoutes(reply, self.config)
+
+	// In a multi table setup, routes can be filtered
+	// by the pipe to the master table.
+	if self.isMultiTable() {
+		pipeFiltered, err := self.fetchPipeFilteredRoutes(ctx, protocol)
+		if err != nil {
+			return nil, err
+		}
+		imported = filterRoutesByDuplicates(imported, pipeFiltered)
+		filtered = append(filtered, pipeFiltered...)
+		sort.Sort(filtered)
+	}
+
+	response = &api.RoutesResponse{
+		Api:      self.makeApiStatus(),
+		Imported: imported,
+		Filtered: filtered,
+	}
+
+	// Cache result
+	self.routesRequiredCache.Set(neighborId, response)
+
+	return response, nil
+}
+
+// Get the routes not exported to the neighbor
+func (self *Bird) fetchNotExportedRoutes(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
+	// Check if we have a cache hit
+	response := self.routesNotExportedCache.Get(neighborId)
+	if response != nil {
+		return response, nil
+	}
+
+	protocol, err := self.fetchProtocol(ctx, neighborId)
+	if err != nil {
+		return nil, err
+	}
+
+	// In a multi table setup the routes are not exported
+	// from the master table by the pipe.
+	exporter := protocol.Name
+	if self.isMultiTable() {
+		exporter = self.getMasterPipeName(protocol.Table)
+		if !REGEX_SYMBOL.MatchString(exporter) {
+			return nil, fmt.Errorf("No pipe for neighbor: %s", neighborId)
+		}
+	}
+
+	reply, err := self.query(ctx, "show route noexport %s all", exporter)
+	if err != nil {
+		return nil, err
+	}
+
+	response = &api.RoutesResponse{
+		Api:         self.makeApiStatus(),
+		NotExported: parseRoutes(reply, self.config),
+	}
+
+	// Cache result
+	self.routesNotExportedCache.Set(neighborId, response)
+
+	return response, nil
+}
+
+// Get filtered, accepted and not exported routes
+func (self *Bird) RoutesContext(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
+	required, err := self.fetchRequiredRoutes(ctx, neighborId)
+	if err != nil {
+		return nil, err
+	}
+
+	notExported, err := self.fetchNotExportedRoutes(ctx, neighborId)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &api.RoutesResponse{
+		Api:         required.Api,
+		Imported:    required.Imported,
+		Filtered:    required.Filtered,
+		NotExported: notExported.NotExported,
+	}
+
+	return response, nil
+}
+
+// Get all received routes
+func (self *Bird) RoutesReceivedContext(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
+	routes, err := self.fetchRequiredRoutes(ctx, neighborId)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &api.RoutesResponse{
+		Api:      routes.Api,
+		Imported: routes.Imported,
+	}
+
+	return response, nil
+}
+
+// Get all filtered routes
+func (self *Bird) RoutesFilteredContext(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
+	routes, err := self.fetchRequiredRoutes(ctx, neighborId)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &api.RoutesResponse{
+		Api:      routes.Api,
+		Filtered: routes.Filtered,
+	}
+
+	return response, nil
+}
+
+// Get all not exported routes
+func (self *Bird) RoutesNotExportedContext(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
+	return self.fetchNotExportedRoutes(ctx, neighborId)
+}
+
+/*
+AllRoutes returns the routes of the master tables
+and all filtered routes.
+In a multi table setup, the filtered routes are
+collected from the peer tables.
+*/
+func (self *Bird) AllRoutesContext(ctx context.Context) (*api.RoutesResponse, error) {
+	response := &api.RoutesResponse{
+		Api:      self.makeApiStatus(),
+		Imported: api.Routes{},
+		Filtered: api.Routes{},
+	}
+
+	for _, table := range self.config.MasterTables {
+		reply, err := self.query(ctx, "show route table %s all", table)
+		if err != nil {
+			return nil, err
+		}
+		response.Imported = append(response.Imported,
+			parseRoutes(reply, self.config)...)
+
+		if self.isMultiTable() {
+			continue
+		}
+
+		reply, err = self.query(ctx, "show route table %s filtered all", table)
+		if err != nil {
+			return nil, err
+		}
+		response.Filtered = append(response.Filtered,
+			parseRoutes(reply, self.config)...)
+	}
+
+	if self.isMultiTable() {
+		protocols, err := self.fetchProtocols(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, protocol := range protocols {
+			if !protocol.IsBgp() || !isProtocolUp(protocol.State) {
+				continue
+			}
+			routes, err := self.fetchRequiredRoutes(ctx, protocol.Name)
+			if err != nil {
+				// The request was canceled, the result is incomplete
+				if ctxErr := ctx.Err(); ctxErr != nil {
+					return nil, ctxErr
+				}
+				log.Println("Could not fetch filtered routes for",
+					protocol.Name, "on", self.config.Name, ":", err)
+				continue
+			}
+			response.Filtered = append(response.Filtered, routes.Filtered...)
+		}
+	}
+
+	// Sort routes for deterministic ordering
+	sort.Sort(response.Imported)
+	sort.Sort(response.Filtered)
+
+	return response, nil
+}
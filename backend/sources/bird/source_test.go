@@ -0,0 +1,204 @@
+package bird
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Serve recorded transcripts on a fake control socket
+func serveTestSocket(t *testing.T, transcripts map[string]string) (string, func()) {
+	dir, err := ioutil.TempDir("", "bird")
+	if err != nil {
+		t.Fatal(err)
+	}
+	socket := filepath.Join(dir, "bird.ctl")
+
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				fmt.Fprintf(conn, "0001 BIRD 2.0.7 ready.\n")
+
+				command, err := bufio.NewReader(conn).ReadString('\n')
+				if err != nil {
+					return
+				}
+				filename, ok := transcripts[strings.TrimSpace(command)]
+				if !ok {
+					fmt.Fprintf(conn, "9001 syntax error\n")
+					return
+				}
+				data, err := ioutil.ReadFile(filename)
+				if err != nil {
+					fmt.Fprintf(conn, "8001 %s\n", err)
+					return
+				}
+				conn.Write(data)
+			}(conn)
+		}
+	}()
+
+	return socket, func() {
+		listener.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestMultiTableRoutes(t *testing.T) {
+	socket, stop := serveTestSocket(t, map[string]string{
+		"show status":                        "testdata/show_status.txt",
+		"show protocols all":                 "testdata/show_protocols_all.txt",
+		"show protocols all pb_0001_as65001": "testdata/show_protocols_all.txt",
+		"show protocols all pb_0003":         "testdata/no_such_protocol.txt",
+		"show route table t_0001_as65001 protocol pb_0001_as65001 all":                         "testdata/show_route_protocol.txt",
+		"show route table t_0001_as65001 filtered protocol pb_0001_as65001 all":                "testdata/show_route_filtered_protocol.txt",
+		"show route table t_0001_as65001 noexport m_0001_as65001 protocol pb_0001_as65001 all": "testdata/show_route_pipe_filtered.txt",
+	})
+	defer stop()
+
+	bird := NewBird(Config{
+		Id:                 "rs1",
+		Socket:             socket,
+		Type:               "multi_table",
+		PeerTablePrefix:    "t",
+		PipeProtocolPrefix: "m",
+	})
+
+	status, err := bird.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Status.RouterId != "192.0.2.254" {
+		t.Error("Unexpected router id:", status.Status.RouterId)
+	}
+
+	neighbours, err := bird.Neighbours()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(neighbours.Neighbours) != 2 {
+		t.Fatal("Expected 2 neighbours, got:", len(neighbours.Neighbours))
+	}
+	// The pipe imported 2 of 3 routes
+	n := neighbours.Neighbours[0]
+	if n.RoutesAccepted != 2 || n.RoutesFiltered != 2 {
+		t.Error("Unexpected route counts:", n.RoutesAccepted, n.RoutesFiltered)
+	}
+
+	routes, err := bird.RoutesReceived("pb_0001_as65001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes.Imported) != 1 {
+		t.Error("Expected 1 imported route, got:", len(routes.Imported))
+	}
+
+	routes, err = bird.RoutesFiltered("pb_0001_as65001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes.Filtered) != 2 {
+		t.Error("Expected 2 filtered routes, got:", len(routes.Filtered))
+	}
+
+	if _, err := bird.RoutesReceived("pb_0003"); err == nil {
+		t.Error("Expected an error for an unknown neighbor")
+	}
+	if _, err := bird.RoutesReceived("pb_0001; show route"); err == nil {
+		t.Error("Expected an error for an invalid neighbor")
+	}
+}
+
+// A control socket never answering
+func serveHangingSocket(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "bird")
+	if err != nil {
+		t.Fatal(err)
+	}
+	socket := filepath.Join(dir, "bird.ctl")
+
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		conns := []net.Conn{}
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				for _, conn := range conns {
+					conn.Close()
+				}
+				close(done)
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+
+	return socket, func() {
+		listener.Close()
+		<-done
+		os.RemoveAll(dir)
+	}
+}
+
+func TestStatusCanceled(t *testing.T) {
+	socket, stop := serveHangingSocket(t)
+	defer stop()
+
+	source := NewBird(Config{Socket: socket})
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+
+	t0 := time.Now()
+	_, err := source.StatusContext(ctx)
+	if err != context.Canceled {
+		t.Error("Expected the query to be canceled, got:", err)
+	}
+	if time.Since(t0) > time.Second {
+		t.Error("Expected the query to return when canceled")
+	}
+}
+
+func TestStatusDeadline(t *testing.T) {
+	socket, stop := serveHangingSocket(t)
+	defer stop()
+
+	source := NewBird(Config{Socket: socket})
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	t0 := time.Now()
+	_, err := source.StatusContext(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("Expected the deadline to be exceeded, got:", err)
+	}
+	if time.Since(t0) > time.Second {
+		t.Error("Expected the query to return at the deadline")
+	}
+}
@@ -0,0 +1,33 @@
+package bird
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/alice-lg/alice-lg/backend/api"
+)
+
+func isProtocolUp(protocol string) bool {
+	protocol = strings.ToLower(protocol)
+	return protocol == "up"
+}
+
+// Remove routes which are contained in filterRoutes
+func filterRoutesByDuplicates(routes api.Routes, filterRoutes api.Routes) api.Routes {
+	filter := make(map[string]bool, len(filterRoutes))
+	for _, route := range filterRoutes {
+		filter[route.Id] = true
+	}
+
+	result := make(api.Routes, 0, len(routes))
+	for _, route := range routes {
+		if !filter[route.Id] {
+			result = append(result, route)
+		}
+	}
+
+	// Sort routes for deterministic ordering
+	sort.Sort(result)
+
+	return result
+}
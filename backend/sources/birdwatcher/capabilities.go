@@ -0,0 +1,234 @@
+package birdwatcher
+
+import (
+	"github.com/alice-lg/alice-lg/backend/api"
+	"github.com/alice-lg/alice-lg/backend/sources"
+
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+Birdwatcher capabilities:
+
+Besides the status and the protocols, the sources rely on
+optional birdwatcher modules, e.g. routes_peer or
+routes_noexport. When a module is not enabled, birdwatcher
+does not serve its endpoint and responds with 404.
+
+The endpoints of these modules are probed when the source
+starts and every probe_interval afterwards, with parameters not
+matching any protocol or table. Requests for a view relying
+on a module which is not available fail with an
+UnsupportedError instead of a generic error.
+
+A module is considered available until a probe shows
+otherwise, e.g. when birdwatcher is not reachable.
+*/
+
+const (
+	DEFAULT_PROBE_INTERVAL = 600
+
+	// Parameters of the probe requests
+	PROBE_PROTOCOL = "alice_probe"
+	PROBE_ADDRESS  = "192.0.2.1"
+	PROBE_PREFIX   = "192.0.2.0/24"
+
+	// Derived capability: All routes can be fetched
+	// for the routes store.
+	CAPABILITY_ROUTES_DUMP = "routes_dump"
+)
+
+// An optional birdwatcher module
+type module struct {
+	name     string
+	endpoint string
+}
+
+var SINGLE_TABLE_MODULES = []module{
+	{"protocols_short", "/protocols/short"},
+	{"routes_protocol", "/routes/protocol/" + PROBE_PROTOCOL},
+	{"routes_filtered", "/routes/filtered/" + PROBE_PROTOCOL},
+	{"routes_noexport", "/routes/noexport/" + PROBE_PROTOCOL},
+	{"routes_prefixed", "/routes/prefix?prefix=" + PROBE_PREFIX},
+	{"routes_table", "/routes/table/" + PROBE_PROTOCOL},
+	{"routes_table_filtered", "/routes/table/" + PROBE_PROTOCOL + "/filtered"},
+}
+
+var MULTI_TABLE_MODULES = []module{
+	{"protocols_short", "/protocols/short"},
+	{"routes_peer", "/routes/peer/" + PROBE_ADDRESS},
+	{"routes_filtered", "/routes/filtered/" + PROBE_PROTOCOL},
+	{"routes_noexport", "/routes/noexport/" + PROBE_PROTOCOL},
+	{"routes_prefixed", "/routes/prefix?prefix=" + PROBE_PREFIX},
+	{"routes_table", "/routes/table/" + PROBE_PROTOCOL},
+	{"routes_pipe_filtered",
+		"/routes/pipe/filtered/?table=" + PROBE_PROTOCOL + "&pipe=" + PROBE_PROTOCOL},
+	{"routes_pipe_filtered_count",
+		"/routes/pipe/filtered/count?table=" + PROBE_PROTOCOL +
+			"&pipe=" + PROBE_PROTOCOL + "&address=" + PROBE_ADDRESS},
+}
+
+// The modules required for dumping all routes
+var ROUTES_DUMP_MODULES = map[string][]string{
+	"single_table": {"routes_table", "routes_table_filtered"},
+	"multi_table":  {"routes_table", "routes_filtered", "routes_pipe_filtered"},
+}
+
+// The capabilities of a birdwatcher
+type ModuleCapabilities struct {
+	// The version of birdwatcher
+	Version string
+
+	// Available modules by name
+	Modules map[string]bool
+
+	CheckedAt time.Time
+}
+
+// Get the modules probed by the source
+func (self *GenericBirdwatcher) probedModules() []module {
+	if self.config.Type == "multi_table" {
+		return MULTI_TABLE_MODULES
+	}
+	return SINGLE_TABLE_MODULES
+}
+
+/*
+Probe the status and the modules. The result of a probe
+failing for another reason than a missing endpoint is
+kept from the previous probe.
+*/
+func (self *GenericBirdwatcher) probeCapabilities() *ModuleCapabilities {
+	previous := self.moduleCapabilities()
+	capabilities := &ModuleCapabilities{
+		Modules:   make(map[string]bool),
+		CheckedAt: time.Now().UTC(),
+	}
+	if previous != nil {
+		capabilities.Version = previous.Version
+	}
+
+	bird, err := self.client.GetJson("/status")
+	if err == nil {
+		info, err := decodeApiInfo(bird)
+		if err == nil {
+			capabilities.Version = info.Version
+		}
+	}
+	if err != nil {
+		log.Println("Birdwatcher", self.config.Id, "status probe failed:", err)
+	}
+
+	for _, m := range self.probedModules() {
+		_, err := self.client.GetJsonTimeout(self.client.statusTimeout, m.endpoint)
+		if statusErr, ok := err.(*StatusError); ok && statusErr.StatusCode == 404 {
+			capabilities.Modules[m.name] = false
+			continue
+		}
+		if _, ok := err.(temporaryError); ok && previous != nil {
+			if available, ok := previous.Modules[m.name]; ok {
+				capabilities.Modules[m.name] = available
+				continue
+			}
+		}
+		capabilities.Modules[m.name] = true
+	}
+
+	// All routes can be fetched if all required modules are available
+	dump := true
+	for _, name := range ROUTES_DUMP_MODULES[self.config.Type] {
+		dump = dump && capabilities.Modules[name]
+	}
+	capabilities.Modules[CAPABILITY_ROUTES_DUMP] = dump
+
+	self.capabilitiesLock.Lock()
+	self.capabilities = capabilities
+	self.capabilitiesLock.Unlock()
+
+	if missing := capabilities.missing(); len(missing) > 0 {
+		log.Println("Birdwatcher", self.config.Id,
+			"version", capabilities.Version,
+			"is missing modules:", strings.Join(missing, ", "))
+	}
+
+	return capabilities
+}
+
+// Probe the capabilities now and periodically afterwards,
+// if a probe interval is configured.
+func (self *GenericBirdwatcher) startProbing() {
+	self.probeCapabilities()
+	if self.config.ProbeInterval <= 0 {
+		return
+	}
+
+	interval := time.Duration(self.config.ProbeInterval) * time.Second
+	for {
+		time.Sleep(interval)
+		self.probeCapabilities()
+	}
+}
+
+// Get the probed capabilities, nil if not yet probed
+func (self *GenericBirdwatcher) moduleCapabilities() *ModuleCapabilities {
+	self.capabilitiesLock.RLock()
+	defer self.capabilitiesLock.RUnlock()
+	return self.capabilities
+}
+
+// Fail if a module is known to be not available
+func (self *GenericBirdwatcher) requireModule(name string) error {
+	capabilities := self.moduleCapabilities()
+	if capabilities == nil {
+		return nil
+	}
+	if available, ok := capabilities.Modules[name]; ok && !available {
+		return &sources.UnsupportedError{
+			Feature: name,
+			Reason:  "module is not enabled in birdwatcher " + capabilities.Version,
+		}
+	}
+	return nil
+}
+
+// Check if a module is not known to be missing
+func (self *GenericBirdwatcher) hasModule(name string) bool {
+	return self.requireModule(name) == nil
+}
+
+// Get the views supported by the available modules
+func (self *GenericBirdwatcher) Capabilities() api.SourceCapabilities {
+	received := self.hasModule("routes_protocol")
+	filtered := self.hasModule("routes_filtered")
+	if self.config.Type == "multi_table" {
+		received = self.hasModule("routes_peer")
+		filtered = filtered && self.hasModule("routes_pipe_filtered")
+	}
+
+	capabilities := api.SourceCapabilities{
+		RoutesReceived:    received,
+		RoutesFiltered:    filtered,
+		RoutesNotExported: self.hasModule("routes_noexport"),
+		Status:            true,
+		NeighboursStatus:  self.hasModule("protocols_short"),
+	}
+	if modules := self.moduleCapabilities(); modules != nil {
+		capabilities.Version = modules.Version
+	}
+	return capabilities
+}
+
+// Get the names of the modules not available
+func (self *ModuleCapabilities) missing() []string {
+	missing := []string{}
+	for name, available := range self.Modules {
+		if !available {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
@@ -0,0 +1,141 @@
+package birdwatcher
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alice-lg/alice-lg/backend/sources"
+)
+
+// A birdwatcher without the noexport and pipe modules
+func testModulesServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			if strings.HasPrefix(req.URL.Path, "/routes/noexport") ||
+				strings.HasPrefix(req.URL.Path, "/routes/pipe") {
+				http.NotFound(w, req)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if req.URL.Path == "/status" {
+				w.Write([]byte(`{"api": {"Version": "2.0.7"}, "status": {}}`))
+				return
+			}
+			w.Write([]byte(`{"api": {"Version": "2.0.7"}, "routes": []}`))
+		}))
+}
+
+func TestProbeCapabilities(t *testing.T) {
+	server := testModulesServer()
+	defer server.Close()
+
+	bw := testMultiTableBirdwatcher(server.URL, 1)
+	if err := bw.requireModule("routes_noexport"); err != nil {
+		t.Error("Expected modules to be available before probing, got:", err)
+	}
+
+	capabilities := bw.probeCapabilities()
+	if capabilities.Version != "2.0.7" {
+		t.Error("Unexpected version:", capabilities.Version)
+	}
+
+	expected := map[string]bool{
+		"protocols_short":            true,
+		"routes_peer":                true,
+		"routes_filtered":            true,
+		"routes_noexport":            false,
+		"routes_prefixed":            true,
+		"routes_table":               true,
+		"routes_pipe_filtered":       false,
+		"routes_pipe_filtered_count": false,
+		CAPABILITY_ROUTES_DUMP:       false,
+	}
+	for name, available := range expected {
+		if capabilities.Modules[name] != available {
+			t.Error("Expected", name, "available:", available)
+		}
+	}
+
+	// Unsupported views fail with a clear error
+	_, err := bw.RoutesNotExported("ID1_AS65001_10.0.0.1")
+	unsupported, ok := err.(*sources.UnsupportedError)
+	if !ok {
+		t.Fatal("Expected unsupported error, got:", err)
+	}
+	if unsupported.Feature != "routes_noexport" {
+		t.Error("Unexpected feature:", unsupported.Feature)
+	}
+
+	if _, err := bw.AllRoutes(); err == nil {
+		t.Error("Expected routes dump to be unsupported")
+	}
+}
+
+func TestProbeCapabilitiesUnreachable(t *testing.T) {
+	server := testModulesServer()
+	bw := testMultiTableBirdwatcher(server.URL, 1)
+	bw.probeCapabilities()
+	server.Close()
+
+	// The previous result is kept
+	capabilities := bw.probeCapabilities()
+	if capabilities.Version != "2.0.7" {
+		t.Error("Expected previous version, got:", capabilities.Version)
+	}
+	if capabilities.Modules["routes_noexport"] ||
+		!capabilities.Modules["routes_peer"] {
+		t.Error("Expected previous modules, got:", capabilities.Modules)
+	}
+}
+
+func TestCapabilitiesViews(t *testing.T) {
+	server := testModulesServer()
+	defer server.Close()
+
+	bw := testMultiTableBirdwatcher(server.URL, 1)
+	if !bw.Capabilities().RoutesNotExported {
+		t.Error("Expected all views to be supported before probing")
+	}
+
+	bw.probeCapabilities()
+	capabilities := bw.Capabilities()
+	if !capabilities.RoutesReceived || !capabilities.Status ||
+		!capabilities.NeighboursStatus {
+		t.Error("Expected views of available modules, got:", capabilities)
+	}
+	// The filtered routes require the pipe module
+	if capabilities.RoutesFiltered || capabilities.RoutesNotExported {
+		t.Error("Expected views of missing modules, got:", capabilities)
+	}
+	if capabilities.LiveLookup {
+		t.Error("Expected lookups to use the routes store")
+	}
+	if capabilities.Version != "2.0.7" {
+		t.Error("Expected the birdwatcher version, got:", capabilities.Version)
+	}
+}
+
+func TestProbeOnStartup(t *testing.T) {
+	server := testModulesServer()
+	defer server.Close()
+
+	// Probed once, even without a probe interval
+	bw := NewBirdwatcher(Config{
+		Id:            "rs1",
+		Api:           server.URL,
+		Type:          "multi_table",
+		ProbeInterval: 0,
+	})
+	deadline := time.Now().Add(5 * time.Second)
+	for bw.Capabilities().Version == "" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	capabilities := bw.Capabilities()
+	if capabilities.Version != "2.0.7" || capabilities.RoutesNotExported {
+		t.Error("Expected the probed capabilities, got:", capabilities)
+	}
+}
@@ -3,42 +3,313 @@ package birdwatcher
 // Http Birdwatcher Client
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"log"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/alice-lg/alice-lg/backend/api"
+	"github.com/alice-lg/alice-lg/backend/sources"
+)
+
+/*
+The client is shared by all requests of a source, keeping
+the connections to the birdwatcher open.
+
+Requests for routes (/routes/...) can take a long time,
+these use the dump timeout. All other requests, e.g. the
+status or protocols, use the status timeout.
+
+Failed requests are retried with an exponential backoff,
+if the error is temporary: A network error or a response
+with the status 502, 503 or 504. Dumping routes is not
+retried when it timed out, as the birdwatcher is likely
+busy with the same request.
+
+The api might be a list of redundant birdwatchers. A request
+failing with a temporary error is made with the next one,
+before it is retried.
+*/
+
+const (
+	DEFAULT_STATUS_TIMEOUT = 10
+	DEFAULT_DUMP_TIMEOUT   = 300
+	DEFAULT_RETRIES        = 2
+
+	RETRY_BACKOFF_MIN = 500 * time.Millisecond
+
+	MAX_IDLE_CONNS_PER_HOST = 8
 )
 
 type ClientResponse map[string]interface{}
 
 type Client struct {
-	Api string
+	endpoints *sources.Endpoints
+
+	http *http.Client
+	err  error
+
+	username string
+	password string
+
+	statusTimeout time.Duration
+	dumpTimeout   time.Duration
+	retries       int
+
+	stats api.RequestStats
+	sync.Mutex
+}
+
+// Make the TLS config for the client
+func NewTLSConfig(config Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: config.TLSInsecureSkipVerify,
+	}
+
+	if config.TLSCA != "" {
+		pem, err := ioutil.ReadFile(config.TLSCA)
+		if err != nil {
+			return nil, fmt.Errorf("could not read tls ca: %s", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in tls ca: %s", config.TLSCA)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if config.TLSCert != "" || config.TLSKey != "" {
+		cert, err := tls.LoadX509KeyPair(config.TLSCert, config.TLSKey)
+		if err != nil {
+			return nil, fmt.Errorf("could not load tls client cert: %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
 }
 
-func NewClient(api string) *Client {
+func NewClient(config Config) *Client {
 	client := &Client{
-		Api: api,
+		endpoints: sources.NewEndpoints(
+			sources.ParseEndpoints(config.Api), config.EndpointSelection),
+
+		username: config.Username,
+		password: config.Password,
+
+		statusTimeout: time.Duration(config.StatusTimeout) * time.Second,
+		dumpTimeout:   time.Duration(config.DumpTimeout) * time.Second,
+		retries:       config.Retries,
+	}
+	if client.statusTimeout == 0 {
+		client.statusTimeout = DEFAULT_STATUS_TIMEOUT * time.Second
 	}
+	if client.dumpTimeout == 0 {
+		client.dumpTimeout = DEFAULT_DUMP_TIMEOUT * time.Second
+	}
+	if client.retries < 0 {
+		client.retries = 0
+	}
+
+	tlsConfig, err := NewTLSConfig(config)
+	if err != nil {
+		// Requests will fail with this error
+		log.Println("Birdwatcher", config.Id, err)
+		client.err = err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	transport.MaxIdleConnsPerHost = MAX_IDLE_CONNS_PER_HOST
+
+	client.http = &http.Client{
+		Transport: transport,
+	}
+
 	return client
 }
 
-// Make API request, parse response and return map or error
-func (self *Client) Get(client *http.Client, url string) (ClientResponse, error) {
-	res, err := client.Get(url)
+// Get the timeout for an endpoint
+func (self *Client) timeout(endpoint string) time.Duration {
+	if strings.HasPrefix(endpoint, "/routes") {
+		return self.dumpTimeout
+	}
+	return self.statusTimeout
+}
+
+// Check if dumping routes timed out
+func (self *Client) isDumpTimeout(endpoint string, err error) bool {
+	return strings.HasPrefix(endpoint, "/routes") &&
+		errors.Is(err, context.DeadlineExceeded)
+}
+
+// A temporary error can be retried
+type temporaryError struct {
+	err error
+}
+
+func (self temporaryError) Error() string {
+	return self.err.Error()
+}
+
+func (self temporaryError) Unwrap() error {
+	return self.err
+}
+
+// An unexpected response status, e.g. 404 if
+// the module of an endpoint is not enabled
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (self *StatusError) Error() string {
+	return "unexpected response: " + self.Status
+}
+
+// Make a single API request
+func (self *Client) do(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return ClientResponse{}, err
+		return nil, err
+	}
+	if self.username != "" || self.password != "" {
+		req.SetBasicAuth(self.username, self.password)
 	}
 
-	// Read body
-	defer res.Body.Close()
-	payload, err := ioutil.ReadAll(res.Body)
+	res, err := self.http.Do(req)
 	if err != nil {
-		return ClientResponse{}, err
+		return nil, temporaryError{err}
+	}
+
+	switch res.StatusCode {
+	case http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		res.Body.Close()
+		return nil, temporaryError{
+			fmt.Errorf("unexpected response: %s", res.Status)}
+	}
+	// Birdwatcher responds with errors as json,
+	// these are handled by the parsers.
+	if res.StatusCode >= 400 && !strings.HasPrefix(
+		res.Header.Get("Content-Type"), "application/json") {
+		res.Body.Close()
+		return nil, &StatusError{
+			StatusCode: res.StatusCode,
+			Status:     res.Status,
+		}
+	}
+
+	return res, nil
+}
+
+// Make API request with a single birdwatcher and read the
+// response body. The response is nil if the request failed.
+func (self *Client) try(
+	parent context.Context,
+	timeout time.Duration,
+	url string,
+	read func(io.Reader) error,
+	retry bool,
+) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	t0 := time.Now()
+	res, err := self.do(ctx, url)
+	if err == nil {
+		err = read(res.Body)
+		res.Body.Close()
+	}
+	self.record(time.Since(t0), err, retry)
+
+	return res, err
+}
+
+/*
+Make API request and read the response body, fail over
+to the next birdwatcher and retry temporary errors.
+Reading the body is not retried.
+*/
+func (self *Client) stream(
+	parent context.Context,
+	timeout time.Duration,
+	endpoint string,
+	read func(io.Reader) error,
+) error {
+	if self.err != nil {
+		return self.err
+	}
+
+	backoff := RETRY_BACKOFF_MIN
+	retry := false
+	for attempt := 0; ; attempt++ {
+		var err error
+		for _, api := range self.endpoints.Order() {
+			var res *http.Response
+			res, err = self.try(parent, timeout, api+endpoint, read, retry)
+			retry = true
+
+			if parent.Err() != nil {
+				return err
+			}
+			if _, temporary := err.(temporaryError); res != nil || !temporary {
+				self.endpoints.Succeeded(api)
+				return err
+			}
+
+			self.endpoints.Failed(api)
+			if self.isDumpTimeout(endpoint, err) {
+				return err
+			}
+			if self.endpoints.Len() > 1 {
+				log.Println("Birdwatcher request", redactUrl(api+endpoint),
+					"failed:", err, "- failing over")
+			}
+		}
+
+		if attempt >= self.retries {
+			return err
+		}
+
+		log.Println("Birdwatcher request", endpoint, "failed:", err,
+			"- retrying in", backoff)
+		select {
+		case <-time.After(backoff):
+		case <-parent.Done():
+			return err
+		}
+		backoff *= 2
 	}
+}
 
-	// Decode json payload
+// Make API request, parse response and return map or error
+func (self *Client) Get(endpoint string, timeout time.Duration) (ClientResponse, error) {
+	return self.GetContext(context.Background(), endpoint, timeout)
+}
+
+// Make API request, which is canceled with the context
+func (self *Client) GetContext(
+	ctx context.Context,
+	endpoint string,
+	timeout time.Duration,
+) (ClientResponse, error) {
 	result := make(ClientResponse)
-	err = json.Unmarshal(payload, &result)
+	err := self.stream(ctx, timeout, endpoint, func(body io.Reader) error {
+		// Decode json payload
+		return json.NewDecoder(body).Decode(&result)
+	})
 	if err != nil {
 		return ClientResponse{}, err
 	}
@@ -48,16 +319,77 @@ func (self *Client) Get(client *http.Client, url string) (ClientResponse, error)
 
 // Make API request, parse response and return map or error
 func (self *Client) GetJson(endpoint string) (ClientResponse, error) {
-	client := &http.Client{}
-
-	return self.Get(client, self.Api + endpoint)
+	return self.Get(endpoint, self.timeout(endpoint))
 }
 
 // Make API request, parse response and return map or error
 func (self *Client) GetJsonTimeout(timeout time.Duration, endpoint string) (ClientResponse, error) {
-	client := &http.Client{
-		Timeout: timeout,
+	return self.Get(endpoint, timeout)
+}
+
+// Make API request, which is canceled with the context
+func (self *Client) GetJsonContext(ctx context.Context, endpoint string) (ClientResponse, error) {
+	return self.GetContext(ctx, endpoint, self.timeout(endpoint))
+}
+
+// Make API request, the response is decoded while reading
+func (self *Client) GetJsonStream(endpoint string, decode func(io.Reader) error) error {
+	return self.GetJsonStreamContext(context.Background(), endpoint, decode)
+}
+
+// Make API request, the response is decoded while reading
+// and the request is canceled with the context
+func (self *Client) GetJsonStreamContext(
+	ctx context.Context,
+	endpoint string,
+	decode func(io.Reader) error,
+) error {
+	return self.stream(ctx, self.timeout(endpoint), endpoint, decode)
+}
+
+// Record the latency and result of a request
+func (self *Client) record(latency time.Duration, err error, retry bool) {
+	self.Lock()
+	defer self.Unlock()
+
+	self.stats.Requests++
+	if retry {
+		self.stats.Retries++
+	}
+	if err != nil {
+		self.stats.Failures++
 	}
+	self.stats.LatencyLast = latency
+	if latency > self.stats.LatencyMax {
+		self.stats.LatencyMax = latency
+	}
+	self.stats.LatencyTotal += latency
+	self.stats.LatencyAvg = self.stats.LatencyTotal /
+		time.Duration(self.stats.Requests)
+}
 
-	return self.Get(client, self.Api + endpoint)
+// Get the request statistics
+func (self *Client) Stats() api.RequestStats {
+	self.Lock()
+	defer self.Unlock()
+	return self.stats
+}
+
+// Get the birdwatcher of the last successful request,
+// if there are redundant birdwatchers.
+func (self *Client) Endpoint() string {
+	if self.endpoints.Len() < 2 {
+		return ""
+	}
+	return redactUrl(self.endpoints.Active())
+}
+
+// Remove the credentials from an url
+func redactUrl(rawUrl string) string {
+	u, err := url.Parse(rawUrl)
+	if err != nil || u.User == nil {
+		return rawUrl
+	}
+	u.User = nil
+	return u.String()
 }
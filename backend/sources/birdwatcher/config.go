@@ -1,10 +1,23 @@
 package birdwatcher
 
+import (
+	"github.com/alice-lg/alice-lg/backend/sources"
+
+	"github.com/go-ini/ini"
+
+	"fmt"
+	"log"
+)
+
 type Config struct {
 	Id   string
 	Name string
 
-	Api             string `ini:"api"`
+	// The api might be a comma separated list of redundant
+	// birdwatchers, selected by priority or round robin.
+	Api               string `ini:"api"`
+	EndpointSelection string `ini:"endpoint_selection"`
+
 	Timezone        string `ini:"timezone"`
 	ServerTime      string `ini:"servertime"`
 	ServerTimeShort string `ini:"servertime_short"`
@@ -15,4 +28,92 @@ type Config struct {
 	PeerTablePrefix         string `ini:"peer_table_prefix"`
 	PipeProtocolPrefix      string `ini:"pipe_protocol_prefix"`
 	NeighborsRefreshTimeout int    `ini:"neighbors_refresh_timeout"`
+
+	// Timeouts in seconds for requests of the status
+	// and protocols, and for dumping routes
+	StatusTimeout int `ini:"status_timeout"`
+	DumpTimeout   int `ini:"dump_timeout"`
+
+	// Retries of requests failing with temporary errors
+	Retries int `ini:"retries"`
+
+	// Peer tables fetched concurrently by a multi table
+	// source, and the timeout in seconds for each table
+	Concurrency  int `ini:"concurrency"`
+	TableTimeout int `ini:"table_timeout"`
+
+	// Interval in seconds for probing the available modules
+	// again. The modules are probed on startup in any case.
+	ProbeInterval int `ini:"probe_interval"`
+
+	// Basic auth credentials
+	Username string `ini:"username"`
+	Password string `ini:"password"`
+
+	// TLS settings: A custom CA, a client
+	// certificate and key, and skipping verification
+	TLSCA                 string `ini:"tls_ca"`
+	TLSCert               string `ini:"tls_cert"`
+	TLSKey                string `ini:"tls_key"`
+	TLSInsecureSkipVerify bool   `ini:"tls_insecure_skip_verify"`
+}
+
+func init() {
+	sources.Register(&sources.Backend{
+		Name:      "birdwatcher",
+		Configure: configure,
+		New: func(config interface{}) sources.Source {
+			return NewBirdwatcher(config.(Config))
+		},
+	})
+}
+
+// Parse the config section of a birdwatcher source
+func configure(
+	base sources.Definition,
+	section *ini.Section,
+) ([]*sources.Definition, error) {
+	c := Config{
+		Id:   base.Id,
+		Name: base.Name,
+
+		Timezone:        "UTC",
+		ServerTime:      "2006-01-02T15:04:05.999999999Z07:00",
+		ServerTimeShort: "2006-01-02",
+		ServerTimeExt:   "Mon, 02 Jan 2006 15:04:05 -0700",
+
+		PeerTablePrefix:    "T",
+		PipeProtocolPrefix: "M",
+
+		StatusTimeout: DEFAULT_STATUS_TIMEOUT,
+		DumpTimeout:   DEFAULT_DUMP_TIMEOUT,
+		Retries:       DEFAULT_RETRIES,
+
+		Concurrency:  DEFAULT_CONCURRENCY,
+		TableTimeout: DEFAULT_TABLE_TIMEOUT,
+
+		ProbeInterval: DEFAULT_PROBE_INTERVAL,
+	}
+	section.MapTo(&c)
+
+	if c.Type != "single_table" &&
+		c.Type != "multi_table" {
+		return nil, fmt.Errorf("unknown birdwatcher type: %s", c.Type)
+	}
+	if len(sources.ParseEndpoints(c.Api)) == 0 {
+		return nil, fmt.Errorf("birdwatcher source without api")
+	}
+	if err := sources.ValidateEndpointSelection(c.EndpointSelection); err != nil {
+		return nil, err
+	}
+	if _, err := NewTLSConfig(c); err != nil {
+		return nil, err
+	}
+
+	log.Println("Adding birdwatcher source of type", c.Type,
+		"with peer_table_prefix", c.PeerTablePrefix,
+		"and pipe_protocol_prefix", c.PipeProtocolPrefix)
+
+	base.Config = c
+	return []*sources.Definition{&base}, nil
 }
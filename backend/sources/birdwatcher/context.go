@@ -0,0 +1,74 @@
+package birdwatcher
+
+import (
+	"context"
+
+	"github.com/alice-lg/alice-lg/backend/api"
+)
+
+// Requests without a context are made with
+// the background context.
+
+func (self *GenericBirdwatcher) Status() (*api.StatusResponse, error) {
+	return self.StatusContext(context.Background())
+}
+
+func (self *GenericBirdwatcher) NeighboursStatus() (*api.NeighboursStatusResponse, error) {
+	return self.NeighboursStatusContext(context.Background())
+}
+
+func (self *GenericBirdwatcher) LookupPrefix(prefix string) (*api.RoutesLookupResponse, error) {
+	return self.LookupPrefixContext(context.Background(), prefix)
+}
+
+// Single table birdwatcher
+
+func (self *SingleTableBirdwatcher) Neighbours() (*api.NeighboursResponse, error) {
+	return self.NeighboursContext(context.Background())
+}
+
+func (self *SingleTableBirdwatcher) Routes(neighbourId string) (*api.RoutesResponse, error) {
+	return self.RoutesContext(context.Background(), neighbourId)
+}
+
+func (self *SingleTableBirdwatcher) RoutesReceived(neighbourId string) (*api.RoutesResponse, error) {
+	return self.RoutesReceivedContext(context.Background(), neighbourId)
+}
+
+func (self *SingleTableBirdwatcher) RoutesFiltered(neighbourId string) (*api.RoutesResponse, error) {
+	return self.RoutesFilteredContext(context.Background(), neighbourId)
+}
+
+func (self *SingleTableBirdwatcher) RoutesNotExported(neighbourId string) (*api.RoutesResponse, error) {
+	return self.RoutesNotExportedContext(context.Background(), neighbourId)
+}
+
+func (self *SingleTableBirdwatcher) AllRoutes() (*api.RoutesResponse, error) {
+	return self.AllRoutesContext(context.Background())
+}
+
+// Multi table birdwatcher
+
+func (self *MultiTableBirdwatcher) Neighbours() (*api.NeighboursResponse, error) {
+	return self.NeighboursContext(context.Background())
+}
+
+func (self *MultiTableBirdwatcher) Routes(neighbourId string) (*api.RoutesResponse, error) {
+	return self.RoutesContext(context.Background(), neighbourId)
+}
+
+func (self *MultiTableBirdwatcher) RoutesReceived(neighbourId string) (*api.RoutesResponse, error) {
+	return self.RoutesReceivedContext(context.Background(), neighbourId)
+}
+
+func (self *MultiTableBirdwatcher) RoutesFiltered(neighbourId string) (*api.RoutesResponse, error) {
+	return self.RoutesFilteredContext(context.Background(), neighbourId)
+}
+
+func (self *MultiTableBirdwatcher) RoutesNotExported(neighbourId string) (*api.RoutesResponse, error) {
+	return self.RoutesNotExportedContext(context.Background(), neighbourId)
+}
+
+func (self *MultiTableBirdwatcher) AllRoutes() (*api.RoutesResponse, error) {
+	return self.AllRoutesContext(context.Background())
+}
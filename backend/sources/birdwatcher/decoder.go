@@ -0,0 +1,126 @@
+package birdwatcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/alice-lg/alice-lg/backend/api"
+)
+
+/*
+Route dumps from the birdwatcher can be very large. Instead
+of reading the entire response and decoding it into a map,
+the response is decoded while reading: The routes are
+decoded one by one and parsed into api.Routes.
+
+All other fields of the response, like the api status,
+are decoded as usual.
+*/
+
+// Keys of a birdwatcher response with a list of routes
+var ROUTES_KEYS = map[string]bool{
+	"routes":   true,
+	"imported": true,
+	"filtered": true,
+}
+
+// A RoutesResponse is a decoded birdwatcher response with routes
+type RoutesResponse struct {
+	// All other fields, e.g. api and ttl
+	Bird ClientResponse
+
+	// The routes by key
+	Routes map[string]api.Routes
+}
+
+// Get the sorted routes of a key
+func (self *RoutesResponse) SortedRoutes(key string) (api.Routes, error) {
+	routes, ok := self.Routes[key]
+	if !ok {
+		return api.Routes{}, fmt.Errorf("Routes response missing")
+	}
+	sort.Sort(routes)
+	return routes, nil
+}
+
+// Check a json delimiter token
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := token.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("unexpected token in response: %v", token)
+	}
+	return nil
+}
+
+// Decode a list of routes from the stream
+func decodeRoutesList(dec *json.Decoder, config Config) (api.Routes, error) {
+	routes := api.Routes{}
+
+	// The list might be null
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return routes, nil
+	}
+	if d, ok := token.(json.Delim); !ok || d != '[' {
+		return nil, fmt.Errorf("unexpected token in routes: %v", token)
+	}
+
+	for dec.More() {
+		rdata := make(map[string]interface{})
+		if err := dec.Decode(&rdata); err != nil {
+			return nil, err
+		}
+		routes = append(routes, parseRouteData(rdata, config))
+	}
+
+	return routes, expectDelim(dec, ']')
+}
+
+// Decode a birdwatcher response with routes
+func decodeRoutesResponse(r io.Reader, config Config) (*RoutesResponse, error) {
+	response := &RoutesResponse{
+		Bird:   make(ClientResponse),
+		Routes: make(map[string]api.Routes),
+	}
+
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
+	}
+
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected token in response: %v", token)
+		}
+
+		if ROUTES_KEYS[key] {
+			routes, err := decodeRoutesList(dec, config)
+			if err != nil {
+				return nil, err
+			}
+			response.Routes[key] = routes
+			continue
+		}
+
+		var value interface{}
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		response.Bird[key] = value
+	}
+
+	return response, expectDelim(dec, '}')
+}
@@ -0,0 +1,141 @@
+package birdwatcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_DecodeRoutesResponse(t *testing.T) {
+	config := Config{
+		Timezone:   "UTC",
+		ServerTime: "2006-01-02T15:04:05.999999999Z07:00",
+	}
+
+	response, err := decodeRoutesResponse(
+		strings.NewReader(API_RESPONSE_ROUTES), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	routes, err := response.SortedRoutes("routes")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Compare with the parser
+	bird, _ := parseTestResponse(API_RESPONSE_ROUTES)
+	expected, _ := parseRoutes(bird, config)
+	if !reflect.DeepEqual(routes, expected) {
+		t.Error("Expected routes to be equal to the parsed routes:", routes)
+	}
+
+	// The api status is decoded as well
+	apiStatus, err := parseApiStatus(response.Bird, config)
+	if err != nil {
+		t.Error(err)
+	}
+	if apiStatus.Version != "1.7.11" || apiStatus.Ttl.IsZero() {
+		t.Error("Unexpected api status:", apiStatus)
+	}
+}
+
+func Test_DecodeRoutesDump(t *testing.T) {
+	config := Config{Timezone: "UTC"}
+	payload := `{"filtered": null, "imported": [
+		{"network": "10.0.0.0/8", "bgp": {"as_path": ["64500"]}},
+		{"network": "10.23.0.0/16"}
+	], "error": "none"}`
+
+	response, err := decodeRoutesResponse(strings.NewReader(payload), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Routes["imported"]) != 2 {
+		t.Error("Expected 2 imported routes:", response.Routes["imported"])
+	}
+	if len(response.Routes["filtered"]) != 0 {
+		t.Error("Expected no filtered routes:", response.Routes["filtered"])
+	}
+	if response.Bird["error"] != "none" {
+		t.Error("Expected other fields to be decoded:", response.Bird)
+	}
+	if _, err := response.SortedRoutes("routes"); err == nil {
+		t.Error("Expected an error for missing routes")
+	}
+
+	// Invalid responses
+	invalid := []string{
+		`[]`,
+		`{"routes": {}}`,
+		`{"routes": [{"network": "10.0.0.0/8"}`,
+	}
+	for _, payload := range invalid {
+		_, err := decodeRoutesResponse(strings.NewReader(payload), config)
+		if err == nil {
+			t.Error("Expected an error for:", payload)
+		}
+	}
+}
+
+// Make a response with many routes
+func makeLargeRoutesResponse(n int) []byte {
+	bird, _ := parseTestResponse(API_RESPONSE_ROUTES)
+	template := bird["routes"].([]interface{})[0].(map[string]interface{})
+
+	routes := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		route := make(map[string]interface{}, len(template))
+		for k, v := range template {
+			route[k] = v
+		}
+		route["network"] = fmt.Sprintf("10.%d.%d.0/24", i/256%256, i%256)
+		routes = append(routes, route)
+	}
+	bird["routes"] = routes
+
+	payload, _ := json.Marshal(bird)
+	return payload
+}
+
+func BenchmarkParseRoutes(b *testing.B) {
+	config := Config{Timezone: "UTC"}
+	payload := makeLargeRoutesResponse(50000)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		// Like the client did: read the body, then decode
+		body, err := ioutil.ReadAll(bytes.NewReader(payload))
+		if err != nil {
+			b.Fatal(err)
+		}
+		bird := make(ClientResponse)
+		if err := json.Unmarshal(body, &bird); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := parseRoutes(bird, config); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeRoutesResponse(b *testing.B) {
+	config := Config{Timezone: "UTC"}
+	payload := makeLargeRoutesResponse(50000)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		response, err := decodeRoutesResponse(bytes.NewReader(payload), config)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := response.SortedRoutes("routes"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
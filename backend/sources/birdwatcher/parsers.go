@@ -36,8 +36,7 @@ func parseServerTime(value interface{}, layout, timezone string) (time.Time, err
 // Make api status from response:
 // The api status is always included in a birdwatcher response
 func parseApiStatus(bird ClientResponse, config Config) (api.ApiStatus, error) {
-	birdApi, ok := bird["api"].(map[string]interface{})
-	if !ok {
+	if _, ok := bird["api"]; !ok {
 		// Define error status
 		status := api.ApiStatus{
 			Version:         "unknown / error",
@@ -55,6 +54,11 @@ func parseApiStatus(bird ClientResponse, config Config) (api.ApiStatus, error) {
 		return status, fmt.Errorf(birdErr)
 	}
 
+	birdApi, err := decodeApiInfo(bird)
+	if err != nil {
+		return api.ApiStatus{}, err
+	}
+
 	// Parse TTL
 	ttl, err := parseServerTime(
 		bird["ttl"],
@@ -66,38 +70,20 @@ func parseApiStatus(bird ClientResponse, config Config) (api.ApiStatus, error) {
 	}
 
 	// Parse Cache Status
-	cacheStatus, _ := parseCacheStatus(birdApi, config)
+	cachedAt, _ := parseServerTime(
+		birdApi.CachedAt,
+		config.ServerTime,
+		config.Timezone,
+	)
 
 	status := api.ApiStatus{
-		Version:         birdApi["Version"].(string),
-		ResultFromCache: birdApi["result_from_cache"].(bool),
+		Version:         birdApi.Version,
+		ResultFromCache: birdApi.ResultFromCache,
 		Ttl:             ttl,
-		CacheStatus:     cacheStatus,
-	}
-
-	return status, nil
-}
-
-// Parse cache status from api response
-func parseCacheStatus(cacheStatus map[string]interface{}, config Config) (api.CacheStatus, error) {
-	cache, ok := cacheStatus["cache_status"].(map[string]interface{})
-	if !ok {
-		return api.CacheStatus{}, fmt.Errorf("Invalid Cache Status")
-	}
-
-	cachedAt, ok := cache["cached_at"].(map[string]interface{})
-	if !ok {
-		return api.CacheStatus{}, fmt.Errorf("Invalid Cache Status")
-	}
-
-	cachedAtTime, err := parseServerTime(cachedAt["date"], config.ServerTime, config.Timezone)
-	if err != nil {
-		return api.CacheStatus{}, err
-	}
-
-	status := api.CacheStatus{
-		CachedAt: cachedAtTime,
-		// We ommit OrigTTL for now...
+		CacheStatus: api.CacheStatus{
+			CachedAt: cachedAt,
+			// We ommit OrigTTL for now...
+		},
 	}
 
 	return status, nil
@@ -105,17 +91,20 @@ func parseCacheStatus(cacheStatus map[string]interface{}, config Config) (api.Ca
 
 // Parse birdwatcher status
 func parseBirdwatcherStatus(bird ClientResponse, config Config) (api.Status, error) {
-	birdStatus := bird["status"].(map[string]interface{})
+	birdStatus, err := decodeStatus(bird)
+	if err != nil {
+		return api.Status{}, err
+	}
 
 	// Get special fields
 	serverTime, _ := parseServerTime(
-		birdStatus["current_server"],
+		birdStatus.CurrentServer,
 		config.ServerTimeShort,
 		config.Timezone,
 	)
 
 	lastReboot, _ := parseServerTime(
-		birdStatus["last_reboot"],
+		birdStatus.LastReboot,
 		config.ServerTimeShort,
 		config.Timezone,
 	)
@@ -125,7 +114,7 @@ func parseBirdwatcherStatus(bird ClientResponse, config Config) (api.Status, err
 	}
 
 	lastReconfig, _ := parseServerTime(
-		birdStatus["last_reconfig"],
+		birdStatus.LastReconfig,
 		config.ServerTimeExt,
 		config.Timezone,
 	)
@@ -136,9 +125,9 @@ func parseBirdwatcherStatus(bird ClientResponse, config Config) (api.Status, err
 		LastReboot:   lastReboot,
 		LastReconfig: lastReconfig,
 		Backend:      "bird",
-		Version:      mustString(birdStatus["version"], "unknown"),
-		Message:      mustString(birdStatus["message"], "unknown"),
-		RouterId:     mustString(birdStatus["router_id"], "unknown"),
+		Version:      stringOr(birdStatus.Version, "unknown"),
+		Message:      stringOr(birdStatus.Message, "unknown"),
+		RouterId:     stringOr(birdStatus.RouterId, "unknown"),
 	}
 
 	return status, nil
@@ -152,49 +141,42 @@ func parseRelativeServerTime(uptime interface{}, config Config) time.Duration {
 
 // Parse neighbours response
 func parseNeighbours(bird ClientResponse, config Config) (api.Neighbours, error) {
+	protocols, err := decodeProtocols(bird)
+	if err != nil {
+		return nil, err
+	}
+	return makeNeighbours(protocols, config), nil
+}
+
+// Make neighbours from protocols
+func makeNeighbours(protocols Protocols, config Config) api.Neighbours {
 	rsId := config.Id
 	neighbours := api.Neighbours{}
-	protocols := bird["protocols"].(map[string]interface{})
-
-	// Iterate over protocols map:
-	for protocolId, proto := range protocols {
-		protocol := proto.(map[string]interface{})
-		routes := protocol["routes"].(map[string]interface{})
-
-		uptime := parseRelativeServerTime(protocol["state_changed"], config)
-		lastError := mustString(protocol["last_error"], "")
 
-		routesReceived := float64(0)
-		if routes != nil {
-			if _, ok := routes["imported"]; ok {
-				routesReceived = routesReceived + routes["imported"].(float64)
-			}
-			if _, ok := routes["filtered"]; ok {
-				routesReceived = routesReceived + routes["filtered"].(float64)
-			}
-		}
+	for protocolId, protocol := range protocols {
+		routes := protocol.Routes
+		uptime := parseRelativeServerTime(protocol.StateChanged, config)
 
 		neighbour := &api.Neighbour{
 			Id: protocolId,
 
-			Address: mustString(protocol["neighbor_address"], "error"),
-			Asn:     mustInt(protocol["neighbor_as"], 0),
-			State: strings.ToLower(
-				mustString(protocol["state"], "unknown")),
-			Description: mustString(protocol["description"], "no description"),
+			Address:     stringOr(protocol.NeighborAddress, "error"),
+			Asn:         protocol.NeighborAs,
+			State:       strings.ToLower(stringOr(protocol.State, "unknown")),
+			Description: stringOr(protocol.Description, "no description"),
 
-			RoutesReceived:  mustInt(routesReceived, 0),
-			RoutesAccepted:  mustInt(routes["imported"], 0),
-			RoutesFiltered:  mustInt(routes["filtered"], 0),
-			RoutesExported:  mustInt(routes["exported"], 0), //TODO protocol_exported?
-			RoutesPreferred: mustInt(routes["preferred"], 0),
+			RoutesReceived:  routes.Imported + routes.Filtered,
+			RoutesAccepted:  routes.Imported,
+			RoutesFiltered:  routes.Filtered,
+			RoutesExported:  routes.Exported, //TODO protocol_exported?
+			RoutesPreferred: routes.Preferred,
 
 			Uptime:    uptime,
-			LastError: lastError,
+			LastError: protocol.LastError,
 
 			RouteServerId: rsId,
 
-			Details: protocol,
+			Details: protocol.Details,
 		}
 
 		neighbours = append(neighbours, neighbour)
@@ -202,23 +184,23 @@ func parseNeighbours(bird ClientResponse, config Config) (api.Neighbours, error)
 
 	sort.Sort(neighbours)
 
-	return neighbours, nil
+	return neighbours
 }
 
 // Parse neighbours response
 func parseNeighboursShort(bird ClientResponse, config Config) (api.NeighboursStatus, error) {
-	neighbours := api.NeighboursStatus{}
-	protocols := bird["protocols"].(map[string]interface{})
-
-	// Iterate over protocols map:
-	for protocolId, proto := range protocols {
-		protocol := proto.(map[string]interface{})
+	protocols, err := decodeProtocolsShort(bird)
+	if err != nil {
+		return nil, err
+	}
 
-		uptime := parseRelativeServerTime(protocol["since"], config)
+	neighbours := api.NeighboursStatus{}
+	for _, protocol := range protocols {
+		uptime := parseRelativeServerTime(protocol.Since, config)
 
 		neighbour := &api.NeighbourStatus{
-			Id:    protocolId,
-			State: mustString(protocol["state"], "unknown"),
+			Id:    protocol.Id,
+			State: stringOr(protocol.State, "unknown"),
 			Since: uptime,
 		}
 
@@ -269,12 +251,24 @@ func parseBgpCommunities(data interface{}) []api.Community {
 	}
 
 	for _, c := range ldata {
-		cdata := c.([]interface{})
+		cdata, ok := c.([]interface{})
+		if !ok {
+			log.Println("Ignoring malformed community:", c)
+			continue
+		}
 		community := api.Community{}
 		for _, cinfo := range cdata {
-			community = append(community, int(cinfo.(float64)))
+			value, ok := cinfo.(float64)
+			if !ok {
+				log.Println("Ignoring malformed community:", cdata)
+				community = nil
+				break
+			}
+			community = append(community, int(value))
+		}
+		if community != nil {
+			communities = append(communities, community)
 		}
-		communities = append(communities, community)
 	}
 
 	return communities
@@ -289,8 +283,8 @@ func parseExtBgpCommunities(data interface{}) []api.ExtCommunity {
 	}
 
 	for _, c := range ldata {
-		cdata := c.([]interface{})
-		if len(cdata) != 3 {
+		cdata, ok := c.([]interface{})
+		if !ok || len(cdata) != 3 {
 			log.Println("Ignoring malformed ext community:", cdata)
 			continue
 		}
@@ -304,34 +298,42 @@ func parseExtBgpCommunities(data interface{}) []api.ExtCommunity {
 	return communities
 }
 
+// Parse a single route
+func parseRouteData(rdata map[string]interface{}, config Config) *api.Route {
+	age := parseRelativeServerTime(rdata["age"], config)
+	rtype := mustStringList(rdata["type"])
+	bgpInfo := parseRouteBgpInfo(rdata["bgp"])
+
+	route := &api.Route{
+		Id:          mustString(rdata["network"], "unknown"),
+		NeighbourId: mustString(rdata["from_protocol"], "unknown neighbour"),
+
+		Network:   mustString(rdata["network"], "unknown net"),
+		Interface: mustString(rdata["interface"], "unknown interface"),
+		Gateway:   mustString(rdata["gateway"], "unknown gateway"),
+		Metric:    mustInt(rdata["metric"], -1),
+		Primary:   mustBool(rdata["primary"], false),
+		Age:       age,
+		Type:      rtype,
+		Bgp:       bgpInfo,
+
+		Details: rdata,
+	}
+
+	return route
+}
+
 // Parse partial routes response
 func parseRoutesData(birdRoutes []interface{}, config Config) api.Routes {
 	routes := api.Routes{}
 
 	for _, data := range birdRoutes {
-		rdata := data.(map[string]interface{})
-
-		age := parseRelativeServerTime(rdata["age"], config)
-		rtype := mustStringList(rdata["type"])
-		bgpInfo := parseRouteBgpInfo(rdata["bgp"])
-
-		route := &api.Route{
-			Id:          mustString(rdata["network"], "unknown"),
-			NeighbourId: mustString(rdata["from_protocol"], "unknown neighbour"),
-
-			Network:   mustString(rdata["network"], "unknown net"),
-			Interface: mustString(rdata["interface"], "unknown interface"),
-			Gateway:   mustString(rdata["gateway"], "unknown gateway"),
-			Metric:    mustInt(rdata["metric"], -1),
-			Primary:   mustBool(rdata["primary"], false),
-			Age:       age,
-			Type:      rtype,
-			Bgp:       bgpInfo,
-
-			Details: rdata,
+		rdata, ok := data.(map[string]interface{})
+		if !ok {
+			log.Println("Ignoring malformed route:", data)
+			continue
 		}
-
-		routes = append(routes, route)
+		routes = append(routes, parseRouteData(rdata, config))
 	}
 	return routes
 }
@@ -6,6 +6,8 @@ import (
 
 	"testing"
 	"time"
+
+	"github.com/alice-lg/alice-lg/backend/sources"
 )
 
 const API_RESPONSE_NEIGHBOURS = `
@@ -146,3 +148,171 @@ func Test_ParseServerTime(t *testing.T) {
 		t.Error("Expected", expected, ", got:", res)
 	}
 }
+
+// Malformed responses and the field expected in the error
+var MALFORMED_RESPONSES = []struct {
+	name    string
+	payload string
+	parse   func(ClientResponse, Config) error
+	field   string
+}{
+	{
+		"api version missing",
+		`{"api":{"result_from_cache":false},"ttl":"2017-05-22T08:34:04Z"}`,
+		func(bird ClientResponse, config Config) error {
+			_, err := parseApiStatus(bird, config)
+			return err
+		},
+		"api.Version",
+	},
+	{
+		"api is not an object",
+		`{"api":"1.7.11"}`,
+		func(bird ClientResponse, config Config) error {
+			_, err := parseApiStatus(bird, config)
+			return err
+		},
+		"api",
+	},
+	{
+		"result_from_cache is a string",
+		`{"api":{"Version":"1.7.11","result_from_cache":"yes"}}`,
+		func(bird ClientResponse, config Config) error {
+			_, err := parseApiStatus(bird, config)
+			return err
+		},
+		"api.result_from_cache",
+	},
+	{
+		"status missing",
+		`{"api":{"Version":"1.7.11"}}`,
+		func(bird ClientResponse, config Config) error {
+			_, err := parseBirdwatcherStatus(bird, config)
+			return err
+		},
+		"status",
+	},
+	{
+		"status router id is a number",
+		`{"api":{"Version":"1.7.11"},"status":{"router_id":42}}`,
+		func(bird ClientResponse, config Config) error {
+			_, err := parseBirdwatcherStatus(bird, config)
+			return err
+		},
+		"status.router_id",
+	},
+	{
+		"protocols missing",
+		`{"api":{"Version":"1.7.11"}}`,
+		func(bird ClientResponse, config Config) error {
+			_, err := parseNeighbours(bird, config)
+			return err
+		},
+		"protocols",
+	},
+	{
+		"protocol is a list",
+		`{"protocols":{"ID103":[]}}`,
+		func(bird ClientResponse, config Config) error {
+			_, err := parseNeighbours(bird, config)
+			return err
+		},
+		"protocols.ID103",
+	},
+	{
+		"bgp neighbor address missing",
+		`{"protocols":{"ID103":{"bird_protocol":"BGP","state":"up"}}}`,
+		func(bird ClientResponse, config Config) error {
+			_, err := parseNeighbours(bird, config)
+			return err
+		},
+		"protocols.ID103.neighbor_address",
+	},
+	{
+		"imported routes is a string",
+		`{"protocols":{"ID103":{"bird_protocol":"BGP","neighbor_address":"194.9.117.1","routes":{"imported":"135"}}}}`,
+		func(bird ClientResponse, config Config) error {
+			_, err := parseNeighbours(bird, config)
+			return err
+		},
+		"protocols.ID103.routes.imported",
+	},
+	{
+		"routes is null",
+		`{"protocols":{"ID103":{"bird_protocol":"BGP","neighbor_address":"194.9.117.1","routes":null}}}`,
+		func(bird ClientResponse, config Config) error {
+			_, err := parseNeighbours(bird, config)
+			return err
+		},
+		"",
+	},
+	{
+		"short protocol state is a bool",
+		`{"protocols":{"ID103":{"state":true}}}`,
+		func(bird ClientResponse, config Config) error {
+			_, err := parseNeighboursShort(bird, config)
+			return err
+		},
+		"protocols.ID103.state",
+	},
+	{
+		"routes count is a string",
+		`{"routes":"42"}`,
+		func(bird ClientResponse, config Config) error {
+			_, err := decodeRoutesCount(bird)
+			return err
+		},
+		"routes",
+	},
+}
+
+func Test_ParseMalformedResponses(t *testing.T) {
+	config := Config{Timezone: "UTC"}
+
+	for _, test := range MALFORMED_RESPONSES {
+		bird, err := parseTestResponse(test.payload)
+		if err != nil {
+			t.Error(test.name, err)
+			continue
+		}
+
+		err = test.parse(bird, config)
+		if test.field == "" {
+			if err != nil {
+				t.Error(test.name, "- unexpected error:", err)
+			}
+			continue
+		}
+
+		responseErr, ok := err.(*sources.InvalidResponseError)
+		if !ok {
+			t.Error(test.name, "- expected a response error, got:", err)
+			continue
+		}
+		if responseErr.Field != test.field {
+			t.Error(test.name, "- expected field", test.field,
+				"got:", responseErr.Field)
+		}
+	}
+}
+
+func Test_ParseMalformedCommunities(t *testing.T) {
+	communities := parseBgpCommunities([]interface{}{
+		[]interface{}{float64(9033), float64(3051)},
+		"65000:1",
+		[]interface{}{float64(65000), "1"},
+	})
+	if len(communities) != 1 {
+		t.Error("Expected malformed communities to be skipped, got:", communities)
+	}
+}
+
+func Test_ParseApiError(t *testing.T) {
+	config := Config{Timezone: "UTC"}
+	bird, _ := parseTestResponse(`{"error":"birdc not reachable"}`)
+
+	_, err := parseApiStatus(bird, config)
+	if err == nil || err.Error() != "birdc not reachable" {
+		t.Error("Expected error from server, got:", err)
+	}
+}
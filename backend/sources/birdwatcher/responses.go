@@ -0,0 +1,374 @@
+package birdwatcher
+
+// Typed birdwatcher responses
+
+import (
+	"fmt"
+
+	"github.com/alice-lg/alice-lg/backend/sources"
+)
+
+/*
+The responses of the birdwatcher endpoints are decoded into
+typed structs. Each field is checked while decoding; a field
+of an unexpected type, or a missing required field, results
+in an InvalidResponseError naming the field, e.g.
+
+    protocols.ID103_AS25074_194.9.117.1.routes.imported
+
+Optional fields fall back to their zero value.
+*/
+
+// Make an error for an invalid field of a response
+func responseError(field string, reason string) error {
+	return &sources.InvalidResponseError{
+		Backend: "birdwatcher",
+		Field:   field,
+		Reason:  reason,
+	}
+}
+
+// Api info, included in every response
+type ApiInfo struct {
+	Version         string
+	ResultFromCache bool
+	CachedAt        string
+}
+
+// Response of /status
+type StatusInfo struct {
+	CurrentServer string
+	LastReboot    string
+	LastReconfig  string
+	Version       string
+	Message       string
+	RouterId      string
+}
+
+// Route counters of a protocol
+type ProtocolRoutes struct {
+	Imported  int
+	Filtered  int
+	Exported  int
+	Preferred int
+}
+
+// A protocol from /protocols or /protocols/bgp
+type Protocol struct {
+	Id              string
+	BirdProtocol    string
+	Table           string
+	NeighborAddress string
+	NeighborAs      int
+	LearntFrom      string
+	State           string
+	StateChanged    string
+	Description     string
+	LastError       string
+	Routes          ProtocolRoutes
+
+	// The protocol as received
+	Details map[string]interface{}
+}
+
+// Protocols by id
+type Protocols map[string]*Protocol
+
+// Get all protocols of a type, e.g. BGP or Pipe
+func (self Protocols) Filter(birdProtocol string) Protocols {
+	protocols := make(Protocols)
+	for id, protocol := range self {
+		if protocol.BirdProtocol == birdProtocol {
+			protocols[id] = protocol
+		}
+	}
+	return protocols
+}
+
+// A protocol from /protocols/short
+type ProtocolShort struct {
+	Id    string
+	State string
+	Since string
+}
+
+// A json object with its path in the response
+type responseObject struct {
+	path string
+	data map[string]interface{}
+}
+
+// Get the json type name of a value
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "bool"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func newResponseObject(path string, value interface{}) (*responseObject, error) {
+	data, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, responseError(
+			path, "expected object, got "+jsonType(value))
+	}
+	return &responseObject{path: path, data: data}, nil
+}
+
+func (self *responseObject) field(key string) string {
+	if self.path == "" {
+		return key
+	}
+	return self.path + "." + key
+}
+
+func (self *responseObject) missing(key string) error {
+	return responseError(self.field(key), "missing")
+}
+
+func (self *responseObject) invalid(key string, expected string) error {
+	return responseError(self.field(key),
+		"expected "+expected+", got "+jsonType(self.data[key]))
+}
+
+// Get a nested object, nil if missing and not required
+func (self *responseObject) object(key string, required bool) (*responseObject, error) {
+	value, ok := self.data[key]
+	if !ok || value == nil {
+		if required {
+			return nil, self.missing(key)
+		}
+		return nil, nil
+	}
+	return newResponseObject(self.field(key), value)
+}
+
+// Get a string, empty if missing and not required
+func (self *responseObject) string(key string, required bool) (string, error) {
+	value, ok := self.data[key]
+	if !ok || value == nil {
+		if required {
+			return "", self.missing(key)
+		}
+		return "", nil
+	}
+	svalue, ok := value.(string)
+	if !ok {
+		return "", self.invalid(key, "string")
+	}
+	return svalue, nil
+}
+
+// Get an optional number
+func (self *responseObject) int(key string) (int, error) {
+	value, ok := self.data[key]
+	if !ok || value == nil {
+		return 0, nil
+	}
+	fvalue, ok := value.(float64)
+	if !ok {
+		return 0, self.invalid(key, "number")
+	}
+	return int(fvalue), nil
+}
+
+// Get an optional bool
+func (self *responseObject) bool(key string) (bool, error) {
+	value, ok := self.data[key]
+	if !ok || value == nil {
+		return false, nil
+	}
+	bvalue, ok := value.(bool)
+	if !ok {
+		return false, self.invalid(key, "bool")
+	}
+	return bvalue, nil
+}
+
+// Decode the api info of a response
+func decodeApiInfo(bird ClientResponse) (*ApiInfo, error) {
+	response := &responseObject{data: bird}
+	birdApi, err := response.object("api", true)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &ApiInfo{}
+	if info.Version, err = birdApi.string("Version", true); err != nil {
+		return nil, err
+	}
+	if info.ResultFromCache, err = birdApi.bool("result_from_cache"); err != nil {
+		return nil, err
+	}
+
+	// The cache status is informational only
+	cache, err := birdApi.object("cache_status", false)
+	if err != nil || cache == nil {
+		return info, nil
+	}
+	cachedAt, err := cache.object("cached_at", false)
+	if err != nil || cachedAt == nil {
+		return info, nil
+	}
+	info.CachedAt, _ = cachedAt.string("date", false)
+
+	return info, nil
+}
+
+// Decode the response of /status
+func decodeStatus(bird ClientResponse) (*StatusInfo, error) {
+	response := &responseObject{data: bird}
+	status, err := response.object("status", true)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &StatusInfo{}
+	fields := map[string]*string{
+		"current_server": &info.CurrentServer,
+		"last_reboot":    &info.LastReboot,
+		"last_reconfig":  &info.LastReconfig,
+		"version":        &info.Version,
+		"message":        &info.Message,
+		"router_id":      &info.RouterId,
+	}
+	for key, value := range fields {
+		if *value, err = status.string(key, false); err != nil {
+			return nil, err
+		}
+	}
+
+	return info, nil
+}
+
+// Decode a single protocol
+func decodeProtocol(path string, id string, data interface{}) (*Protocol, error) {
+	object, err := newResponseObject(path, data)
+	if err != nil {
+		return nil, err
+	}
+
+	protocol := &Protocol{
+		Id:      id,
+		Details: object.data,
+	}
+
+	if protocol.BirdProtocol, err = object.string("bird_protocol", false); err != nil {
+		return nil, err
+	}
+	// The neighbor address is required for BGP sessions
+	isBgp := protocol.BirdProtocol == "BGP"
+
+	fields := []struct {
+		key      string
+		value    *string
+		required bool
+	}{
+		{"table", &protocol.Table, false},
+		{"neighbor_address", &protocol.NeighborAddress, isBgp},
+		{"learnt_from", &protocol.LearntFrom, false},
+		{"state", &protocol.State, false},
+		{"state_changed", &protocol.StateChanged, false},
+		{"description", &protocol.Description, false},
+		{"last_error", &protocol.LastError, false},
+	}
+	for _, f := range fields {
+		if *f.value, err = object.string(f.key, f.required); err != nil {
+			return nil, err
+		}
+	}
+
+	if protocol.NeighborAs, err = object.int("neighbor_as"); err != nil {
+		return nil, err
+	}
+
+	routes, err := object.object("routes", false)
+	if err != nil {
+		return nil, err
+	}
+	if routes == nil {
+		return protocol, nil
+	}
+
+	counters := map[string]*int{
+		"imported":  &protocol.Routes.Imported,
+		"filtered":  &protocol.Routes.Filtered,
+		"exported":  &protocol.Routes.Exported,
+		"preferred": &protocol.Routes.Preferred,
+	}
+	for key, value := range counters {
+		if *value, err = routes.int(key); err != nil {
+			return nil, err
+		}
+	}
+
+	return protocol, nil
+}
+
+// Decode the response of /protocols or /protocols/bgp
+func decodeProtocols(bird ClientResponse) (Protocols, error) {
+	response := &responseObject{data: bird}
+	object, err := response.object("protocols", true)
+	if err != nil {
+		return nil, err
+	}
+
+	protocols := make(Protocols, len(object.data))
+	for id, data := range object.data {
+		protocol, err := decodeProtocol(object.field(id), id, data)
+		if err != nil {
+			return nil, err
+		}
+		protocols[id] = protocol
+	}
+
+	return protocols, nil
+}
+
+// Decode the response of /protocols/short
+func decodeProtocolsShort(bird ClientResponse) ([]*ProtocolShort, error) {
+	response := &responseObject{data: bird}
+	object, err := response.object("protocols", true)
+	if err != nil {
+		return nil, err
+	}
+
+	protocols := make([]*ProtocolShort, 0, len(object.data))
+	for id, data := range object.data {
+		protocol, err := newResponseObject(object.field(id), data)
+		if err != nil {
+			return nil, err
+		}
+
+		short := &ProtocolShort{Id: id}
+		if short.State, err = protocol.string("state", false); err != nil {
+			return nil, err
+		}
+		if short.Since, err = protocol.string("since", false); err != nil {
+			return nil, err
+		}
+		protocols = append(protocols, short)
+	}
+
+	return protocols, nil
+}
+
+// Decode the response of a routes count, e.g. /routes/pipe/filtered/count
+func decodeRoutesCount(bird ClientResponse) (int, error) {
+	response := &responseObject{data: bird}
+	if _, ok := bird["routes"]; !ok {
+		return 0, response.missing("routes")
+	}
+	return response.int("routes")
+}
@@ -5,13 +5,17 @@ import (
 	"github.com/alice-lg/alice-lg/backend/caches"
 	"github.com/alice-lg/alice-lg/backend/sources"
 
+	"context"
 	"fmt"
+	"io"
 	"sort"
+	"sync"
 	"time"
 )
 
 type Birdwatcher interface {
 	sources.Source
+	sources.ContextSource
 }
 
 type GenericBirdwatcher struct {
@@ -26,11 +30,29 @@ type GenericBirdwatcher struct {
 	routesNotExportedCache *caches.RoutesCache
 
 	// Mutices:
-	routesFetchMutex *LockMap
+	routesFetchMutex *sources.LockMap
+
+	// Capabilities: The available modules
+	capabilities     *ModuleCapabilities
+	capabilitiesLock sync.RWMutex
 }
 
+// Make a birdwatcher source, the available
+// modules are probed in the background.
 func NewBirdwatcher(config Config) Birdwatcher {
-	client := NewClient(config.Api)
+	birdwatcher := newBirdwatcher(config)
+	switch source := birdwatcher.(type) {
+	case *SingleTableBirdwatcher:
+		go source.startProbing()
+	case *MultiTableBirdwatcher:
+		go source.startProbing()
+	}
+	return birdwatcher
+}
+
+// Make a birdwatcher source without probing
+func newBirdwatcher(config Config) Birdwatcher {
+	client := NewClient(config)
 
 	// Cache settings:
 	// TODO: Maybe read from config file
@@ -59,7 +81,7 @@ func NewBirdwatcher(config Config) Birdwatcher {
 		singleTableBirdwatcher.routesRequiredCache = routesRequiredCache
 		singleTableBirdwatcher.routesNotExportedCache = routesNotExportedCache
 
-		singleTableBirdwatcher.routesFetchMutex = NewLockMap()
+		singleTableBirdwatcher.routesFetchMutex = sources.NewLockMap()
 
 		birdwatcher = singleTableBirdwatcher
 	} else if config.Type == "multi_table" {
@@ -73,7 +95,9 @@ func NewBirdwatcher(config Config) Birdwatcher {
 		multiTableBirdwatcher.routesRequiredCache = routesRequiredCache
 		multiTableBirdwatcher.routesNotExportedCache = routesNotExportedCache
 
-		multiTableBirdwatcher.routesFetchMutex = NewLockMap()
+		multiTableBirdwatcher.routesFetchMutex = sources.NewLockMap()
+		multiTableBirdwatcher.workers = newWorkerPool(config)
+		multiTableBirdwatcher.dumpWorkers = newWorkerPool(config)
 
 		birdwatcher = multiTableBirdwatcher
 	}
@@ -81,27 +105,6 @@ func NewBirdwatcher(config Config) Birdwatcher {
 	return birdwatcher
 }
 
-func (self *GenericBirdwatcher) filterProtocols(protocols map[string]interface{}, protocol string) map[string]interface{} {
-	response := make(map[string]interface{})
-	response["protocols"] = make(map[string]interface{})
-
-	for protocolId, protocolData := range protocols {
-		if protocolData.(map[string]interface{})["bird_protocol"] == protocol {
-			response["protocols"].(map[string]interface{})[protocolId] = protocolData
-		}
-	}
-
-	return response
-}
-
-func (self *GenericBirdwatcher) filterProtocolsBgp(bird ClientResponse) map[string]interface{} {
-	return self.filterProtocols(bird["protocols"].(map[string]interface{}), "BGP")
-}
-
-func (self *GenericBirdwatcher) filterProtocolsPipe(bird ClientResponse) map[string]interface{} {
-	return self.filterProtocols(bird["protocols"].(map[string]interface{}), "Pipe")
-}
-
 func (self *GenericBirdwatcher) filterRoutesByPeerOrLearntFrom(routes api.Routes, peer string, learntFrom string) api.Routes {
 	result_routes := make(api.Routes, 0, len(routes))
 
@@ -164,13 +167,18 @@ func (self *GenericBirdwatcher) filterRoutesByNeighborId(routes api.Routes, neig
 	return routes
 }
 
-func (self *GenericBirdwatcher) fetchProtocolsShort() (*api.ApiStatus, map[string]interface{}, error) {
+func (self *GenericBirdwatcher) fetchProtocolsShort(ctx context.Context) (*api.ApiStatus, map[string]interface{}, error) {
+	if err := self.requireModule("protocols_short"); err != nil {
+		return nil, nil, err
+	}
+
 	// Query birdwatcher
 	timeout := 2 * time.Second
 	if self.config.NeighborsRefreshTimeout > 0 {
 		timeout = time.Duration(self.config.NeighborsRefreshTimeout) * time.Second
 	}
-	bird, err := self.client.GetJsonTimeout(timeout, "/protocols/short?uncached=true")
+	bird, err := self.client.GetContext(ctx,
+		"/protocols/short?uncached=true", timeout)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -188,6 +196,29 @@ func (self *GenericBirdwatcher) fetchProtocolsShort() (*api.ApiStatus, map[strin
 	return &apiStatus, bird, nil
 }
 
+// Fetch routes, the response is decoded while reading
+func (self *GenericBirdwatcher) fetchRoutes(
+	ctx context.Context,
+	endpoint string,
+) (*api.ApiStatus, *RoutesResponse, error) {
+	var response *RoutesResponse
+	err := self.client.GetJsonStreamContext(ctx, endpoint, func(body io.Reader) error {
+		var err error
+		response, err = decodeRoutesResponse(body, self.config)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	apiStatus, err := parseApiStatus(response.Bird, self.config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &apiStatus, response, nil
+}
+
 func (self *GenericBirdwatcher) ExpireCaches() int {
 	count := self.routesRequiredCache.Expire()
 	count += self.routesNotExportedCache.Expire()
@@ -195,9 +226,9 @@ func (self *GenericBirdwatcher) ExpireCaches() int {
 	return count
 }
 
-func (self *GenericBirdwatcher) Status() (*api.StatusResponse, error) {
+func (self *GenericBirdwatcher) StatusContext(ctx context.Context) (*api.StatusResponse, error) {
 	// Query birdwatcher
-	bird, err := self.client.GetJson("/status")
+	bird, err := self.client.GetJsonContext(ctx, "/status")
 	if err != nil {
 		return nil, err
 	}
@@ -214,6 +245,14 @@ func (self *GenericBirdwatcher) Status() (*api.StatusResponse, error) {
 		return nil, err
 	}
 
+	stats := self.client.Stats()
+	birdStatus.Requests = &stats
+	birdStatus.Endpoint = self.client.Endpoint()
+
+	if capabilities := self.moduleCapabilities(); capabilities != nil {
+		birdStatus.Capabilities = capabilities.Modules
+	}
+
 	response := &api.StatusResponse{
 		Api:    apiStatus,
 		Status: birdStatus,
@@ -223,9 +262,9 @@ func (self *GenericBirdwatcher) Status() (*api.StatusResponse, error) {
 }
 
 // Get live neighbor status
-func (self *GenericBirdwatcher) NeighboursStatus() (*api.NeighboursStatusResponse, error) {
+func (self *GenericBirdwatcher) NeighboursStatusContext(ctx context.Context) (*api.NeighboursStatusResponse, error) {
 	// Query birdwatcher
-	apiStatus, birdProtocols, err := self.fetchProtocolsShort()
+	apiStatus, birdProtocols, err := self.fetchProtocolsShort(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -245,27 +284,25 @@ func (self *GenericBirdwatcher) NeighboursStatus() (*api.NeighboursStatusRespons
 }
 
 // Make routes lookup
-func (self *GenericBirdwatcher) LookupPrefix(prefix string) (*api.RoutesLookupResponse, error) {
+func (self *GenericBirdwatcher) LookupPrefixContext(ctx context.Context, prefix string) (*api.RoutesLookupResponse, error) {
 	// Get RS info
 	rs := api.Routeserver{
 		Id:   self.config.Id,
 		Name: self.config.Name,
 	}
 
-	// Query prefix on RS
-	bird, err := self.client.GetJson("/routes/prefix?prefix=" + prefix)
-	if err != nil {
+	if err := self.requireModule("routes_prefixed"); err != nil {
 		return nil, err
 	}
 
-	// Parse API status
-	apiStatus, err := parseApiStatus(bird, self.config)
+	// Query prefix on RS
+	apiStatus, bird, err := self.fetchRoutes(ctx, "/routes/prefix?prefix="+prefix)
 	if err != nil {
 		return nil, err
 	}
 
 	// Parse routes
-	routes, err := parseRoutes(bird, self.config)
+	routes, err := bird.SortedRoutes("routes")
 
 	// Add corresponding neighbour and source rs to result
 	results := api.LookupRoutes{}
@@ -294,7 +331,7 @@ func (self *GenericBirdwatcher) LookupPrefix(prefix string) (*api.RoutesLookupRe
 
 	// Make result
 	response := &api.RoutesLookupResponse{
-		Api:    apiStatus,
+		Api:    *apiStatus,
 		Routes: results,
 	}
 	return response, nil
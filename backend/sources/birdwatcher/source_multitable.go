@@ -2,16 +2,23 @@ package birdwatcher
 
 import (
 	"github.com/alice-lg/alice-lg/backend/api"
+	"github.com/alice-lg/alice-lg/backend/sources"
 
+	"context"
 	"strings"
 
-	"fmt"
 	"log"
 	"sort"
 )
 
 type MultiTableBirdwatcher struct {
 	GenericBirdwatcher
+
+	// Workers for fetching peer tables. Dumping the routes
+	// for the store has its own workers, so it does not hold
+	// up the requests of users.
+	workers     *workerPool
+	dumpWorkers *workerPool
 }
 
 func (self *MultiTableBirdwatcher) getMasterPipeName(table string) string {
@@ -23,36 +30,24 @@ func (self *MultiTableBirdwatcher) getMasterPipeName(table string) string {
 	}
 }
 
-func (self *MultiTableBirdwatcher) parseProtocolToTableTree(bird ClientResponse) map[string]interface{} {
-	protocols := bird["protocols"].(map[string]interface{})
-
-	response := make(map[string]interface{})
-
-	for _, protocolData := range protocols {
-		protocol := protocolData.(map[string]interface{})
-
-		if protocol["bird_protocol"] == "BGP" {
-			table := protocol["table"].(string)
-			neighborAddress := protocol["neighbor_address"].(string)
-
-			if _, ok := response[table]; !ok {
-				response[table] = make(map[string]interface{})
-			}
-
-			if _, ok := response[table].(map[string]interface{})[neighborAddress]; !ok {
-				response[table].(map[string]interface{})[neighborAddress] = make(map[string]interface{})
-			}
+// Get the BGP protocols by table and neighbor address
+func (self *MultiTableBirdwatcher) parseProtocolToTableTree(protocols Protocols) map[string]map[string]*Protocol {
+	response := make(map[string]map[string]*Protocol)
 
-			response[table].(map[string]interface{})[neighborAddress] = protocol
+	for _, protocol := range protocols.Filter("BGP") {
+		table := protocol.Table
+		if _, ok := response[table]; !ok {
+			response[table] = make(map[string]*Protocol)
 		}
+		response[table][protocol.NeighborAddress] = protocol
 	}
 
 	return response
 }
 
-func (self *MultiTableBirdwatcher) fetchProtocols() (*api.ApiStatus, map[string]interface{}, error) {
+func (self *MultiTableBirdwatcher) fetchProtocols(ctx context.Context) (*api.ApiStatus, Protocols, error) {
 	// Query birdwatcher
-	bird, err := self.client.GetJson("/protocols")
+	bird, err := self.client.GetJsonContext(ctx, "/protocols")
 	if err != nil {
 		return nil, nil, err
 	}
@@ -63,140 +58,147 @@ func (self *MultiTableBirdwatcher) fetchProtocols() (*api.ApiStatus, map[string]
 		return nil, nil, err
 	}
 
-	if _, ok := bird["protocols"]; !ok {
-		return nil, nil, fmt.Errorf("Failed to fetch protocols")
+	protocols, err := decodeProtocols(bird)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return &apiStatus, bird, nil
+	return &apiStatus, protocols, nil
 }
 
-func (self *MultiTableBirdwatcher) fetchReceivedRoutes(neighborId string) (*api.ApiStatus, api.Routes, error) {
+func (self *MultiTableBirdwatcher) fetchReceivedRoutes(ctx context.Context, neighborId string) (*api.ApiStatus, api.Routes, error) {
+	if err := self.requireModule("routes_peer"); err != nil {
+		return nil, nil, err
+	}
+
 	// Query birdwatcher
-	_, birdProtocols, err := self.fetchProtocols()
+	_, protocols, err := self.fetchProtocols(ctx)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	protocols := birdProtocols["protocols"].(map[string]interface{})
-
-	if _, ok := protocols[neighborId]; !ok {
-		return nil, nil, fmt.Errorf("Invalid Neighbor")
+	protocol, ok := protocols[neighborId]
+	if !ok {
+		return nil, nil, &sources.NotFoundError{Resource: "neighbor", Id: neighborId}
 	}
 
-	peer := protocols[neighborId].(map[string]interface{})["neighbor_address"].(string)
+	peer := protocol.NeighborAddress
 
 	// Query birdwatcher
-	bird, err := self.client.GetJson("/routes/peer/" + peer)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	// Use api status from first request
-	apiStatus, err := parseApiStatus(bird, self.config)
+	apiStatus, bird, err := self.fetchRoutes(ctx, "/routes/peer/"+peer)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	// Parse the routes
-	received, err := parseRoutes(bird, self.config)
+	received, err := bird.SortedRoutes("routes")
 	if err != nil {
 		log.Println("WARNING Could not retrieve received routes:", err)
 		log.Println("Is the 'routes_peer' module active in birdwatcher?")
-		return &apiStatus, nil, err
+		return apiStatus, nil, err
 	}
 
-	return &apiStatus, received, nil
+	return apiStatus, received, nil
 }
 
-func (self *MultiTableBirdwatcher) fetchFilteredRoutes(neighborId string) (*api.ApiStatus, api.Routes, error) {
+func (self *MultiTableBirdwatcher) fetchFilteredRoutes(ctx context.Context, neighborId string) (*api.ApiStatus, api.Routes, error) {
 	// Query birdwatcher
-	_, birdProtocols, err := self.fetchProtocols()
+	_, protocols, err := self.fetchProtocols(ctx)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	protocols := birdProtocols["protocols"].(map[string]interface{})
+	protocol, ok := protocols[neighborId]
+	if !ok {
+		return nil, nil, &sources.NotFoundError{Resource: "neighbor", Id: neighborId}
+	}
+
+	return self.fetchProtocolFilteredRoutes(ctx, protocol)
+}
 
-	if _, ok := protocols[neighborId]; !ok {
-		return nil, nil, fmt.Errorf("Invalid Neighbor")
+// Fetch the filtered routes of a protocol and its pipe to master
+func (self *MultiTableBirdwatcher) fetchProtocolFilteredRoutes(
+	ctx context.Context,
+	protocol *Protocol,
+) (*api.ApiStatus, api.Routes, error) {
+	if err := self.requireModule("routes_filtered"); err != nil {
+		return nil, nil, err
 	}
 
 	// Stage 1 filters
-	birdFiltered, err := self.client.GetJson("/routes/filtered/" + neighborId)
+	apiStatus, birdFiltered, err := self.fetchRoutes(ctx, "/routes/filtered/"+protocol.Id)
 	if err != nil {
 		log.Println("WARNING Could not retrieve filtered routes:", err)
 		log.Println("Is the 'routes_filtered' module active in birdwatcher?")
 		return nil, nil, err
 	}
 
-	// Use api status from first request
-	apiStatus, err := parseApiStatus(birdFiltered, self.config)
-	if err != nil {
-		return nil, nil, err
-	}
-
 	// Parse the routes
-	filtered := parseRoutesData(birdFiltered["routes"].([]interface{}), self.config)
+	filtered := birdFiltered.Routes["routes"]
 
 	// Stage 2 filters
-	table := protocols[neighborId].(map[string]interface{})["table"].(string)
+	table := protocol.Table
 	pipeName := self.getMasterPipeName(table)
 
 	// If there is no pipe to master, there is nothing left to do
 	if pipeName == "" {
-		return &apiStatus, filtered, nil
+		return apiStatus, filtered, nil
+	}
+	if err := self.requireModule("routes_pipe_filtered"); err != nil {
+		return apiStatus, nil, err
 	}
 
 	// Query birdwatcher
-	birdPipeFiltered, err := self.client.GetJson("/routes/pipe/filtered/?table=" + table + "&pipe=" + pipeName)
+	_, birdPipeFiltered, err := self.fetchRoutes(ctx,
+		"/routes/pipe/filtered/?table="+table+"&pipe="+pipeName)
 	if err != nil {
 		log.Println("WARNING Could not retrieve filtered routes:", err)
 		log.Println("Is the 'pipe_filtered' module active in birdwatcher?")
-		return &apiStatus, nil, err
+		return apiStatus, nil, err
 	}
 
 	// Parse the routes
-	pipeFiltered := parseRoutesData(birdPipeFiltered["routes"].([]interface{}), self.config)
+	pipeFiltered := birdPipeFiltered.Routes["routes"]
 
 	// Sort routes for deterministic ordering
 	filtered = append(filtered, pipeFiltered...)
 	sort.Sort(filtered)
 
-	return &apiStatus, filtered, nil
+	return apiStatus, filtered, nil
 }
 
-func (self *MultiTableBirdwatcher) fetchNotExportedRoutes(neighborId string) (*api.ApiStatus, api.Routes, error) {
+func (self *MultiTableBirdwatcher) fetchNotExportedRoutes(ctx context.Context, neighborId string) (*api.ApiStatus, api.Routes, error) {
+	if err := self.requireModule("routes_noexport"); err != nil {
+		return nil, nil, err
+	}
+
 	// Query birdwatcher
-	_, birdProtocols, err := self.fetchProtocols()
+	_, protocols, err := self.fetchProtocols(ctx)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	protocols := birdProtocols["protocols"].(map[string]interface{})
-
-	if _, ok := protocols[neighborId]; !ok {
-		return nil, nil, fmt.Errorf("Invalid Neighbor")
+	protocol, ok := protocols[neighborId]
+	if !ok {
+		return nil, nil, &sources.NotFoundError{Resource: "neighbor", Id: neighborId}
 	}
 
-	table := protocols[neighborId].(map[string]interface{})["table"].(string)
+	table := protocol.Table
 	pipeName := self.getMasterPipeName(table)
 
 	// Query birdwatcher
-	bird, err := self.client.GetJson("/routes/noexport/" + pipeName)
-
-	// Use api status from first request
-	apiStatus, err := parseApiStatus(bird, self.config)
+	apiStatus, bird, err := self.fetchRoutes(ctx, "/routes/noexport/"+pipeName)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	notExported, err := parseRoutes(bird, self.config)
+	notExported, err := bird.SortedRoutes("routes")
 	if err != nil {
 		log.Println("WARNING Could not retrieve routes not exported:", err)
 		log.Println("Is the 'routes_noexport' module active in birdwatcher?")
 	}
 
-	return &apiStatus, notExported, nil
+	return apiStatus, notExported, nil
 }
 
 /*
@@ -211,7 +213,7 @@ RoutesNotExported() API.
 
 A route deduplication is applied.
 */
-func (self *MultiTableBirdwatcher) fetchRequiredRoutes(neighborId string) (*api.RoutesResponse, error) {
+func (self *MultiTableBirdwatcher) fetchRequiredRoutes(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
 	// Allow only one concurrent request for this neighbor
 	// to our backend server.
 	self.routesFetchMutex.Lock(neighborId)
@@ -224,13 +226,13 @@ func (self *MultiTableBirdwatcher) fetchRequiredRoutes(neighborId string) (*api.
 	}
 
 	// First: get routes received
-	apiStatus, receivedRoutes, err := self.fetchReceivedRoutes(neighborId)
+	apiStatus, receivedRoutes, err := self.fetchReceivedRoutes(ctx, neighborId)
 	if err != nil {
 		return nil, err
 	}
 
 	// Second: get routes filtered
-	_, filteredRoutes, err := self.fetchFilteredRoutes(neighborId)
+	_, filteredRoutes, err := self.fetchFilteredRoutes(ctx, neighborId)
 	if err != nil {
 		return nil, err
 	}
@@ -257,8 +259,43 @@ func (self *MultiTableBirdwatcher) fetchRequiredRoutes(neighborId string) (*api.
 	return response, nil
 }
 
+// The number of routes of a peer filtered by the pipe to master
+type pipeFilteredCount struct {
+	protocol        *Protocol
+	neighborAddress string
+
+	routes int
+	found  bool
+}
+
+// Query the number of routes filtered by the pipe
+func (self *MultiTableBirdwatcher) fetchPipeFilteredCount(
+	ctx context.Context,
+	count *pipeFilteredCount,
+) error {
+	table := count.protocol.Table
+	pipe := self.getMasterPipeName(table)
+
+	bird, err := self.client.GetJsonContext(ctx,
+		"/routes/pipe/filtered/count?table="+table+"&pipe="+pipe+"&address="+count.neighborAddress)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := bird["routes"]; !ok {
+		return nil
+	}
+	count.routes, err = decodeRoutesCount(bird)
+	if err != nil {
+		return err
+	}
+	count.found = true
+
+	return nil
+}
+
 // Get neighbors from protocols
-func (self *MultiTableBirdwatcher) Neighbours() (*api.NeighboursResponse, error) {
+func (self *MultiTableBirdwatcher) NeighboursContext(ctx context.Context) (*api.NeighboursResponse, error) {
 	// Check if we hit the cache
 	response := self.neighborsCache.Get()
 	if response != nil {
@@ -266,45 +303,36 @@ func (self *MultiTableBirdwatcher) Neighbours() (*api.NeighboursResponse, error)
 	}
 
 	// Query birdwatcher
-	apiStatus, birdProtocols, err := self.fetchProtocols()
+	apiStatus, protocols, err := self.fetchProtocols(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	// Parse the neighbors
-	neighbours, err := parseNeighbours(self.filterProtocolsBgp(birdProtocols), self.config)
-	if err != nil {
-		return nil, err
-	}
+	neighbours := makeNeighbours(protocols.Filter("BGP"), self.config)
 
-	pipes := self.filterProtocolsPipe(birdProtocols)["protocols"].(map[string]interface{})
-	tree := self.parseProtocolToTableTree(birdProtocols)
+	pipes := protocols.Filter("Pipe")
+	tree := self.parseProtocolToTableTree(protocols)
 
 	// Now determine the session count for each neighbor and check if the pipe
 	// did filter anything
 	filtered := make(map[string]int)
-	for table, _ := range tree {
+	counts := []*pipeFilteredCount{}
+	for table, peers := range tree {
 		allRoutesImported := int64(0)
 		pipeRoutesImported := int64(0)
 
 		// Sum up all routes from all peers for a table
-		for _, protocol := range tree[table].(map[string]interface{}) {
+		for _, protocol := range peers {
 			// Skip peers that are not up (start/down)
-			if !isProtocolUp(protocol.(map[string]interface{})["state"].(string)) {
+			if !isProtocolUp(protocol.State) {
 				continue
 			}
-			allRoutesImported += int64(protocol.(map[string]interface{})["routes"].(map[string]interface{})["imported"].(float64))
+			allRoutesImported += int64(protocol.Routes.Imported)
 
 			pipeName := self.getMasterPipeName(table)
-
-			if _, ok := pipes[pipeName]; ok {
-				if _, ok := pipes[pipeName].(map[string]interface{})["routes"].(map[string]interface{})["imported"]; ok {
-					pipeRoutesImported = int64(pipes[pipeName].(map[string]interface{})["routes"].(map[string]interface{})["imported"].(float64))
-				} else {
-					continue
-				}
-			} else {
-				continue
+			if pipe, ok := pipes[pipeName]; ok {
+				pipeRoutesImported = int64(pipe.Routes.Imported)
 			}
 		}
 
@@ -318,10 +346,10 @@ func (self *MultiTableBirdwatcher) Neighbours() (*api.NeighboursResponse, error)
 			continue
 		}
 
-		if len(tree[table].(map[string]interface{})) == 1 {
+		if len(peers) == 1 {
 			// Single router
-			for _, protocol := range tree[table].(map[string]interface{}) {
-				filtered[protocol.(map[string]interface{})["protocol"].(string)] = int(allRoutesImported - pipeRoutesImported)
+			for _, protocol := range peers {
+				filtered[protocol.Id] = int(allRoutesImported - pipeRoutesImported)
 			}
 		} else {
 			// Multiple routers
@@ -329,35 +357,47 @@ func (self *MultiTableBirdwatcher) Neighbours() (*api.NeighboursResponse, error)
 				// 0 is a special condition, which means that the pipe did filter ALL routes of
 				// all peers. Therefore we already know the amount of filtered routes and don't have
 				// to query birdwatcher again.
-				for _, protocol := range tree[table].(map[string]interface{}) {
+				for _, protocol := range peers {
 					// Skip peers that are not up (start/down)
-					if !isProtocolUp(protocol.(map[string]interface{})["state"].(string)) {
+					if !isProtocolUp(protocol.State) {
 						continue
 					}
-					filtered[protocol.(map[string]interface{})["protocol"].(string)] = int(protocol.(map[string]interface{})["routes"].(map[string]interface{})["imported"].(float64))
+					filtered[protocol.Id] = protocol.Routes.Imported
 				}
 			} else {
 				// Otherwise the pipe did import at least some routes which means that
 				// we have to query birdwatcher to get the count for each peer.
-				for neighborAddress, protocol := range tree[table].(map[string]interface{}) {
-					table := protocol.(map[string]interface{})["table"].(string)
-					pipe := self.getMasterPipeName(table)
-
-					count, err := self.client.GetJson("/routes/pipe/filtered/count?table=" + table + "&pipe=" + pipe + "&address=" + neighborAddress)
-					if err != nil {
-						log.Println("WARNING Could not retrieve filtered routes count:", err)
-						log.Println("Is the 'pipe_filtered_count' module active in birdwatcher?")
-						return nil, err
-					}
-
-					if _, ok := count["routes"]; ok {
-						filtered[protocol.(map[string]interface{})["protocol"].(string)] = int(count["routes"].(float64))
-					}
+				for neighborAddress, protocol := range peers {
+					counts = append(counts, &pipeFilteredCount{
+						protocol:        protocol,
+						neighborAddress: neighborAddress,
+					})
 				}
 			}
 		}
 	}
 
+	// Query the filtered routes count of each peer. Without the
+	// module, the neighbors are shown without the pipe filtered routes.
+	if err := self.requireModule("routes_pipe_filtered_count"); err != nil {
+		counts = nil
+	}
+	tables := make([]string, len(counts))
+	for i, count := range counts {
+		tables[i] = count.protocol.Table
+	}
+	failed := self.workers.fetchTables(ctx, tables, func(ctx context.Context, i int) error {
+		return self.fetchPipeFilteredCount(ctx, counts[i])
+	})
+	if len(failed) > 0 {
+		log.Println("Is the 'pipe_filtered_count' module active in birdwatcher?")
+	}
+	for _, count := range counts {
+		if count.found {
+			filtered[count.protocol.Id] = count.routes
+		}
+	}
+
 	// Update the results with the information about filtered routes from the pipe
 	for _, neighbor := range neighbours {
 		if pipeRoutesFiltered, ok := filtered[neighbor.Id]; ok {
@@ -371,6 +411,13 @@ func (self *MultiTableBirdwatcher) Neighbours() (*api.NeighboursResponse, error)
 		Neighbours: neighbours,
 	}
 
+	// Report the failed tables along with the neighbors,
+	// the filtered routes of these neighbors are incomplete.
+	// The result is not cached, so it is retried.
+	if len(failed) > 0 {
+		return response, &sources.PartialError{Failed: failed}
+	}
+
 	// Cache result
 	self.neighborsCache.Set(response)
 
@@ -378,17 +425,20 @@ func (self *MultiTableBirdwatcher) Neighbours() (*api.NeighboursResponse, error)
 }
 
 // Get filtered and exported routes
-func (self *MultiTableBirdwatcher) Routes(neighbourId string) (*api.RoutesResponse, error) {
+func (self *MultiTableBirdwatcher) RoutesContext(ctx context.Context, neighbourId string) (*api.RoutesResponse, error) {
 	response := &api.RoutesResponse{}
 	// Fetch required routes first (received and filtered)
 	// However: Store in separate cache for faster access
-	required, err := self.fetchRequiredRoutes(neighbourId)
+	required, err := self.fetchRequiredRoutes(ctx, neighbourId)
 	if err != nil {
 		return nil, err
 	}
 
 	// Optional: NoExport
-	_, notExported, err := self.fetchNotExportedRoutes(neighbourId)
+	_, notExported, err := self.fetchNotExportedRoutes(ctx, neighbourId)
+	if _, ok := err.(*sources.UnsupportedError); ok {
+		err = nil
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -402,7 +452,7 @@ func (self *MultiTableBirdwatcher) Routes(neighbourId string) (*api.RoutesRespon
 }
 
 // Get all received routes
-func (self *MultiTableBirdwatcher) RoutesReceived(neighborId string) (*api.RoutesResponse, error) {
+func (self *MultiTableBirdwatcher) RoutesReceivedContext(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
 	response := &api.RoutesResponse{}
 
 	// Check if we have a cache hit
@@ -414,7 +464,7 @@ func (self *MultiTableBirdwatcher) RoutesReceived(neighborId string) (*api.Route
 	}
 
 	// Fetch required routes first (received and filtered)
-	routes, err := self.fetchRequiredRoutes(neighborId)
+	routes, err := self.fetchRequiredRoutes(ctx, neighborId)
 	if err != nil {
 		return nil, err
 	}
@@ -426,7 +476,7 @@ func (self *MultiTableBirdwatcher) RoutesReceived(neighborId string) (*api.Route
 }
 
 // Get all filtered routes
-func (self *MultiTableBirdwatcher) RoutesFiltered(neighborId string) (*api.RoutesResponse, error) {
+func (self *MultiTableBirdwatcher) RoutesFilteredContext(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
 	response := &api.RoutesResponse{}
 
 	// Check if we have a cache hit
@@ -438,7 +488,7 @@ func (self *MultiTableBirdwatcher) RoutesFiltered(neighborId string) (*api.Route
 	}
 
 	// Fetch required routes first (received and filtered)
-	routes, err := self.fetchRequiredRoutes(neighborId)
+	routes, err := self.fetchRequiredRoutes(ctx, neighborId)
 	if err != nil {
 		return nil, err
 	}
@@ -450,7 +500,7 @@ func (self *MultiTableBirdwatcher) RoutesFiltered(neighborId string) (*api.Route
 }
 
 // Get all not exported routes
-func (self *MultiTableBirdwatcher) RoutesNotExported(neighborId string) (*api.RoutesResponse, error) {
+func (self *MultiTableBirdwatcher) RoutesNotExportedContext(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
 	// Check if we have a cache hit
 	response := self.routesNotExportedCache.Get(neighborId)
 	if response != nil {
@@ -458,7 +508,7 @@ func (self *MultiTableBirdwatcher) RoutesNotExported(neighborId string) (*api.Ro
 	}
 
 	// Fetch not exported routes
-	apiStatus, routes, err := self.fetchNotExportedRoutes(neighborId)
+	apiStatus, routes, err := self.fetchNotExportedRoutes(ctx, neighborId)
 	if err != nil {
 		return nil, err
 	}
@@ -474,51 +524,79 @@ func (self *MultiTableBirdwatcher) RoutesNotExported(neighborId string) (*api.Ro
 	return response, nil
 }
 
-func (self *MultiTableBirdwatcher) AllRoutes() (*api.RoutesResponse, error) {
+func (self *MultiTableBirdwatcher) AllRoutesContext(ctx context.Context) (*api.RoutesResponse, error) {
+	if err := self.requireModule(CAPABILITY_ROUTES_DUMP); err != nil {
+		return nil, err
+	}
+
 	// Query birdwatcher
-	_, birdProtocols, err := self.fetchProtocols()
+	_, protocols, err := self.fetchProtocols(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	// Fetch received routes first
-	birdImported, err := self.client.GetJson("/routes/table/master")
+	apiStatus, birdImported, err := self.fetchRoutes(ctx, "/routes/table/master")
 	if err != nil {
 		return nil, err
 	}
 
 	// Use api status from first request
-	apiStatus, err := parseApiStatus(birdImported, self.config)
+	response := &api.RoutesResponse{
+		Api: *apiStatus,
+	}
+
+	// Sort routes for deterministic ordering
+	imported, err := birdImported.SortedRoutes("routes")
 	if err != nil {
 		return nil, err
 	}
+	response.Imported = imported
 
-	response := &api.RoutesResponse{
-		Api: apiStatus,
+	// Fetch the filtered routes of all protocols. The protocols
+	// are sorted, so the routes are in the same order, no matter
+	// how many tables are fetched concurrently.
+	protocolsBgp := protocols.Filter("BGP")
+	ids := make([]string, 0, len(protocolsBgp))
+	for id := range protocolsBgp {
+		ids = append(ids, id)
 	}
+	sort.Strings(ids)
 
-	// Parse the routes
-	imported := parseRoutesData(birdImported["routes"].([]interface{}), self.config)
-	// Sort routes for deterministic ordering
-	sort.Sort(imported)
-	response.Imported = imported
+	tables := make([]string, len(ids))
+	for i, id := range ids {
+		tables[i] = protocolsBgp[id].Table
+	}
 
-	// Iterate over all the protocols and fetch the filtered routes for everyone
-	protocolsBgp := self.filterProtocolsBgp(birdProtocols)
-	for protocolId, protocolsData := range protocolsBgp["protocols"].(map[string]interface{}) {
-		peer := protocolsData.(map[string]interface{})["neighbor_address"].(string)
-		learntFrom := mustString(protocolsData.(map[string]interface{})["learnt_from"], peer)
+	results := make([]api.Routes, len(ids))
+	failed := self.dumpWorkers.fetchTables(ctx, tables, func(ctx context.Context, i int) error {
+		protocol := protocolsBgp[ids[i]]
+		peer := protocol.NeighborAddress
+		learntFrom := stringOr(protocol.LearntFrom, peer)
 
-		// Fetch filtered routes
-		_, filtered, err := self.fetchFilteredRoutes(protocolId)
+		_, filtered, err := self.fetchProtocolFilteredRoutes(ctx, protocol)
 		if err != nil {
-			continue
+			return err
 		}
 
 		// Perform route deduplication
-		filtered = self.filterRoutesByPeerOrLearntFrom(filtered, peer, learntFrom)
+		results[i] = self.filterRoutesByPeerOrLearntFrom(filtered, peer, learntFrom)
+		return nil
+	})
+
+	// The request was canceled, the result is incomplete
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, filtered := range results {
 		response.Filtered = append(response.Filtered, filtered...)
 	}
 
+	// Report the failed tables along with the result
+	if len(failed) > 0 {
+		return response, &sources.PartialError{Failed: failed}
+	}
+
 	return response, nil
 }
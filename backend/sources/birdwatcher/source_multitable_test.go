@@ -1,7 +1,18 @@
 package birdwatcher
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/alice-lg/alice-lg/backend/api"
+	"github.com/alice-lg/alice-lg/backend/sources"
 )
 
 func TestGetMasterPipeName(t *testing.T) {
@@ -23,3 +34,163 @@ func TestGetMasterPipeName(t *testing.T) {
 	}
 
 }
+
+// Serve the recorded multi table responses from testdata,
+// tables in fail respond with an error.
+func testMultiTableServer(fail map[string]bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			name := strings.Trim(req.URL.Path, "/")
+			if strings.HasPrefix(name, "routes/pipe/filtered") {
+				name = "routes/pipe/filtered/" + req.URL.Query().Get("table")
+			}
+			if fail[name] {
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+
+			name = strings.Replace(name, "/", "_", -1)
+			data, err := ioutil.ReadFile("testdata/multi_table/" + name + ".json")
+			if err != nil {
+				http.NotFound(w, req)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(data)
+		}))
+}
+
+func testMultiTableBirdwatcher(api string, concurrency int) *MultiTableBirdwatcher {
+	config := Config{
+		Id:                 "rs1",
+		Api:                api,
+		Type:               "multi_table",
+		Timezone:           "UTC",
+		ServerTime:         "2006-01-02T15:04:05Z07:00",
+		ServerTimeShort:    "2006-01-02 15:04:05",
+		PeerTablePrefix:    "T",
+		PipeProtocolPrefix: "M",
+		Concurrency:        concurrency,
+	}
+	return newBirdwatcher(config).(*MultiTableBirdwatcher)
+}
+
+// Reset the age of the routes, derived from the current time
+func resetRoutesAge(routes api.Routes) {
+	for _, r := range routes {
+		r.Age = 0
+	}
+}
+
+// Encode the routes of a result for comparing
+// with the recorded result
+func encodeAllRoutes(t *testing.T, res *api.RoutesResponse) []byte {
+	resetRoutesAge(res.Imported)
+	resetRoutesAge(res.Filtered)
+	data, err := json.MarshalIndent(struct {
+		Imported api.Routes `json:"imported"`
+		Filtered api.Routes `json:"filtered"`
+	}{res.Imported, res.Filtered}, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestAllRoutesConcurrent(t *testing.T) {
+	server := testMultiTableServer(nil)
+	defer server.Close()
+
+	// The result of fetching the tables one after
+	// another, before the tables were fetched by workers.
+	// 5 peers with 3 imported routes each, 2 filtered and
+	// one filtered by the pipe to master.
+	expected, err := ioutil.ReadFile(
+		"testdata/multi_table/all_routes_expected.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, concurrency := range []int{1, 4} {
+		res, err := testMultiTableBirdwatcher(server.URL, concurrency).AllRoutes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Imported) != 15 || len(res.Filtered) != 15 {
+			t.Error("Unexpected number of routes:",
+				len(res.Imported), len(res.Filtered))
+		}
+		if !bytes.Equal(encodeAllRoutes(t, res), expected) {
+			t.Error("Expected the recorded result with concurrency", concurrency)
+		}
+	}
+}
+
+func TestAllRoutesPartialFailure(t *testing.T) {
+	server := testMultiTableServer(map[string]bool{
+		"routes/pipe/filtered/T65003": true,
+	})
+	defer server.Close()
+
+	res, err := testMultiTableBirdwatcher(server.URL, 4).AllRoutes()
+	partial, ok := err.(*sources.PartialError)
+	if !ok {
+		t.Fatal("Expected partial error, got:", err)
+	}
+	if len(partial.Failed) != 1 || partial.Failed["T65003"] == nil {
+		t.Error("Expected table T65003 to fail, got:", partial.Failed)
+	}
+
+	// The routes of the other tables are present
+	if res == nil || len(res.Imported) != 15 {
+		t.Fatal("Expected imported routes in partial result")
+	}
+	for _, r := range res.Filtered {
+		if r.Gateway == "10.0.0.3" || r.Gateway == "10.0.0.4" {
+			t.Error("Unexpected route of failed table:", r.Network)
+		}
+	}
+	if len(res.Filtered) != 9 {
+		t.Error("Expected 9 filtered routes, got:", len(res.Filtered))
+	}
+}
+
+func TestAllRoutesCanceled(t *testing.T) {
+	// Birdwatcher does not respond until the request is canceled
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			<-req.Context().Done()
+		}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	t0 := time.Now()
+	_, err := testMultiTableBirdwatcher(server.URL, 4).AllRoutesContext(ctx)
+	if err == nil {
+		t.Error("Expected an error for the canceled request")
+	}
+	if time.Since(t0) > 5*time.Second {
+		t.Error("Expected the request to be canceled, took:", time.Since(t0))
+	}
+}
+
+func TestNeighboursPartialFailure(t *testing.T) {
+	server := testMultiTableServer(map[string]bool{
+		"routes/pipe/filtered/T65003": true,
+	})
+	defer server.Close()
+
+	res, err := testMultiTableBirdwatcher(server.URL, 4).Neighbours()
+	partial, ok := err.(*sources.PartialError)
+	if !ok {
+		t.Fatal("Expected partial error, got:", err)
+	}
+	if partial.Failed["T65003"] == nil {
+		t.Error("Expected table T65003 to fail, got:", partial.Failed)
+	}
+	if res == nil || len(res.Neighbours) == 0 {
+		t.Fatal("Expected the neighbors in the partial result")
+	}
+}
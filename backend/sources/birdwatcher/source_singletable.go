@@ -2,9 +2,10 @@ package birdwatcher
 
 import (
 	"github.com/alice-lg/alice-lg/backend/api"
+	"github.com/alice-lg/alice-lg/backend/sources"
 
+	"context"
 	"log"
-	"sort"
 )
 
 
@@ -13,72 +14,69 @@ type SingleTableBirdwatcher struct {
 }
 
 
-func (self *SingleTableBirdwatcher) fetchReceivedRoutes(neighborId string) (*api.ApiStatus, api.Routes, error) {
-	// Query birdwatcher
-	bird, err := self.client.GetJson("/routes/protocol/" + neighborId)
-	if err != nil {
+func (self *SingleTableBirdwatcher) fetchReceivedRoutes(ctx context.Context, neighborId string) (*api.ApiStatus, api.Routes, error) {
+	if err := self.requireModule("routes_protocol"); err != nil {
 		return nil, nil, err
 	}
 
-	// Use api status from first request
-	apiStatus, err := parseApiStatus(bird, self.config)
+	// Query birdwatcher
+	apiStatus, bird, err := self.fetchRoutes(ctx, "/routes/protocol/"+neighborId)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	// Parse the routes
-	received, err := parseRoutes(bird, self.config)
+	received, err := bird.SortedRoutes("routes")
 	if err != nil {
 		log.Println("WARNING Could not retrieve received routes:", err)
 		log.Println("Is the 'routes_protocol' module active in birdwatcher?")
-		return &apiStatus, nil, err
+		return apiStatus, nil, err
 	}
 
-	return &apiStatus, received, nil
+	return apiStatus, received, nil
 }
 
-func (self *SingleTableBirdwatcher) fetchFilteredRoutes(neighborId string) (*api.ApiStatus, api.Routes, error) {
-	// Query birdwatcher
-	bird, err := self.client.GetJson("/routes/filtered/" + neighborId)
-	if err != nil {
+func (self *SingleTableBirdwatcher) fetchFilteredRoutes(ctx context.Context, neighborId string) (*api.ApiStatus, api.Routes, error) {
+	if err := self.requireModule("routes_filtered"); err != nil {
 		return nil, nil, err
 	}
 
-	// Use api status from first request
-	apiStatus, err := parseApiStatus(bird, self.config)
+	// Query birdwatcher
+	apiStatus, bird, err := self.fetchRoutes(ctx, "/routes/filtered/"+neighborId)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	// Parse the routes
-	filtered, err := parseRoutes(bird, self.config)
+	filtered, err := bird.SortedRoutes("routes")
 	if err != nil {
 		log.Println("WARNING Could not retrieve filtered routes:", err)
 		log.Println("Is the 'routes_filtered' module active in birdwatcher?")
-		return &apiStatus, nil, err
+		return apiStatus, nil, err
 	}
 
-	return &apiStatus, filtered, nil
+	return apiStatus, filtered, nil
 }
 
-func (self *SingleTableBirdwatcher) fetchNotExportedRoutes(neighborId string) (*api.ApiStatus, api.Routes, error) {
-	// Query birdwatcher
-	bird, err := self.client.GetJson("/routes/noexport/" + neighborId)
+func (self *SingleTableBirdwatcher) fetchNotExportedRoutes(ctx context.Context, neighborId string) (*api.ApiStatus, api.Routes, error) {
+	if err := self.requireModule("routes_noexport"); err != nil {
+		return nil, nil, err
+	}
 
-	// Use api status from first request
-	apiStatus, err := parseApiStatus(bird, self.config)
+	// Query birdwatcher
+	apiStatus, bird, err := self.fetchRoutes(ctx, "/routes/noexport/"+neighborId)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	// Parse the routes
-	notExported, err := parseRoutes(bird, self.config)
+	notExported, err := bird.SortedRoutes("routes")
 	if err != nil {
 		log.Println("WARNING Could not retrieve routes not exported:", err)
 		log.Println("Is the 'routes_noexport' module active in birdwatcher?")
 	}
 
-	return &apiStatus, notExported, nil
+	return apiStatus, notExported, nil
 }
 
 /*
@@ -93,7 +91,7 @@ RoutesNotExported() API.
 
 A route deduplication is applied.
 */
-func (self *SingleTableBirdwatcher) fetchRequiredRoutes(neighborId string) (*api.RoutesResponse, error) {
+func (self *SingleTableBirdwatcher) fetchRequiredRoutes(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
 	// Allow only one concurrent request for this neighbor
 	// to our backend server.
 	self.routesFetchMutex.Lock(neighborId)
@@ -106,13 +104,13 @@ func (self *SingleTableBirdwatcher) fetchRequiredRoutes(neighborId string) (*api
 	}
 
 	// First: get routes received
-	apiStatus, receivedRoutes, err := self.fetchReceivedRoutes(neighborId)
+	apiStatus, receivedRoutes, err := self.fetchReceivedRoutes(ctx, neighborId)
 	if err != nil {
 		return nil, err
 	}
 
 	// Second: get routes filtered
-	_, filteredRoutes, err := self.fetchFilteredRoutes(neighborId)
+	_, filteredRoutes, err := self.fetchFilteredRoutes(ctx, neighborId)
 	if err != nil {
 		return nil, err
 	}
@@ -141,7 +139,7 @@ func (self *SingleTableBirdwatcher) fetchRequiredRoutes(neighborId string) (*api
 
 
 // Get neighbors from protocols
-func (self *SingleTableBirdwatcher) Neighbours() (*api.NeighboursResponse, error) {
+func (self *SingleTableBirdwatcher) NeighboursContext(ctx context.Context) (*api.NeighboursResponse, error) {
 	// Check if we hit the cache
 	response := self.neighborsCache.Get()
 	if response != nil {
@@ -149,7 +147,7 @@ func (self *SingleTableBirdwatcher) Neighbours() (*api.NeighboursResponse, error
 	}
 
 	// Query birdwatcher
-	bird, err := self.client.GetJson("/protocols/bgp")
+	bird, err := self.client.GetJsonContext(ctx, "/protocols/bgp")
 	if err != nil {
 		return nil, err
 	}
@@ -178,17 +176,20 @@ func (self *SingleTableBirdwatcher) Neighbours() (*api.NeighboursResponse, error
 }
 
 // Get filtered and exported routes
-func (self *SingleTableBirdwatcher) Routes(neighbourId string) (*api.RoutesResponse, error) {
+func (self *SingleTableBirdwatcher) RoutesContext(ctx context.Context, neighbourId string) (*api.RoutesResponse, error) {
 	response := &api.RoutesResponse{}
 
 	// Fetch required routes first (received and filtered)
-	required, err := self.fetchRequiredRoutes(neighbourId)
+	required, err := self.fetchRequiredRoutes(ctx, neighbourId)
 	if err != nil {
 		return nil, err
 	}
 
 	// Optional: NoExport
-	_, notExported, err := self.fetchNotExportedRoutes(neighbourId)
+	_, notExported, err := self.fetchNotExportedRoutes(ctx, neighbourId)
+	if _, ok := err.(*sources.UnsupportedError); ok {
+		err = nil
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -202,7 +203,7 @@ func (self *SingleTableBirdwatcher) Routes(neighbourId string) (*api.RoutesRespo
 }
 
 // Get all received routes
-func (self *SingleTableBirdwatcher) RoutesReceived(neighborId string) (*api.RoutesResponse, error) {
+func (self *SingleTableBirdwatcher) RoutesReceivedContext(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
 	response := &api.RoutesResponse{}
 
 	// Check if we hit the cache
@@ -215,7 +216,7 @@ func (self *SingleTableBirdwatcher) RoutesReceived(neighborId string) (*api.Rout
 
 	// Fetch required routes first (received and filtered)
 	// However: Store in separate cache for faster access
-	routes, err := self.fetchRequiredRoutes(neighborId)
+	routes, err := self.fetchRequiredRoutes(ctx, neighborId)
 	if err != nil {
 		return nil, err
 	}
@@ -227,7 +228,7 @@ func (self *SingleTableBirdwatcher) RoutesReceived(neighborId string) (*api.Rout
 }
 
 // Get all filtered routes
-func (self *SingleTableBirdwatcher) RoutesFiltered(neighborId string) (*api.RoutesResponse, error) {
+func (self *SingleTableBirdwatcher) RoutesFilteredContext(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
 	response := &api.RoutesResponse{}
 
 	// Check if we hit the cache
@@ -240,7 +241,7 @@ func (self *SingleTableBirdwatcher) RoutesFiltered(neighborId string) (*api.Rout
 
 	// Fetch required routes first (received and filtered)
 	// However: Store in separate cache for faster access
-	routes, err := self.fetchRequiredRoutes(neighborId)
+	routes, err := self.fetchRequiredRoutes(ctx, neighborId)
 	if err != nil {
 		return nil, err
 	}
@@ -252,7 +253,7 @@ func (self *SingleTableBirdwatcher) RoutesFiltered(neighborId string) (*api.Rout
 }
 
 // Get all not exported routes
-func (self *SingleTableBirdwatcher) RoutesNotExported(neighborId string) (*api.RoutesResponse, error) {
+func (self *SingleTableBirdwatcher) RoutesNotExportedContext(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
 	// Check if we hit the cache
 	response := self.routesNotExportedCache.Get(neighborId)
 	if response != nil {
@@ -260,7 +261,7 @@ func (self *SingleTableBirdwatcher) RoutesNotExported(neighborId string) (*api.R
 	}
 
 	// Fetch not exported routes
-	apiStatus, routes, err := self.fetchNotExportedRoutes(neighborId)
+	apiStatus, routes, err := self.fetchNotExportedRoutes(ctx, neighborId)
 	if err != nil {
 		return nil, err
 	}
@@ -276,39 +277,39 @@ func (self *SingleTableBirdwatcher) RoutesNotExported(neighborId string) (*api.R
 	return response, nil
 }
 
-func (self *SingleTableBirdwatcher) AllRoutes() (*api.RoutesResponse, error) {
-	// First fetch all routes from the master table
-	birdImported, err := self.client.GetJson("/routes/table/master")
-	if err != nil {
+func (self *SingleTableBirdwatcher) AllRoutesContext(ctx context.Context) (*api.RoutesResponse, error) {
+	if err := self.requireModule(CAPABILITY_ROUTES_DUMP); err != nil {
 		return nil, err
 	}
 
-	// Then fetch all filtered routes from the master table
-	birdFiltered, err := self.client.GetJson("/routes/table/master/filtered")
+	// First fetch all routes from the master table
+	_, birdImported, err := self.fetchRoutes(ctx, "/routes/table/master")
 	if err != nil {
 		return nil, err
 	}
 
-	// Use api status from second request
-	apiStatus, err := parseApiStatus(birdFiltered, self.config)
+	// Then fetch all filtered routes from the master table
+	apiStatus, birdFiltered, err := self.fetchRoutes(ctx, "/routes/table/master/filtered")
 	if err != nil {
 		return nil, err
 	}
 
+	// Use api status from second request
 	response := &api.RoutesResponse{
-		Api:    apiStatus,
+		Api: *apiStatus,
 	}
 
-	// Parse the routes
-	imported := parseRoutesData(birdImported["routes"].([]interface{}), self.config)
 	// Sort routes for deterministic ordering
-	sort.Sort(imported)
+	imported, err := birdImported.SortedRoutes("routes")
+	if err != nil {
+		return nil, err
+	}
 	response.Imported = imported
 
-	// Parse the routes
-	filtered := parseRoutesData(birdFiltered["routes"].([]interface{}), self.config)
-	// Sort routes for deterministic ordering
-	sort.Sort(filtered)
+	filtered, err := birdFiltered.SortedRoutes("routes")
+	if err != nil {
+		return nil, err
+	}
 	response.Filtered = filtered
 
 	return response, nil
@@ -0,0 +1,103 @@
+package birdwatcher
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+/*
+The multi table birdwatcher fetches the routes of each
+peer table with a separate request. These requests are
+made by a bounded pool of workers, shared by the requests
+of a source. Dumping the routes for the store uses another
+pool, so a long running dump does not block other requests.
+The number of workers of each pool is configured with
+`concurrency`; with a concurrency of 1 the tables are
+fetched one after another.
+
+Fetching a table is canceled after the `table_timeout`,
+or when the context of the request is done.
+A failed table does not fail the entire result; the
+errors are returned by table.
+*/
+
+const (
+	DEFAULT_CONCURRENCY   = 8
+	DEFAULT_TABLE_TIMEOUT = 120
+)
+
+// A bounded pool of workers
+type workerPool struct {
+	slots   chan struct{}
+	timeout time.Duration
+}
+
+func newWorkerPool(config Config) *workerPool {
+	concurrency := config.Concurrency
+	if concurrency <= 0 {
+		concurrency = DEFAULT_CONCURRENCY
+	}
+	timeout := time.Duration(config.TableTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = DEFAULT_TABLE_TIMEOUT * time.Second
+	}
+
+	return &workerPool{
+		slots:   make(chan struct{}, concurrency),
+		timeout: timeout,
+	}
+}
+
+/*
+Run the fetch for each table with the next free worker,
+and wait for all of them. The fetch gets the index of the
+table, results should be stored by index to keep the order
+of the tables.
+
+The errors of failed fetches are returned by table.
+*/
+func (self *workerPool) fetchTables(
+	ctx context.Context,
+	tables []string,
+	fetch func(ctx context.Context, i int) error,
+) map[string]error {
+	errs := make([]error, len(tables))
+
+	wg := sync.WaitGroup{}
+	for i := range tables {
+		// Tables not yet fetched fail when canceled
+		select {
+		case self.slots <- struct{}{}:
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+
+		go func(i int) {
+			defer func() {
+				<-self.slots
+				wg.Done()
+			}()
+
+			tableCtx, cancel := context.WithTimeout(ctx, self.timeout)
+			defer cancel()
+
+			errs[i] = fetch(tableCtx, i)
+		}(i)
+	}
+	wg.Wait()
+
+	failed := make(map[string]error)
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		log.Println("WARNING Could not fetch table", tables[i], ":", err)
+		failed[tables[i]] = err
+	}
+
+	return failed
+}
@@ -17,6 +17,14 @@ func mustString(value interface{}, fallback string) string {
 	return sval
 }
 
+// Use fallback for empty string
+func stringOr(value string, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
 // Assert list of strings
 func mustStringList(data interface{}) []string {
 	list := []string{}
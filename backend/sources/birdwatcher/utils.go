@@ -3,7 +3,6 @@ package birdwatcher
 import (
 	"fmt"
 	"strings"
-	"sync"
 
 	"github.com/alice-lg/alice-lg/backend/api"
 )
@@ -26,33 +25,6 @@ func getNeighbourById(neighbours api.Neighbours, id string) (*api.Neighbour, err
 	return unknown, fmt.Errorf("Neighbour not found")
 }
 
-/*
-LockMap: Uses the sync.Map to manage locks, accessed by a key.
-TODO: Maybe this would be a nice generic helper
-*/
-type LockMap struct {
-	locks *sync.Map
-}
-
-func NewLockMap() *LockMap {
-	return &LockMap{
-		locks: &sync.Map{},
-	}
-}
-
-func (self *LockMap) Lock(key string) {
-	mutex, _ := self.locks.LoadOrStore(key, &sync.Mutex{})
-	mutex.(*sync.Mutex).Lock()
-}
-
-func (self *LockMap) Unlock(key string) {
-	mutex, ok := self.locks.Load(key)
-	if !ok {
-		return // Nothing to unlock
-	}
-	mutex.(*sync.Mutex).Unlock()
-}
-
 func isProtocolUp(protocol string) bool {
 	protocol = strings.ToLower(protocol)
 	return protocol == "up"
@@ -0,0 +1,47 @@
+package bmp
+
+import (
+	"github.com/alice-lg/alice-lg/backend/sources"
+
+	"github.com/go-ini/ini"
+)
+
+type Config struct {
+	Id   string
+	Name string
+
+	// Listen is the address the BMP station accepts
+	// sessions from monitored routers on.
+	Listen string `ini:"listen"`
+
+	// Routers optionally restricts the routers allowed
+	// to connect to the station to the given addresses.
+	Routers []string `ini:"routers"`
+}
+
+func init() {
+	sources.Register(&sources.Backend{
+		Name:      "bmp",
+		Configure: configure,
+		New: func(config interface{}) sources.Source {
+			return NewBMP(config.(Config))
+		},
+		// The station accepts sessions from the start
+		Eager: true,
+	})
+}
+
+// Parse the config section of a BMP source
+func configure(
+	base sources.Definition,
+	section *ini.Section,
+) ([]*sources.Definition, error) {
+	c := Config{
+		Id:   base.Id,
+		Name: base.Name,
+	}
+	section.MapTo(&c)
+
+	base.Config = c
+	return []*sources.Definition{&base}, nil
+}
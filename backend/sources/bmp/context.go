@@ -0,0 +1,42 @@
+package bmp
+
+import (
+	"context"
+
+	"github.com/alice-lg/alice-lg/backend/api"
+)
+
+// Requests without a context are made with
+// the background context.
+
+func (self *BMP) Status() (*api.StatusResponse, error) {
+	return self.StatusContext(context.Background())
+}
+
+func (self *BMP) Neighbours() (*api.NeighboursResponse, error) {
+	return self.NeighboursContext(context.Background())
+}
+
+func (self *BMP) NeighboursStatus() (*api.NeighboursStatusResponse, error) {
+	return self.NeighboursStatusContext(context.Background())
+}
+
+func (self *BMP) Routes(neighborId string) (*api.RoutesResponse, error) {
+	return self.RoutesContext(context.Background(), neighborId)
+}
+
+func (self *BMP) RoutesReceived(neighborId string) (*api.RoutesResponse, error) {
+	return self.RoutesReceivedContext(context.Background(), neighborId)
+}
+
+func (self *BMP) RoutesFiltered(neighborId string) (*api.RoutesResponse, error) {
+	return self.RoutesFilteredContext(context.Background(), neighborId)
+}
+
+func (self *BMP) RoutesNotExported(neighborId string) (*api.RoutesResponse, error) {
+	return self.RoutesNotExportedContext(context.Background(), neighborId)
+}
+
+func (self *BMP) AllRoutes() (*api.RoutesResponse, error) {
+	return self.AllRoutesContext(context.Background())
+}
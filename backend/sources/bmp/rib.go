@@ -0,0 +1,241 @@
+package bmp
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/alice-lg/alice-lg/backend/api"
+	"github.com/alice-lg/alice-lg/backend/sources/bgputil"
+
+	"github.com/osrg/gobgp/pkg/packet/bgp"
+	"github.com/osrg/gobgp/pkg/packet/bmp"
+)
+
+// A RibEntry is a route as it was announced by the peer
+type RibEntry struct {
+	Network  string
+	PathId   uint32
+	Bgp      api.BgpInfo
+	Received time.Time
+}
+
+// A Rib holds the routes of a peer, keyed by
+// network and path identifier.
+type Rib map[string]*RibEntry
+
+/*
+Peer is the state of a BGP session monitored by a router:
+The session state from Peer Up / Peer Down notifications and
+the pre- and post-policy Adj-RIB-In from route monitoring.
+*/
+type Peer struct {
+	Id      string
+	Router  string
+	Address string
+	Asn     int
+	BgpId   string
+
+	Up        bool
+	Since     time.Time
+	LastError string
+
+	PrePolicy  Rib
+	PostPolicy Rib
+
+	// Routers are not required to send the post-policy
+	// Adj-RIB-In. We fall back to the pre-policy RIB
+	// for accepted routes if we never received it.
+	HasPostPolicy bool
+}
+
+// Get the id of a peer as seen by a router. Peers in
+// different route distinguishers are kept separate.
+func peerId(router string, header *bmp.BMPPeerHeader) string {
+	id := router + "_" + header.PeerAddress.String()
+	if header.PeerDistinguisher != 0 {
+		id += fmt.Sprintf("_%d", header.PeerDistinguisher)
+	}
+	return id
+}
+
+// Make a new peer from a per peer header
+func newPeer(router string, header *bmp.BMPPeerHeader) *Peer {
+	return &Peer{
+		Id:      peerId(router, header),
+		Router:  router,
+		Address: header.PeerAddress.String(),
+		Asn:     int(header.PeerAS),
+		BgpId:   net.IP(header.PeerBGPID).String(),
+
+		PrePolicy:  Rib{},
+		PostPolicy: Rib{},
+	}
+}
+
+// Get the time of a per peer header. Routers may
+// not set the timestamp, we use the current time then.
+func peerTimestamp(header *bmp.BMPPeerHeader) time.Time {
+	if header.Timestamp == 0 {
+		return time.Now().UTC()
+	}
+	sec := int64(header.Timestamp)
+	nsec := int64((header.Timestamp - float64(sec)) * 1e9)
+	return time.Unix(sec, nsec).UTC()
+}
+
+// Get the key of a prefix in the rib
+func ribKey(prefix bgp.AddrPrefixInterface) string {
+	if prefix.PathIdentifier() == 0 {
+		return prefix.String()
+	}
+	return fmt.Sprintf("%s_%d", prefix.String(), prefix.PathIdentifier())
+}
+
+// Reset the session state and drop all routes
+func (self *Peer) reset() {
+	self.PrePolicy = Rib{}
+	self.PostPolicy = Rib{}
+	self.HasPostPolicy = false
+}
+
+/*
+Apply a BGP update received through route monitoring
+to the pre- or post-policy Adj-RIB-In of the peer.
+*/
+func (self *Peer) update(
+	postPolicy bool,
+	received time.Time,
+	msg *bgp.BGPMessage,
+) {
+	update, ok := msg.Body.(*bgp.BGPUpdate)
+	if !ok {
+		return
+	}
+
+	rib := self.PrePolicy
+	if postPolicy {
+		rib = self.PostPolicy
+		self.HasPostPolicy = true
+	}
+
+	// Withdraws
+	for _, prefix := range update.WithdrawnRoutes {
+		delete(rib, ribKey(prefix))
+	}
+
+	announced := []bgp.AddrPrefixInterface{}
+	for _, prefix := range update.NLRI {
+		announced = append(announced, prefix)
+	}
+
+	for _, attr := range update.PathAttributes {
+		switch a := attr.(type) {
+		case *bgp.PathAttributeMpReachNLRI:
+			announced = append(announced, a.Value...)
+		case *bgp.PathAttributeMpUnreachNLRI:
+			for _, prefix := range a.Value {
+				delete(rib, ribKey(prefix))
+			}
+		}
+	}
+
+	if len(announced) == 0 {
+		return
+	}
+
+	info := bgputil.DecodePathAttributes(update.PathAttributes)
+	for _, prefix := range announced {
+		rib[ribKey(prefix)] = &RibEntry{
+			Network:  prefix.String(),
+			PathId:   prefix.PathIdentifier(),
+			Bgp:      info,
+			Received: received,
+		}
+	}
+}
+
+// Get the accepted routes of the peer
+func (self *Peer) accepted() Rib {
+	if self.HasPostPolicy {
+		return self.PostPolicy
+	}
+	return self.PrePolicy
+}
+
+// Get the filtered routes: All pre-policy routes
+// which are not present in the post-policy rib.
+func (self *Peer) filtered() Rib {
+	filtered := Rib{}
+	if !self.HasPostPolicy {
+		return filtered
+	}
+	for key, entry := range self.PrePolicy {
+		if _, ok := self.PostPolicy[key]; !ok {
+			filtered[key] = entry
+		}
+	}
+	return filtered
+}
+
+// Make an api route from a rib entry
+func (self *Peer) route(key string, entry *RibEntry, now time.Time) *api.Route {
+	return &api.Route{
+		Id:          key,
+		NeighbourId: self.Id,
+
+		Network: entry.Network,
+		Gateway: entry.Bgp.NextHop,
+		Bgp:     entry.Bgp,
+		Age:     now.Sub(entry.Received),
+		Type:    []string{"BGP"},
+
+		Details: api.Details{
+			"router":  self.Router,
+			"path_id": entry.PathId,
+		},
+	}
+}
+
+// Get the routes of a rib
+func (self *Peer) routes(rib Rib, now time.Time) api.Routes {
+	routes := make(api.Routes, 0, len(rib))
+	for key, entry := range rib {
+		routes = append(routes, self.route(key, entry, now))
+	}
+	return routes
+}
+
+// Make an api neighbour from the peer
+func (self *Peer) neighbour(rsId string, now time.Time) *api.Neighbour {
+	state := "down"
+	if self.Up {
+		state = "up"
+	}
+
+	accepted := len(self.accepted())
+	filtered := len(self.filtered())
+
+	return &api.Neighbour{
+		Id: self.Id,
+
+		Address:     self.Address,
+		Asn:         self.Asn,
+		State:       state,
+		Description: fmt.Sprintf("AS%d via %s", self.Asn, self.Router),
+
+		RoutesReceived: accepted + filtered,
+		RoutesFiltered: filtered,
+		RoutesAccepted: accepted,
+
+		Uptime:    now.Sub(self.Since),
+		LastError: self.LastError,
+
+		RouteServerId: rsId,
+
+		Details: map[string]interface{}{
+			"router": self.Router,
+			"bgp_id": self.BgpId,
+		},
+	}
+}
@@ -0,0 +1,281 @@
+package bmp
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/alice-lg/alice-lg/backend/api"
+	"github.com/alice-lg/alice-lg/backend/sources"
+)
+
+const (
+	DEFAULT_LISTEN = ":11019"
+)
+
+/*
+BMP is a source backed by the state of a BMP station.
+All data is kept in memory and updated as messages
+arrive, so responses are always current and not cached.
+*/
+type BMP struct {
+	config  Config
+	station *Station
+}
+
+// Make a new BMP source and start accepting sessions
+func NewBMP(config Config) *BMP {
+	if config.Listen == "" {
+		config.Listen = DEFAULT_LISTEN
+	}
+
+	station := NewStation(config.Routers)
+	go func() {
+		log.Println("BMP: Station", config.Id, "listening on", config.Listen)
+		err := station.ListenAndServe(config.Listen)
+		log.Println("BMP: Station", config.Id, "stopped:", err)
+	}()
+
+	return &BMP{
+		config:  config,
+		station: station,
+	}
+}
+
+// Make api status for responses
+func (self *BMP) makeApiStatus() api.ApiStatus {
+	now := time.Now().UTC()
+	return api.ApiStatus{
+		Version:         "bmp",
+		ResultFromCache: false,
+		Ttl:             now,
+		CacheStatus: api.CacheStatus{
+			CachedAt: now,
+		},
+	}
+}
+
+// Get a peer by neighbor id
+func (self *BMP) getPeer(neighborId string) (*Peer, error) {
+	peer, ok := self.station.peers[neighborId]
+	if !ok {
+		return nil, &sources.NotFoundError{Resource: "neighbor", Id: neighborId}
+	}
+	return peer, nil
+}
+
+// There is nothing cached
+func (self *BMP) ExpireCaches() int {
+	return 0
+}
+
+// The station only sees the Adj-RIB-In of the peers,
+// the routes sent to a peer are not monitored.
+func (self *BMP) Capabilities() api.SourceCapabilities {
+	return api.SourceCapabilities{
+		RoutesReceived:    true,
+		RoutesFiltered:    true,
+		RoutesNotExported: false,
+		Status:            true,
+		LiveLookup:        false,
+		NeighboursStatus:  true,
+	}
+}
+
+func (self *BMP) StatusContext(ctx context.Context) (*api.StatusResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	self.station.RLock()
+	defer self.station.RUnlock()
+
+	lastReboot := time.Time{}
+	names := []string{}
+	for _, router := range self.station.routers {
+		if router.Connected.After(lastReboot) {
+			lastReboot = router.Connected
+		}
+		name := router.Address
+		if router.Name != "" {
+			name = router.Name
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	message := "no routers connected"
+	if len(names) > 0 {
+		message = fmt.Sprintf("monitoring %v", names)
+	}
+
+	response := &api.StatusResponse{
+		Api: self.makeApiStatus(),
+		Status: api.Status{
+			ServerTime:   time.Now().UTC(),
+			LastReboot:   lastReboot,
+			LastReconfig: lastReboot,
+			Message:      message,
+			Backend:      "bmp",
+		},
+	}
+
+	return response, nil
+}
+
+func (self *BMP) NeighboursContext(ctx context.Context) (*api.NeighboursResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	self.station.RLock()
+	defer self.station.RUnlock()
+
+	now := time.Now().UTC()
+	neighbours := make(api.Neighbours, 0, len(self.station.peers))
+	for _, peer := range self.station.peers {
+		neighbours = append(neighbours, peer.neighbour(self.config.Id, now))
+	}
+	sort.Sort(neighbours)
+
+	response := &api.NeighboursResponse{
+		Api:        self.makeApiStatus(),
+		Neighbours: neighbours,
+	}
+
+	return response, nil
+}
+
+// Get live neighbor status
+func (self *BMP) NeighboursStatusContext(ctx context.Context) (*api.NeighboursStatusResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	self.station.RLock()
+	defer self.station.RUnlock()
+
+	now := time.Now().UTC()
+	status := make(api.NeighboursStatus, 0, len(self.station.peers))
+	for _, peer := range self.station.peers {
+		state := "down"
+		if peer.Up {
+			state = "up"
+		}
+		status = append(status, &api.NeighbourStatus{
+			Id:    peer.Id,
+			State: state,
+			Since: now.Sub(peer.Since),
+		})
+	}
+	sort.Sort(status)
+
+	response := &api.NeighboursStatusResponse{
+		Api:        self.makeApiStatus(),
+		Neighbours: status,
+	}
+
+	return response, nil
+}
+
+// Get filtered and accepted routes
+func (self *BMP) RoutesContext(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	self.station.RLock()
+	defer self.station.RUnlock()
+
+	peer, err := self.getPeer(neighborId)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	imported := peer.routes(peer.accepted(), now)
+	filtered := peer.routes(peer.filtered(), now)
+	sort.Sort(imported)
+	sort.Sort(filtered)
+
+	response := &api.RoutesResponse{
+		Api:         self.makeApiStatus(),
+		Imported:    imported,
+		Filtered:    filtered,
+		NotExported: api.Routes{},
+	}
+
+	return response, nil
+}
+
+// Get all received routes
+func (self *BMP) RoutesReceivedContext(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
+	routes, err := self.RoutesContext(ctx, neighborId)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &api.RoutesResponse{
+		Api:      routes.Api,
+		Imported: routes.Imported,
+	}
+
+	return response, nil
+}
+
+// Get all filtered routes
+func (self *BMP) RoutesFilteredContext(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
+	routes, err := self.RoutesContext(ctx, neighborId)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &api.RoutesResponse{
+		Api:      routes.Api,
+		Filtered: routes.Filtered,
+	}
+
+	return response, nil
+}
+
+// The Adj-RIB-Out is not monitored, so we can not
+// tell which routes were not exported.
+func (self *BMP) RoutesNotExportedContext(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
+	response := &api.RoutesResponse{
+		Api:         self.makeApiStatus(),
+		NotExported: api.Routes{},
+	}
+
+	return response, nil
+}
+
+// Get the routes of all peers
+func (self *BMP) AllRoutesContext(ctx context.Context) (*api.RoutesResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	self.station.RLock()
+	defer self.station.RUnlock()
+
+	now := time.Now().UTC()
+	imported := api.Routes{}
+	filtered := api.Routes{}
+	for _, peer := range self.station.peers {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		imported = append(imported, peer.routes(peer.accepted(), now)...)
+		filtered = append(filtered, peer.routes(peer.filtered(), now)...)
+	}
+
+	response := &api.RoutesResponse{
+		Api:      self.makeApiStatus(),
+		Imported: imported,
+		Filtered: filtered,
+	}
+
+	return response, nil
+}
@@ -0,0 +1,207 @@
+package bmp
+
+import (
+	"bufio"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/osrg/gobgp/pkg/packet/bmp"
+)
+
+const (
+	// BMP messages carry at most a BGP message, which
+	// is limited to 4096 bytes. We allow for extended
+	// messages (RFC 8654) nevertheless.
+	MAX_MESSAGE_SIZE = 65535 + bmp.BMP_HEADER_SIZE + bmp.BMP_PEER_HEADER_SIZE
+)
+
+// Get a description of a peer down reason
+func decodePeerDownReason(reason uint8) string {
+	switch reason {
+	case bmp.BMP_PEER_DOWN_REASON_LOCAL_BGP_NOTIFICATION:
+		return "local system closed the session with a notification"
+	case bmp.BMP_PEER_DOWN_REASON_LOCAL_NO_NOTIFICATION:
+		return "local system closed the session"
+	case bmp.BMP_PEER_DOWN_REASON_REMOTE_BGP_NOTIFICATION:
+		return "remote system closed the session with a notification"
+	case bmp.BMP_PEER_DOWN_REASON_REMOTE_NO_NOTIFICATION:
+		return "remote system closed the session"
+	case bmp.BMP_PEER_DOWN_REASON_PEER_DE_CONFIGURED:
+		return "peer de-configured"
+	}
+	return "unknown reason"
+}
+
+// A Router is a monitored router connected to the station
+type Router struct {
+	Address   string
+	Name      string
+	Connected time.Time
+}
+
+/*
+Station accepts BMP sessions from monitored routers
+and keeps the state of all monitored peers in memory.
+*/
+type Station struct {
+	allowed map[string]bool
+
+	routers map[string]*Router
+	peers   map[string]*Peer
+	sync.RWMutex
+}
+
+// Make a new station. If no routers are given,
+// all routers are allowed to connect.
+func NewStation(routers []string) *Station {
+	allowed := make(map[string]bool)
+	for _, r := range routers {
+		allowed[net.ParseIP(r).String()] = true
+	}
+
+	return &Station{
+		allowed: allowed,
+		routers: make(map[string]*Router),
+		peers:   make(map[string]*Peer),
+	}
+}
+
+// Listen for BMP sessions and serve them. This blocks
+// until the listener fails.
+func (self *Station) ListenAndServe(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	return self.Serve(listener)
+}
+
+// Accept BMP sessions from the listener
+func (self *Station) Serve(listener net.Listener) error {
+	defer listener.Close()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go self.handleConn(conn)
+	}
+}
+
+// Handle a BMP session of a monitored router
+func (self *Station) handleConn(conn net.Conn) {
+	defer conn.Close()
+
+	host, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+	router := net.ParseIP(host).String()
+	if len(self.allowed) > 0 && !self.allowed[router] {
+		log.Println("BMP: Rejecting session from unknown router:", router)
+		return
+	}
+
+	log.Println("BMP: Accepted session from router:", router)
+	self.Lock()
+	self.routers[router] = &Router{
+		Address:   router,
+		Connected: time.Now().UTC(),
+	}
+	self.Unlock()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 4096), MAX_MESSAGE_SIZE)
+	scanner.Split(bmp.SplitBMP)
+
+	for scanner.Scan() {
+		msg, err := bmp.ParseBMPMessage(scanner.Bytes())
+		if err != nil {
+			log.Println("BMP: Error while parsing message from", router, ":", err)
+			continue
+		}
+		self.handleMessage(router, msg)
+	}
+	if err := scanner.Err(); err != nil {
+		log.Println("BMP: Session with router", router, "failed:", err)
+	}
+
+	log.Println("BMP: Session with router", router, "closed")
+	self.disconnect(router)
+}
+
+// Mark all peers of a router as down, as we
+// lost track of their state.
+func (self *Station) disconnect(router string) {
+	self.Lock()
+	defer self.Unlock()
+
+	delete(self.routers, router)
+	now := time.Now().UTC()
+	for _, peer := range self.peers {
+		if peer.Router != router {
+			continue
+		}
+		peer.Up = false
+		peer.Since = now
+		peer.LastError = "BMP session closed"
+		peer.reset()
+	}
+}
+
+// Get the peer for a per peer header, it will be
+// created if not present.
+func (self *Station) getPeer(router string, header *bmp.BMPPeerHeader) *Peer {
+	id := peerId(router, header)
+	peer, ok := self.peers[id]
+	if !ok {
+		peer = newPeer(router, header)
+		self.peers[id] = peer
+	}
+	return peer
+}
+
+// Apply a BMP message to the state of the station
+func (self *Station) handleMessage(router string, msg *bmp.BMPMessage) {
+	self.Lock()
+	defer self.Unlock()
+
+	switch body := msg.Body.(type) {
+	case *bmp.BMPInitiation:
+		for _, tlv := range body.Info {
+			info, ok := tlv.(*bmp.BMPInfoTLVString)
+			if !ok || info.Type != bmp.BMP_INIT_TLV_TYPE_SYS_NAME {
+				continue
+			}
+			if r, ok := self.routers[router]; ok {
+				r.Name = info.Value
+			}
+		}
+
+	case *bmp.BMPPeerUpNotification:
+		peer := self.getPeer(router, &msg.PeerHeader)
+		peer.reset()
+		peer.Up = true
+		peer.Since = peerTimestamp(&msg.PeerHeader)
+		peer.LastError = ""
+
+	case *bmp.BMPPeerDownNotification:
+		peer := self.getPeer(router, &msg.PeerHeader)
+		peer.reset()
+		peer.Up = false
+		peer.Since = peerTimestamp(&msg.PeerHeader)
+		peer.LastError = decodePeerDownReason(body.Reason)
+
+	case *bmp.BMPRouteMonitoring:
+		peer := self.getPeer(router, &msg.PeerHeader)
+		// Route monitoring implies an established
+		// session, even if we missed the peer up.
+		if !peer.Up {
+			peer.Up = true
+			peer.Since = peerTimestamp(&msg.PeerHeader)
+		}
+		peer.update(
+			msg.PeerHeader.IsPostPolicy(),
+			peerTimestamp(&msg.PeerHeader),
+			body.BGPUpdate)
+	}
+}
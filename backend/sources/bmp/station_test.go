@@ -0,0 +1,239 @@
+package bmp
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/osrg/gobgp/pkg/packet/bgp"
+	"github.com/osrg/gobgp/pkg/packet/bmp"
+)
+
+const (
+	TEST_ROUTER = "127.0.0.1"
+	TEST_PEER   = "192.0.2.1"
+)
+
+func testPeerHeader(flags uint8) bmp.BMPPeerHeader {
+	return *bmp.NewBMPPeerHeader(
+		bmp.BMP_PEER_TYPE_GLOBAL, flags, 0,
+		TEST_PEER, 65001, "192.0.2.1", 1500000000)
+}
+
+func testAttributes(nexthop string) []bgp.PathAttributeInterface {
+	return []bgp.PathAttributeInterface{
+		bgp.NewPathAttributeOrigin(bgp.BGP_ORIGIN_ATTR_TYPE_IGP),
+		bgp.NewPathAttributeAsPath([]bgp.AsPathParamInterface{
+			bgp.NewAs4PathParam(bgp.BGP_ASPATH_ATTR_TYPE_SEQ,
+				[]uint32{65001, 4200000000}),
+		}),
+		bgp.NewPathAttributeNextHop(nexthop),
+		bgp.NewPathAttributeCommunities([]uint32{65001<<16 | 42}),
+		bgp.NewPathAttributeLargeCommunities([]*bgp.LargeCommunity{
+			bgp.NewLargeCommunity(65001, 1, 2),
+		}),
+	}
+}
+
+// Make the messages of a monitored session: The peer
+// announces three prefixes and only two are accepted.
+func testSession() []*bmp.BMPMessage {
+	open := bgp.NewBGPOpenMessage(65001, 90, TEST_PEER, nil)
+
+	v6Attrs := func() []bgp.PathAttributeInterface {
+		return append(testAttributes("192.0.2.1"),
+			bgp.NewPathAttributeMpReachNLRI("2001:db8::1",
+				[]bgp.AddrPrefixInterface{
+					bgp.NewIPv6AddrPrefix(32, "2001:db8::"),
+				}))
+	}
+
+	return []*bmp.BMPMessage{
+		bmp.NewBMPInitiation([]bmp.BMPInfoTLVInterface{
+			bmp.NewBMPInfoTLVString(bmp.BMP_INIT_TLV_TYPE_SYS_NAME, "rs1"),
+		}),
+		bmp.NewBMPPeerUpNotification(
+			testPeerHeader(0), "198.51.100.1", 179, 42000, open, open),
+
+		// Pre policy
+		bmp.NewBMPRouteMonitoring(testPeerHeader(0),
+			bgp.NewBGPUpdateMessage(nil, testAttributes("192.0.2.1"),
+				[]*bgp.IPAddrPrefix{
+					bgp.NewIPAddrPrefix(24, "10.1.0.0"),
+					bgp.NewIPAddrPrefix(24, "10.2.0.0"),
+				})),
+		bmp.NewBMPRouteMonitoring(testPeerHeader(0),
+			bgp.NewBGPUpdateMessage(nil, v6Attrs(), nil)),
+
+		// Post policy
+		bmp.NewBMPRouteMonitoring(
+			testPeerHeader(bmp.BMP_PEER_FLAG_POST_POLICY),
+			bgp.NewBGPUpdateMessage(nil, testAttributes("192.0.2.1"),
+				[]*bgp.IPAddrPrefix{
+					bgp.NewIPAddrPrefix(24, "10.1.0.0"),
+				})),
+		bmp.NewBMPRouteMonitoring(
+			testPeerHeader(bmp.BMP_PEER_FLAG_POST_POLICY),
+			bgp.NewBGPUpdateMessage(nil, v6Attrs(), nil)),
+	}
+}
+
+// Serialize and parse the messages, as if
+// received from a router.
+func handleMessages(t *testing.T, station *Station, msgs []*bmp.BMPMessage) {
+	for _, msg := range msgs {
+		data, err := msg.Serialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg, err = bmp.ParseBMPMessage(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		station.handleMessage(TEST_ROUTER, msg)
+	}
+}
+
+func TestRouteMonitoring(t *testing.T) {
+	station := NewStation(nil)
+	source := &BMP{config: Config{Id: "rs1"}, station: station}
+	neighborId := TEST_ROUTER + "_" + TEST_PEER
+
+	handleMessages(t, station, testSession())
+
+	neighbours, err := source.Neighbours()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(neighbours.Neighbours) != 1 {
+		t.Fatal("Expected 1 neighbor, got:", len(neighbours.Neighbours))
+	}
+	n := neighbours.Neighbours[0]
+	if n.Id != neighborId || n.Asn != 65001 || n.State != "up" {
+		t.Error("Unexpected neighbor:", n)
+	}
+	if n.RoutesReceived != 3 || n.RoutesAccepted != 2 || n.RoutesFiltered != 1 {
+		t.Error("Unexpected route counts:", n.RoutesReceived,
+			n.RoutesAccepted, n.RoutesFiltered)
+	}
+
+	routes, err := source.Routes(neighborId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes.Imported) != 2 {
+		t.Fatal("Expected 2 imported routes, got:", len(routes.Imported))
+	}
+	if len(routes.Filtered) != 1 || routes.Filtered[0].Network != "10.2.0.0/24" {
+		t.Fatal("Expected 10.2.0.0/24 to be filtered, got:", routes.Filtered)
+	}
+
+	for _, r := range routes.Imported {
+		if r.Network == "2001:db8::/32" && r.Gateway != "192.0.2.1" {
+			// The NEXT_HOP attribute takes precedence
+			t.Error("Unexpected gateway:", r.Gateway)
+		}
+		if len(r.Bgp.AsPath) != 2 || r.Bgp.AsPath[1] != 4200000000 {
+			t.Error("Unexpected as path:", r.Bgp.AsPath)
+		}
+		if len(r.Bgp.Communities) != 1 || r.Bgp.Communities[0][1] != 42 {
+			t.Error("Unexpected communities:", r.Bgp.Communities)
+		}
+		if len(r.Bgp.LargeCommunities) != 1 || r.Bgp.LargeCommunities[0][2] != 2 {
+			t.Error("Unexpected large communities:", r.Bgp.LargeCommunities)
+		}
+	}
+
+	// Withdraw the filtered route
+	handleMessages(t, station, []*bmp.BMPMessage{
+		bmp.NewBMPRouteMonitoring(testPeerHeader(0),
+			bgp.NewBGPUpdateMessage([]*bgp.IPAddrPrefix{
+				bgp.NewIPAddrPrefix(24, "10.2.0.0"),
+			}, nil, nil)),
+	})
+	routes, _ = source.Routes(neighborId)
+	if len(routes.Filtered) != 0 {
+		t.Error("Expected withdrawn route to be gone:", routes.Filtered)
+	}
+
+	// Session goes down
+	handleMessages(t, station, []*bmp.BMPMessage{
+		bmp.NewBMPPeerDownNotification(testPeerHeader(0),
+			bmp.BMP_PEER_DOWN_REASON_REMOTE_NO_NOTIFICATION, nil, nil),
+	})
+	status, _ := source.NeighboursStatus()
+	if status.Neighbours[0].State != "down" {
+		t.Error("Expected neighbor to be down")
+	}
+	routes, _ = source.Routes(neighborId)
+	if len(routes.Imported) != 0 {
+		t.Error("Expected routes to be dropped on peer down")
+	}
+}
+
+func TestSessionClosed(t *testing.T) {
+	station := NewStation([]string{TEST_ROUTER})
+
+	listener, err := net.Listen("tcp", TEST_ROUTER+":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	client, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan bool)
+	go func() {
+		station.handleConn(conn)
+		done <- true
+	}()
+
+	for _, msg := range testSession() {
+		data, _ := msg.Serialize()
+		if _, err := client.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client.Close()
+	<-done
+
+	peer := station.peers[TEST_ROUTER+"_"+TEST_PEER]
+	if peer == nil {
+		t.Fatal("Expected peer to be known")
+	}
+	if peer.Up || peer.LastError != "BMP session closed" {
+		t.Error("Expected peer to be down after the session was closed")
+	}
+	if len(station.routers) != 0 {
+		t.Error("Expected router to be disconnected")
+	}
+}
+
+func TestRoutesCanceled(t *testing.T) {
+	station := NewStation([]string{TEST_ROUTER})
+	handleMessages(t, station, testSession())
+	source := &BMP{config: Config{Id: "rs1"}, station: station}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := source.AllRoutesContext(ctx)
+	if err != context.Canceled {
+		t.Error("Expected the request to be canceled, got:", err)
+	}
+
+	routes, err := source.AllRoutes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes.Imported) == 0 {
+		t.Error("Expected routes without a context")
+	}
+}
@@ -0,0 +1,177 @@
+package sources
+
+import (
+	"context"
+
+	"github.com/alice-lg/alice-lg/backend/api"
+)
+
+// A ContextSource is a Source with requests, which are
+// canceled when the context is done, e.g. when the
+// client went away or the deadline exceeded.
+type ContextSource interface {
+	ExpireCaches() int
+	StatusContext(ctx context.Context) (*api.StatusResponse, error)
+	NeighboursContext(ctx context.Context) (*api.NeighboursResponse, error)
+	NeighboursStatusContext(ctx context.Context) (*api.NeighboursStatusResponse, error)
+	RoutesContext(ctx context.Context, neighbourId string) (*api.RoutesResponse, error)
+	RoutesReceivedContext(ctx context.Context, neighbourId string) (*api.RoutesResponse, error)
+	RoutesFilteredContext(ctx context.Context, neighbourId string) (*api.RoutesResponse, error)
+	RoutesNotExportedContext(ctx context.Context, neighbourId string) (*api.RoutesResponse, error)
+	AllRoutesContext(ctx context.Context) (*api.RoutesResponse, error)
+}
+
+// A PrefixLookupContextSource can look up a prefix,
+// the lookup is canceled when the context is done.
+type PrefixLookupContextSource interface {
+	LookupPrefixContext(ctx context.Context, prefix string) (*api.RoutesLookupResponse, error)
+}
+
+/*
+WithContext gets the context aware variant of a source.
+
+Sources not supporting contexts are adapted: The request
+returns as soon as the context is done, however the request
+to the backend keeps running in the background.
+*/
+func WithContext(source Source) ContextSource {
+	if contextSource, ok := source.(ContextSource); ok {
+		return contextSource
+	}
+	return &contextAdapter{source: source}
+}
+
+// WithLookupContext gets the context aware variant of
+// a prefix lookup source, if the source supports lookups.
+func WithLookupContext(source Source) (PrefixLookupContextSource, bool) {
+	if contextSource, ok := source.(PrefixLookupContextSource); ok {
+		return contextSource, true
+	}
+	lookupSource, ok := source.(PrefixLookupSource)
+	if !ok {
+		return nil, false
+	}
+	return &lookupContextAdapter{source: lookupSource}, true
+}
+
+// Wait for the result of a request or the context
+func await(ctx context.Context, request func() (interface{}, error)) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		value interface{}
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		value, err := request()
+		done <- result{value, err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.value, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Adapt a source to the ContextSource interface
+type contextAdapter struct {
+	source Source
+}
+
+func (self *contextAdapter) ExpireCaches() int {
+	return self.source.ExpireCaches()
+}
+
+func (self *contextAdapter) StatusContext(ctx context.Context) (*api.StatusResponse, error) {
+	res, err := await(ctx, func() (interface{}, error) {
+		return self.source.Status()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*api.StatusResponse), nil
+}
+
+func (self *contextAdapter) NeighboursContext(ctx context.Context) (*api.NeighboursResponse, error) {
+	res, err := await(ctx, func() (interface{}, error) {
+		return self.source.Neighbours()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*api.NeighboursResponse), nil
+}
+
+func (self *contextAdapter) NeighboursStatusContext(ctx context.Context) (*api.NeighboursStatusResponse, error) {
+	res, err := await(ctx, func() (interface{}, error) {
+		return self.source.NeighboursStatus()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*api.NeighboursStatusResponse), nil
+}
+
+// Wait for a routes request
+func (self *contextAdapter) awaitRoutes(
+	ctx context.Context,
+	request func() (*api.RoutesResponse, error),
+) (*api.RoutesResponse, error) {
+	res, err := await(ctx, func() (interface{}, error) {
+		// Keep the routes of partial results
+		return request()
+	})
+	routes, _ := res.(*api.RoutesResponse)
+	return routes, err
+}
+
+func (self *contextAdapter) RoutesContext(ctx context.Context, neighbourId string) (*api.RoutesResponse, error) {
+	return self.awaitRoutes(ctx, func() (*api.RoutesResponse, error) {
+		return self.source.Routes(neighbourId)
+	})
+}
+
+func (self *contextAdapter) RoutesReceivedContext(ctx context.Context, neighbourId string) (*api.RoutesResponse, error) {
+	return self.awaitRoutes(ctx, func() (*api.RoutesResponse, error) {
+		return self.source.RoutesReceived(neighbourId)
+	})
+}
+
+func (self *contextAdapter) RoutesFilteredContext(ctx context.Context, neighbourId string) (*api.RoutesResponse, error) {
+	return self.awaitRoutes(ctx, func() (*api.RoutesResponse, error) {
+		return self.source.RoutesFiltered(neighbourId)
+	})
+}
+
+func (self *contextAdapter) RoutesNotExportedContext(ctx context.Context, neighbourId string) (*api.RoutesResponse, error) {
+	return self.awaitRoutes(ctx, func() (*api.RoutesResponse, error) {
+		return self.source.RoutesNotExported(neighbourId)
+	})
+}
+
+func (self *contextAdapter) AllRoutesContext(ctx context.Context) (*api.RoutesResponse, error) {
+	return self.awaitRoutes(ctx, self.source.AllRoutes)
+}
+
+// Adapt a prefix lookup source
+type lookupContextAdapter struct {
+	source PrefixLookupSource
+}
+
+func (self *lookupContextAdapter) LookupPrefixContext(
+	ctx context.Context,
+	prefix string,
+) (*api.RoutesLookupResponse, error) {
+	res, err := await(ctx, func() (interface{}, error) {
+		return self.source.LookupPrefix(prefix)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*api.RoutesLookupResponse), nil
+}
@@ -0,0 +1,110 @@
+package sources
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alice-lg/alice-lg/backend/api"
+)
+
+// A source blocking until released
+type blockingSource struct {
+	release chan struct{}
+}
+
+func (self *blockingSource) wait() {
+	<-self.release
+}
+
+func (self *blockingSource) ExpireCaches() int {
+	return 0
+}
+
+func (self *blockingSource) Capabilities() api.SourceCapabilities {
+	return api.SourceCapabilities{}
+}
+
+func (self *blockingSource) Status() (*api.StatusResponse, error) {
+	self.wait()
+	return &api.StatusResponse{}, nil
+}
+
+func (self *blockingSource) Neighbours() (*api.NeighboursResponse, error) {
+	self.wait()
+	return &api.NeighboursResponse{}, nil
+}
+
+func (self *blockingSource) NeighboursStatus() (*api.NeighboursStatusResponse, error) {
+	self.wait()
+	return &api.NeighboursStatusResponse{}, nil
+}
+
+func (self *blockingSource) Routes(neighbourId string) (*api.RoutesResponse, error) {
+	self.wait()
+	return &api.RoutesResponse{}, nil
+}
+
+func (self *blockingSource) RoutesReceived(neighbourId string) (*api.RoutesResponse, error) {
+	return self.Routes(neighbourId)
+}
+
+func (self *blockingSource) RoutesFiltered(neighbourId string) (*api.RoutesResponse, error) {
+	return self.Routes(neighbourId)
+}
+
+func (self *blockingSource) RoutesNotExported(neighbourId string) (*api.RoutesResponse, error) {
+	return self.Routes(neighbourId)
+}
+
+func (self *blockingSource) AllRoutes() (*api.RoutesResponse, error) {
+	self.wait()
+	return &api.RoutesResponse{
+		Imported: api.Routes{&api.Route{Id: "r1"}},
+	}, &PartialError{}
+}
+
+func TestWithContextCanceled(t *testing.T) {
+	source := &blockingSource{release: make(chan struct{})}
+	defer close(source.release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	t0 := time.Now()
+	_, err := WithContext(source).NeighboursContext(ctx)
+	if err != context.DeadlineExceeded {
+		t.Error("Expected deadline exceeded, got:", err)
+	}
+	if time.Since(t0) > time.Second {
+		t.Error("Expected the request to return when the context is done")
+	}
+
+	// Requests with a done context are not made
+	_, err = WithContext(source).StatusContext(ctx)
+	if err != context.DeadlineExceeded {
+		t.Error("Expected deadline exceeded, got:", err)
+	}
+}
+
+func TestWithContextPartialResult(t *testing.T) {
+	source := &blockingSource{release: make(chan struct{})}
+	close(source.release)
+
+	routes, err := WithContext(source).AllRoutesContext(context.Background())
+	if _, ok := err.(*PartialError); !ok {
+		t.Error("Expected partial error, got:", err)
+	}
+	if routes == nil || len(routes.Imported) != 1 {
+		t.Error("Expected the routes of the partial result, got:", routes)
+	}
+}
+
+func TestWithLookupContext(t *testing.T) {
+	source := &blockingSource{release: make(chan struct{})}
+	close(source.release)
+
+	if _, ok := WithLookupContext(source); ok {
+		t.Error("Expected source without prefix lookup to be rejected")
+	}
+}
@@ -0,0 +1,141 @@
+package sources
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+Redundant endpoints:
+
+A source might be served by more than one endpoint, e.g.
+two birdwatchers of the same route server. The endpoints are
+configured as a comma separated list.
+
+With the `priority` selection, requests are made to the
+first endpoint, and fail over to the next ones. With
+`round_robin`, each request starts with the next endpoint.
+
+An endpoint which failed is tried last for the hold down
+period, so requests do not wait for it again and again.
+*/
+
+const (
+	ENDPOINTS_PRIORITY    = "priority"
+	ENDPOINTS_ROUND_ROBIN = "round_robin"
+
+	ENDPOINT_HOLD_DOWN = 30 * time.Second
+)
+
+// Endpoints of a source
+type Endpoints struct {
+	endpoints []string
+	selection string
+
+	next     int
+	active   string
+	failedAt map[string]time.Time
+
+	sync.Mutex
+}
+
+// Split a comma separated list of endpoints
+func ParseEndpoints(value string) []string {
+	endpoints := []string{}
+	for _, endpoint := range strings.Split(value, ",") {
+		endpoint = strings.TrimSpace(endpoint)
+		if endpoint == "" {
+			continue
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints
+}
+
+// Check the selection of the endpoints
+func ValidateEndpointSelection(selection string) error {
+	switch selection {
+	case "", ENDPOINTS_PRIORITY, ENDPOINTS_ROUND_ROBIN:
+		return nil
+	}
+	return fmt.Errorf(
+		"unknown endpoint_selection '%s', expected %s or %s",
+		selection, ENDPOINTS_PRIORITY, ENDPOINTS_ROUND_ROBIN)
+}
+
+// Make endpoints, the selection defaults to priority
+func NewEndpoints(endpoints []string, selection string) *Endpoints {
+	if selection == "" {
+		selection = ENDPOINTS_PRIORITY
+	}
+	active := ""
+	if len(endpoints) > 0 {
+		active = endpoints[0]
+	}
+	return &Endpoints{
+		endpoints: endpoints,
+		selection: selection,
+		active:    active,
+		failedAt:  make(map[string]time.Time),
+	}
+}
+
+// Get the endpoints in the order of the attempts
+// for the next request.
+func (self *Endpoints) Order() []string {
+	self.Lock()
+	defer self.Unlock()
+
+	n := len(self.endpoints)
+	start := 0
+	if self.selection == ENDPOINTS_ROUND_ROBIN && n > 0 {
+		start = self.next % n
+		self.next = (self.next + 1) % n
+	}
+
+	now := time.Now()
+	order := make([]string, 0, n)
+	holdDown := []string{}
+	for i := 0; i < n; i++ {
+		endpoint := self.endpoints[(start+i)%n]
+		if now.Sub(self.failedAt[endpoint]) < ENDPOINT_HOLD_DOWN {
+			holdDown = append(holdDown, endpoint)
+			continue
+		}
+		order = append(order, endpoint)
+	}
+
+	return append(order, holdDown...)
+}
+
+// Record a request answered by the endpoint
+func (self *Endpoints) Succeeded(endpoint string) {
+	self.Lock()
+	defer self.Unlock()
+
+	self.active = endpoint
+	delete(self.failedAt, endpoint)
+}
+
+// Record a request failed by the endpoint
+func (self *Endpoints) Failed(endpoint string) {
+	self.Lock()
+	defer self.Unlock()
+
+	self.failedAt[endpoint] = time.Now()
+}
+
+// Get the endpoint of the last successful request
+func (self *Endpoints) Active() string {
+	self.Lock()
+	defer self.Unlock()
+
+	return self.active
+}
+
+// Get the number of endpoints
+func (self *Endpoints) Len() int {
+	return len(self.endpoints)
+}
@@ -0,0 +1,71 @@
+package sources
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseEndpoints(t *testing.T) {
+	endpoints := ParseEndpoints(" http://rs1a:29184/,http://rs1b:29184/ , ")
+	expected := []string{"http://rs1a:29184/", "http://rs1b:29184/"}
+	if !reflect.DeepEqual(endpoints, expected) {
+		t.Error("Unexpected endpoints:", endpoints)
+	}
+	if len(ParseEndpoints("")) != 0 {
+		t.Error("Expected no endpoints")
+	}
+}
+
+func TestValidateEndpointSelection(t *testing.T) {
+	for _, selection := range []string{"", "priority", "round_robin"} {
+		if err := ValidateEndpointSelection(selection); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := ValidateEndpointSelection("random"); err == nil {
+		t.Error("Expected an error for an unknown selection")
+	}
+}
+
+func TestEndpointsPriority(t *testing.T) {
+	endpoints := NewEndpoints([]string{"a", "b", "c"}, "")
+	if order := endpoints.Order(); !reflect.DeepEqual(order, []string{"a", "b", "c"}) {
+		t.Error("Unexpected order:", order)
+	}
+	if endpoints.Active() != "a" {
+		t.Error("Expected the first endpoint to be active")
+	}
+
+	// A failed endpoint is tried last
+	endpoints.Failed("a")
+	if order := endpoints.Order(); !reflect.DeepEqual(order, []string{"b", "c", "a"}) {
+		t.Error("Unexpected order:", order)
+	}
+	endpoints.Succeeded("b")
+	if endpoints.Active() != "b" {
+		t.Error("Expected b to be active, got:", endpoints.Active())
+	}
+
+	// The endpoint is back
+	endpoints.Succeeded("a")
+	if order := endpoints.Order(); !reflect.DeepEqual(order, []string{"a", "b", "c"}) {
+		t.Error("Unexpected order:", order)
+	}
+}
+
+func TestEndpointsRoundRobin(t *testing.T) {
+	endpoints := NewEndpoints([]string{"a", "b"}, ENDPOINTS_ROUND_ROBIN)
+	first := endpoints.Order()
+	second := endpoints.Order()
+	if !reflect.DeepEqual(first, []string{"a", "b"}) ||
+		!reflect.DeepEqual(second, []string{"b", "a"}) {
+		t.Error("Unexpected order:", first, second)
+	}
+
+	endpoints.Failed("a")
+	for i := 0; i < 2; i++ {
+		if order := endpoints.Order(); order[0] != "b" {
+			t.Error("Expected the failed endpoint to be tried last:", order)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package sources
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A PartialError is returned along with a result, when
+// some parts of it, e.g. the routes of a table, failed.
+type PartialError struct {
+	// Errors by part, e.g. the table name
+	Failed map[string]error
+}
+
+func (self *PartialError) Error() string {
+	parts := make([]string, 0, len(self.Failed))
+	for part := range self.Failed {
+		parts = append(parts, part)
+	}
+	sort.Strings(parts)
+
+	return fmt.Sprintf("%d part(s) failed: %s",
+		len(parts), strings.Join(parts, ", "))
+}
+
+// An InvalidResponseError is returned when the response
+// of a backend is invalid, e.g. a field has an unexpected type.
+type InvalidResponseError struct {
+	Backend string
+	Field   string
+	Reason  string
+}
+
+func (self *InvalidResponseError) Error() string {
+	return fmt.Sprintf("invalid %s response: %s: %s",
+		self.Backend, self.Field, self.Reason)
+}
+
+// A NotFoundError is returned for requests of a resource
+// the source does not know, e.g. an unknown neighbor.
+type NotFoundError struct {
+	Resource string
+	Id       string
+}
+
+func (self *NotFoundError) Error() string {
+	return self.Resource + " not found: " + self.Id
+}
+
+// An UnsupportedError is returned for requests a source
+// does not support, e.g. when a module is not available.
+type UnsupportedError struct {
+	Feature string
+	Reason  string
+}
+
+func (self *UnsupportedError) Error() string {
+	if self.Reason == "" {
+		return "not supported: " + self.Feature
+	}
+	return "not supported: " + self.Feature + ": " + self.Reason
+}
+
+// An InvalidRequestError is returned for requests with
+// malformed parameters, e.g. a partial prefix.
+type InvalidRequestError struct {
+	Param  string
+	Value  string
+	Reason string
+}
+
+func (self *InvalidRequestError) Error() string {
+	return fmt.Sprintf("invalid %s: %s: %s",
+		self.Param, self.Value, self.Reason)
+}
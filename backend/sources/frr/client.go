@@ -0,0 +1,114 @@
+package frr
+
+// Adapters for running FRR show commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"time"
+)
+
+type ClientResponse map[string]interface{}
+
+// A Transport runs a show command and
+// returns the raw json output. The command
+// is canceled when the context is done.
+type Transport interface {
+	Query(ctx context.Context, command string) ([]byte, error)
+}
+
+// HttpTransport sends the commands to an http adapter
+// like: GET <api>?command=show+bgp+summary+json
+type HttpTransport struct {
+	Api string
+
+	http *http.Client
+}
+
+func NewHttpTransport(api string, timeout time.Duration) *HttpTransport {
+	return &HttpTransport{
+		Api: api,
+		http: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+func (self *HttpTransport) Query(ctx context.Context, command string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		self.Api+"?command="+url.QueryEscape(command), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := self.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unexpected response from adapter: %s", res.Status)
+	}
+
+	return ioutil.ReadAll(res.Body)
+}
+
+// ExecTransport runs vtysh locally
+type ExecTransport struct {
+	Vtysh string
+
+	timeout time.Duration
+}
+
+func NewExecTransport(vtysh string, timeout time.Duration) *ExecTransport {
+	return &ExecTransport{
+		Vtysh:   vtysh,
+		timeout: timeout,
+	}
+}
+
+func (self *ExecTransport) Query(parent context.Context, command string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(parent, self.timeout)
+	defer cancel()
+
+	return exec.CommandContext(ctx, self.Vtysh, "-c", command).Output()
+}
+
+type Client struct {
+	transport Transport
+}
+
+func NewClient(transport Transport) *Client {
+	return &Client{
+		transport: transport,
+	}
+}
+
+// Run command and decode the json output
+func (self *Client) GetJson(command string) (ClientResponse, error) {
+	return self.GetJsonContext(context.Background(), command)
+}
+
+// Run command, which is canceled with the context
+func (self *Client) GetJsonContext(
+	ctx context.Context,
+	command string,
+) (ClientResponse, error) {
+	payload, err := self.transport.Query(ctx, command)
+	if err != nil {
+		return ClientResponse{}, err
+	}
+
+	result := make(ClientResponse)
+	err = json.Unmarshal(payload, &result)
+	if err != nil {
+		return ClientResponse{}, err
+	}
+
+	return result, nil
+}
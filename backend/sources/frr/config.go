@@ -0,0 +1,53 @@
+package frr
+
+import (
+	"github.com/alice-lg/alice-lg/backend/sources"
+
+	"github.com/go-ini/ini"
+)
+
+type Config struct {
+	Id   string
+	Name string
+
+	// Api is the url of an http adapter, executing
+	// vtysh commands on the route server. When no api
+	// is configured, vtysh is executed locally.
+	Api string `ini:"api"`
+
+	// Vtysh is the path of the vtysh binary.
+	// Default: vtysh
+	Vtysh string `ini:"vtysh"`
+
+	// Timeout in seconds for queries
+	Timeout int `ini:"timeout"`
+
+	// CacheTtl is the time in seconds a response is
+	// considered to be valid.
+	CacheTtl int `ini:"cache_ttl"`
+}
+
+func init() {
+	sources.Register(&sources.Backend{
+		Name:      "frr",
+		Configure: configure,
+		New: func(config interface{}) sources.Source {
+			return NewFRR(config.(Config))
+		},
+	})
+}
+
+// Parse the config section of a FRR source
+func configure(
+	base sources.Definition,
+	section *ini.Section,
+) ([]*sources.Definition, error) {
+	c := Config{
+		Id:   base.Id,
+		Name: base.Name,
+	}
+	section.MapTo(&c)
+
+	base.Config = c
+	return []*sources.Definition{&base}, nil
+}
@@ -0,0 +1,42 @@
+package frr
+
+import (
+	"context"
+
+	"github.com/alice-lg/alice-lg/backend/api"
+)
+
+// Requests without a context are made with
+// the background context.
+
+func (self *FRR) Status() (*api.StatusResponse, error) {
+	return self.StatusContext(context.Background())
+}
+
+func (self *FRR) Neighbours() (*api.NeighboursResponse, error) {
+	return self.NeighboursContext(context.Background())
+}
+
+func (self *FRR) NeighboursStatus() (*api.NeighboursStatusResponse, error) {
+	return self.NeighboursStatusContext(context.Background())
+}
+
+func (self *FRR) Routes(neighborId string) (*api.RoutesResponse, error) {
+	return self.RoutesContext(context.Background(), neighborId)
+}
+
+func (self *FRR) RoutesReceived(neighborId string) (*api.RoutesResponse, error) {
+	return self.RoutesReceivedContext(context.Background(), neighborId)
+}
+
+func (self *FRR) RoutesFiltered(neighborId string) (*api.RoutesResponse, error) {
+	return self.RoutesFilteredContext(context.Background(), neighborId)
+}
+
+func (self *FRR) RoutesNotExported(neighborId string) (*api.RoutesResponse, error) {
+	return self.RoutesNotExportedContext(context.Background(), neighborId)
+}
+
+func (self *FRR) AllRoutes() (*api.RoutesResponse, error) {
+	return self.AllRoutesContext(context.Background())
+}
@@ -0,0 +1,332 @@
+package frr
+
+// Decoders for the json output of FRR's vtysh
+
+import (
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alice-lg/alice-lg/backend/api"
+)
+
+// Address families and their keys in the summary
+// and neighbor responses
+var families = map[string]string{
+	"ipv4": "ipv4Unicast",
+	"ipv6": "ipv6Unicast",
+}
+
+// Assert string, provide default
+func mustString(value interface{}, fallback string) string {
+	sval, ok := value.(string)
+	if !ok {
+		return fallback
+	}
+	return sval
+}
+
+func mustInt(value interface{}, fallback int) int {
+	fval, ok := value.(float64)
+	if !ok {
+		return fallback
+	}
+	return int(fval)
+}
+
+func mustBool(value interface{}, fallback bool) bool {
+	val, ok := value.(bool)
+	if !ok {
+		return fallback
+	}
+	return val
+}
+
+// Assert nested object, returns an empty map when
+// the value is missing
+func mustMap(value interface{}) map[string]interface{} {
+	mval, ok := value.(map[string]interface{})
+	if !ok {
+		return map[string]interface{}{}
+	}
+	return mval
+}
+
+// Map the FRR session state to up / down
+func decodeState(state string) string {
+	if strings.ToLower(state) == "established" {
+		return "up"
+	}
+	return "down"
+}
+
+// Decode a neighbor from the show bgp neighbors output
+func decodeNeighbor(
+	rsId string,
+	address string,
+	data map[string]interface{},
+) *api.Neighbour {
+	state := decodeState(mustString(data["bgpState"], "unknown"))
+
+	accepted := 0
+	exported := 0
+	afInfo := mustMap(data["addressFamilyInfo"])
+	for _, key := range families {
+		af := mustMap(afInfo[key])
+		accepted += mustInt(af["acceptedPrefixCounter"], 0)
+		exported += mustInt(af["sentPrefixCounter"], 0)
+	}
+
+	uptime := time.Duration(0)
+	if state == "up" {
+		uptime = time.Duration(mustInt(data["bgpTimerUpMsec"], 0)) * time.Millisecond
+	}
+
+	neighbor := &api.Neighbour{
+		Id: address,
+
+		Address:     address,
+		Asn:         mustInt(data["remoteAs"], 0),
+		State:       state,
+		Description: mustString(data["nbrDesc"], "no description"),
+
+		// FRR only counts the accepted routes, the received
+		// routes are not known before they are listed.
+		RoutesAccepted: accepted,
+		RoutesExported: exported,
+
+		Uptime:    uptime,
+		LastError: mustString(data["lastResetDueTo"], ""),
+
+		RouteServerId: rsId,
+
+		Details: data,
+	}
+
+	return neighbor
+}
+
+// Decode the show bgp neighbors response: The
+// response is an object with the neighbor address as key.
+func decodeNeighbors(rsId string, res ClientResponse) api.Neighbours {
+	neighbors := api.Neighbours{}
+	for address, data := range res {
+		ndata, ok := data.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		neighbors = append(neighbors, decodeNeighbor(rsId, address, ndata))
+	}
+
+	sort.Sort(neighbors)
+
+	return neighbors
+}
+
+// Decode the peers of all families in the
+// bgp summary into a neighbors status list
+func decodeNeighborsStatus(res ClientResponse) api.NeighboursStatus {
+	seen := map[string]bool{}
+	status := api.NeighboursStatus{}
+	for _, key := range families {
+		peers := mustMap(mustMap(res[key])["peers"])
+		for address, data := range peers {
+			if seen[address] {
+				continue
+			}
+			seen[address] = true
+
+			peer := mustMap(data)
+			since := time.Duration(mustInt(peer["peerUptimeMsec"], 0)) * time.Millisecond
+			status = append(status, &api.NeighbourStatus{
+				Id:    address,
+				State: decodeState(mustString(peer["state"], "unknown")),
+				Since: since,
+			})
+		}
+	}
+
+	sort.Sort(status)
+
+	return status
+}
+
+// Get the router id from the summary
+func decodeStatus(res ClientResponse) api.Status {
+	routerId := "unknown"
+	for _, key := range families {
+		if id, ok := mustMap(res[key])["routerId"].(string); ok {
+			routerId = id
+			break
+		}
+	}
+
+	return api.Status{
+		ServerTime: time.Now().UTC(),
+		Backend:    "frr",
+		Version:    "unknown",
+		Message:    "unknown",
+		RouterId:   routerId,
+	}
+}
+
+// Decode an as path like "65001 65002"
+func decodeAsPath(path string) []int {
+	asPath := []int{}
+	path = strings.NewReplacer("{", " ", "}", " ", ",", " ").Replace(path)
+	for _, token := range strings.Fields(path) {
+		asn, err := strconv.Atoi(token)
+		if err != nil {
+			continue
+		}
+		asPath = append(asPath, asn)
+	}
+	return asPath
+}
+
+// Decode communities from the string representation
+// provided by FRR, e.g. "65000:1 65000:2"
+func decodeCommunities(value interface{}) api.Communities {
+	communities := api.Communities{}
+	str := mustString(mustMap(value)["string"], "")
+	for _, c := range strings.Fields(str) {
+		community := api.Community{}
+		for _, part := range strings.Split(c, ":") {
+			val, err := strconv.Atoi(part)
+			if err != nil {
+				// Well-known communities like "no-export"
+				// are not decoded.
+				community = nil
+				break
+			}
+			community = append(community, val)
+		}
+		if community != nil {
+			communities = append(communities, community)
+		}
+	}
+	return communities
+}
+
+// Decode extended communities from the string
+// representation, e.g. "RT:65000:100 SoO:65000:1"
+func decodeExtCommunities(value interface{}) api.ExtCommunities {
+	communities := api.ExtCommunities{}
+	str := mustString(mustMap(value)["string"], "")
+	for _, c := range strings.Fields(str) {
+		tokens := strings.SplitN(c, ":", 3)
+		if len(tokens) != 3 {
+			log.Println("Ignoring malformed ext community:", c)
+			continue
+		}
+		communities = append(communities, api.ExtCommunity{
+			strings.ToLower(tokens[0]), tokens[1], tokens[2],
+		})
+	}
+	return communities
+}
+
+// Get the next hop of a path. The path either has a
+// nextHop or a list of nexthops.
+func decodeNextHop(data map[string]interface{}) string {
+	if nh, ok := data["nextHop"].(string); ok {
+		return nh
+	}
+	nexthops, ok := data["nexthops"].([]interface{})
+	if !ok || len(nexthops) == 0 {
+		return "unknown"
+	}
+	return mustString(mustMap(nexthops[0])["ip"], "unknown")
+}
+
+// Decode a path into a route
+func decodeRoute(
+	neighborId string,
+	prefix string,
+	data map[string]interface{},
+) *api.Route {
+	nextHop := decodeNextHop(data)
+
+	bgp := api.BgpInfo{
+		Origin:           mustString(data["origin"], "unknown"),
+		AsPath:           decodeAsPath(mustString(data["path"], "")),
+		NextHop:          nextHop,
+		LocalPref:        mustInt(data["locPrf"], 0),
+		Med:              mustInt(data["metric"], 0),
+		Communities:      decodeCommunities(data["community"]),
+		LargeCommunities: decodeCommunities(data["largeCommunity"]),
+		ExtCommunities:   decodeExtCommunities(data["extendedCommunity"]),
+	}
+
+	primary := mustBool(data["bestpath"], false) || mustBool(data["best"], false)
+
+	route := &api.Route{
+		Id:          prefix,
+		NeighbourId: mustString(data["peerId"], neighborId),
+
+		Network:   prefix,
+		Interface: "unknown",
+		Gateway:   nextHop,
+		Metric:    mustInt(data["metric"], 0),
+		Primary:   primary,
+		Type:      []string{"BGP"},
+		Bgp:       bgp,
+
+		Details: data,
+	}
+
+	return route
+}
+
+// Decode the received-routes or advertised-routes
+// response: These are maps with the prefix as key
+// and a single path as value.
+func decodeRoutesTable(
+	neighborId string,
+	table interface{},
+) api.Routes {
+	routes := api.Routes{}
+	for prefix, data := range mustMap(table) {
+		routes = append(routes, decodeRoute(neighborId, prefix, mustMap(data)))
+	}
+	return routes
+}
+
+// Decode the rib response: This is a map with
+// the prefix as key and a list of paths.
+func decodeRib(neighborId string, rib interface{}) api.Routes {
+	routes := api.Routes{}
+	for prefix, data := range mustMap(rib) {
+		paths, ok := data.([]interface{})
+		if !ok {
+			continue
+		}
+		for _, path := range paths {
+			routes = append(routes, decodeRoute(neighborId, prefix, mustMap(path)))
+		}
+	}
+	return routes
+}
+
+// Get the rejected routes: These are all routes in the
+// Adj-RIB-In of the neighbor, which were not accepted.
+func rejectedRoutes(
+	received api.Routes,
+	accepted api.Routes,
+) api.Routes {
+	acceptedKeys := make(map[string]bool, len(accepted))
+	for _, route := range accepted {
+		acceptedKeys[route.Network] = true
+	}
+
+	rejected := api.Routes{}
+	for _, route := range received {
+		if !acceptedKeys[route.Network] {
+			rejected = append(rejected, route)
+		}
+	}
+
+	return rejected
+}
@@ -0,0 +1,131 @@
+package frr
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+)
+
+// Load testdata from file
+func loadTestResponse(filename string) (ClientResponse, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return ClientResponse{}, err
+	}
+	result := make(ClientResponse)
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
+
+func TestDecodeNeighbors(t *testing.T) {
+	res, err := loadTestResponse("testdata/neighbors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	neighbors := decodeNeighbors("rs1", res)
+	if len(neighbors) != 2 {
+		t.Fatal("Expected 2 neighbors, got:", len(neighbors))
+	}
+
+	n := neighbors[0]
+	if n.Id != "192.0.2.1" || n.Asn != 65001 {
+		t.Error("Unexpected neighbor:", n.Id, n.Asn)
+	}
+	if n.State != "up" {
+		t.Error("Expected state up, got:", n.State)
+	}
+	if n.RoutesAccepted != 1 || n.RoutesExported != 10 {
+		t.Error("Unexpected route counts:", n.RoutesAccepted, n.RoutesExported)
+	}
+	if n.Uptime.Hours() != 1 {
+		t.Error("Expected an uptime of 1h, got:", n.Uptime)
+	}
+
+	n = neighbors[1]
+	if n.State != "down" || n.LastError != "Hold Timer Expired" {
+		t.Error("Unexpected state:", n.State, n.LastError)
+	}
+}
+
+func TestDecodeNeighborsStatus(t *testing.T) {
+	res, err := loadTestResponse("testdata/summary.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status := decodeNeighborsStatus(res)
+	if len(status) != 2 {
+		t.Fatal("Expected 2 neighbors, got:", len(status))
+	}
+	if status[0].Id != "192.0.2.1" || status[0].State != "up" {
+		t.Error("Unexpected status:", status[0])
+	}
+
+	if decodeStatus(res).RouterId != "192.0.2.254" {
+		t.Error("Unexpected router id:", decodeStatus(res).RouterId)
+	}
+}
+
+func TestDecodeRoutes(t *testing.T) {
+	receivedRes, err := loadTestResponse("testdata/received_routes.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	acceptedRes, err := loadTestResponse("testdata/accepted_routes.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	received := decodeRoutesTable("192.0.2.1", receivedRes["receivedRoutes"])
+	accepted := decodeRib("192.0.2.1", acceptedRes["routes"])
+	if len(received) != 2 {
+		t.Fatal("Expected 2 received routes, got:", len(received))
+	}
+	if len(accepted) != 1 {
+		t.Fatal("Expected 1 accepted route, got:", len(accepted))
+	}
+
+	rejected := rejectedRoutes(received, accepted)
+	if len(rejected) != 1 || rejected[0].Network != "10.0.0.0/8" {
+		t.Error("Unexpected rejected routes:", rejected)
+	}
+
+	r := accepted[0]
+	if r.Gateway != "192.0.2.1" || r.NeighbourId != "192.0.2.1" {
+		t.Error("Unexpected gateway / neighbor:", r.Gateway, r.NeighbourId)
+	}
+	if !r.Primary {
+		t.Error("Expected route to be primary")
+	}
+	if len(r.Bgp.AsPath) != 2 {
+		t.Error("Unexpected as path:", r.Bgp.AsPath)
+	}
+	// The well-known no-export community is skipped
+	if len(r.Bgp.Communities) != 2 {
+		t.Error("Unexpected communities:", r.Bgp.Communities)
+	}
+	if len(r.Bgp.LargeCommunities) != 1 {
+		t.Error("Unexpected large communities:", r.Bgp.LargeCommunities)
+	}
+	if len(r.Bgp.ExtCommunities) != 1 ||
+		r.Bgp.ExtCommunities[0].String() != "rt:65000:100" {
+		t.Error("Unexpected ext communities:", r.Bgp.ExtCommunities)
+	}
+}
+
+func TestValidateNeighborId(t *testing.T) {
+	valid := []string{"192.0.2.1", "2001:db8::1", "swp1"}
+	for _, id := range valid {
+		if err := validateNeighborId(id); err != nil {
+			t.Error(err)
+		}
+	}
+
+	invalid := []string{"192.0.2.1 json\nshow run", "; reboot", ""}
+	for _, id := range invalid {
+		if err := validateNeighborId(id); err == nil {
+			t.Error("Expected an error for:", id)
+		}
+	}
+}
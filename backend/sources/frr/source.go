@@ -0,0 +1,457 @@
+package frr
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/alice-lg/alice-lg/backend/api"
+	"github.com/alice-lg/alice-lg/backend/caches"
+	"github.com/alice-lg/alice-lg/backend/sources"
+)
+
+const (
+	CMD_SUMMARY           = "show bgp summary json"
+	CMD_NEIGHBORS         = "show bgp neighbors json"
+	CMD_NEIGHBOR          = "show bgp neighbors %s json"
+	CMD_RIB               = "show bgp %s unicast json"
+	CMD_ROUTES_RECEIVED   = "show bgp %s unicast neighbors %s received-routes json"
+	CMD_ROUTES_ACCEPTED   = "show bgp %s unicast neighbors %s routes json"
+	CMD_ROUTES_ADVERTISED = "show bgp %s unicast neighbors %s advertised-routes json"
+
+	DEFAULT_VTYSH     = "vtysh"
+	DEFAULT_TIMEOUT   = 30
+	DEFAULT_CACHE_TTL = 300
+)
+
+// Neighbor ids are passed to vtysh: Only allow
+// addresses and interface names.
+var REGEX_NEIGHBOR_ID = regexp.MustCompile(`^[0-9a-zA-Z.:_-]+$`)
+
+type FRR struct {
+	config Config
+	client *Client
+
+	// Caches: Neighbors
+	neighborsCache *caches.NeighborsCache
+
+	// Caches: Routes
+	routesRequiredCache    *caches.RoutesCache
+	routesNotExportedCache *caches.RoutesCache
+
+	// Mutices:
+	routesFetchMutex *sources.LockMap
+}
+
+func NewFRR(config Config) *FRR {
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = DEFAULT_TIMEOUT
+	}
+	if config.CacheTtl == 0 {
+		config.CacheTtl = DEFAULT_CACHE_TTL
+	}
+	if config.Vtysh == "" {
+		config.Vtysh = DEFAULT_VTYSH
+	}
+
+	// Use the http adapter if configured, otherwise
+	// fall back to running vtysh.
+	var transport Transport
+	if config.Api != "" {
+		transport = NewHttpTransport(
+			config.Api, time.Duration(timeout)*time.Second)
+	} else {
+		transport = NewExecTransport(
+			config.Vtysh, time.Duration(timeout)*time.Second)
+	}
+
+	// Cache settings:
+	// TODO: Maybe read from config file
+	neighborsCacheDisable := false
+
+	routesCacheDisabled := false
+	routesCacheMaxSize := 128
+
+	// Initialize caches
+	neighborsCache := caches.NewNeighborsCache(neighborsCacheDisable)
+	routesRequiredCache := caches.NewRoutesCache(
+		routesCacheDisabled, routesCacheMaxSize)
+	routesNotExportedCache := caches.NewRoutesCache(
+		routesCacheDisabled, routesCacheMaxSize)
+
+	return &FRR{
+		config: config,
+		client: NewClient(transport),
+
+		neighborsCache: neighborsCache,
+
+		routesRequiredCache:    routesRequiredCache,
+		routesNotExportedCache: routesNotExportedCache,
+
+		routesFetchMutex: sources.NewLockMap(),
+	}
+}
+
+// Make api status for responses. The ttl
+// is derived from the configured cache ttl.
+func (self *FRR) makeApiStatus() api.ApiStatus {
+	now := time.Now().UTC()
+	return api.ApiStatus{
+		Version:         "frr",
+		ResultFromCache: false,
+		Ttl:             now.Add(time.Duration(self.config.CacheTtl) * time.Second),
+		CacheStatus: api.CacheStatus{
+			CachedAt: now,
+			OrigTtl:  self.config.CacheTtl,
+		},
+	}
+}
+
+func validateNeighborId(neighborId string) error {
+	if !REGEX_NEIGHBOR_ID.MatchString(neighborId) {
+		return &sources.NotFoundError{Resource: "neighbor", Id: neighborId}
+	}
+	return nil
+}
+
+// Get the address families configured for the neighbor
+func (self *FRR) neighborFamilies(
+	ctx context.Context,
+	neighborId string,
+) ([]string, error) {
+	res, err := self.client.GetJsonContext(ctx,
+		fmt.Sprintf(CMD_NEIGHBOR, neighborId))
+	if err != nil {
+		return nil, err
+	}
+	data, ok := res[neighborId].(map[string]interface{})
+	if !ok {
+		return nil, &sources.NotFoundError{Resource: "neighbor", Id: neighborId}
+	}
+
+	afInfo := mustMap(data["addressFamilyInfo"])
+	configured := []string{}
+	for family, key := range families {
+		if _, ok := afInfo[key]; ok {
+			configured = append(configured, family)
+		}
+	}
+	sort.Strings(configured)
+	return configured, nil
+}
+
+// Run a neighbor command for all address families
+// and collect the results. Families which are not
+// configured for the neighbor are skipped.
+func (self *FRR) queryNeighborFamilies(
+	ctx context.Context,
+	command string,
+	neighborId string,
+) ([]ClientResponse, error) {
+	if err := validateNeighborId(neighborId); err != nil {
+		return nil, err
+	}
+	configured, err := self.neighborFamilies(ctx, neighborId)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []ClientResponse{}
+	for _, family := range configured {
+		res, err := self.client.GetJsonContext(ctx,
+			fmt.Sprintf(command, family, neighborId))
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+func (self *FRR) ExpireCaches() int {
+	count := self.routesRequiredCache.Expire()
+	count += self.routesNotExportedCache.Expire()
+
+	return count
+}
+
+func (self *FRR) Capabilities() api.SourceCapabilities {
+	return api.SourceCapabilities{
+		RoutesReceived:    true,
+		RoutesFiltered:    true,
+		RoutesNotExported: true,
+		Status:            true,
+		LiveLookup:        false,
+		NeighboursStatus:  true,
+	}
+}
+
+func (self *FRR) StatusContext(ctx context.Context) (*api.StatusResponse, error) {
+	res, err := self.client.GetJsonContext(ctx, CMD_SUMMARY)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &api.StatusResponse{
+		Api:    self.makeApiStatus(),
+		Status: decodeStatus(res),
+	}
+
+	return response, nil
+}
+
+func (self *FRR) NeighboursContext(ctx context.Context) (*api.NeighboursResponse, error) {
+	// Check if we hit the cache
+	response := self.neighborsCache.Get()
+	if response != nil {
+		return response, nil
+	}
+
+	res, err := self.client.GetJsonContext(ctx, CMD_NEIGHBORS)
+	if err != nil {
+		return nil, err
+	}
+
+	response = &api.NeighboursResponse{
+		Api:        self.makeApiStatus(),
+		Neighbours: decodeNeighbors(self.config.Id, res),
+	}
+
+	// Cache result
+	self.neighborsCache.Set(response)
+
+	return response, nil
+}
+
+// Get live neighbor status
+func (self *FRR) NeighboursStatusContext(ctx context.Context) (*api.NeighboursStatusResponse, error) {
+	res, err := self.client.GetJsonContext(ctx, CMD_SUMMARY)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &api.NeighboursStatusResponse{
+		Api:        self.makeApiStatus(),
+		Neighbours: decodeNeighborsStatus(res),
+	}
+
+	return response, nil
+}
+
+/*
+fetchRequiredRoutes gets the received routes (Adj-RIB-In)
+and the accepted routes of the neighbor.
+All received routes not accepted are considered filtered.
+
+This requires soft-reconfiguration inbound to be
+enabled for the neighbor.
+*/
+func (self *FRR) fetchRequiredRoutes(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
+	// Allow only one concurrent request for this neighbor
+	self.routesFetchMutex.Lock(neighborId)
+	defer self.routesFetchMutex.Unlock(neighborId)
+
+	// Check if we have a cache hit
+	response := self.routesRequiredCache.Get(neighborId)
+	if response != nil {
+		return response, nil
+	}
+
+	receivedRes, err := self.queryNeighborFamilies(ctx,
+		CMD_ROUTES_RECEIVED, neighborId)
+	if err != nil {
+		return nil, err
+	}
+
+	acceptedRes, err := self.queryNeighborFamilies(ctx,
+		CMD_ROUTES_ACCEPTED, neighborId)
+	if err != nil {
+		return nil, err
+	}
+
+	received := api.Routes{}
+	for _, res := range receivedRes {
+		received = append(received,
+			decodeRoutesTable(neighborId, res["receivedRoutes"])...)
+	}
+
+	accepted := api.Routes{}
+	for _, res := range acceptedRes {
+		accepted = append(accepted, decodeRib(neighborId, res["routes"])...)
+	}
+
+	filtered := rejectedRoutes(received, accepted)
+
+	// Sort routes for deterministic ordering
+	sort.Sort(accepted)
+	sort.Sort(filtered)
+
+	response = &api.RoutesResponse{
+		Api:      self.makeApiStatus(),
+		Imported: accepted,
+		Filtered: filtered,
+	}
+
+	// Cache result
+	self.routesRequiredCache.Set(neighborId, response)
+
+	return response, nil
+}
+
+/*
+fetchNotExportedRoutes gets all best routes from the
+RIB, which are not advertised to the neighbor.
+*/
+func (self *FRR) fetchNotExportedRoutes(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
+	// Check if we have a cache hit
+	response := self.routesNotExportedCache.Get(neighborId)
+	if response != nil {
+		return response, nil
+	}
+
+	advertisedRes, err := self.queryNeighborFamilies(ctx,
+		CMD_ROUTES_ADVERTISED, neighborId)
+	if err != nil {
+		return nil, err
+	}
+
+	advertised := map[string]bool{}
+	for _, res := range advertisedRes {
+		for prefix := range mustMap(res["advertisedRoutes"]) {
+			advertised[prefix] = true
+		}
+	}
+
+	notExported := api.Routes{}
+	for family := range families {
+		res, err := self.client.GetJsonContext(ctx, fmt.Sprintf(CMD_RIB, family))
+		if err != nil {
+			return nil, err
+		}
+		for _, route := range decodeRib("", res["routes"]) {
+			// Routes learned from the neighbor are never
+			// sent back to it.
+			if !route.Primary || route.NeighbourId == neighborId {
+				continue
+			}
+			if advertised[route.Network] {
+				continue
+			}
+			notExported = append(notExported, route)
+		}
+	}
+
+	// Sort routes for deterministic ordering
+	sort.Sort(notExported)
+
+	response = &api.RoutesResponse{
+		Api:         self.makeApiStatus(),
+		NotExported: notExported,
+	}
+
+	// Cache result
+	self.routesNotExportedCache.Set(neighborId, response)
+
+	return response, nil
+}
+
+// Get filtered, accepted and not exported routes
+func (self *FRR) RoutesContext(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
+	required, err := self.fetchRequiredRoutes(ctx, neighborId)
+	if err != nil {
+		return nil, err
+	}
+
+	notExported, err := self.fetchNotExportedRoutes(ctx, neighborId)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &api.RoutesResponse{
+		Api:         required.Api,
+		Imported:    required.Imported,
+		Filtered:    required.Filtered,
+		NotExported: notExported.NotExported,
+	}
+
+	return response, nil
+}
+
+// Get all received routes
+func (self *FRR) RoutesReceivedContext(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
+	routes, err := self.fetchRequiredRoutes(ctx, neighborId)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &api.RoutesResponse{
+		Api:      routes.Api,
+		Imported: routes.Imported,
+	}
+
+	return response, nil
+}
+
+// Get all filtered routes
+func (self *FRR) RoutesFilteredContext(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
+	routes, err := self.fetchRequiredRoutes(ctx, neighborId)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &api.RoutesResponse{
+		Api:      routes.Api,
+		Filtered: routes.Filtered,
+	}
+
+	return response, nil
+}
+
+// Get all not exported routes
+func (self *FRR) RoutesNotExportedContext(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
+	return self.fetchNotExportedRoutes(ctx, neighborId)
+}
+
+/*
+AllRoutes collects the accepted and filtered routes
+of all established neighbors.
+*/
+func (self *FRR) AllRoutesContext(ctx context.Context) (*api.RoutesResponse, error) {
+	neighbors, err := self.NeighboursContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &api.RoutesResponse{
+		Api:      self.makeApiStatus(),
+		Imported: api.Routes{},
+		Filtered: api.Routes{},
+	}
+
+	for _, neighbor := range neighbors.Neighbours {
+		if neighbor.State != "up" {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		routes, err := self.fetchRequiredRoutes(ctx, neighbor.Id)
+		if err != nil {
+			log.Println("Could not fetch routes for neighbor",
+				neighbor.Id, "on", self.config.Name, ":", err)
+			continue
+		}
+		response.Imported = append(response.Imported, routes.Imported...)
+		response.Filtered = append(response.Filtered, routes.Filtered...)
+	}
+
+	// Sort routes for deterministic ordering
+	sort.Sort(response.Imported)
+	sort.Sort(response.Filtered)
+
+	return response, nil
+}
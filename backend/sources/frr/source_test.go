@@ -0,0 +1,83 @@
+package frr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/alice-lg/alice-lg/backend/sources"
+)
+
+// A transport answering commands with recorded responses
+type testTransport struct {
+	responses map[string][]byte
+	commands  []string
+}
+
+func (self *testTransport) Query(ctx context.Context, command string) ([]byte, error) {
+	self.commands = append(self.commands, command)
+	res, ok := self.responses[command]
+	if !ok {
+		return nil, fmt.Errorf("exit status 1")
+	}
+	return res, nil
+}
+
+func newTestFRR(t *testing.T) (*FRR, *testTransport) {
+	transport := &testTransport{responses: map[string][]byte{}}
+	load := func(command, filename string) {
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		transport.responses[command] = data
+	}
+	load(fmt.Sprintf(CMD_ROUTES_RECEIVED, "ipv4", "192.0.2.1"),
+		"testdata/received_routes.json")
+	load(fmt.Sprintf(CMD_ROUTES_ACCEPTED, "ipv4", "192.0.2.1"),
+		"testdata/accepted_routes.json")
+
+	// The neighbor is only configured for ipv4
+	neighbors, err := loadTestResponse("testdata/neighbors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	neighbor, _ := json.Marshal(ClientResponse{
+		"192.0.2.1": neighbors["192.0.2.1"],
+	})
+	transport.responses[fmt.Sprintf(CMD_NEIGHBOR, "192.0.2.1")] = neighbor
+	transport.responses[fmt.Sprintf(CMD_NEIGHBOR, "192.0.2.9")] =
+		[]byte(`{"bgpNoSuchNeighbor": true}`)
+
+	frr := NewFRR(Config{Id: "rs1"})
+	frr.client = NewClient(transport)
+	return frr, transport
+}
+
+func TestRoutesSkipFamilies(t *testing.T) {
+	frr, transport := newTestFRR(t)
+
+	response, err := frr.RoutesReceived("192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Imported) == 0 {
+		t.Error("Expected accepted routes")
+	}
+	for _, command := range transport.commands {
+		if command == fmt.Sprintf(CMD_ROUTES_RECEIVED, "ipv6", "192.0.2.1") {
+			t.Error("Expected the ipv6 family to be skipped")
+		}
+	}
+}
+
+func TestRoutesUnknownNeighbor(t *testing.T) {
+	frr, _ := newTestFRR(t)
+
+	_, err := frr.RoutesReceived("192.0.2.9")
+	if _, ok := err.(*sources.NotFoundError); !ok {
+		t.Error("Expected a NotFoundError, got:", err)
+	}
+}
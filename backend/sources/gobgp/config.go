@@ -1,13 +1,160 @@
 package gobgp
 
+import (
+	"github.com/alice-lg/alice-lg/backend/sources"
+
+	"github.com/go-ini/ini"
+
+	"fmt"
+	"log"
+)
+
 type Config struct {
 	Id   string
 	Name string
 
-	Host     string `ini:"host"`
-	Insecure bool   `ini:"insecure"`
-	// ProcessingTimeout is a timeout in seconds configured per gRPC call to a given GoBGP daemon
-	ProcessingTimeout int    `ini:"processing_timeout"`
-	TLSCert           string `ini:"tls_crt"`
-	TLSCommonName     string `ini:"tls_common_name"`
+	// Host might be a comma separated list of redundant daemons,
+	// selected by priority or round robin.
+	Host              string `ini:"host"`
+	EndpointSelection string `ini:"endpoint_selection"`
+
+	Insecure bool `ini:"insecure"`
+	// ProcessingTimeout is a timeout in seconds configured per gRPC call
+	// processing a table of a given GoBGP daemon, e.g. listing routes
+	ProcessingTimeout int `ini:"processing_timeout"`
+	// RequestTimeout is a timeout in seconds for requests not processing
+	// a table, e.g. listing the peers or getting the status
+	RequestTimeout int `ini:"request_timeout"`
+	// KeepaliveInterval is the time in seconds after which the connection
+	// is checked with a keepalive ping, if there is no activity.
+	// Keepalives are disabled if not set.
+	KeepaliveInterval int `ini:"keepalive_interval"`
+	// KeepaliveTimeout is the time in seconds to wait for
+	// the response to a keepalive ping
+	KeepaliveTimeout int `ini:"keepalive_timeout"`
+
+	TLSCert       string `ini:"tls_crt"`
+	TLSCommonName string `ini:"tls_common_name"`
+	// CacheTtl is the time in seconds a routes or
+	// neighbors response is considered to be valid.
+	CacheTtl int `ini:"cache_ttl"`
+	// LiveLookup enables prefix lookups on the GoBGP daemon,
+	// instead of searching the routes store.
+	LiveLookup bool `ini:"live_lookup"`
+	// WatchEvents keeps the peers and routes in memory, updated
+	// from the monitoring streams of the GoBGP daemon.
+	WatchEvents bool `ini:"watch_events"`
+	// Families are the address families of the routes,
+	// e.g. ipv4-unicast or ipv4-flowspec.
+	Families []string `ini:"families"`
+	// Vrfs are provided as additional sources, scoped to
+	// the VRF. With *, the VRFs are listed at startup.
+	Vrfs []string `ini:"vrfs"`
+	// Vrf is the VRF of a source, set for the additional sources
+	Vrf string
+}
+
+func init() {
+	sources.Register(&sources.Backend{
+		Name:      "gobgp",
+		Configure: configure,
+		New: func(config interface{}) sources.Source {
+			return NewGoBGP(config.(Config))
+		},
+	})
+}
+
+// Parse the config section of a GoBGP source,
+// the VRFs are additional sources.
+func configure(
+	base sources.Definition,
+	section *ini.Section,
+) ([]*sources.Definition, error) {
+	c := Config{
+		Id:   base.Id,
+		Name: base.Name,
+	}
+	section.MapTo(&c)
+
+	// Update defaults:
+	//  - processing_timeout
+	if c.ProcessingTimeout == 0 {
+		c.ProcessingTimeout = 300
+	}
+	//  - request_timeout
+	if c.RequestTimeout == 0 {
+		c.RequestTimeout = 10
+	}
+	//  - keepalive_timeout
+	if c.KeepaliveTimeout == 0 {
+		c.KeepaliveTimeout = 20
+	}
+	//  - cache_ttl
+	if c.CacheTtl == 0 {
+		c.CacheTtl = DEFAULT_CACHE_TTL
+	}
+	if len(sources.ParseEndpoints(c.Host)) == 0 {
+		return nil, fmt.Errorf("gobgp source without host")
+	}
+	if err := sources.ValidateEndpointSelection(c.EndpointSelection); err != nil {
+		return nil, err
+	}
+
+	//  - families
+	if len(c.Families) == 0 {
+		c.Families = DEFAULT_FAMILIES
+	}
+	if _, err := ParseFamilies(c.Families); err != nil {
+		return nil, err
+	}
+
+	base.Config = c
+	base.LiveLookup = c.LiveLookup
+	definitions := []*sources.Definition{&base}
+
+	if len(c.Vrfs) == 0 {
+		return definitions, nil
+	}
+	return append(definitions, configureVrfs(base, section)...), nil
+}
+
+/*
+Make the sources for the VRFs of a GoBGP daemon. The id
+of a source is derived from the id of the section and the
+name of the VRF, e.g. [source.rs2.gobgp] with the VRF red
+becomes rs2-red.
+*/
+func configureVrfs(
+	base sources.Definition,
+	section *ini.Section,
+) []*sources.Definition {
+	config := base.Config.(Config)
+	vrfs := config.Vrfs
+	if len(vrfs) == 1 && vrfs[0] == "*" {
+		log.Println("Discovering VRFs of", config.Host)
+		discovered, err := DiscoverVrfs(config)
+		if err != nil {
+			// The daemon might not be reachable yet, this
+			// must not prevent the other sources from starting.
+			log.Println(section.Name(), "could not discover vrfs:", err)
+		}
+		vrfs = discovered
+	}
+
+	definitions := make([]*sources.Definition, 0, len(vrfs))
+	for _, vrf := range vrfs {
+		definition := base
+		definition.Id = base.Id + "-" + vrf
+		definition.Name = fmt.Sprintf("%s (%s)", base.Name, vrf)
+
+		c := config
+		c.Id = definition.Id
+		c.Name = definition.Name
+		c.Vrf = vrf
+		definition.Config = c
+
+		definitions = append(definitions, &definition)
+	}
+
+	return definitions
 }
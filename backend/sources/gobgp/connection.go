@@ -0,0 +1,303 @@
+package gobgp
+
+import (
+	"github.com/alice-lg/alice-lg/backend/sources"
+
+	gobgpapi "github.com/osrg/gobgp/api"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
+
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+/*
+Connection to the GoBGP daemon:
+
+The daemon is dialed on the first request, not when the
+source is created. Dialing does not wait for the daemon to
+be reachable; gRPC reconnects in the background with an
+exponential backoff, and requests fail while the daemon is
+unavailable.
+
+When dialing fails (e.g. the TLS certificate can not be
+loaded), it is retried with an exponential backoff as well.
+
+The host might be a list of redundant daemons. Requests are
+made with the first daemon (or the next one, with round
+robin) which is connected or connecting; a daemon failing
+the connectivity check is skipped.
+
+When a daemon is unavailable or does not answer in time,
+the request is retried with the next daemon. The daemon
+is tried last for the hold down period of the endpoints.
+*/
+
+const (
+	RECONNECT_BACKOFF_MIN = 1 * time.Second
+	RECONNECT_BACKOFF_MAX = 60 * time.Second
+)
+
+type connection struct {
+	sync.Mutex
+	config Config
+	host   string
+
+	conn   *grpc.ClientConn
+	client gobgpapi.GobgpApiClient
+
+	err     error
+	backoff time.Duration
+	retryAt time.Time
+}
+
+func newConnection(config Config, host string) *connection {
+	return &connection{
+		config: config,
+		host:   host,
+	}
+}
+
+// Make the options for dialing the GoBGP daemon
+func dialOptions(config Config) ([]grpc.DialOption, error) {
+	dialOpts := []grpc.DialOption{
+		grpc.WithBackoffMaxDelay(RECONNECT_BACKOFF_MAX),
+	}
+	if config.Insecure {
+		dialOpts = append(dialOpts, grpc.WithInsecure())
+	} else {
+		creds, err := credentials.NewClientTLSFromFile(config.TLSCert, config.TLSCommonName)
+		if err != nil {
+			return nil, fmt.Errorf("could not load tls cert: %s", err)
+		}
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(creds))
+	}
+
+	if config.KeepaliveInterval > 0 {
+		dialOpts = append(dialOpts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:    time.Second * time.Duration(config.KeepaliveInterval),
+			Timeout: time.Second * time.Duration(config.KeepaliveTimeout),
+		}))
+	}
+
+	return dialOpts, nil
+}
+
+// Connect to the GoBGP daemon
+func dial(config Config, host string) (*grpc.ClientConn, error) {
+	dialOpts, err := dialOptions(config)
+	if err != nil {
+		return nil, err
+	}
+	return grpc.Dial(host, dialOpts...)
+}
+
+// Get the client, dial if not connected
+func (self *connection) get() (gobgpapi.GobgpApiClient, error) {
+	self.Lock()
+	defer self.Unlock()
+
+	if self.client != nil {
+		return self.client, nil
+	}
+
+	now := time.Now()
+	if now.Before(self.retryAt) {
+		return nil, fmt.Errorf(
+			"not connected to %s: %s", self.host, self.err)
+	}
+
+	conn, err := dial(self.config, self.host)
+	if err != nil {
+		self.backoff *= 2
+		if self.backoff < RECONNECT_BACKOFF_MIN {
+			self.backoff = RECONNECT_BACKOFF_MIN
+		}
+		if self.backoff > RECONNECT_BACKOFF_MAX {
+			self.backoff = RECONNECT_BACKOFF_MAX
+		}
+		self.err = err
+		self.retryAt = now.Add(self.backoff)
+
+		log.Println("GoBGP", self.config.Id, "could not connect to",
+			self.host, ":", err, "- retrying in", self.backoff)
+		return nil, fmt.Errorf(
+			"not connected to %s: %s", self.host, err)
+	}
+
+	self.conn = conn
+	self.client = gobgpapi.NewGobgpApiClient(conn)
+	self.err = nil
+	self.backoff = 0
+
+	return self.client, nil
+}
+
+// Check the connectivity to the daemon
+func (self *connection) check() error {
+	if _, err := self.get(); err != nil {
+		return err
+	}
+
+	state := self.conn.GetState()
+	if state == connectivity.TransientFailure ||
+		state == connectivity.Shutdown {
+		return fmt.Errorf(
+			"connection to %s failed: %s", self.host, state)
+	}
+	return nil
+}
+
+// The connections to redundant GoBGP daemons
+type pool struct {
+	endpoints *sources.Endpoints
+	hosts     map[string]*connection
+}
+
+func newPool(config Config) *pool {
+	hosts := sources.ParseEndpoints(config.Host)
+	conns := make(map[string]*connection)
+	for _, host := range hosts {
+		conns[host] = newConnection(config, host)
+	}
+	return &pool{
+		endpoints: sources.NewEndpoints(hosts, config.EndpointSelection),
+		hosts:     conns,
+	}
+}
+
+// Get the client of the next available daemon
+func (self *pool) get() (gobgpapi.GobgpApiClient, error) {
+	err := fmt.Errorf("no host configured")
+	hosts := self.endpoints.Order()
+	for i, host := range hosts {
+		conn := self.hosts[host]
+
+		// The last daemon is used, even if the check
+		// fails: Requests fail with the error of the daemon.
+		if i < len(hosts)-1 {
+			if err = conn.check(); err != nil {
+				self.endpoints.Failed(host)
+				continue
+			}
+		}
+
+		var client gobgpapi.GobgpApiClient
+		client, err = conn.get()
+		if err != nil {
+			self.endpoints.Failed(host)
+			continue
+		}
+		self.endpoints.Succeeded(host)
+		return client, nil
+	}
+	return nil, err
+}
+
+// Check if a request failed because of the daemon,
+// and might succeed with the next one
+func isUnavailable(parent context.Context, err error) bool {
+	if parent.Err() != nil {
+		return false // Retrying is pointless
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+// Make a request with the next available daemon, failing
+// over to the next one if the daemon is unavailable.
+// Each attempt is canceled after the timeout.
+func (self *pool) do(
+	parent context.Context,
+	timeout time.Duration,
+	request func(context.Context, gobgpapi.GobgpApiClient) error,
+) error {
+	err := fmt.Errorf("no host configured")
+	hosts := self.endpoints.Order()
+	for i, host := range hosts {
+		conn := self.hosts[host]
+
+		// The last daemon is used, even if the check
+		// fails: Requests fail with the error of the daemon.
+		if i < len(hosts)-1 {
+			if err = conn.check(); err != nil {
+				self.endpoints.Failed(host)
+				continue
+			}
+		}
+
+		var client gobgpapi.GobgpApiClient
+		client, err = conn.get()
+		if err != nil {
+			self.endpoints.Failed(host)
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(parent, timeout)
+		err = request(ctx, client)
+		cancel()
+		if !isUnavailable(parent, err) {
+			self.endpoints.Succeeded(host)
+			return err
+		}
+
+		self.endpoints.Failed(host)
+		if i < len(hosts)-1 {
+			log.Println("GoBGP request to", host, "failed:", err,
+				"- failing over")
+		}
+	}
+	return err
+}
+
+// Check the connectivity to any daemon
+func (self *pool) check() error {
+	err := fmt.Errorf("no host configured")
+	for _, host := range self.endpoints.Order() {
+		if err = self.hosts[host].check(); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// Get the host of the daemon in use, if redundant
+func (self *pool) active() string {
+	if self.endpoints.Len() < 2 {
+		return ""
+	}
+	return self.endpoints.Active()
+}
+
+// Get the client of the source
+func (gobgp *GoBGP) client() (gobgpapi.GobgpApiClient, error) {
+	return gobgp.conns.get()
+}
+
+// Make a request, e.g. listing the peers
+func (gobgp *GoBGP) request(
+	parent context.Context,
+	request func(context.Context, gobgpapi.GobgpApiClient) error,
+) error {
+	return gobgp.conns.do(parent,
+		time.Second*time.Duration(gobgp.config.RequestTimeout), request)
+}
+
+// Make a request processing a table
+func (gobgp *GoBGP) process(
+	parent context.Context,
+	request func(context.Context, gobgpapi.GobgpApiClient) error,
+) error {
+	return gobgp.conns.do(parent,
+		time.Second*time.Duration(gobgp.config.ProcessingTimeout), request)
+}
@@ -0,0 +1,206 @@
+package gobgp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	gobgpapi "github.com/osrg/gobgp/api"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// A GoBGP daemon only answering GetBgp
+type testDaemon struct {
+	gobgpapi.GobgpApiServer
+	routerId string
+}
+
+func (self *testDaemon) GetBgp(
+	ctx context.Context,
+	req *gobgpapi.GetBgpRequest,
+) (*gobgpapi.GetBgpResponse, error) {
+	return &gobgpapi.GetBgpResponse{
+		Global: &gobgpapi.Global{RouterId: self.routerId},
+	}, nil
+}
+
+// Serve a test daemon, the address is returned
+func serveTestDaemon(t *testing.T, routerId string) (string, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	gobgpapi.RegisterGobgpApiServer(server, &testDaemon{routerId: routerId})
+	go server.Serve(listener)
+	return listener.Addr().String(), server.Stop
+}
+
+func TestConnectionBackoff(t *testing.T) {
+	conn := newConnection(Config{
+		Id:      "rs1",
+		TLSCert: "/does/not/exist.crt",
+	}, "localhost:50051")
+
+	_, err := conn.get()
+	if err == nil {
+		t.Error("Expected an error without a tls cert")
+	}
+	if conn.backoff != RECONNECT_BACKOFF_MIN {
+		t.Error("Unexpected backoff:", conn.backoff)
+	}
+
+	// Not dialed again before the backoff expired
+	_, err = conn.get()
+	if err == nil {
+		t.Error("Expected an error while backing off")
+	}
+	if conn.backoff != RECONNECT_BACKOFF_MIN {
+		t.Error("Expected no dial while backing off:", conn.backoff)
+	}
+	if err := conn.check(); err == nil {
+		t.Error("Expected the check to fail")
+	}
+}
+
+func TestConnectionLazy(t *testing.T) {
+	gobgp := NewGoBGP(Config{
+		Id:             "rs1",
+		Host:           "127.0.0.1:1",
+		Insecure:       true,
+		RequestTimeout: 1,
+	})
+	conn := gobgp.conns.hosts["127.0.0.1:1"]
+	if conn.client != nil {
+		t.Error("Expected the daemon not to be dialed")
+	}
+
+	// The daemon is not reachable
+	_, err := gobgp.Neighbours()
+	if err == nil {
+		t.Error("Expected an error for an unreachable daemon")
+	}
+	if conn.client == nil {
+		t.Error("Expected the daemon to be dialed")
+	}
+}
+
+func TestPoolFailover(t *testing.T) {
+	gobgp := NewGoBGP(Config{
+		Id:             "rs1",
+		Host:           "127.0.0.1:1, 127.0.0.1:2",
+		Insecure:       true,
+		RequestTimeout: 1,
+	})
+
+	// The first daemon is backing off
+	primary := gobgp.conns.hosts["127.0.0.1:1"]
+	primary.err = fmt.Errorf("connection refused")
+	primary.retryAt = time.Now().Add(time.Minute)
+
+	if _, err := gobgp.client(); err != nil {
+		t.Fatal(err)
+	}
+	if active := gobgp.conns.active(); active != "127.0.0.1:2" {
+		t.Error("Expected the second daemon to be active, got:", active)
+	}
+	if gobgp.conns.hosts["127.0.0.1:2"].client == nil {
+		t.Error("Expected the second daemon to be dialed")
+	}
+}
+
+func TestPoolRequestFailover(t *testing.T) {
+	addr, stop := serveTestDaemon(t, "10.0.0.2")
+	defer stop()
+
+	// The first daemon refuses connections
+	gobgp := NewGoBGP(Config{
+		Id:             "rs1",
+		Host:           "127.0.0.1:1," + addr,
+		Insecure:       true,
+		RequestTimeout: 1,
+	})
+
+	status, err := gobgp.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Status.RouterId != "10.0.0.2" {
+		t.Error("Unexpected router id:", status.Status.RouterId)
+	}
+	if status.Status.Endpoint != addr {
+		t.Error("Expected the second daemon to be active, got:",
+			status.Status.Endpoint)
+	}
+
+	// The failed daemon is tried last
+	if order := gobgp.conns.endpoints.Order(); order[0] != addr {
+		t.Error("Expected the first daemon to be tried last:", order)
+	}
+}
+
+func TestPoolRequestTimeout(t *testing.T) {
+	addr, stop := serveTestDaemon(t, "10.0.0.2")
+	defer stop()
+
+	// The first daemon does not answer
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	gobgp := NewGoBGP(Config{
+		Id:             "rs1",
+		Host:           listener.Addr().String() + "," + addr,
+		Insecure:       true,
+		RequestTimeout: 1,
+	})
+
+	status, err := gobgp.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Status.Endpoint != addr {
+		t.Error("Expected the second daemon to be active, got:",
+			status.Status.Endpoint)
+	}
+}
+
+func TestPoolRequestNoRetry(t *testing.T) {
+	gobgp := NewGoBGP(Config{
+		Id:       "rs1",
+		Host:     "127.0.0.1:1, 127.0.0.1:2",
+		Insecure: true,
+	})
+
+	// The daemon answered the request
+	attempts := 0
+	err := gobgp.request(context.Background(), func(
+		ctx context.Context, client gobgpapi.GobgpApiClient,
+	) error {
+		attempts++
+		return status.Error(codes.NotFound, "peer not found")
+	})
+	if status.Code(err) != codes.NotFound || attempts != 1 {
+		t.Error("Expected the error without a retry, got:", err, attempts)
+	}
+
+	// The request was canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	attempts = 0
+	gobgp.request(ctx, func(
+		ctx context.Context, client gobgpapi.GobgpApiClient,
+	) error {
+		attempts++
+		return status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	})
+	if attempts != 1 {
+		t.Error("Expected no retry of a canceled request:", attempts)
+	}
+}
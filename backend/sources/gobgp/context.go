@@ -0,0 +1,46 @@
+package gobgp
+
+import (
+	"context"
+
+	"github.com/alice-lg/alice-lg/backend/api"
+)
+
+// Requests without a context are made with
+// the background context.
+
+func (gobgp *GoBGP) Status() (*api.StatusResponse, error) {
+	return gobgp.StatusContext(context.Background())
+}
+
+func (gobgp *GoBGP) Neighbours() (*api.NeighboursResponse, error) {
+	return gobgp.NeighboursContext(context.Background())
+}
+
+func (gobgp *GoBGP) NeighboursStatus() (*api.NeighboursStatusResponse, error) {
+	return gobgp.NeighboursStatusContext(context.Background())
+}
+
+func (gobgp *GoBGP) Routes(neighbourId string) (*api.RoutesResponse, error) {
+	return gobgp.RoutesContext(context.Background(), neighbourId)
+}
+
+func (gobgp *GoBGP) RoutesReceived(neighbourId string) (*api.RoutesResponse, error) {
+	return gobgp.RoutesReceivedContext(context.Background(), neighbourId)
+}
+
+func (gobgp *GoBGP) RoutesFiltered(neighbourId string) (*api.RoutesResponse, error) {
+	return gobgp.RoutesFilteredContext(context.Background(), neighbourId)
+}
+
+func (gobgp *GoBGP) RoutesNotExported(neighbourId string) (*api.RoutesResponse, error) {
+	return gobgp.RoutesNotExportedContext(context.Background(), neighbourId)
+}
+
+func (gobgp *GoBGP) AllRoutes() (*api.RoutesResponse, error) {
+	return gobgp.AllRoutesContext(context.Background())
+}
+
+func (gobgp *GoBGP) LookupPrefix(prefix string) (*api.RoutesLookupResponse, error) {
+	return gobgp.LookupPrefixContext(context.Background(), prefix)
+}
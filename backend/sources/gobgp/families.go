@@ -0,0 +1,101 @@
+package gobgp
+
+import (
+	"github.com/alice-lg/alice-lg/backend/api"
+	"github.com/alice-lg/alice-lg/backend/sources/gobgp/apiutil"
+	gobgpapi "github.com/osrg/gobgp/api"
+	"github.com/osrg/gobgp/pkg/packet/bgp"
+
+	"fmt"
+	"strings"
+)
+
+// The address families used, when not configured
+var DEFAULT_FAMILIES = []string{"ipv4-unicast", "ipv6-unicast"}
+
+/*
+Parse the configured address families. The names are
+the ones used by GoBGP, e.g. ipv4-unicast, l3vpn-ipv4-unicast,
+ipv4-labelled-unicast or ipv4-flowspec.
+*/
+func ParseFamilies(names []string) ([]gobgpapi.Family, error) {
+	if len(names) == 0 {
+		names = DEFAULT_FAMILIES
+	}
+
+	families := make([]gobgpapi.Family, 0, len(names))
+	for _, name := range names {
+		rf, err := bgp.GetRouteFamily(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		afi, safi := bgp.RouteFamilyToAfiSafi(rf)
+		families = append(families, *apiutil.ToApiFamily(afi, safi))
+	}
+	return families, nil
+}
+
+// Get the name of an address family
+func familyName(family *gobgpapi.Family) string {
+	if family == nil {
+		return ""
+	}
+	return apiutil.ToRouteFamily(family).String()
+}
+
+// Get the value of a FlowSpec component without the type
+func flowSpecValue(component bgp.FlowSpecComponentInterface) string {
+	value := component.String()
+	value = strings.TrimPrefix(value, fmt.Sprintf("[%s: ", component.Type()))
+	return strings.TrimSuffix(value, "]")
+}
+
+func decodeFlowSpec(route *api.Route, nlri *bgp.FlowSpecNLRI) {
+	if rd := nlri.RD(); rd != nil {
+		route.RouteDistinguisher = rd.String()
+	}
+	route.FlowSpec = make([]api.FlowSpecRule, 0, len(nlri.Value))
+	for _, component := range nlri.Value {
+		route.FlowSpec = append(route.FlowSpec, api.FlowSpecRule{
+			Type:  component.Type().String(),
+			Value: flowSpecValue(component),
+		})
+	}
+}
+
+func decodeLabels(route *api.Route, stack bgp.MPLSLabelStack) {
+	route.Labels = make([]int, 0, len(stack.Labels))
+	for _, label := range stack.Labels {
+		route.Labels = append(route.Labels, int(label))
+	}
+}
+
+// Add the family and the details of non unicast NLRIs to a route
+func decodeNlri(route *api.Route, family *gobgpapi.Family, nlri bgp.AddrPrefixInterface) {
+	route.Family = familyName(family)
+
+	switch n := nlri.(type) {
+	case *bgp.LabeledVPNIPAddrPrefix:
+		route.Network = n.IPPrefix()
+		route.RouteDistinguisher = n.RD.String()
+		decodeLabels(route, n.Labels)
+	case *bgp.LabeledVPNIPv6AddrPrefix:
+		route.Network = n.IPPrefix()
+		route.RouteDistinguisher = n.RD.String()
+		decodeLabels(route, n.Labels)
+	case *bgp.LabeledIPAddrPrefix:
+		decodeLabels(route, n.Labels)
+	case *bgp.LabeledIPv6AddrPrefix:
+		decodeLabels(route, n.Labels)
+	case *bgp.FlowSpecIPv4Unicast:
+		decodeFlowSpec(route, &n.FlowSpecNLRI)
+	case *bgp.FlowSpecIPv6Unicast:
+		decodeFlowSpec(route, &n.FlowSpecNLRI)
+	case *bgp.FlowSpecIPv4VPN:
+		decodeFlowSpec(route, &n.FlowSpecNLRI)
+	case *bgp.FlowSpecIPv6VPN:
+		decodeFlowSpec(route, &n.FlowSpecNLRI)
+	case *bgp.FlowSpecL2VPN:
+		decodeFlowSpec(route, &n.FlowSpecNLRI)
+	}
+}
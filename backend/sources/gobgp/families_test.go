@@ -0,0 +1,85 @@
+package gobgp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alice-lg/alice-lg/backend/sources/gobgp/apiutil"
+	gobgpapi "github.com/osrg/gobgp/api"
+	"github.com/osrg/gobgp/pkg/packet/bgp"
+)
+
+func TestParseFamilies(t *testing.T) {
+	families, err := ParseFamilies([]string{
+		"ipv4-unicast", " l3vpn-ipv6-unicast", "ipv4-flowspec"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(families) != 3 {
+		t.Fatal("Expected 3 families, got:", families)
+	}
+	if families[1].Afi != gobgpapi.Family_AFI_IP6 ||
+		families[1].Safi != gobgpapi.Family_SAFI_MPLS_VPN {
+		t.Error("Unexpected family:", families[1])
+	}
+	if familyName(&families[2]) != "ipv4-flowspec" {
+		t.Error("Unexpected family name:", familyName(&families[2]))
+	}
+
+	families, err = ParseFamilies(nil)
+	if err != nil || len(families) != 2 {
+		t.Error("Expected the default families:", families, err)
+	}
+
+	if _, err := ParseFamilies([]string{"ipv4-unicorn"}); err == nil {
+		t.Error("Expected unknown family to be rejected")
+	}
+}
+
+func TestParseNonUnicastPaths(t *testing.T) {
+	gobgp := &GoBGP{}
+	attrs := []bgp.PathAttributeInterface{
+		bgp.NewPathAttributeOrigin(bgp.BGP_ORIGIN_ATTR_TYPE_IGP),
+	}
+
+	vpn := bgp.NewLabeledVPNIPAddrPrefix(24, "10.23.42.0",
+		*bgp.NewMPLSLabelStack(100, 200),
+		bgp.NewRouteDistinguisherTwoOctetAS(65000, 1))
+	path := apiutil.NewPath(vpn, false, attrs, time.Now())
+	err, route := gobgp.parsePathIntoRoute(path, vpn.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if route.Family != "l3vpn-ipv4-unicast" ||
+		route.Network != "10.23.42.0/24" ||
+		route.RouteDistinguisher != "65000:1" {
+		t.Error("Unexpected vpn route:", route)
+	}
+	if len(route.Labels) != 2 || route.Labels[0] != 100 {
+		t.Error("Unexpected labels:", route.Labels)
+	}
+
+	flowspec := bgp.NewFlowSpecIPv4Unicast([]bgp.FlowSpecComponentInterface{
+		bgp.NewFlowSpecDestinationPrefix(bgp.NewIPAddrPrefix(24, "10.23.42.0")),
+		bgp.NewFlowSpecComponent(bgp.FLOW_SPEC_TYPE_IP_PROTO,
+			[]*bgp.FlowSpecComponentItem{
+				bgp.NewFlowSpecComponentItem(bgp.DEC_NUM_OP_EQ, 6),
+			}),
+	})
+	path = apiutil.NewPath(flowspec, false, attrs, time.Now())
+	err, route = gobgp.parsePathIntoRoute(path, flowspec.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if route.Family != "ipv4-flowspec" || len(route.FlowSpec) != 2 {
+		t.Fatal("Unexpected flowspec route:", route)
+	}
+	if route.FlowSpec[0].Type != "destination" ||
+		route.FlowSpec[0].Value != "10.23.42.0/24" {
+		t.Error("Unexpected destination:", route.FlowSpec[0])
+	}
+	if route.FlowSpec[1].Type != "protocol" ||
+		route.FlowSpec[1].Value != "==tcp" {
+		t.Error("Unexpected protocol:", route.FlowSpec[1])
+	}
+}
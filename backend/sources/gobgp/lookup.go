@@ -0,0 +1,167 @@
+package gobgp
+
+import (
+	"github.com/alice-lg/alice-lg/backend/api"
+	"github.com/alice-lg/alice-lg/backend/sources"
+	gobgpapi "github.com/osrg/gobgp/api"
+
+	"context"
+	"io"
+	"net"
+	"time"
+)
+
+/*
+Make the prefix filter for a lookup query:
+
+ - a prefix matches the prefix and all more specifics
+ - an address matches all prefixes covering it
+
+The family is derived from the address.
+*/
+func lookupPrefixFilter(query string) (*gobgpapi.TableLookupPrefix, *gobgpapi.Family, error) {
+	var ip net.IP
+	filter := &gobgpapi.TableLookupPrefix{}
+
+	if _, network, err := net.ParseCIDR(query); err == nil {
+		ip = network.IP
+		filter.Prefix = network.String()
+		filter.LookupOption = gobgpapi.TableLookupOption_LOOKUP_LONGER
+		ones, bits := network.Mask.Size()
+		if ones == bits {
+			filter.LookupOption = gobgpapi.TableLookupOption_LOOKUP_EXACT
+		}
+	} else if ip = net.ParseIP(query); ip != nil {
+		filter.Prefix = ip.String()
+		filter.LookupOption = gobgpapi.TableLookupOption_LOOKUP_SHORTER
+	} else {
+		return nil, nil, &sources.InvalidRequestError{
+			Param:  "prefix",
+			Value:  query,
+			Reason: "not an address or prefix",
+		}
+	}
+
+	family := &gobgpapi.Family{
+		Afi:  gobgpapi.Family_AFI_IP,
+		Safi: gobgpapi.Family_SAFI_UNICAST,
+	}
+	if ip.To4() == nil {
+		family.Afi = gobgpapi.Family_AFI_IP6
+	}
+
+	return filter, family, nil
+}
+
+/*
+LookupPrefix searches the global RIB of the GoBGP daemon, or
+the table of the VRF, for the prefix, instead of relying on a
+routes store dump.
+Only accepted routes are part of the global RIB.
+*/
+func (gobgp *GoBGP) LookupPrefixContext(parent context.Context, prefix string) (*api.RoutesLookupResponse, error) {
+	filter, family, err := lookupPrefixFilter(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	peers, err := gobgp.GetNeighbours(parent)
+	if err != nil {
+		return nil, err
+	}
+	neighbours := make(map[string]*api.Neighbour, len(peers))
+	for _, peer := range peers {
+		neighbour := gobgp.parseNeighbour(peer)
+		neighbours[neighbour.Id] = neighbour
+	}
+
+	tableType, name := gobgp.lookupTable()
+	var destinations []*gobgpapi.Destination
+	err = gobgp.process(parent, func(ctx context.Context, client gobgpapi.GobgpApiClient) error {
+		pathStream, err := client.ListPath(ctx, &gobgpapi.ListPathRequest{
+			TableType: tableType,
+			Name:      name,
+			Family:    family,
+			Prefixes:  []*gobgpapi.TableLookupPrefix{filter},
+		})
+		if err != nil {
+			return err
+		}
+
+		destinations = []*gobgpapi.Destination{}
+		for {
+			_path, err := pathStream.Recv()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return err
+			}
+			destinations = append(destinations, _path.Destination)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rs := api.Routeserver{
+		Id:   gobgp.config.Id,
+		Name: gobgp.config.Name,
+	}
+
+	routes := api.LookupRoutes{}
+	for _, destination := range destinations {
+		for _, path := range destination.Paths {
+			err, route := gobgp.parsePathIntoRoute(path, destination.Prefix)
+			if err != nil {
+				return nil, err
+			}
+
+			neighbour, ok := neighbours[route.NeighbourId]
+			if !ok {
+				neighbour = &api.Neighbour{
+					Id:            route.NeighbourId,
+					Address:       path.NeighborIp,
+					Asn:           int(path.SourceAsn),
+					RouteServerId: gobgp.config.Id,
+				}
+			}
+
+			routes = append(routes, &api.LookupRoute{
+				Id:          route.Id,
+				NeighbourId: route.NeighbourId,
+				Neighbour:   neighbour,
+
+				Routeserver: rs,
+
+				State: "imported",
+
+				Network:   route.Network,
+				Interface: route.Interface,
+				Gateway:   route.Gateway,
+				Metric:    route.Metric,
+				Bgp:       route.Bgp,
+				Age:       route.Age,
+				Type:      route.Type,
+				Primary:   route.Primary,
+
+				Details: route.Details,
+			})
+		}
+	}
+
+	now := time.Now().UTC()
+	response := &api.RoutesLookupResponse{
+		Api: api.ApiStatus{
+			Version:         "gobgp",
+			ResultFromCache: false,
+			Ttl:             now,
+			CacheStatus: api.CacheStatus{
+				CachedAt: now,
+			},
+		},
+		Routes: routes,
+	}
+
+	return response, nil
+}
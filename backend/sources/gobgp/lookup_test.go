@@ -0,0 +1,52 @@
+package gobgp
+
+import (
+	"testing"
+
+	"github.com/alice-lg/alice-lg/backend/sources"
+	gobgpapi "github.com/osrg/gobgp/api"
+)
+
+func TestLookupPrefixFilter(t *testing.T) {
+	tests := []struct {
+		query  string
+		prefix string
+		option gobgpapi.TableLookupOption
+		afi    gobgpapi.Family_Afi
+	}{
+		{"10.23.42.0/24", "10.23.42.0/24", gobgpapi.TableLookupOption_LOOKUP_LONGER, gobgpapi.Family_AFI_IP},
+		{"10.23.42.1/24", "10.23.42.0/24", gobgpapi.TableLookupOption_LOOKUP_LONGER, gobgpapi.Family_AFI_IP},
+		{"10.23.42.1/32", "10.23.42.1/32", gobgpapi.TableLookupOption_LOOKUP_EXACT, gobgpapi.Family_AFI_IP},
+		{"10.23.42.1", "10.23.42.1", gobgpapi.TableLookupOption_LOOKUP_SHORTER, gobgpapi.Family_AFI_IP},
+		{"2001:db8::/32", "2001:db8::/32", gobgpapi.TableLookupOption_LOOKUP_LONGER, gobgpapi.Family_AFI_IP6},
+		{"2001:db8::1", "2001:db8::1", gobgpapi.TableLookupOption_LOOKUP_SHORTER, gobgpapi.Family_AFI_IP6},
+	}
+
+	for _, test := range tests {
+		filter, family, err := lookupPrefixFilter(test.query)
+		if err != nil {
+			t.Error(test.query, err)
+			continue
+		}
+		if filter.Prefix != test.prefix ||
+			filter.LookupOption != test.option ||
+			family.Afi != test.afi {
+			t.Error("Unexpected filter for", test.query, ":", filter, family)
+		}
+	}
+
+	_, _, err := lookupPrefixFilter("10.23.")
+	if _, ok := err.(*sources.InvalidRequestError); !ok {
+		t.Error("Expected partial prefix to be rejected, got:", err)
+	}
+}
+
+func TestPeerHash(t *testing.T) {
+	// Neighbor ids must not change
+	if id := PeerHashWithASAndAddress(65001, "192.0.2.1"); id != "63c6765f7c" {
+		t.Error("Unexpected peer hash:", id)
+	}
+	if PeerHashWithASAndAddress(65001, "192.0.2.1") == PeerHashWithASAndAddress(65002, "192.0.2.1") {
+		t.Error("Expected peer hash to depend on the asn")
+	}
+}
@@ -0,0 +1,244 @@
+package gobgp
+
+import (
+	"github.com/alice-lg/alice-lg/backend/sources/gobgp/apiutil"
+	"github.com/golang/protobuf/ptypes/any"
+	gobgpapi "github.com/osrg/gobgp/api"
+	"github.com/osrg/gobgp/pkg/packet/bgp"
+
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+/*
+Neighbour details:
+
+The details of a neighbour are decoded from the peer
+state, timers and capabilities:
+
+    session_state   FSM state, e.g. established, active, idle
+    admin_state     up, down or pfx_ct (prefix limit exceeded)
+    router_id       The router id of the neighbour
+    local_as        The local AS of the session
+    peer_group      The peer group, if any
+    peer_type       internal or external
+    flops           Number of times the session went down
+    timers          hold_time, keepalive_interval, connect_retry
+                    and negotiated_hold_time in seconds,
+                    downtime as timestamp, if down
+    messages        received and sent: counters of open, update,
+                    notification, keepalive, refresh, discarded,
+                    withdraw_update, withdraw_prefix and total
+    capabilities    local, remote and negotiated: a list of
+                    capabilities with name and value, e.g.
+                    (multiprotocol, ipv4-unicast) or (4-octet-as, 65000).
+                    Negotiated are the remote capabilities, which
+                    are announced locally as well.
+    last_error      As below, if any
+
+The GoBGP API does not provide the reason a session
+went down. The last error is only set when the neighbour
+is administratively down or exceeded the prefix limit.
+*/
+
+// A Capability of a BGP session
+type Capability struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Get the details of a peer
+func decodeNeighbourDetails(peer *gobgpapi.Peer) map[string]interface{} {
+	details := map[string]interface{}{}
+	state := peer.State
+	if state == nil {
+		return details
+	}
+
+	details["session_state"] = strings.ToLower(state.SessionState.String())
+	details["admin_state"] = strings.ToLower(state.AdminState.String())
+	details["router_id"] = state.RouterId
+	details["local_as"] = state.LocalAs
+	details["peer_type"] = decodePeerType(state.PeerType)
+	details["flops"] = state.Flops
+
+	details["peer_group"] = state.PeerGroup
+	if state.PeerGroup == "" && peer.Conf != nil {
+		details["peer_group"] = peer.Conf.PeerGroup
+	}
+
+	if peer.Timers != nil {
+		details["timers"] = decodeTimers(peer.Timers)
+	}
+
+	if state.Messages != nil {
+		details["messages"] = map[string]interface{}{
+			"received": decodeMessages(state.Messages.Received),
+			"sent":     decodeMessages(state.Messages.Sent),
+		}
+	}
+
+	local := decodeCapabilities(state.LocalCap)
+	remote := decodeCapabilities(state.RemoteCap)
+	details["capabilities"] = map[string]interface{}{
+		"local":      local,
+		"remote":     remote,
+		"negotiated": negotiatedCapabilities(local, remote),
+	}
+
+	if lastError := decodeLastError(state); lastError != "" {
+		details["last_error"] = lastError
+	}
+
+	return details
+}
+
+func decodePeerType(peerType uint32) string {
+	if peerType == 0 {
+		return "internal"
+	}
+	return "external"
+}
+
+// Get the last error from the admin state
+func decodeLastError(state *gobgpapi.PeerState) string {
+	switch state.AdminState {
+	case gobgpapi.PeerState_DOWN:
+		return "Administratively down"
+	case gobgpapi.PeerState_PFX_CT:
+		return "Maximum prefix limit exceeded"
+	}
+	return ""
+}
+
+func decodeTimers(timers *gobgpapi.Timers) map[string]interface{} {
+	result := map[string]interface{}{}
+	if timers.Config != nil {
+		result["hold_time"] = timers.Config.HoldTime
+		result["keepalive_interval"] = timers.Config.KeepaliveInterval
+		result["connect_retry"] = timers.Config.ConnectRetry
+	}
+	if timers.State != nil {
+		result["negotiated_hold_time"] = timers.State.NegotiatedHoldTime
+		if timers.State.KeepaliveInterval > 0 {
+			result["keepalive_interval"] = timers.State.KeepaliveInterval
+		}
+		if timers.State.Downtime != nil && timers.State.Downtime.Seconds > 0 {
+			result["downtime"] = time.Unix(
+				timers.State.Downtime.Seconds,
+				int64(timers.State.Downtime.Nanos)).UTC()
+		}
+	}
+	return result
+}
+
+func decodeMessages(message *gobgpapi.Message) map[string]uint64 {
+	if message == nil {
+		message = &gobgpapi.Message{}
+	}
+	return map[string]uint64{
+		"open":            message.Open,
+		"update":          message.Update,
+		"notification":    message.Notification,
+		"keepalive":       message.Keepalive,
+		"refresh":         message.Refresh,
+		"discarded":       message.Discarded,
+		"withdraw_update": message.WithdrawUpdate,
+		"withdraw_prefix": message.WithdrawPrefix,
+		"total":           message.Total,
+	}
+}
+
+// Decode the capabilities of a session
+func decodeCapabilities(values []*any.Any) []Capability {
+	capabilities := []Capability{}
+	if len(values) == 0 {
+		return capabilities
+	}
+
+	decoded, err := apiutil.UnmarshalCapabilities(values)
+	if err != nil {
+		log.Println("GoBGP could not decode capabilities:", err)
+		return capabilities
+	}
+
+	for _, c := range decoded {
+		capabilities = append(capabilities, decodeCapability(c)...)
+	}
+	return capabilities
+}
+
+func decodeCapability(c bgp.ParameterCapabilityInterface) []Capability {
+	name := c.Code().String()
+	switch c := c.(type) {
+	case *bgp.CapMultiProtocol:
+		return []Capability{{name, c.CapValue.String()}}
+	case *bgp.CapFourOctetASNumber:
+		return []Capability{{name, fmt.Sprintf("%d", c.CapValue)}}
+	case *bgp.CapGracefulRestart:
+		return []Capability{{name, fmt.Sprintf("%ds", c.Time)}}
+	case *bgp.CapAddPath:
+		capabilities := make([]Capability, 0, len(c.Tuples))
+		for _, t := range c.Tuples {
+			capabilities = append(capabilities, Capability{
+				name, fmt.Sprintf("%s %s", t.RouteFamily, t.Mode)})
+		}
+		return capabilities
+	case *bgp.CapExtendedNexthop:
+		capabilities := make([]Capability, 0, len(c.Tuples))
+		for _, t := range c.Tuples {
+			family := bgp.AfiSafiToRouteFamily(t.NLRIAFI, uint8(t.NLRISAFI))
+			capabilities = append(capabilities, Capability{
+				name, fmt.Sprintf("%s nexthop %s", family, afiName(t.NexthopAFI))})
+		}
+		return capabilities
+	case *bgp.CapLongLivedGracefulRestart:
+		capabilities := make([]Capability, 0, len(c.Tuples))
+		for _, t := range c.Tuples {
+			family := bgp.AfiSafiToRouteFamily(t.AFI, t.SAFI)
+			capabilities = append(capabilities, Capability{
+				name, fmt.Sprintf("%s %ds", family, t.RestartTime)})
+		}
+		return capabilities
+	}
+	return []Capability{{name, ""}}
+}
+
+func afiName(afi uint16) string {
+	switch afi {
+	case bgp.AFI_IP:
+		return "ipv4"
+	case bgp.AFI_IP6:
+		return "ipv6"
+	}
+	return fmt.Sprintf("afi(%d)", afi)
+}
+
+/*
+Get the capabilities announced by both sides. The values
+of the remote capabilities are used, e.g. its AS number,
+only the address families must match.
+*/
+func negotiatedCapabilities(local, remote []Capability) []Capability {
+	key := func(c Capability) string {
+		if c.Name == bgp.BGP_CAP_MULTIPROTOCOL.String() {
+			return c.Name + " " + c.Value
+		}
+		return c.Name
+	}
+
+	announced := make(map[string]bool, len(local))
+	for _, c := range local {
+		announced[key(c)] = true
+	}
+
+	negotiated := []Capability{}
+	for _, c := range remote {
+		if announced[key(c)] {
+			negotiated = append(negotiated, c)
+		}
+	}
+	return negotiated
+}
@@ -0,0 +1,111 @@
+package gobgp
+
+import (
+	"testing"
+
+	"github.com/alice-lg/alice-lg/backend/sources/gobgp/apiutil"
+	gobgpapi "github.com/osrg/gobgp/api"
+	"github.com/osrg/gobgp/pkg/packet/bgp"
+)
+
+func TestDecodeNeighbourDetails(t *testing.T) {
+	local, _ := apiutil.MarshalCapabilities([]bgp.ParameterCapabilityInterface{
+		bgp.NewCapMultiProtocol(bgp.RF_IPv4_UC),
+		bgp.NewCapMultiProtocol(bgp.RF_IPv6_UC),
+		bgp.NewCapRouteRefresh(),
+		bgp.NewCapFourOctetASNumber(65000),
+	})
+	remote, _ := apiutil.MarshalCapabilities([]bgp.ParameterCapabilityInterface{
+		bgp.NewCapMultiProtocol(bgp.RF_IPv4_UC),
+		bgp.NewCapFourOctetASNumber(65001),
+		bgp.NewCapAddPath([]*bgp.CapAddPathTuple{
+			bgp.NewCapAddPathTuple(bgp.RF_IPv4_UC, bgp.BGP_ADD_PATH_RECEIVE),
+		}),
+	})
+
+	peer := testPeer("192.0.2.1", gobgpapi.PeerState_ACTIVE)
+	peer.Conf.PeerGroup = "customers"
+	peer.State.AdminState = gobgpapi.PeerState_PFX_CT
+	peer.State.LocalCap = local
+	peer.State.RemoteCap = remote
+	peer.State.Messages = &gobgpapi.Messages{
+		Received: &gobgpapi.Message{Update: 23, Total: 42},
+	}
+	peer.Timers = &gobgpapi.Timers{
+		Config: &gobgpapi.TimersConfig{HoldTime: 90, KeepaliveInterval: 30},
+		State:  &gobgpapi.TimersState{NegotiatedHoldTime: 60},
+	}
+
+	gobgp := &GoBGP{config: Config{Id: "rs1"}}
+	neighbour := gobgp.parseNeighbour(peer)
+	details := neighbour.Details
+
+	if details["session_state"] != "active" || details["admin_state"] != "pfx_ct" {
+		t.Error("Unexpected state:", details["session_state"], details["admin_state"])
+	}
+	if details["peer_group"] != "customers" {
+		t.Error("Unexpected peer group:", details["peer_group"])
+	}
+	if neighbour.LastError != "Maximum prefix limit exceeded" {
+		t.Error("Unexpected last error:", neighbour.LastError)
+	}
+
+	timers := details["timers"].(map[string]interface{})
+	if timers["hold_time"] != uint64(90) || timers["negotiated_hold_time"] != uint64(60) {
+		t.Error("Unexpected timers:", timers)
+	}
+
+	messages := details["messages"].(map[string]interface{})
+	received := messages["received"].(map[string]uint64)
+	if received["update"] != 23 || received["total"] != 42 {
+		t.Error("Unexpected received messages:", received)
+	}
+	sent := messages["sent"].(map[string]uint64)
+	if sent["total"] != 0 {
+		t.Error("Unexpected sent messages:", sent)
+	}
+
+	capabilities := details["capabilities"].(map[string]interface{})
+	if len(capabilities["local"].([]Capability)) != 4 {
+		t.Error("Unexpected local capabilities:", capabilities["local"])
+	}
+	remoteCaps := capabilities["remote"].([]Capability)
+	if remoteCaps[2] != (Capability{"add-path", "ipv4-unicast receive"}) {
+		t.Error("Unexpected add path capability:", remoteCaps[2])
+	}
+	expected := []Capability{
+		{"multiprotocol", "ipv4-unicast"},
+		{"4-octet-as", "65001"},
+	}
+	negotiated := capabilities["negotiated"].([]Capability)
+	if len(negotiated) != len(expected) {
+		t.Fatal("Unexpected negotiated capabilities:", negotiated)
+	}
+	for i, c := range expected {
+		if negotiated[i] != c {
+			t.Error("Expected", c, "got:", negotiated[i])
+		}
+	}
+}
+
+func TestParseNeighbourRouteCounts(t *testing.T) {
+	peer := testPeer("192.0.2.1", gobgpapi.PeerState_ESTABLISHED)
+	peer.AfiSafis = []*gobgpapi.AfiSafi{
+		{State: &gobgpapi.AfiSafiState{Received: 10, Accepted: 8, Advertised: 5}},
+		{State: &gobgpapi.AfiSafiState{Received: 6, Accepted: 5, Advertised: 3}},
+	}
+
+	gobgp := &GoBGP{config: Config{Id: "rs1"}}
+	neighbour := gobgp.parseNeighbour(peer)
+
+	if neighbour.RoutesReceived != 16 || neighbour.RoutesAccepted != 13 {
+		t.Error("Unexpected received and accepted routes:",
+			neighbour.RoutesReceived, neighbour.RoutesAccepted)
+	}
+	if neighbour.RoutesFiltered != 3 {
+		t.Error("Expected 3 filtered routes, got:", neighbour.RoutesFiltered)
+	}
+	if neighbour.RoutesExported != 8 {
+		t.Error("Expected 8 exported routes, got:", neighbour.RoutesExported)
+	}
+}
@@ -0,0 +1,246 @@
+package gobgp
+
+import (
+	"github.com/alice-lg/alice-lg/backend/api"
+	gobgpapi "github.com/osrg/gobgp/api"
+
+	"context"
+	"io"
+	"log"
+)
+
+/*
+Not exported routes:
+
+The routes of a neighbour's Loc-RIB, which are not in its
+Adj-RIB-Out. For route server clients this is the local RIB
+of the client, otherwise the global RIB (or the VRF table).
+Routes learned from the neighbour itself are never sent back
+and are excluded.
+
+Only the best path of a prefix is advertised, so a prefix is
+exported if any of its paths is in the Adj-RIB-Out. For the
+prefixes which are not exported, the best path is reported.
+The reason is added to the details of a route: Rejected by
+the export policy (as reported by GoBGP for the Adj-RIB-Out),
+or not advertised for other reasons, e.g. well known
+communities.
+*/
+
+const (
+	NOT_EXPORTED_REASON_POLICY = "export policy"
+	NOT_EXPORTED_REASON_OTHER  = "not advertised"
+)
+
+// Iterate the paths of a table in all families
+func (gobgp *GoBGP) listTable(
+	parent context.Context,
+	tableType gobgpapi.TableType,
+	name string,
+	enableFiltered bool,
+	fn func(prefix string, path *gobgpapi.Path),
+) error {
+	// The paths are collected first, as the request
+	// might be retried with another daemon.
+	var destinations []*gobgpapi.Destination
+	err := gobgp.process(parent, func(ctx context.Context, client gobgpapi.GobgpApiClient) error {
+		destinations = []*gobgpapi.Destination{}
+		for _, family := range gobgp.families {
+			family := family
+			pathStream, err := client.ListPath(ctx, &gobgpapi.ListPathRequest{
+				TableType:      tableType,
+				Name:           name,
+				Family:         &family,
+				EnableFiltered: enableFiltered,
+			})
+			if err != nil {
+				return err
+			}
+
+			for {
+				resp, err := pathStream.Recv()
+				if err == io.EOF {
+					break
+				} else if err != nil {
+					return err
+				}
+				destinations = append(destinations, resp.Destination)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, destination := range destinations {
+		for _, path := range destination.Paths {
+			fn(destination.Prefix, path)
+		}
+	}
+	return nil
+}
+
+// Get the Loc-RIB from which routes are exported to a peer
+func (gobgp *GoBGP) locRib(peer *gobgpapi.Peer) (gobgpapi.TableType, string) {
+	if peer.RouteServer != nil && peer.RouteServer.RouteServerClient {
+		return gobgpapi.TableType_LOCAL, peer.State.NeighborAddress
+	}
+	return gobgp.lookupTable()
+}
+
+// Get the names of the export policies applied to a peer
+func (gobgp *GoBGP) exportPolicies(parent context.Context, peer *gobgpapi.Peer) ([]string, error) {
+	name := "global"
+	if peer.RouteServer != nil && peer.RouteServer.RouteServerClient {
+		name = peer.State.NeighborAddress
+	}
+
+	var policies []string
+	err := gobgp.request(parent, func(ctx context.Context, client gobgpapi.GobgpApiClient) error {
+		stream, err := client.ListPolicyAssignment(ctx, &gobgpapi.ListPolicyAssignmentRequest{
+			Name:      name,
+			Direction: gobgpapi.PolicyDirection_EXPORT,
+		})
+		if err != nil {
+			return err
+		}
+
+		policies = []string{}
+		for {
+			resp, err := stream.Recv()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return err
+			}
+			for _, policy := range resp.Assignment.Policies {
+				policies = append(policies, policy.Name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+/*
+A notExported set collects the not exported routes of
+a peer. The Adj-RIB-Out is added first, then the Loc-RIB.
+*/
+type notExported struct {
+	address  string
+	policies []string
+
+	exported map[string]bool
+	rejected map[string]bool
+	seen     map[string]bool
+
+	routes api.Routes
+}
+
+func newNotExported(address string, policies []string) *notExported {
+	return &notExported{
+		address:  address,
+		policies: policies,
+		exported: make(map[string]bool),
+		rejected: make(map[string]bool),
+		seen:     make(map[string]bool),
+		routes:   make(api.Routes, 0),
+	}
+}
+
+// Add a path of the Adj-RIB-Out
+func (self *notExported) addAdjRibOut(prefix string, path *gobgpapi.Path) {
+	if path.Filtered {
+		self.rejected[prefix] = true
+	} else {
+		self.exported[prefix] = true
+	}
+}
+
+// Add a path of the Loc-RIB, parsed into a route
+func (self *notExported) addLocRib(
+	prefix string,
+	path *gobgpapi.Path,
+	parse func(*gobgpapi.Path, string) (error, *api.Route),
+) {
+	// Other paths are never advertised, and routes learned
+	// from the neighbour are not sent back.
+	if !path.Best || path.NeighborIp == self.address {
+		return
+	}
+	if self.exported[prefix] || self.seen[prefix] {
+		return
+	}
+	self.seen[prefix] = true
+
+	err, route := parse(path, prefix)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	details := api.Details{}
+	switch {
+	case self.rejected[prefix]:
+		details["reason"] = NOT_EXPORTED_REASON_POLICY
+		if len(self.policies) > 0 {
+			details["export_policies"] = self.policies
+		}
+	default:
+		details["reason"] = NOT_EXPORTED_REASON_OTHER
+	}
+	route.Details = details
+
+	self.routes = append(self.routes, route)
+}
+
+// Get the routes of the Loc-RIB not sent to a neighbour
+func (gobgp *GoBGP) RoutesNotExportedContext(ctx context.Context, neighbourId string) (*api.RoutesResponse, error) {
+	gobgp.notExportedFetchMutex.Lock(neighbourId)
+	defer gobgp.notExportedFetchMutex.Unlock(neighbourId)
+
+	// Check if we have a cache hit
+	response := gobgp.routesNotExportedCache.Get(neighbourId)
+	if response != nil {
+		return response, nil
+	}
+
+	peer, err := gobgp.lookupNeighbour(ctx, neighbourId)
+	if err != nil {
+		return nil, err
+	}
+	address := peer.State.NeighborAddress
+
+	policies, err := gobgp.exportPolicies(ctx, peer)
+	if err != nil {
+		log.Println("GoBGP", gobgp.config.Id, "could not get export policies of", address, ":", err)
+	}
+
+	routes := newNotExported(address, policies)
+	err = gobgp.listTable(ctx, gobgpapi.TableType_ADJ_OUT, address, true, routes.addAdjRibOut)
+	if err != nil {
+		return nil, err
+	}
+
+	tableType, name := gobgp.locRib(peer)
+	err = gobgp.listTable(ctx, tableType, name, false, func(prefix string, path *gobgpapi.Path) {
+		routes.addLocRib(prefix, path, gobgp.parsePathIntoRoute)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := NewRoutesResponse()
+	result.Api = gobgp.makeApiStatus()
+	result.NotExported = routes.routes
+	response = &result
+
+	// Cache result
+	gobgp.routesNotExportedCache.Set(neighbourId, response)
+
+	return response, nil
+}
@@ -0,0 +1,58 @@
+package gobgp
+
+import (
+	"testing"
+
+	gobgpapi "github.com/osrg/gobgp/api"
+)
+
+func TestNotExported(t *testing.T) {
+	gobgp := &GoBGP{}
+	path := func(prefix, source string, best, filtered bool) *gobgpapi.Path {
+		p := testPath(source, prefix, 24, false)
+		p.Best = best
+		p.Filtered = filtered
+		return p
+	}
+
+	routes := newNotExported("192.0.2.1", []string{"rs-export"})
+
+	// Adj-RIB-Out
+	routes.addAdjRibOut("10.0.1.0/24", path("10.0.1.0", "192.0.2.2", true, false))
+	routes.addAdjRibOut("10.0.2.0/24", path("10.0.2.0", "192.0.2.2", true, true))
+
+	// Loc-RIB
+	locRib := []struct {
+		prefix string
+		path   *gobgpapi.Path
+	}{
+		{"10.0.1.0/24", path("10.0.1.0", "192.0.2.2", true, false)},  // exported
+		{"10.0.1.0/24", path("10.0.1.0", "192.0.2.3", false, false)}, // not best
+		{"10.0.2.0/24", path("10.0.2.0", "192.0.2.2", true, false)},
+		{"10.0.2.0/24", path("10.0.2.0", "192.0.2.2", true, false)}, // duplicate
+		{"10.0.3.0/24", path("10.0.3.0", "192.0.2.2", true, false)},
+		{"10.0.3.0/24", path("10.0.3.0", "192.0.2.3", false, false)}, // not best
+		{"10.0.4.0/24", path("10.0.4.0", "192.0.2.1", true, false)},  // own route
+		{"10.0.4.0/24", path("10.0.4.0", "192.0.2.3", false, false)}, // not best
+	}
+	for _, entry := range locRib {
+		routes.addLocRib(entry.prefix, entry.path, gobgp.parsePathIntoRoute)
+	}
+
+	expected := map[string]string{
+		"10.0.2.0/24": NOT_EXPORTED_REASON_POLICY,
+		"10.0.3.0/24": NOT_EXPORTED_REASON_OTHER,
+	}
+	if len(routes.routes) != len(expected) {
+		t.Fatal("Expected", len(expected), "not exported routes, got:", len(routes.routes))
+	}
+	for _, route := range routes.routes {
+		if route.Details["reason"] != expected[route.Network] {
+			t.Error("Unexpected reason for", route.Network, ":", route.Details["reason"])
+		}
+	}
+	if policies, ok := routes.routes[0].Details["export_policies"].([]string); !ok ||
+		policies[0] != "rs-export" {
+		t.Error("Expected export policies:", routes.routes[0].Details)
+	}
+}
@@ -1,10 +1,12 @@
 package gobgp
 
 import (
+	"github.com/alice-lg/alice-lg/backend/sources/bgputil"
 	"github.com/alice-lg/alice-lg/backend/sources/gobgp/apiutil"
 	"github.com/osrg/gobgp/pkg/packet/bgp"
 
 	"github.com/alice-lg/alice-lg/backend/api"
+	"github.com/alice-lg/alice-lg/backend/sources"
 	gobgpapi "github.com/osrg/gobgp/api"
 
 	"context"
@@ -14,15 +16,6 @@ import (
 	"time"
 )
 
-var families []gobgpapi.Family = []gobgpapi.Family{gobgpapi.Family{
-	Afi:  gobgpapi.Family_AFI_IP,
-	Safi: gobgpapi.Family_SAFI_UNICAST,
-}, gobgpapi.Family{
-	Afi:  gobgpapi.Family_AFI_IP6,
-	Safi: gobgpapi.Family_SAFI_UNICAST,
-},
-}
-
 func NewRoutesResponse() api.RoutesResponse {
 	routes := api.RoutesResponse{}
 	routes.Imported = make(api.Routes, 0)
@@ -31,9 +24,9 @@ func NewRoutesResponse() api.RoutesResponse {
 	return routes
 }
 
-func (gobgp *GoBGP) lookupNeighbour(neighborId string) (*gobgpapi.Peer, error) {
+func (gobgp *GoBGP) lookupNeighbour(ctx context.Context, neighborId string) (*gobgpapi.Peer, error) {
 
-	peers, err := gobgp.GetNeighbours()
+	peers, err := gobgp.GetNeighbours(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -44,26 +37,35 @@ func (gobgp *GoBGP) lookupNeighbour(neighborId string) (*gobgpapi.Peer, error) {
 		}
 	}
 
-	return nil, fmt.Errorf("Could not lookup neighbour")
+	return nil, &sources.NotFoundError{Resource: "neighbor", Id: neighborId}
 }
 
-func (gobgp *GoBGP) GetNeighbours() ([]*gobgpapi.Peer, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(gobgp.config.ProcessingTimeout))
-	defer cancel()
-
-	peerStream, err := gobgp.client.ListPeer(ctx, &gobgpapi.ListPeerRequest{EnableAdvertised: true})
-	if err != nil {
-		return nil, err
-	}
+func (gobgp *GoBGP) GetNeighbours(parent context.Context) ([]*gobgpapi.Peer, error) {
+	var peers []*gobgpapi.Peer
+	err := gobgp.request(parent, func(ctx context.Context, client gobgpapi.GobgpApiClient) error {
+		peerStream, err := client.ListPeer(ctx, &gobgpapi.ListPeerRequest{EnableAdvertised: true})
+		if err != nil {
+			return err
+		}
 
-	peers := make([]*gobgpapi.Peer, 0)
+		peers = make([]*gobgpapi.Peer, 0)
 
-	for {
-		peer, err := peerStream.Recv()
-		if err == io.EOF {
-			break
+		for {
+			peer, err := peerStream.Recv()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return err
+			}
+			if !gobgp.inView(peer.Peer) {
+				continue
+			}
+			peers = append(peers, peer.Peer)
 		}
-		peers = append(peers, peer.Peer)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return peers, nil
 }
@@ -125,11 +127,7 @@ func (gobgp *GoBGP) parsePathIntoRoute(path *gobgpapi.Path, prefix string) (erro
 
 		case *bgp.PathAttributeExtendedCommunities:
 			communities := attr.(*bgp.PathAttributeExtendedCommunities)
-			for _, community := range communities.Value {
-				if _community, ok := community.(*bgp.TwoOctetAsSpecificExtended); ok {
-					route.Bgp.ExtCommunities = append(route.Bgp.ExtCommunities, api.ExtCommunity{_community.AS, _community.LocalAdmin})
-				}
-			}
+			route.Bgp.ExtCommunities = append(route.Bgp.ExtCommunities, bgputil.DecodeExtCommunities(communities.Value)...)
 		case *bgp.PathAttributeLargeCommunities:
 			communities := attr.(*bgp.PathAttributeLargeCommunities)
 			for _, community := range communities.Values {
@@ -140,52 +138,67 @@ func (gobgp *GoBGP) parsePathIntoRoute(path *gobgpapi.Path, prefix string) (erro
 
 	route.Metric = (route.Bgp.LocalPref + route.Bgp.Med)
 
+	nlri, err := apiutil.GetNativeNlri(path)
+	if err != nil {
+		return err, nil
+	}
+	decodeNlri(&route, path.Family, nlri)
+
 	return nil, &route
 }
 
-func (gobgp *GoBGP) GetRoutes(peer *gobgpapi.Peer, tableType gobgpapi.TableType, response *api.RoutesResponse) error {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(gobgp.config.ProcessingTimeout))
-	defer cancel()
-
-	for _, family := range families {
-
-		pathStream, err := gobgp.client.ListPath(ctx, &gobgpapi.ListPathRequest{
-			Name:           peer.State.NeighborAddress,
-			TableType:      tableType,
-			Family:         &family,
-			EnableFiltered: true,
-		})
-
-		if err != nil {
-			log.Print(err)
-			continue
-		}
-
-		rib := make([]*gobgpapi.Destination, 0)
-		for {
-			_path, err := pathStream.Recv()
-			if err == io.EOF {
-				break
-			} else if err != nil {
+func (gobgp *GoBGP) GetRoutes(
+	parent context.Context,
+	peer *gobgpapi.Peer,
+	tableType gobgpapi.TableType,
+	response *api.RoutesResponse,
+) error {
+	var rib []*gobgpapi.Destination
+	err := gobgp.process(parent, func(ctx context.Context, client gobgpapi.GobgpApiClient) error {
+		rib = make([]*gobgpapi.Destination, 0)
+		for _, family := range gobgp.families {
+			family := family
+			pathStream, err := client.ListPath(ctx, &gobgpapi.ListPathRequest{
+				Name:           peer.State.NeighborAddress,
+				TableType:      tableType,
+				Family:         &family,
+				EnableFiltered: true,
+			})
+
+			if err != nil {
 				log.Print(err)
-				return err
+				continue
 			}
-			rib = append(rib, _path.Destination)
-		}
 
-		for _, destination := range rib {
-			for _, path := range destination.Paths {
-				err, route := gobgp.parsePathIntoRoute(path, destination.Prefix)
-				if err != nil {
-					log.Println(err)
-					continue
+			for {
+				_path, err := pathStream.Recv()
+				if err == io.EOF {
+					break
+				} else if err != nil {
+					log.Print(err)
+					return err
 				}
+				rib = append(rib, _path.Destination)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
 
-				if path.Filtered {
-					response.Filtered = append(response.Filtered, route)
-				} else {
-					response.Imported = append(response.Imported, route)
-				}
+	for _, destination := range rib {
+		for _, path := range destination.Paths {
+			err, route := gobgp.parsePathIntoRoute(path, destination.Prefix)
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+
+			if path.Filtered {
+				response.Filtered = append(response.Filtered, route)
+			} else {
+				response.Imported = append(response.Imported, route)
 			}
 		}
 	}
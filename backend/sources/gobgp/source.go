@@ -3,10 +3,8 @@ package gobgp
 import (
 	api "github.com/alice-lg/alice-lg/backend/api"
 	"github.com/alice-lg/alice-lg/backend/caches"
+	"github.com/alice-lg/alice-lg/backend/sources"
 	gobgpapi "github.com/osrg/gobgp/api"
-	"google.golang.org/grpc/credentials"
-
-	"google.golang.org/grpc"
 
 	"context"
 	"fmt"
@@ -15,9 +13,14 @@ import (
 	"time"
 )
 
+const (
+	DEFAULT_CACHE_TTL = 300
+)
+
 type GoBGP struct {
-	config Config
-	client gobgpapi.GobgpApiClient
+	config   Config
+	conns    *pool
+	families []gobgpapi.Family
 
 	// Caches: Neighbors
 	neighborsCache *caches.NeighborsCache
@@ -27,27 +30,18 @@ type GoBGP struct {
 	routesReceivedCache    *caches.RoutesCache
 	routesFilteredCache    *caches.RoutesCache
 	routesNotExportedCache *caches.RoutesCache
-}
-
-func NewGoBGP(config Config) *GoBGP {
 
-	dialOpts := make([]grpc.DialOption, 0)
-	if config.Insecure {
-		dialOpts = append(dialOpts, grpc.WithInsecure())
-	} else {
-		creds, err := credentials.NewClientTLSFromFile(config.TLSCert, config.TLSCommonName)
-		if err != nil {
-			log.Fatalf("could not load tls cert: %s", err)
-		}
-		dialOpts = append(dialOpts, grpc.WithTransportCredentials(creds))
-	}
+	// Mutices:
+	routesFetchMutex      *sources.LockMap
+	notExportedFetchMutex *sources.LockMap
 
-	conn, err := grpc.Dial(config.Host, dialOpts...)
-	if err != nil {
-		log.Fatalf("did not connect: %v", err)
-	}
+	// State from the monitoring streams, if enabled
+	state *watchState
+}
 
-	client := gobgpapi.NewGobgpApiClient(conn)
+func NewGoBGP(config Config) *GoBGP {
+	// The families are validated when loading the config
+	families, _ := ParseFamilies(config.Families)
 
 	// Cache settings:
 	// TODO: Maybe read from config file
@@ -67,9 +61,10 @@ func NewGoBGP(config Config) *GoBGP {
 	routesNotExportedCache := caches.NewRoutesCache(
 		routesCacheDisabled, routesCacheMaxSize)
 
-	return &GoBGP{
-		config: config,
-		client: client,
+	gobgp := &GoBGP{
+		config:   config,
+		conns:    newPool(config),
+		families: families,
 
 		neighborsCache: neighborsCache,
 
@@ -77,113 +72,197 @@ func NewGoBGP(config Config) *GoBGP {
 		routesReceivedCache:    routesReceivedCache,
 		routesFilteredCache:    routesFilteredCache,
 		routesNotExportedCache: routesNotExportedCache,
+
+		routesFetchMutex:      sources.NewLockMap(),
+		notExportedFetchMutex: sources.NewLockMap(),
+	}
+
+	if config.WatchEvents {
+		gobgp.state = newWatchState(len(families))
+		gobgp.watch()
+	}
+
+	return gobgp
+}
+
+// Make api status for responses. The ttl
+// is derived from the configured cache ttl.
+func (gobgp *GoBGP) makeApiStatus() api.ApiStatus {
+	now := time.Now().UTC()
+	return api.ApiStatus{
+		Version:         "gobgp",
+		ResultFromCache: false,
+		Ttl:             now.Add(time.Duration(gobgp.config.CacheTtl) * time.Second),
+		CacheStatus: api.CacheStatus{
+			CachedAt: now,
+			OrigTtl:  gobgp.config.CacheTtl,
+		},
 	}
 }
 
+// Check if the state from the monitoring streams can be used
+func (gobgp *GoBGP) watching() bool {
+	return gobgp.state != nil && gobgp.state.ready()
+}
+
 func (gobgp *GoBGP) ExpireCaches() int {
 	count := gobgp.routesRequiredCache.Expire()
+	count += gobgp.routesReceivedCache.Expire()
+	count += gobgp.routesFilteredCache.Expire()
 	count += gobgp.routesNotExportedCache.Expire()
 
 	return count
 }
 
-func (gobgp *GoBGP) NeighboursStatus() (*api.NeighboursStatusResponse, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(gobgp.config.ProcessingTimeout))
-	defer cancel()
+// Lookups are answered by the daemon, if configured
+func (gobgp *GoBGP) Capabilities() api.SourceCapabilities {
+	return api.SourceCapabilities{
+		RoutesReceived:    true,
+		RoutesFiltered:    true,
+		RoutesNotExported: true,
+		Status:            true,
+		LiveLookup:        gobgp.config.LiveLookup,
+		NeighboursStatus:  true,
+	}
+}
 
+func (gobgp *GoBGP) NeighboursStatusContext(ctx context.Context) (*api.NeighboursStatusResponse, error) {
 	response := api.NeighboursStatusResponse{}
+	response.Api = gobgp.makeApiStatus()
 	response.Neighbours = make(api.NeighboursStatus, 0)
 
-	resp, err := gobgp.client.ListPeer(ctx, &gobgpapi.ListPeerRequest{})
-	if err != nil {
-		return nil, err
-	}
-	for {
-		_resp, err := resp.Recv()
-		if err == io.EOF {
-			break
-		}
-
-		ns := api.NeighbourStatus{}
-		ns.Id = PeerHash(_resp.Peer)
-
-		switch _resp.Peer.State.SessionState {
-		case gobgpapi.PeerState_ESTABLISHED:
-			ns.State = "up"
-		default:
-			ns.State = "down"
-		}
-
-		if _resp.Peer.Timers.State.Uptime != nil {
-			ns.Since = time.Now().Sub(time.Unix(_resp.Peer.Timers.State.Uptime.Seconds, int64(_resp.Peer.Timers.State.Uptime.Nanos)))
+	// The status is not cached
+	var neighbours api.Neighbours
+	if gobgp.watching() {
+		neighbours = gobgp.state.neighbours(gobgp.parseNeighbour)
+	} else {
+		_neighbours, err := gobgp.fetchNeighbours(ctx)
+		if err != nil {
+			return nil, err
 		}
+		neighbours = _neighbours
+	}
 
+	for _, neigh := range neighbours {
+		response.Neighbours = append(response.Neighbours, &api.NeighbourStatus{
+			Id:    neigh.Id,
+			State: neigh.State,
+			Since: neigh.Uptime,
+		})
 	}
 	return &response, nil
 }
 
-func (gobgp *GoBGP) Status() (*api.StatusResponse, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(gobgp.config.ProcessingTimeout))
-	defer cancel()
-
-	resp, err := gobgp.client.GetBgp(ctx, &gobgpapi.GetBgpRequest{})
+func (gobgp *GoBGP) StatusContext(parent context.Context) (*api.StatusResponse, error) {
+	if err := gobgp.conns.check(); err != nil {
+		return nil, err
+	}
+	var resp *gobgpapi.GetBgpResponse
+	err := gobgp.request(parent, func(ctx context.Context, client gobgpapi.GobgpApiClient) error {
+		var err error
+		resp, err = client.GetBgp(ctx, &gobgpapi.GetBgpRequest{})
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	response := api.StatusResponse{}
+	response.Api = gobgp.makeApiStatus()
 	response.Status.RouterId = resp.Global.RouterId
 	response.Status.Backend = "gobgp"
+	response.Status.Endpoint = gobgp.conns.active()
 	return &response, nil
 }
 
-func (gobgp *GoBGP) Neighbours() (*api.NeighboursResponse, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(gobgp.config.ProcessingTimeout))
-	defer cancel()
-
+func (gobgp *GoBGP) NeighboursContext(ctx context.Context) (*api.NeighboursResponse, error) {
 	response := api.NeighboursResponse{}
-	response.Neighbours = make(api.Neighbours, 0)
+	response.Api = gobgp.makeApiStatus()
+	if gobgp.watching() {
+		// The state of the monitoring streams is always current
+		response.Neighbours = gobgp.state.neighbours(gobgp.parseNeighbour)
+		return &response, nil
+	}
 
-	resp, err := gobgp.client.ListPeer(ctx, &gobgpapi.ListPeerRequest{EnableAdvertised: true})
+	// Check if we hit the cache
+	if cached := gobgp.neighborsCache.Get(); cached != nil {
+		return cached, nil
+	}
+
+	neighbours, err := gobgp.fetchNeighbours(ctx)
 	if err != nil {
 		return nil, err
 	}
-	for {
-		_resp, err := resp.Recv()
-		if err == io.EOF {
-			break
-		}
+	response.Neighbours = neighbours
+
+	// Cache result
+	gobgp.neighborsCache.Set(&response)
 
-		neigh := api.Neighbour{}
+	return &response, nil
+}
 
-		neigh.Address = _resp.Peer.State.NeighborAddress
-		neigh.Asn = int(_resp.Peer.State.PeerAs)
-		switch _resp.Peer.State.SessionState {
-		case gobgpapi.PeerState_ESTABLISHED:
-			neigh.State = "up"
-		default:
-			neigh.State = "down"
+// Get the neighbors from the daemon
+func (gobgp *GoBGP) fetchNeighbours(parent context.Context) (api.Neighbours, error) {
+	neighbours := make(api.Neighbours, 0)
+	err := gobgp.request(parent, func(ctx context.Context, client gobgpapi.GobgpApiClient) error {
+		neighbours = neighbours[:0]
+		resp, err := client.ListPeer(ctx, &gobgpapi.ListPeerRequest{EnableAdvertised: true})
+		if err != nil {
+			return err
+		}
+		for {
+			_resp, err := resp.Recv()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return err
+			}
+
+			neighbours = append(neighbours, gobgp.parseNeighbour(_resp.Peer))
 		}
-		neigh.Description = _resp.Peer.Conf.Description
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		neigh.Id = PeerHash(_resp.Peer)
-		neigh.RouteServerId = gobgp.config.Id
+	return neighbours, nil
+}
 
-		response.Neighbours = append(response.Neighbours, &neigh)
-		for _, afiSafi := range _resp.Peer.AfiSafis {
-			neigh.RoutesReceived += int(afiSafi.State.Received)
-			neigh.RoutesExported += int(afiSafi.State.Advertised)
-			neigh.RoutesAccepted += int(afiSafi.State.Accepted)
-			neigh.RoutesFiltered += (neigh.RoutesReceived - neigh.RoutesAccepted)
-		}
+func (gobgp *GoBGP) parseNeighbour(peer *gobgpapi.Peer) *api.Neighbour {
+	neigh := api.Neighbour{}
 
-		if _resp.Peer.Timers.State.Uptime != nil {
-			neigh.Uptime = time.Now().Sub(time.Unix(_resp.Peer.Timers.State.Uptime.Seconds, int64(_resp.Peer.Timers.State.Uptime.Nanos)))
-		}
+	neigh.Address = peer.State.NeighborAddress
+	neigh.Asn = int(peer.State.PeerAs)
+	switch peer.State.SessionState {
+	case gobgpapi.PeerState_ESTABLISHED:
+		neigh.State = "up"
+	default:
+		neigh.State = "down"
+	}
+	if peer.Conf != nil {
+		neigh.Description = peer.Conf.Description
+	}
 
+	neigh.Id = PeerHash(peer)
+	neigh.RouteServerId = gobgp.config.Id
+	neigh.Details = decodeNeighbourDetails(peer)
+	if lastError, ok := neigh.Details["last_error"].(string); ok {
+		neigh.LastError = lastError
 	}
 
-	return &response, nil
+	for _, afiSafi := range peer.AfiSafis {
+		neigh.RoutesReceived += int(afiSafi.State.Received)
+		neigh.RoutesExported += int(afiSafi.State.Advertised)
+		neigh.RoutesAccepted += int(afiSafi.State.Accepted)
+	}
+	neigh.RoutesFiltered = neigh.RoutesReceived - neigh.RoutesAccepted
+
+	if peer.Timers != nil && peer.Timers.State != nil && peer.Timers.State.Uptime != nil {
+		neigh.Uptime = time.Now().Sub(time.Unix(peer.Timers.State.Uptime.Seconds, int64(peer.Timers.State.Uptime.Nanos)))
+	}
+
+	return &neigh
 }
 
 // Get neighbors from neighbors summary
@@ -198,18 +277,8 @@ func (gobgp *GoBGP) bgpProtocolsNeighbors() (*api.NeighboursResponse, error) {
 }
 
 // Get filtered and exported routes
-func (gobgp *GoBGP) Routes(neighbourId string) (*api.RoutesResponse, error) {
-	neigh, err := gobgp.lookupNeighbour(neighbourId)
-	if err != nil {
-		return nil, err
-	}
-
-	routes := NewRoutesResponse()
-	err = gobgp.GetRoutes(neigh, gobgpapi.TableType_ADJ_IN, &routes)
-	if err != nil {
-		return nil, err
-	}
-	return &routes, nil
+func (gobgp *GoBGP) RoutesContext(ctx context.Context, neighbourId string) (*api.RoutesResponse, error) {
+	return gobgp.fetchRequiredRoutes(ctx, neighbourId)
 }
 
 /*
@@ -218,90 +287,115 @@ RoutesRequired is a specialized request to fetch:
  - RoutesExported and
  - RoutesFiltered
 
-from Birdwatcher. As the not exported routes can be very many
-these are optional and can be loaded on demand using the
-RoutesNotExported() API.
+from the Adj-RIB-In of the neighbour. As the not exported
+routes can be very many these are optional and can be loaded
+on demand using the RoutesNotExported() API.
 
-A route deduplication is applied.
+The response is cached, only one request per neighbour
+is sent to the GoBGP daemon at a time.
 */
+func (gobgp *GoBGP) fetchRequiredRoutes(ctx context.Context, neighbourId string) (*api.RoutesResponse, error) {
+	// Allow only one concurrent request for this neighbor
+	// to the GoBGP daemon.
+	gobgp.routesFetchMutex.Lock(neighbourId)
+	defer gobgp.routesFetchMutex.Unlock(neighbourId)
+
+	// Check if we have a cache hit
+	response := gobgp.routesRequiredCache.Get(neighbourId)
+	if response != nil {
+		return response, nil
+	}
 
-func (gobgp *GoBGP) getRoutes(neighbourId string) (*api.RoutesResponse, error) {
-	neigh, err := gobgp.lookupNeighbour(neighbourId)
+	neigh, err := gobgp.lookupNeighbour(ctx, neighbourId)
 	if err != nil {
 		return nil, err
 	}
 
 	routes := NewRoutesResponse()
-	err = gobgp.GetRoutes(neigh, gobgpapi.TableType_ADJ_IN, &routes)
+	err = gobgp.GetRoutes(ctx, neigh, gobgpapi.TableType_ADJ_IN, &routes)
 	if err != nil {
 		return nil, err
 	}
-	return &routes, nil
+	routes.Api = gobgp.makeApiStatus()
+	response = &routes
+
+	// Cache result
+	gobgp.routesRequiredCache.Set(neighbourId, response)
+
+	return response, nil
 }
 
 func (gobgp *GoBGP) RoutesRequired(neighbourId string) (*api.RoutesResponse, error) {
-	return gobgp.getRoutes(neighbourId)
+	return gobgp.fetchRequiredRoutes(context.Background(), neighbourId)
 }
 
 // Get all received routes
-func (gobgp *GoBGP) RoutesReceived(neighbourId string) (*api.RoutesResponse, error) {
-	neigh, err := gobgp.lookupNeighbour(neighbourId)
-	if err != nil {
-		return nil, err
+func (gobgp *GoBGP) RoutesReceivedContext(ctx context.Context, neighbourId string) (*api.RoutesResponse, error) {
+	// Check if we have a cache hit
+	response := gobgp.routesReceivedCache.Get(neighbourId)
+	if response != nil {
+		return response, nil
 	}
 
-	routes := NewRoutesResponse()
-	err = gobgp.GetRoutes(neigh, gobgpapi.TableType_ADJ_IN, &routes)
+	routes, err := gobgp.fetchRequiredRoutes(ctx, neighbourId)
 	if err != nil {
 		return nil, err
 	}
-	routes.Filtered = nil
-	return &routes, nil
+	response = &api.RoutesResponse{
+		Api:      routes.Api,
+		Imported: routes.Imported,
+	}
+
+	// Cache result
+	gobgp.routesReceivedCache.Set(neighbourId, response)
+
+	return response, nil
 }
 
 // Get all filtered routes
-func (gobgp *GoBGP) RoutesFiltered(neighbourId string) (*api.RoutesResponse, error) {
-	routes, err := gobgp.getRoutes(neighbourId)
-	if err != nil {
-		log.Print(err)
+func (gobgp *GoBGP) RoutesFilteredContext(ctx context.Context, neighbourId string) (*api.RoutesResponse, error) {
+	// Check if we have a cache hit
+	response := gobgp.routesFilteredCache.Get(neighbourId)
+	if response != nil {
+		return response, nil
 	}
-	routes.Imported = nil
-	return routes, err
-}
 
-// Get all not exported routes
-func (gobgp *GoBGP) RoutesNotExported(neighbourId string) (*api.RoutesResponse, error) {
-	neigh, err := gobgp.lookupNeighbour(neighbourId)
+	routes, err := gobgp.fetchRequiredRoutes(ctx, neighbourId)
 	if err != nil {
 		return nil, err
 	}
-	routes := NewRoutesResponse()
-	err = gobgp.GetRoutes(neigh, gobgpapi.TableType_ADJ_OUT, &routes)
-	if err != nil {
-		return nil, err
+	response = &api.RoutesResponse{
+		Api:      routes.Api,
+		Filtered: routes.Filtered,
 	}
-	routes.NotExported = routes.Filtered
-	return &routes, nil
-}
 
-// Make routes lookup
-func (gobgp *GoBGP) LookupPrefix(prefix string) (*api.RoutesLookupResponse, error) {
-	return nil, fmt.Errorf("Not implemented LookupPrefix")
+	// Cache result
+	gobgp.routesFilteredCache.Set(neighbourId, response)
+
+	return response, nil
 }
 
 /*
 AllRoutes:
 	Here a routes dump (filtered, received) is returned, which is used to learn all prefixes to build up a local store for searching.
 */
-func (gobgp *GoBGP) AllRoutes() (*api.RoutesResponse, error) {
+func (gobgp *GoBGP) AllRoutesContext(ctx context.Context) (*api.RoutesResponse, error) {
 	routes := NewRoutesResponse()
-	peers, err := gobgp.GetNeighbours()
+	if gobgp.watching() {
+		gobgp.state.routes(&routes)
+		return &routes, nil
+	}
+
+	peers, err := gobgp.GetNeighbours(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	for _, peer := range peers {
-		err = gobgp.GetRoutes(peer, gobgpapi.TableType_ADJ_IN, &routes)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		err = gobgp.GetRoutes(ctx, peer, gobgpapi.TableType_ADJ_IN, &routes)
 		if err != nil {
 			log.Print(err)
 		}
@@ -0,0 +1,122 @@
+package gobgp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alice-lg/alice-lg/backend/api"
+)
+
+func TestRoutesCaching(t *testing.T) {
+	gobgp := NewGoBGP(Config{
+		Id:       "rs1",
+		Host:     "127.0.0.1:1",
+		Insecure: true,
+		CacheTtl: 300,
+	})
+
+	// The daemon is not reachable, responses
+	// can only be served from the cache.
+	required := NewRoutesResponse()
+	required.Api = gobgp.makeApiStatus()
+	required.Imported = api.Routes{&api.Route{Id: "imported"}}
+	required.Filtered = api.Routes{&api.Route{Id: "filtered"}}
+	gobgp.routesRequiredCache.Set("neigh1", &required)
+
+	if required.Api.Ttl.Sub(time.Now()) < 299*time.Second {
+		t.Error("Unexpected ttl:", required.Api.Ttl)
+	}
+
+	received, err := gobgp.RoutesReceived("neigh1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(received.Imported) != 1 || len(received.Filtered) != 0 {
+		t.Error("Unexpected received routes:", received)
+	}
+
+	filtered, err := gobgp.RoutesFiltered("neigh1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filtered.Filtered) != 1 || len(filtered.Imported) != 0 {
+		t.Error("Unexpected filtered routes:", filtered)
+	}
+	if gobgp.routesFilteredCache.Get("neigh1") != filtered {
+		t.Error("Expected the filtered routes to be cached")
+	}
+
+	// The required routes are not modified
+	if len(required.Imported) != 1 || len(required.Filtered) != 1 {
+		t.Error("Unexpected required routes:", required)
+	}
+
+	// Expire all
+	expired := NewRoutesResponse()
+	gobgp.routesNotExportedCache.Set("neigh1", &expired)
+	required.Api.Ttl = time.Now().Add(-time.Second)
+	received.Api.Ttl = required.Api.Ttl
+	filtered.Api.Ttl = required.Api.Ttl
+	if count := gobgp.ExpireCaches(); count != 4 {
+		t.Error("Expected 4 expired responses, got:", count)
+	}
+}
+
+func TestNeighboursCaching(t *testing.T) {
+	gobgp := NewGoBGP(Config{
+		Id:       "rs1",
+		Host:     "127.0.0.1:1",
+		Insecure: true,
+		CacheTtl: 300,
+	})
+
+	// The daemon is not reachable, the neighbors
+	// can only be served from the cache.
+	cached := &api.NeighboursResponse{
+		Api:        gobgp.makeApiStatus(),
+		Neighbours: api.Neighbours{&api.Neighbour{Id: "neigh1"}},
+	}
+	gobgp.neighborsCache.Set(cached)
+
+	neighbours, err := gobgp.Neighbours()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if neighbours != cached {
+		t.Error("Expected the neighbors to be served from the cache")
+	}
+
+	// The status of the neighbors is always requested
+	if _, err := gobgp.NeighboursStatus(); err == nil {
+		t.Error("Expected the neighbors status not to be cached")
+	}
+
+	cached.Api.Ttl = time.Now().Add(-time.Second)
+	if _, err := gobgp.Neighbours(); err == nil {
+		t.Error("Expected the neighbors cache to be expired")
+	}
+}
+
+func TestStatusTtl(t *testing.T) {
+	addr, stop := serveTestDaemon(t, "192.0.2.1")
+	defer stop()
+
+	gobgp := NewGoBGP(Config{
+		Id:             "rs1",
+		Host:           addr,
+		Insecure:       true,
+		CacheTtl:       300,
+		RequestTimeout: 1,
+	})
+
+	status, err := gobgp.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Api.Ttl.Sub(time.Now()) < 299*time.Second {
+		t.Error("Unexpected ttl:", status.Api.Ttl)
+	}
+	if status.Api.CacheStatus.OrigTtl != 300 {
+		t.Error("Unexpected cache status:", status.Api.CacheStatus)
+	}
+}
@@ -17,7 +17,9 @@ func PeerHash(peer *api.Peer) string {
 
 func PeerHashWithASAndAddress(asn uint32, address string) string {
 	h := sha1.New()
-	io.WriteString(h, string(asn))
+	// The asn is hashed as a single rune, which
+	// keeps neighbor ids stable across versions.
+	io.WriteString(h, string(rune(asn)))
 	io.WriteString(h, address)
 	sum := h.Sum(nil)
 	return fmt.Sprintf("%x", sum[0:5])
@@ -0,0 +1,90 @@
+package gobgp
+
+import (
+	"github.com/alice-lg/alice-lg/backend/sources"
+
+	gobgpapi "github.com/osrg/gobgp/api"
+
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+/*
+VRFs of the GoBGP daemon are views of a source:
+A source with a configured VRF only sees the peers
+in the VRF and looks up prefixes in the VRF table.
+When VRFs are configured, the global view only
+sees the peers not in any VRF.
+*/
+
+// Get the names of the VRFs of the first
+// reachable GoBGP daemon
+func DiscoverVrfs(config Config) ([]string, error) {
+	err := fmt.Errorf("no host configured")
+	for _, host := range sources.ParseEndpoints(config.Host) {
+		var names []string
+		names, err = discoverVrfs(config, host)
+		if err == nil {
+			return names, nil
+		}
+	}
+	return nil, err
+}
+
+// Get the names of the VRFs of a GoBGP daemon
+func discoverVrfs(config Config, host string) ([]string, error) {
+	conn, err := dial(config, host)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(config.RequestTimeout))
+	defer cancel()
+
+	client := gobgpapi.NewGobgpApiClient(conn)
+	vrfStream, err := client.ListVrf(ctx, &gobgpapi.ListVrfRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for {
+		resp, err := vrfStream.Recv()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		names = append(names, resp.Vrf.Name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// Check if a peer is seen by the source
+func (gobgp *GoBGP) inView(peer *gobgpapi.Peer) bool {
+	vrf := ""
+	if peer.Conf != nil {
+		vrf = peer.Conf.Vrf
+	}
+	if gobgp.config.Vrf != "" {
+		return vrf == gobgp.config.Vrf
+	}
+	if len(gobgp.config.Vrfs) > 0 {
+		return vrf == ""
+	}
+	return true
+}
+
+// Get the table for prefix lookups
+func (gobgp *GoBGP) lookupTable() (gobgpapi.TableType, string) {
+	if gobgp.config.Vrf != "" {
+		return gobgpapi.TableType_VRF, gobgp.config.Vrf
+	}
+	return gobgpapi.TableType_GLOBAL, ""
+}
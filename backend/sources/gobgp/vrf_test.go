@@ -0,0 +1,31 @@
+package gobgp
+
+import (
+	"testing"
+
+	gobgpapi "github.com/osrg/gobgp/api"
+)
+
+func TestInView(t *testing.T) {
+	global := testPeer("192.0.2.1", gobgpapi.PeerState_ESTABLISHED)
+	red := testPeer("192.0.2.2", gobgpapi.PeerState_ESTABLISHED)
+	red.Conf.Vrf = "red"
+
+	gobgp := &GoBGP{config: Config{}}
+	if !gobgp.inView(global) || !gobgp.inView(red) {
+		t.Error("Expected all peers without vrfs configured")
+	}
+
+	gobgp = &GoBGP{config: Config{Vrfs: []string{"red"}}}
+	if !gobgp.inView(global) || gobgp.inView(red) {
+		t.Error("Expected only peers without vrf in the global view")
+	}
+
+	gobgp = &GoBGP{config: Config{Vrfs: []string{"red"}, Vrf: "red"}}
+	if gobgp.inView(global) || !gobgp.inView(red) {
+		t.Error("Expected only peers in the vrf")
+	}
+	if table, name := gobgp.lookupTable(); table != gobgpapi.TableType_VRF || name != "red" {
+		t.Error("Expected lookups in the vrf table, got:", table, name)
+	}
+}
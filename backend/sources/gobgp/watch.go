@@ -0,0 +1,696 @@
+package gobgp
+
+import (
+	"github.com/alice-lg/alice-lg/backend/api"
+	"github.com/alice-lg/alice-lg/backend/sources/gobgp/apiutil"
+	gobgpapi "github.com/osrg/gobgp/api"
+	"github.com/osrg/gobgp/pkg/packet/bgp"
+
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+/*
+Watching the GoBGP daemon:
+
+The peer states and the Adj-RIB-In of all peers are kept in
+memory and are updated from the MonitorPeer and MonitorTable
+streams. (The GoBGP API used here has no WatchEvent call,
+these are the streams it is built upon.)
+
+The paths of the table streams are applied to the ribs as they
+are received. The streams carry the pre-policy updates, but not
+the result of the import policy for route server clients, so
+the updated prefixes are looked up with their filtered state.
+The Adj-RIB-In of a peer is only listed when the session is
+established, after resubscribing or for families without
+prefix lookups, like FlowSpec.
+*/
+
+const (
+	WATCH_BACKOFF_MIN      = 1 * time.Second
+	WATCH_BACKOFF_MAX      = 60 * time.Second
+	WATCH_REFRESH_INTERVAL = 5 * time.Second
+)
+
+// A ribEntry is a route received from a peer
+type ribEntry struct {
+	route    *api.Route
+	filtered bool
+	received time.Time
+}
+
+// The paths of a prefix, keyed by path identifier
+type ribPaths map[uint32]*ribEntry
+
+// A rib holds the routes of a peer, keyed by prefix
+type rib map[string]ribPaths
+
+/*
+watchState is the in memory state of the daemon.
+Peers and ribs are keyed by the neighbor address.
+*/
+type watchState struct {
+	sync.RWMutex
+
+	peers map[string]*gobgpapi.Peer
+	ribs  map[string]rib
+
+	// Peers with ribs to be listed, and the updated
+	// prefixes of peers to be looked up.
+	dirty   map[string]bool
+	pending map[string]map[string]*gobgpapi.Family
+
+	// The state is in sync when the peers were listed,
+	// all table streams are subscribed and the ribs
+	// were listed since.
+	peersReady bool
+	streams    int
+	wanted     int
+	resync     int
+	synced     int
+}
+
+func newWatchState(streams int) *watchState {
+	return &watchState{
+		wanted: streams,
+
+		peers:   make(map[string]*gobgpapi.Peer),
+		ribs:    make(map[string]rib),
+		dirty:   make(map[string]bool),
+		pending: make(map[string]map[string]*gobgpapi.Family),
+	}
+}
+
+func isEstablished(peer *gobgpapi.Peer) bool {
+	return peer.State != nil &&
+		peer.State.SessionState == gobgpapi.PeerState_ESTABLISHED
+}
+
+// GoBGP only supports prefix lookups for unicast tables
+func canLookup(family *gobgpapi.Family) bool {
+	rf := apiutil.ToRouteFamily(family)
+	return rf == bgp.RF_IPv4_UC || rf == bgp.RF_IPv6_UC
+}
+
+// Check if the state can be used instead of polling
+func (self *watchState) ready() bool {
+	self.RLock()
+	defer self.RUnlock()
+	return self.peersReady &&
+		self.streams == self.wanted &&
+		self.synced == self.resync
+}
+
+// Request listing the ribs of all established peers
+func (self *watchState) requestResync() {
+	self.Lock()
+	defer self.Unlock()
+	self.resync++
+	for address, peer := range self.peers {
+		if isEstablished(peer) {
+			self.dirty[address] = true
+		}
+	}
+}
+
+// Replace all peers, e.g. after resubscribing
+func (self *watchState) setPeers(peers []*gobgpapi.Peer) {
+	self.Lock()
+	known := make(map[string]bool, len(peers))
+	for _, peer := range peers {
+		known[peer.State.NeighborAddress] = true
+	}
+	for address := range self.peers {
+		if !known[address] {
+			self.removePeer(address)
+		}
+	}
+	for _, peer := range peers {
+		self.updatePeer(peer)
+	}
+	self.peersReady = true
+	self.Unlock()
+
+	self.requestResync()
+}
+
+// Update a peer. The routes of a peer are dropped when
+// the session goes down. Must be called with the lock held.
+func (self *watchState) updatePeer(peer *gobgpapi.Peer) {
+	address := peer.State.NeighborAddress
+	prev, ok := self.peers[address]
+	self.peers[address] = peer
+	if !isEstablished(peer) {
+		delete(self.ribs, address)
+		delete(self.dirty, address)
+		delete(self.pending, address)
+		return
+	}
+	if !ok || !isEstablished(prev) {
+		self.dirty[address] = true
+	}
+}
+
+// Remove a peer. Must be called with the lock held.
+func (self *watchState) removePeer(address string) {
+	delete(self.peers, address)
+	delete(self.ribs, address)
+	delete(self.dirty, address)
+	delete(self.pending, address)
+}
+
+// Apply an update from the pre-policy table stream
+func (self *watchState) applyPath(
+	address string,
+	family *gobgpapi.Family,
+	prefix string,
+	id uint32,
+	route *api.Route,
+	withdraw bool,
+) {
+	self.Lock()
+	defer self.Unlock()
+
+	peer, ok := self.peers[address]
+	if !ok || !isEstablished(peer) {
+		return
+	}
+
+	routes, ok := self.ribs[address]
+	if !ok {
+		routes = make(rib)
+		self.ribs[address] = routes
+	}
+
+	// The import policy result is looked up, which also
+	// corrects a listing of the rib racing with the update.
+	if canLookup(family) {
+		prefixes, ok := self.pending[address]
+		if !ok {
+			prefixes = make(map[string]*gobgpapi.Family)
+			self.pending[address] = prefixes
+		}
+		prefixes[prefix] = family
+	} else {
+		self.dirty[address] = true
+	}
+
+	paths := routes[prefix]
+	if withdraw {
+		delete(paths, id)
+		if len(paths) == 0 {
+			delete(routes, prefix)
+		}
+		return
+	}
+	if paths == nil {
+		paths = make(ribPaths)
+		routes[prefix] = paths
+	}
+
+	// Keep the previous import policy result
+	// until the prefix was looked up.
+	entry := &ribEntry{
+		route:    route,
+		received: time.Now().Add(-route.Age),
+	}
+	if prev, ok := paths[id]; ok {
+		entry.filtered = prev.filtered
+	}
+	paths[id] = entry
+}
+
+// Replace the rib of a peer with a listing
+func (self *watchState) setRib(address string, routes rib) {
+	self.Lock()
+	defer self.Unlock()
+	if peer, ok := self.peers[address]; !ok || !isEstablished(peer) {
+		return
+	}
+	self.ribs[address] = routes
+}
+
+// Replace the paths of looked up prefixes. Prefixes
+// without paths in the lookup were withdrawn.
+func (self *watchState) setPrefixes(address string, prefixes []string, lookup rib) {
+	self.Lock()
+	defer self.Unlock()
+	if peer, ok := self.peers[address]; !ok || !isEstablished(peer) {
+		return
+	}
+	routes, ok := self.ribs[address]
+	if !ok {
+		routes = make(rib)
+		self.ribs[address] = routes
+	}
+	for _, prefix := range prefixes {
+		if paths, ok := lookup[prefix]; ok {
+			routes[prefix] = paths
+		} else {
+			delete(routes, prefix)
+		}
+	}
+}
+
+// Take the dirty peers for listing their ribs
+// and the pending prefixes of the other peers.
+func (self *watchState) takeDirty() (
+	[]*gobgpapi.Peer,
+	map[string]map[string]*gobgpapi.Family,
+	int,
+) {
+	self.Lock()
+	defer self.Unlock()
+	peers := make([]*gobgpapi.Peer, 0, len(self.dirty))
+	for address := range self.dirty {
+		if peer, ok := self.peers[address]; ok {
+			peers = append(peers, peer)
+		}
+		// Listing the rib covers the pending prefixes
+		delete(self.pending, address)
+	}
+	pending := self.pending
+	self.dirty = make(map[string]bool)
+	self.pending = make(map[string]map[string]*gobgpapi.Family)
+	return peers, pending, self.resync
+}
+
+// Mark the listed peers as synced, or dirty again
+// when listing a rib failed. Prefixes of failed lookups
+// are pending again.
+func (self *watchState) doneDirty(
+	failed []string,
+	failedPrefixes map[string]map[string]*gobgpapi.Family,
+	resync int,
+) {
+	self.Lock()
+	defer self.Unlock()
+	for _, address := range failed {
+		if _, ok := self.peers[address]; ok {
+			self.dirty[address] = true
+		}
+	}
+	for address, prefixes := range failedPrefixes {
+		if _, ok := self.peers[address]; !ok {
+			continue
+		}
+		pending, ok := self.pending[address]
+		if !ok {
+			pending = make(map[string]*gobgpapi.Family)
+			self.pending[address] = pending
+		}
+		for prefix, family := range prefixes {
+			pending[prefix] = family
+		}
+	}
+	if len(failed) == 0 && resync > self.synced {
+		self.synced = resync
+	}
+}
+
+func (self *watchState) setStream(up bool) {
+	self.Lock()
+	defer self.Unlock()
+	if up {
+		self.streams++
+	} else {
+		self.streams--
+	}
+}
+
+func (self *watchState) setPeersReady(ready bool) {
+	self.Lock()
+	defer self.Unlock()
+	self.peersReady = ready
+}
+
+// Get the neighbours with route counts from the ribs
+func (self *watchState) neighbours(parse func(*gobgpapi.Peer) *api.Neighbour) api.Neighbours {
+	self.RLock()
+	defer self.RUnlock()
+
+	neighbours := make(api.Neighbours, 0, len(self.peers))
+	for address, peer := range self.peers {
+		neighbour := parse(peer)
+		if routes, ok := self.ribs[address]; ok {
+			neighbour.RoutesReceived = 0
+			neighbour.RoutesFiltered = 0
+			for _, paths := range routes {
+				neighbour.RoutesReceived += len(paths)
+				for _, entry := range paths {
+					if entry.filtered {
+						neighbour.RoutesFiltered++
+					}
+				}
+			}
+			neighbour.RoutesAccepted = neighbour.RoutesReceived - neighbour.RoutesFiltered
+		}
+		neighbours = append(neighbours, neighbour)
+	}
+	return neighbours
+}
+
+// Get the accepted and filtered routes of all peers
+func (self *watchState) routes(response *api.RoutesResponse) {
+	self.RLock()
+	defer self.RUnlock()
+
+	now := time.Now()
+	for _, routes := range self.ribs {
+		for _, paths := range routes {
+			for _, entry := range paths {
+				route := *entry.route
+				route.Age = now.Sub(entry.received)
+				if entry.filtered {
+					response.Filtered = append(response.Filtered, &route)
+				} else {
+					response.Imported = append(response.Imported, &route)
+				}
+			}
+		}
+	}
+}
+
+// Start watching the daemon
+func (gobgp *GoBGP) watch() {
+	go gobgp.resubscribe("peers", gobgp.watchPeers)
+	for _, family := range gobgp.families {
+		family := family
+		name := fmt.Sprintf("table %s", familyName(&family))
+		go gobgp.resubscribe(name, func() error {
+			return gobgp.watchTable(&family)
+		})
+	}
+	go gobgp.refreshRibs()
+}
+
+// Keep a stream subscribed, retrying with backoff
+func (gobgp *GoBGP) resubscribe(name string, subscribe func() error) {
+	backoff := WATCH_BACKOFF_MIN
+	for {
+		start := time.Now()
+		err := subscribe()
+		log.Println("GoBGP", gobgp.config.Id, name, "stream closed:", err)
+
+		if time.Since(start) > WATCH_BACKOFF_MAX {
+			backoff = WATCH_BACKOFF_MIN
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > WATCH_BACKOFF_MAX {
+			backoff = WATCH_BACKOFF_MAX
+		}
+	}
+}
+
+// Subscribe to peer state changes
+func (gobgp *GoBGP) watchPeers() error {
+	client, err := gobgp.client()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.MonitorPeer(ctx, &gobgpapi.MonitorPeerRequest{})
+	if err != nil {
+		return err
+	}
+	defer gobgp.state.setPeersReady(false)
+
+	// Changes after subscribing are received from the
+	// stream, so the listing is not missing any.
+	peers, err := gobgp.GetNeighbours(context.Background())
+	if err != nil {
+		return err
+	}
+	gobgp.state.setPeers(peers)
+
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		gobgp.applyPeerState(resp.Peer)
+	}
+}
+
+/*
+Apply a peer state change. The change only has the session
+state, so the peer is listed for the details.
+*/
+func (gobgp *GoBGP) applyPeerState(peer *gobgpapi.Peer) {
+	address := peer.State.NeighborAddress
+	details, err := gobgp.getNeighbour(address)
+	if err != nil {
+		log.Println("GoBGP", gobgp.config.Id, "could not get peer", address, ":", err)
+	}
+
+	gobgp.state.Lock()
+	defer gobgp.state.Unlock()
+	if err == nil && details == nil {
+		gobgp.state.removePeer(address)
+		return
+	}
+	if details == nil {
+		// Keep the details we have
+		prev, ok := gobgp.state.peers[address]
+		if ok {
+			details = &gobgpapi.Peer{}
+			*details = *prev
+			state := *prev.State
+			state.SessionState = peer.State.SessionState
+			details.State = &state
+		} else if gobgp.config.Vrf == "" && len(gobgp.config.Vrfs) == 0 {
+			details = peer
+		} else {
+			// The VRF of the peer is not known
+			return
+		}
+	}
+	gobgp.state.updatePeer(details)
+}
+
+// Subscribe to the pre-policy Adj-RIB-In updates of a family
+func (gobgp *GoBGP) watchTable(family *gobgpapi.Family) error {
+	client, err := gobgp.client()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.MonitorTable(ctx, &gobgpapi.MonitorTableRequest{
+		TableType: gobgpapi.TableType_ADJ_IN,
+		Family:    family,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Routes received before subscribing are listed
+	gobgp.state.setStream(true)
+	defer gobgp.state.setStream(false)
+	gobgp.state.requestResync()
+
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		gobgp.applyPath(family, resp.Path)
+	}
+}
+
+// Apply a path from the table stream
+func (gobgp *GoBGP) applyPath(family *gobgpapi.Family, path *gobgpapi.Path) {
+	nlri, err := apiutil.GetNativeNlri(path)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	prefix := nlri.String()
+	address := path.NeighborIp
+
+	if path.IsWithdraw {
+		gobgp.state.applyPath(address, family, prefix, path.Identifier, nil, true)
+		return
+	}
+
+	err, route := gobgp.parsePathIntoRoute(path, prefix)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	gobgp.state.applyPath(address, family, prefix, path.Identifier, route, false)
+}
+
+// List the ribs of dirty peers and look up
+// the pending prefixes of the other peers
+func (gobgp *GoBGP) refreshRibs() {
+	for {
+		time.Sleep(WATCH_REFRESH_INTERVAL)
+
+		peers, pending, resync := gobgp.state.takeDirty()
+		failed := []string{}
+		for _, peer := range peers {
+			address := peer.State.NeighborAddress
+			routes, err := gobgp.getRib(peer)
+			if err != nil {
+				log.Println("GoBGP", gobgp.config.Id, "could not list routes of", address, ":", err)
+				failed = append(failed, address)
+				continue
+			}
+			gobgp.state.setRib(address, routes)
+		}
+
+		failedPrefixes := make(map[string]map[string]*gobgpapi.Family)
+		for address, prefixes := range pending {
+			routes, err := gobgp.lookupRib(address, prefixes)
+			if err != nil {
+				log.Println("GoBGP", gobgp.config.Id, "could not look up routes of", address, ":", err)
+				failedPrefixes[address] = prefixes
+				continue
+			}
+			updated := make([]string, 0, len(prefixes))
+			for prefix := range prefixes {
+				updated = append(updated, prefix)
+			}
+			gobgp.state.setPrefixes(address, updated, routes)
+		}
+		gobgp.state.doneDirty(failed, failedPrefixes, resync)
+	}
+}
+
+// List the Adj-RIB-In of a peer
+func (gobgp *GoBGP) getRib(peer *gobgpapi.Peer) (rib, error) {
+	requests := make([]*gobgpapi.ListPathRequest, 0, len(gobgp.families))
+	for _, family := range gobgp.families {
+		family := family
+		requests = append(requests, &gobgpapi.ListPathRequest{
+			Name:           peer.State.NeighborAddress,
+			TableType:      gobgpapi.TableType_ADJ_IN,
+			Family:         &family,
+			EnableFiltered: true,
+		})
+	}
+	return gobgp.listRib(requests)
+}
+
+// Look up prefixes in the Adj-RIB-In of a peer
+func (gobgp *GoBGP) lookupRib(
+	address string,
+	prefixes map[string]*gobgpapi.Family,
+) (rib, error) {
+	requests := make(map[string]*gobgpapi.ListPathRequest)
+	for prefix, family := range prefixes {
+		name := familyName(family)
+		req, ok := requests[name]
+		if !ok {
+			req = &gobgpapi.ListPathRequest{
+				Name:           address,
+				TableType:      gobgpapi.TableType_ADJ_IN,
+				Family:         family,
+				EnableFiltered: true,
+			}
+			requests[name] = req
+		}
+		req.Prefixes = append(req.Prefixes, &gobgpapi.TableLookupPrefix{
+			Prefix: prefix,
+		})
+	}
+
+	list := make([]*gobgpapi.ListPathRequest, 0, len(requests))
+	for _, req := range requests {
+		list = append(list, req)
+	}
+	return gobgp.listRib(list)
+}
+
+// Get the routes of Adj-RIB-In listings
+func (gobgp *GoBGP) listRib(requests []*gobgpapi.ListPathRequest) (rib, error) {
+	var routes rib
+	err := gobgp.process(context.Background(), func(ctx context.Context, client gobgpapi.GobgpApiClient) error {
+		routes = make(rib)
+		now := time.Now()
+		for _, req := range requests {
+			pathStream, err := client.ListPath(ctx, req)
+			if err != nil {
+				return err
+			}
+
+			for {
+				resp, err := pathStream.Recv()
+				if err == io.EOF {
+					break
+				} else if err != nil {
+					return err
+				}
+				destination := resp.Destination
+				for _, path := range destination.Paths {
+					err, route := gobgp.parsePathIntoRoute(path, destination.Prefix)
+					if err != nil {
+						log.Println(err)
+						continue
+					}
+					paths, ok := routes[destination.Prefix]
+					if !ok {
+						paths = make(ribPaths)
+						routes[destination.Prefix] = paths
+					}
+					paths[path.Identifier] = &ribEntry{
+						route:    route,
+						filtered: path.Filtered,
+						received: now.Add(-route.Age),
+					}
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return routes, nil
+}
+
+// Get a single peer, nil if it does not exist
+// or is not seen by the source
+func (gobgp *GoBGP) getNeighbour(address string) (*gobgpapi.Peer, error) {
+	var peer *gobgpapi.Peer
+	err := gobgp.request(context.Background(), func(ctx context.Context, client gobgpapi.GobgpApiClient) error {
+		peerStream, err := client.ListPeer(ctx, &gobgpapi.ListPeerRequest{
+			Address:          address,
+			EnableAdvertised: true,
+		})
+		if err != nil {
+			return err
+		}
+
+		peer = nil
+		for {
+			resp, err := peerStream.Recv()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return err
+			}
+			if gobgp.inView(resp.Peer) {
+				peer = resp.Peer
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return peer, nil
+}
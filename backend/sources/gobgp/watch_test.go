@@ -0,0 +1,195 @@
+package gobgp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alice-lg/alice-lg/backend/api"
+	"github.com/alice-lg/alice-lg/backend/sources/gobgp/apiutil"
+	gobgpapi "github.com/osrg/gobgp/api"
+	"github.com/osrg/gobgp/pkg/packet/bgp"
+)
+
+func testPeer(address string, state gobgpapi.PeerState_SessionState) *gobgpapi.Peer {
+	return &gobgpapi.Peer{
+		Conf: &gobgpapi.PeerConf{
+			NeighborAddress: address,
+			PeerAs:          65001,
+			Description:     "test peer",
+		},
+		State: &gobgpapi.PeerState{
+			NeighborAddress: address,
+			PeerAs:          65001,
+			SessionState:    state,
+		},
+	}
+}
+
+func testPath(address, prefix string, length uint8, withdraw bool) *gobgpapi.Path {
+	attrs := []bgp.PathAttributeInterface{
+		bgp.NewPathAttributeOrigin(bgp.BGP_ORIGIN_ATTR_TYPE_IGP),
+		bgp.NewPathAttributeNextHop(address),
+		bgp.NewPathAttributeAsPath([]bgp.AsPathParamInterface{
+			bgp.NewAs4PathParam(bgp.BGP_ASPATH_ATTR_TYPE_SEQ, []uint32{65001}),
+		}),
+	}
+	path := apiutil.NewPath(
+		bgp.NewIPAddrPrefix(length, prefix), withdraw, attrs, time.Now())
+	path.NeighborIp = address
+	path.SourceAsn = 65001
+	return path
+}
+
+func TestWatchState(t *testing.T) {
+	families, _ := ParseFamilies(nil)
+	gobgp := &GoBGP{
+		config:   Config{Id: "rs1"},
+		families: families,
+		state:    newWatchState(len(families)),
+	}
+	state := gobgp.state
+
+	state.setPeers([]*gobgpapi.Peer{
+		testPeer("192.0.2.1", gobgpapi.PeerState_ESTABLISHED),
+		testPeer("192.0.2.2", gobgpapi.PeerState_ACTIVE),
+	})
+	if state.ready() {
+		t.Error("Expected state not to be ready before listing the ribs")
+	}
+
+	// Only established peers are listed
+	peers, _, resync := state.takeDirty()
+	if len(peers) != 1 || peers[0].State.NeighborAddress != "192.0.2.1" {
+		t.Error("Unexpected dirty peers:", peers)
+	}
+	for range gobgp.families {
+		state.setStream(true)
+	}
+	state.doneDirty([]string{}, nil, resync)
+	if !state.ready() {
+		t.Error("Expected state to be ready")
+	}
+
+	family := &gobgp.families[0]
+	gobgp.applyPath(family, testPath("192.0.2.1", "10.0.0.0", 8, false))
+	gobgp.applyPath(family, testPath("192.0.2.1", "10.23.0.0", 16, false))
+	gobgp.applyPath(family, testPath("192.0.2.1", "10.23.0.0", 16, false))
+	// Not established
+	gobgp.applyPath(family, testPath("192.0.2.2", "10.42.0.0", 16, false))
+
+	routes := NewRoutesResponse()
+	state.routes(&routes)
+	if len(routes.Imported) != 2 {
+		t.Error("Expected 2 imported routes, got:", len(routes.Imported))
+	}
+	if routes.Imported[0].Bgp.NextHop != "192.0.2.1" {
+		t.Error("Unexpected next hop:", routes.Imported[0].Bgp.NextHop)
+	}
+
+	gobgp.applyPath(family, testPath("192.0.2.1", "10.0.0.0", 8, true))
+	routes = NewRoutesResponse()
+	state.routes(&routes)
+	if len(routes.Imported) != 1 || routes.Imported[0].Network != "10.23.0.0/16" {
+		t.Error("Expected withdrawn route to be removed:", routes.Imported)
+	}
+
+	// Only the updated prefixes are looked up
+	peers, pending, resync := state.takeDirty()
+	if len(peers) != 0 {
+		t.Error("Expected no rib to be listed:", peers)
+	}
+	prefixes := pending["192.0.2.1"]
+	if len(pending) != 1 || len(prefixes) != 2 ||
+		prefixes["10.0.0.0/8"] == nil ||
+		prefixes["10.23.0.0/16"] == nil {
+		t.Error("Unexpected pending prefixes:", pending)
+	}
+
+	// A failed lookup is retried
+	state.doneDirty([]string{}, pending, resync)
+	_, pending, resync = state.takeDirty()
+	if len(pending["192.0.2.1"]) != 2 {
+		t.Error("Expected the prefixes to be pending again:", pending)
+	}
+	state.doneDirty([]string{}, nil, resync)
+
+	// Filtered routes from the lookup
+	routes = NewRoutesResponse()
+	state.routes(&routes)
+	filtered := *routes.Imported[0]
+	state.setPrefixes("192.0.2.1", []string{"10.0.0.0/8", "10.23.0.0/16"}, rib{
+		"10.23.0.0/16": ribPaths{
+			0: &ribEntry{route: &filtered, filtered: true},
+		},
+	})
+
+	neighbours := state.neighbours(gobgp.parseNeighbour)
+	for _, neighbour := range neighbours {
+		if neighbour.Address == "192.0.2.1" {
+			if neighbour.RoutesReceived != 1 ||
+				neighbour.RoutesFiltered != 1 ||
+				neighbour.RoutesAccepted != 0 {
+				t.Error("Unexpected route counts:", neighbour)
+			}
+			if neighbour.Description != "test peer" {
+				t.Error("Unexpected description:", neighbour.Description)
+			}
+		}
+	}
+	if len(neighbours) != 2 {
+		t.Error("Expected 2 neighbours, got:", len(neighbours))
+	}
+
+	// Prefixes without paths in the lookup were withdrawn
+	state.setPrefixes("192.0.2.1", []string{"10.23.0.0/16"}, rib{})
+	routes = NewRoutesResponse()
+	state.routes(&routes)
+	if len(routes.Imported) != 0 || len(routes.Filtered) != 0 {
+		t.Error("Expected the routes to be removed:", routes)
+	}
+
+	// Session down
+	state.Lock()
+	state.updatePeer(testPeer("192.0.2.1", gobgpapi.PeerState_IDLE))
+	state.Unlock()
+	routes = NewRoutesResponse()
+	state.routes(&routes)
+	if len(routes.Imported) != 0 || len(routes.Filtered) != 0 {
+		t.Error("Expected routes to be dropped when the session is down")
+	}
+
+	// Resubscribing requires listing the ribs again
+	state.setStream(false)
+	if state.ready() {
+		t.Error("Expected state not to be ready after a stream error")
+	}
+	state.setStream(true)
+	state.requestResync()
+	if state.ready() {
+		t.Error("Expected state not to be ready before listing the ribs again")
+	}
+}
+
+func TestWatchStateListFamilies(t *testing.T) {
+	families, _ := ParseFamilies([]string{"ipv4-unicast", "ipv4-flowspec"})
+	state := newWatchState(len(families))
+	state.setPeers([]*gobgpapi.Peer{
+		testPeer("192.0.2.1", gobgpapi.PeerState_ESTABLISHED),
+	})
+	_, _, resync := state.takeDirty()
+	state.doneDirty([]string{}, nil, resync)
+
+	route := &api.Route{Network: "10.0.0.0/8"}
+	state.applyPath("192.0.2.1", &families[0], "10.0.0.0/8", 0, route, false)
+	peers, pending, _ := state.takeDirty()
+	if len(peers) != 0 || len(pending["192.0.2.1"]) != 1 {
+		t.Error("Expected the prefix to be looked up:", peers, pending)
+	}
+
+	// FlowSpec tables can not be looked up by prefix
+	state.applyPath("192.0.2.1", &families[1], "[destination: 10.0.0.0/8]", 0, route, false)
+	peers, pending, _ = state.takeDirty()
+	if len(peers) != 1 || len(pending) != 0 {
+		t.Error("Expected the rib to be listed:", peers, pending)
+	}
+}
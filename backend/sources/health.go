@@ -0,0 +1,360 @@
+package sources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/alice-lg/alice-lg/backend/api"
+)
+
+/*
+Source health:
+
+Each source is wrapped by a HealthSource, tracking the
+latency and the consecutive failures of its requests.
+
+After `failures` consecutive failed requests, the circuit
+opens: Requests fail fast with a CircuitOpenError instead
+of waiting for a hanging backend. After the cool-down, the
+next request probes the source. The circuit closes, when
+this request succeeds, otherwise it opens again.
+
+Requests for unsupported views or unknown neighbors, invalid
+requests, partial results and requests canceled by the client
+are not failures of the source.
+*/
+
+const (
+	HEALTH_CLOSED    = "closed"
+	HEALTH_OPEN      = "open"
+	HEALTH_HALF_OPEN = "half_open"
+
+	DEFAULT_HEALTH_FAILURES = 5
+	DEFAULT_HEALTH_COOLDOWN = 30
+)
+
+// Configure the circuit breaker
+type HealthConfig struct {
+	// Consecutive failures opening the circuit,
+	// the circuit never opens if not set.
+	Failures int
+
+	// Time requests fail fast, before probing again
+	CoolDown time.Duration
+}
+
+// A CircuitOpenError is returned while the circuit is open
+type CircuitOpenError struct {
+	RetryAt   time.Time
+	LastError error
+}
+
+func (self *CircuitOpenError) Error() string {
+	return fmt.Sprintf(
+		"source unavailable until %s, last error: %s",
+		self.RetryAt.Format(time.RFC3339), self.LastError)
+}
+
+// A HealthSource tracks the health of a source
+type HealthSource struct {
+	id      string
+	source  Source
+	context ContextSource
+	config  HealthConfig
+
+	state       string
+	failures    int
+	lastError   error
+	lastErrorAt time.Time
+	retryAt     time.Time
+	stats       api.RequestStats
+
+	sync.Mutex
+}
+
+// Wrap a source with a circuit breaker
+func NewHealthSource(id string, source Source, config HealthConfig) *HealthSource {
+	return &HealthSource{
+		id:      id,
+		source:  source,
+		context: WithContext(source),
+		config:  config,
+		state:   HEALTH_CLOSED,
+	}
+}
+
+// Get the wrapped source
+func (self *HealthSource) Source() Source {
+	return self.source
+}
+
+// Get the health of the source
+func (self *HealthSource) Health() api.SourceHealth {
+	self.Lock()
+	defer self.Unlock()
+
+	health := api.SourceHealth{
+		State:       self.state,
+		Failures:    self.failures,
+		LastErrorAt: self.lastErrorAt,
+		RetryAt:     self.retryAt,
+		Requests:    self.stats,
+	}
+	if self.lastError != nil {
+		health.LastError = self.lastError.Error()
+	}
+	return health
+}
+
+// Check if a request may be made, the first
+// request after the cool-down probes the source.
+func (self *HealthSource) admit() error {
+	self.Lock()
+	defer self.Unlock()
+
+	switch self.state {
+	case HEALTH_OPEN:
+		if time.Now().Before(self.retryAt) {
+			break
+		}
+		self.state = HEALTH_HALF_OPEN
+		return nil
+	case HEALTH_HALF_OPEN:
+		// The source is being probed
+	default:
+		return nil
+	}
+
+	return &CircuitOpenError{
+		RetryAt:   self.retryAt,
+		LastError: self.lastError,
+	}
+}
+
+// Check if a request can not be answered by the source,
+// e.g. a neighbor which does not exist or a malformed query.
+func isRequestError(err error) bool {
+	var (
+		unsupportedErr *UnsupportedError
+		notFoundErr    *NotFoundError
+		invalidErr     *InvalidRequestError
+	)
+	return errors.As(err, &unsupportedErr) ||
+		errors.As(err, &notFoundErr) ||
+		errors.As(err, &invalidErr)
+}
+
+// Check if an error is a failure of the source
+func isSourceFailure(err error) bool {
+	var partialErr *PartialError
+	return err != nil &&
+		!isRequestError(err) &&
+		!errors.As(err, &partialErr)
+}
+
+// Record the result of a request
+func (self *HealthSource) record(latency time.Duration, err error) {
+	self.Lock()
+	defer self.Unlock()
+
+	self.stats.Requests++
+	self.stats.LatencyLast = latency
+	if latency > self.stats.LatencyMax {
+		self.stats.LatencyMax = latency
+	}
+	self.stats.LatencyTotal += latency
+	self.stats.LatencyAvg = self.stats.LatencyTotal /
+		time.Duration(self.stats.Requests)
+
+	// The client went away, this tells
+	// nothing about the source.
+	if errors.Is(err, context.Canceled) {
+		if self.state == HEALTH_HALF_OPEN {
+			self.state = HEALTH_OPEN
+		}
+		return
+	}
+
+	if err != nil && !isRequestError(err) {
+		self.lastError = err
+		self.lastErrorAt = time.Now().UTC()
+	}
+
+	if !isSourceFailure(err) {
+		if self.state != HEALTH_CLOSED {
+			log.Println("Source", self.id, "recovered, closing circuit")
+		}
+		self.state = HEALTH_CLOSED
+		self.failures = 0
+		return
+	}
+
+	self.stats.Failures++
+	self.failures++
+
+	if self.state == HEALTH_HALF_OPEN ||
+		(self.config.Failures > 0 && self.failures >= self.config.Failures) {
+		if self.state != HEALTH_OPEN {
+			log.Println("Source", self.id, "failed", self.failures,
+				"times, opening circuit for", self.config.CoolDown, "-", err)
+		}
+		self.state = HEALTH_OPEN
+		self.retryAt = time.Now().UTC().Add(self.config.CoolDown)
+	}
+}
+
+// Make a request through the circuit breaker
+func (self *HealthSource) call(ctx context.Context, request func() error) error {
+	if err := self.admit(); err != nil {
+		return err
+	}
+
+	t0 := time.Now()
+	err := request()
+
+	// The error of a canceled request might not tell,
+	// e.g. when it is wrapped by the backend.
+	result := err
+	if err != nil && ctx.Err() == context.Canceled {
+		result = context.Canceled
+	}
+	self.record(time.Since(t0), result)
+
+	return err
+}
+
+func (self *HealthSource) ExpireCaches() int {
+	return self.source.ExpireCaches()
+}
+
+func (self *HealthSource) Capabilities() api.SourceCapabilities {
+	return self.source.Capabilities()
+}
+
+func (self *HealthSource) StatusContext(ctx context.Context) (*api.StatusResponse, error) {
+	var response *api.StatusResponse
+	err := self.call(ctx, func() (err error) {
+		response, err = self.context.StatusContext(ctx)
+		return err
+	})
+	return response, err
+}
+
+func (self *HealthSource) NeighboursContext(ctx context.Context) (*api.NeighboursResponse, error) {
+	var response *api.NeighboursResponse
+	err := self.call(ctx, func() (err error) {
+		response, err = self.context.NeighboursContext(ctx)
+		return err
+	})
+	return response, err
+}
+
+func (self *HealthSource) NeighboursStatusContext(ctx context.Context) (*api.NeighboursStatusResponse, error) {
+	var response *api.NeighboursStatusResponse
+	err := self.call(ctx, func() (err error) {
+		response, err = self.context.NeighboursStatusContext(ctx)
+		return err
+	})
+	return response, err
+}
+
+// Make a routes request through the circuit breaker
+func (self *HealthSource) callRoutes(
+	ctx context.Context,
+	request func() (*api.RoutesResponse, error),
+) (*api.RoutesResponse, error) {
+	var response *api.RoutesResponse
+	err := self.call(ctx, func() (err error) {
+		response, err = request()
+		return err
+	})
+	return response, err
+}
+
+func (self *HealthSource) RoutesContext(ctx context.Context, neighbourId string) (*api.RoutesResponse, error) {
+	return self.callRoutes(ctx, func() (*api.RoutesResponse, error) {
+		return self.context.RoutesContext(ctx, neighbourId)
+	})
+}
+
+func (self *HealthSource) RoutesReceivedContext(ctx context.Context, neighbourId string) (*api.RoutesResponse, error) {
+	return self.callRoutes(ctx, func() (*api.RoutesResponse, error) {
+		return self.context.RoutesReceivedContext(ctx, neighbourId)
+	})
+}
+
+func (self *HealthSource) RoutesFilteredContext(ctx context.Context, neighbourId string) (*api.RoutesResponse, error) {
+	return self.callRoutes(ctx, func() (*api.RoutesResponse, error) {
+		return self.context.RoutesFilteredContext(ctx, neighbourId)
+	})
+}
+
+func (self *HealthSource) RoutesNotExportedContext(ctx context.Context, neighbourId string) (*api.RoutesResponse, error) {
+	return self.callRoutes(ctx, func() (*api.RoutesResponse, error) {
+		return self.context.RoutesNotExportedContext(ctx, neighbourId)
+	})
+}
+
+func (self *HealthSource) AllRoutesContext(ctx context.Context) (*api.RoutesResponse, error) {
+	return self.callRoutes(ctx, func() (*api.RoutesResponse, error) {
+		return self.context.AllRoutesContext(ctx)
+	})
+}
+
+// Prefix lookups fail if not supported by the source
+func (self *HealthSource) LookupPrefixContext(ctx context.Context, prefix string) (*api.RoutesLookupResponse, error) {
+	source, ok := WithLookupContext(self.source)
+	if !ok {
+		return nil, &UnsupportedError{Feature: "lookup_prefix"}
+	}
+
+	var response *api.RoutesLookupResponse
+	err := self.call(ctx, func() (err error) {
+		response, err = source.LookupPrefixContext(ctx, prefix)
+		return err
+	})
+	return response, err
+}
+
+// Requests without a context are made with
+// the background context.
+
+func (self *HealthSource) Status() (*api.StatusResponse, error) {
+	return self.StatusContext(context.Background())
+}
+
+func (self *HealthSource) Neighbours() (*api.NeighboursResponse, error) {
+	return self.NeighboursContext(context.Background())
+}
+
+func (self *HealthSource) NeighboursStatus() (*api.NeighboursStatusResponse, error) {
+	return self.NeighboursStatusContext(context.Background())
+}
+
+func (self *HealthSource) Routes(neighbourId string) (*api.RoutesResponse, error) {
+	return self.RoutesContext(context.Background(), neighbourId)
+}
+
+func (self *HealthSource) RoutesReceived(neighbourId string) (*api.RoutesResponse, error) {
+	return self.RoutesReceivedContext(context.Background(), neighbourId)
+}
+
+func (self *HealthSource) RoutesFiltered(neighbourId string) (*api.RoutesResponse, error) {
+	return self.RoutesFilteredContext(context.Background(), neighbourId)
+}
+
+func (self *HealthSource) RoutesNotExported(neighbourId string) (*api.RoutesResponse, error) {
+	return self.RoutesNotExportedContext(context.Background(), neighbourId)
+}
+
+func (self *HealthSource) AllRoutes() (*api.RoutesResponse, error) {
+	return self.AllRoutesContext(context.Background())
+}
+
+func (self *HealthSource) LookupPrefix(prefix string) (*api.RoutesLookupResponse, error) {
+	return self.LookupPrefixContext(context.Background(), prefix)
+}
@@ -0,0 +1,198 @@
+package sources
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alice-lg/alice-lg/backend/api"
+)
+
+// A source failing with the configured error
+type failingSource struct {
+	blockingSource
+	err      error
+	requests int
+}
+
+func (self *failingSource) Status() (*api.StatusResponse, error) {
+	self.requests++
+	if self.err != nil {
+		return nil, self.err
+	}
+	return &api.StatusResponse{}, nil
+}
+
+func TestHealthSourceOpen(t *testing.T) {
+	source := &failingSource{err: fmt.Errorf("connection refused")}
+	health := NewHealthSource("rs1", source, HealthConfig{
+		Failures: 3,
+		CoolDown: time.Hour,
+	})
+
+	for i := 0; i < 3; i++ {
+		if _, err := health.Status(); err != source.err {
+			t.Error("Expected the error of the source, got:", err)
+		}
+	}
+	if state := health.Health().State; state != HEALTH_OPEN {
+		t.Error("Expected an open circuit, got:", state)
+	}
+
+	// Fail fast
+	_, err := health.Status()
+	if _, ok := err.(*CircuitOpenError); !ok {
+		t.Error("Expected a CircuitOpenError, got:", err)
+	}
+	if source.requests != 3 {
+		t.Error("Expected 3 requests to the source, got:", source.requests)
+	}
+
+	status := health.Health()
+	if status.Failures != 3 || status.Requests.Failures != 3 {
+		t.Error("Unexpected failures:", status.Failures)
+	}
+	if status.LastError != "connection refused" {
+		t.Error("Unexpected last error:", status.LastError)
+	}
+}
+
+func TestHealthSourceProbe(t *testing.T) {
+	source := &failingSource{err: fmt.Errorf("connection refused")}
+	health := NewHealthSource("rs1", source, HealthConfig{
+		Failures: 1,
+		CoolDown: 0,
+	})
+
+	// The probe fails and opens the circuit again
+	health.Status()
+	health.Status()
+	if state := health.Health().State; state != HEALTH_OPEN {
+		t.Error("Expected an open circuit, got:", state)
+	}
+	if source.requests != 2 {
+		t.Error("Expected the source to be probed, got:", source.requests)
+	}
+
+	// The probe succeeds and closes the circuit
+	source.err = nil
+	if _, err := health.Status(); err != nil {
+		t.Error(err)
+	}
+	status := health.Health()
+	if status.State != HEALTH_CLOSED || status.Failures != 0 {
+		t.Error("Expected a closed circuit, got:", status)
+	}
+}
+
+func TestHealthSourceHalfOpen(t *testing.T) {
+	source := &failingSource{err: fmt.Errorf("connection refused")}
+	health := NewHealthSource("rs1", source, HealthConfig{
+		Failures: 1,
+		CoolDown: 0,
+	})
+	health.Status()
+
+	// While probing, other requests fail fast
+	if err := health.admit(); err != nil {
+		t.Error("Expected the probe to be admitted, got:", err)
+	}
+	if err := health.admit(); err == nil {
+		t.Error("Expected requests to fail while probing")
+	}
+
+	// A canceled probe does not close the circuit
+	health.record(0, context.Canceled)
+	if state := health.Health().State; state != HEALTH_OPEN {
+		t.Error("Expected an open circuit, got:", state)
+	}
+}
+
+func TestHealthSourceNotFailures(t *testing.T) {
+	source := &failingSource{err: &UnsupportedError{Feature: "status"}}
+	health := NewHealthSource("rs1", source, HealthConfig{
+		Failures: 1,
+		CoolDown: time.Hour,
+	})
+	health.Status()
+
+	source.err = &PartialError{
+		Failed: map[string]error{"master": fmt.Errorf("timeout")},
+	}
+	health.Status()
+
+	source.err = &NotFoundError{Resource: "neighbor", Id: "R192_42"}
+	health.Status()
+
+	status := health.Health()
+	if status.State != HEALTH_CLOSED || status.Failures != 0 {
+		t.Error("Expected a closed circuit, got:", status)
+	}
+	if status.Requests.Requests != 3 {
+		t.Error("Expected 3 requests, got:", status.Requests.Requests)
+	}
+}
+
+func TestHealthSourceInvalidRequests(t *testing.T) {
+	source := &failingSource{err: &InvalidRequestError{
+		Param:  "prefix",
+		Value:  "10.0",
+		Reason: "not an address or prefix",
+	}}
+	health := NewHealthSource("rs1", source, HealthConfig{
+		Failures: 3,
+		CoolDown: time.Hour,
+	})
+
+	for i := 0; i < 10; i++ {
+		health.Status()
+	}
+
+	status := health.Health()
+	if status.State != HEALTH_CLOSED || status.Failures != 0 {
+		t.Error("Expected a closed circuit, got:", status)
+	}
+	if source.requests != 10 {
+		t.Error("Expected 10 requests to the source, got:", source.requests)
+	}
+	if status.LastError != "" {
+		t.Error("Expected no source error, got:", status.LastError)
+	}
+}
+
+func TestHealthSourceCanceled(t *testing.T) {
+	source := &failingSource{
+		err: fmt.Errorf("request failed: %w", context.Canceled),
+	}
+	health := NewHealthSource("rs1", source, HealthConfig{
+		Failures: 1,
+		CoolDown: time.Hour,
+	})
+
+	// Wrapped cancellation
+	health.Status()
+
+	// The error does not tell, but the context was canceled
+	source.err = fmt.Errorf("connection closed")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	health.call(ctx, func() error {
+		return source.err
+	})
+
+	if status := health.Health(); status.State != HEALTH_CLOSED {
+		t.Error("Expected a closed circuit, got:", status)
+	}
+}
+
+func TestHealthSourceDisabled(t *testing.T) {
+	source := &failingSource{err: fmt.Errorf("connection refused")}
+	health := NewHealthSource("rs1", source, HealthConfig{})
+	for i := 0; i < 10; i++ {
+		health.Status()
+	}
+	if state := health.Health().State; state != HEALTH_CLOSED {
+		t.Error("Expected a closed circuit, got:", state)
+	}
+}
@@ -0,0 +1,32 @@
+package sources
+
+import (
+	"sync"
+)
+
+/*
+LockMap: Uses the sync.Map to manage locks, accessed by a key.
+Sources use it to fetch the routes of a neighbor only once.
+*/
+type LockMap struct {
+	locks *sync.Map
+}
+
+func NewLockMap() *LockMap {
+	return &LockMap{
+		locks: &sync.Map{},
+	}
+}
+
+func (self *LockMap) Lock(key string) {
+	mutex, _ := self.locks.LoadOrStore(key, &sync.Mutex{})
+	mutex.(*sync.Mutex).Lock()
+}
+
+func (self *LockMap) Unlock(key string) {
+	mutex, ok := self.locks.Load(key)
+	if !ok {
+		return // Nothing to unlock
+	}
+	mutex.(*sync.Mutex).Unlock()
+}
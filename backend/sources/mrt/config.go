@@ -0,0 +1,47 @@
+package mrt
+
+import (
+	"github.com/alice-lg/alice-lg/backend/sources"
+
+	"github.com/go-ini/ini"
+)
+
+type Config struct {
+	Id   string
+	Name string
+
+	// File is the path to an MRT dump or to a directory
+	// of dumps. In case of a directory, the most recent
+	// dump is used. Dumps may be compressed using gzip
+	// or bzip2, indicated by the file extension.
+	File string `ini:"file"`
+
+	// ReloadInterval is the time in seconds after which
+	// we check for a newer dump.
+	ReloadInterval int `ini:"reload_interval"`
+}
+
+func init() {
+	sources.Register(&sources.Backend{
+		Name:      "mrt",
+		Configure: configure,
+		New: func(config interface{}) sources.Source {
+			return NewMRT(config.(Config))
+		},
+	})
+}
+
+// Parse the config section of a MRT source
+func configure(
+	base sources.Definition,
+	section *ini.Section,
+) ([]*sources.Definition, error) {
+	c := Config{
+		Id:   base.Id,
+		Name: base.Name,
+	}
+	section.MapTo(&c)
+
+	base.Config = c
+	return []*sources.Definition{&base}, nil
+}
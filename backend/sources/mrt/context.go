@@ -0,0 +1,42 @@
+package mrt
+
+import (
+	"context"
+
+	"github.com/alice-lg/alice-lg/backend/api"
+)
+
+// Requests without a context are made with
+// the background context.
+
+func (self *MRT) Status() (*api.StatusResponse, error) {
+	return self.StatusContext(context.Background())
+}
+
+func (self *MRT) Neighbours() (*api.NeighboursResponse, error) {
+	return self.NeighboursContext(context.Background())
+}
+
+func (self *MRT) NeighboursStatus() (*api.NeighboursStatusResponse, error) {
+	return self.NeighboursStatusContext(context.Background())
+}
+
+func (self *MRT) Routes(neighborId string) (*api.RoutesResponse, error) {
+	return self.RoutesContext(context.Background(), neighborId)
+}
+
+func (self *MRT) RoutesReceived(neighborId string) (*api.RoutesResponse, error) {
+	return self.RoutesReceivedContext(context.Background(), neighborId)
+}
+
+func (self *MRT) RoutesFiltered(neighborId string) (*api.RoutesResponse, error) {
+	return self.RoutesFilteredContext(context.Background(), neighborId)
+}
+
+func (self *MRT) RoutesNotExported(neighborId string) (*api.RoutesResponse, error) {
+	return self.RoutesNotExportedContext(context.Background(), neighborId)
+}
+
+func (self *MRT) AllRoutes() (*api.RoutesResponse, error) {
+	return self.AllRoutesContext(context.Background())
+}
@@ -0,0 +1,192 @@
+package mrt
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/osrg/gobgp/pkg/packet/bgp"
+	"github.com/osrg/gobgp/pkg/packet/mrt"
+)
+
+var errNotAllBytesAvailable = fmt.Errorf("not all bytes available")
+
+/*
+Expand the MP_REACH_NLRI attribute of a RIB entry. RFC 6396
+only includes the next hop in RIB entries, as the address
+family and NLRI are known from the RIB entry header:
+
+	+---------------------------------------------------------+
+	| Next Hop Address Length (1 octet)                       |
+	+---------------------------------------------------------+
+	| Next Hop Address (variable)                             |
+	+---------------------------------------------------------+
+
+The attribute is rewritten to the full form, so it can be
+decoded like in an update message. Attributes already in
+the full form are returned as is.
+*/
+func expandMpReach(afi uint16, safi uint8, attr []byte, value []byte) []byte {
+	if len(value) == 0 || int(value[0]) != len(value)-1 {
+		return attr
+	}
+
+	expanded := make([]byte, 0, 4+3+len(value)+1)
+	expanded = append(expanded,
+		uint8(bgp.BGP_ATTR_FLAG_OPTIONAL|bgp.BGP_ATTR_FLAG_EXTENDED_LENGTH),
+		uint8(bgp.BGP_ATTR_TYPE_MP_REACH_NLRI), 0, 0)
+	binary.BigEndian.PutUint16(expanded[2:], uint16(3+len(value)+1))
+
+	expanded = append(expanded, 0, 0, safi)
+	binary.BigEndian.PutUint16(expanded[4:], afi)
+
+	expanded = append(expanded, value...)
+	expanded = append(expanded, 0) // Reserved
+
+	return expanded
+}
+
+// Decode the path attributes of a RIB entry
+func decodePathAttributes(
+	afi uint16,
+	safi uint8,
+	data []byte,
+) ([]bgp.PathAttributeInterface, error) {
+	attrs := []bgp.PathAttributeInterface{}
+	for len(data) > 0 {
+		if len(data) < 3 {
+			return nil, errNotAllBytesAvailable
+		}
+		flags := bgp.BGPAttrFlag(data[0])
+		headerLen := 3
+		valueLen := int(data[2])
+		if flags&bgp.BGP_ATTR_FLAG_EXTENDED_LENGTH != 0 {
+			if len(data) < 4 {
+				return nil, errNotAllBytesAvailable
+			}
+			headerLen = 4
+			valueLen = int(binary.BigEndian.Uint16(data[2:4]))
+		}
+		attrLen := headerLen + valueLen
+		if len(data) < attrLen {
+			return nil, errNotAllBytesAvailable
+		}
+
+		raw := data[:attrLen]
+		if bgp.BGPAttrType(data[1]) == bgp.BGP_ATTR_TYPE_MP_REACH_NLRI {
+			raw = expandMpReach(afi, safi, raw, raw[headerLen:])
+		}
+
+		attr, err := bgp.GetPathAttribute(raw)
+		if err != nil {
+			return nil, err
+		}
+		if err := attr.DecodeFromBytes(raw); err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, attr)
+
+		data = data[attrLen:]
+	}
+
+	return attrs, nil
+}
+
+// Decode a single RIB entry and return the remaining data
+func decodeRibEntry(
+	afi uint16,
+	safi uint8,
+	addPath bool,
+	data []byte,
+) (*mrt.RibEntry, []byte, error) {
+	headerLen := 8
+	if addPath {
+		headerLen = 12
+	}
+	if len(data) < headerLen {
+		return nil, nil, errNotAllBytesAvailable
+	}
+
+	entry := &mrt.RibEntry{
+		PeerIndex:      binary.BigEndian.Uint16(data[0:2]),
+		OriginatedTime: binary.BigEndian.Uint32(data[2:6]),
+	}
+	if addPath {
+		entry.PathIdentifier = binary.BigEndian.Uint32(data[6:10])
+	}
+
+	attrsLen := int(binary.BigEndian.Uint16(data[headerLen-2 : headerLen]))
+	data = data[headerLen:]
+	if len(data) < attrsLen {
+		return nil, nil, errNotAllBytesAvailable
+	}
+
+	attrs, err := decodePathAttributes(afi, safi, data[:attrsLen])
+	if err != nil {
+		return nil, nil, err
+	}
+	entry.PathAttributes = attrs
+
+	return entry, data[attrsLen:], nil
+}
+
+/*
+Decode a TABLE_DUMP_V2 RIB record. Only unicast RIBs are
+decoded, for all other subtypes nil is returned.
+*/
+func decodeRib(subType mrt.MRTSubTypeTableDumpv2, data []byte) (*mrt.Rib, error) {
+	var afi uint16
+	safi := uint8(bgp.SAFI_UNICAST)
+	addPath := false
+
+	switch subType {
+	case mrt.RIB_IPV4_UNICAST:
+		afi = bgp.AFI_IP
+	case mrt.RIB_IPV6_UNICAST:
+		afi = bgp.AFI_IP6
+	case mrt.RIB_IPV4_UNICAST_ADDPATH:
+		afi = bgp.AFI_IP
+		addPath = true
+	case mrt.RIB_IPV6_UNICAST_ADDPATH:
+		afi = bgp.AFI_IP6
+		addPath = true
+	default:
+		return nil, nil
+	}
+
+	if len(data) < 4 {
+		return nil, errNotAllBytesAvailable
+	}
+	rib := &mrt.Rib{
+		SequenceNumber: binary.BigEndian.Uint32(data[:4]),
+		RouteFamily:    bgp.AfiSafiToRouteFamily(afi, safi),
+	}
+	data = data[4:]
+
+	prefix, err := bgp.NewPrefixFromRouteFamily(afi, safi)
+	if err != nil {
+		return nil, err
+	}
+	if err := prefix.DecodeFromBytes(data); err != nil {
+		return nil, err
+	}
+	rib.Prefix = prefix
+	data = data[prefix.Len():]
+
+	if len(data) < 2 {
+		return nil, errNotAllBytesAvailable
+	}
+	count := int(binary.BigEndian.Uint16(data[:2]))
+	data = data[2:]
+
+	rib.Entries = make([]*mrt.RibEntry, 0, count)
+	for i := 0; i < count; i++ {
+		var entry *mrt.RibEntry
+		entry, data, err = decodeRibEntry(afi, safi, addPath, data)
+		if err != nil {
+			return nil, err
+		}
+		rib.Entries = append(rib.Entries, entry)
+	}
+
+	return rib, nil
+}
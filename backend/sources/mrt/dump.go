@@ -0,0 +1,362 @@
+package mrt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"time"
+
+	"github.com/alice-lg/alice-lg/backend/api"
+	"github.com/alice-lg/alice-lg/backend/sources/bgputil"
+
+	"github.com/osrg/gobgp/pkg/packet/bgp"
+	"github.com/osrg/gobgp/pkg/packet/mrt"
+)
+
+const (
+	// RIB records hold all paths of a prefix, which
+	// can get large on collectors with many peers.
+	MAX_RECORD_SIZE = 16 * 1024 * 1024
+)
+
+// A RibEntry is a path of a prefix learned from a peer
+type RibEntry struct {
+	Network    string
+	PathId     uint32
+	Bgp        api.BgpInfo
+	Originated time.Time
+}
+
+// A Peer from the peer index table or
+// a BGP4MP session.
+type Peer struct {
+	Id      string
+	Address string
+	Asn     int
+	BgpId   string
+
+	Up    bool
+	Since time.Time
+
+	Rib map[string]*RibEntry
+}
+
+/*
+Dump is the state read from an MRT file: Either a TABLE_DUMP_V2
+RIB snapshot or the result of replaying BGP4MP messages.
+*/
+type Dump struct {
+	File      string
+	ModTime   time.Time
+	Timestamp time.Time
+
+	CollectorId string
+	ViewName    string
+
+	Peers map[string]*Peer
+
+	// Peers by their position in the peer index table
+	index []*Peer
+}
+
+func newDump() *Dump {
+	return &Dump{
+		Peers: make(map[string]*Peer),
+		index: []*Peer{},
+	}
+}
+
+// Get the peer with the address, it will
+// be created if not present.
+func (self *Dump) getPeer(address net.IP, asn uint32) *Peer {
+	id := address.String()
+	peer, ok := self.Peers[id]
+	if !ok {
+		peer = &Peer{
+			Id:      id,
+			Address: id,
+			Asn:     int(asn),
+			Rib:     make(map[string]*RibEntry),
+		}
+		self.Peers[id] = peer
+	}
+	return peer
+}
+
+// Get the key of a path in the rib
+func ribKey(network string, pathId uint32) string {
+	if pathId == 0 {
+		return network
+	}
+	return fmt.Sprintf("%s_%d", network, pathId)
+}
+
+// Add the peers from the peer index table
+func (self *Dump) addPeerIndexTable(table *mrt.PeerIndexTable) {
+	self.CollectorId = table.CollectorBgpId.String()
+	self.ViewName = table.ViewName
+	self.index = make([]*Peer, 0, len(table.Peers))
+	for _, p := range table.Peers {
+		peer := self.getPeer(p.IpAddress, p.AS)
+		peer.BgpId = p.BgpId.String()
+		self.index = append(self.index, peer)
+	}
+}
+
+// Add the paths of a prefix to the peers
+func (self *Dump) addRib(rib *mrt.Rib) error {
+	network := rib.Prefix.String()
+	for _, entry := range rib.Entries {
+		if int(entry.PeerIndex) >= len(self.index) {
+			return fmt.Errorf(
+				"peer index %d of %s not in peer index table",
+				entry.PeerIndex, network)
+		}
+		peer := self.index[entry.PeerIndex]
+		peer.Up = true
+		peer.Rib[ribKey(network, entry.PathIdentifier)] = &RibEntry{
+			Network:    network,
+			PathId:     entry.PathIdentifier,
+			Bgp:        bgputil.DecodePathAttributes(entry.PathAttributes),
+			Originated: time.Unix(int64(entry.OriginatedTime), 0).UTC(),
+		}
+	}
+	return nil
+}
+
+// Apply a BGP4MP state change to the peer
+func (self *Dump) applyStateChange(t time.Time, change *mrt.BGP4MPStateChange) {
+	peer := self.getPeer(change.PeerIpAddress, change.PeerAS)
+	up := change.NewState == mrt.ESTABLISHED
+	if up != peer.Up {
+		peer.Since = t
+	}
+	if !up {
+		peer.Rib = make(map[string]*RibEntry)
+	}
+	peer.Up = up
+}
+
+// Apply a BGP update received by the collector
+func (self *Dump) applyMessage(t time.Time, msg *mrt.BGP4MPMessage) {
+	update, ok := msg.BGPMessage.Body.(*bgp.BGPUpdate)
+	if !ok {
+		return
+	}
+	peer := self.getPeer(msg.PeerIpAddress, msg.PeerAS)
+	if !peer.Up {
+		// We did not see the session coming up,
+		// the update implies an established session.
+		peer.Up = true
+		peer.Since = t
+	}
+
+	for _, prefix := range update.WithdrawnRoutes {
+		delete(peer.Rib, ribKey(prefix.String(), prefix.PathIdentifier()))
+	}
+
+	announced := []bgp.AddrPrefixInterface{}
+	for _, prefix := range update.NLRI {
+		announced = append(announced, prefix)
+	}
+	for _, attr := range update.PathAttributes {
+		switch a := attr.(type) {
+		case *bgp.PathAttributeMpReachNLRI:
+			announced = append(announced, a.Value...)
+		case *bgp.PathAttributeMpUnreachNLRI:
+			for _, prefix := range a.Value {
+				delete(peer.Rib, ribKey(prefix.String(), prefix.PathIdentifier()))
+			}
+		}
+	}
+	if len(announced) == 0 {
+		return
+	}
+
+	info := bgputil.DecodePathAttributes(update.PathAttributes)
+	for _, prefix := range announced {
+		network := prefix.String()
+		peer.Rib[ribKey(network, prefix.PathIdentifier())] = &RibEntry{
+			Network:    network,
+			PathId:     prefix.PathIdentifier(),
+			Bgp:        info,
+			Originated: t,
+		}
+	}
+}
+
+// Apply a single MRT record to the dump
+func (self *Dump) applyRecord(data []byte) (err error) {
+	// The decoders may fail hard on malformed records
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("malformed record: %v", r)
+		}
+	}()
+
+	header := &mrt.MRTHeader{}
+	if err := header.DecodeFromBytes(data[:mrt.MRT_COMMON_HEADER_LEN]); err != nil {
+		return err
+	}
+	body := data[mrt.MRT_COMMON_HEADER_LEN:]
+
+	t := header.GetTime().UTC()
+	if t.After(self.Timestamp) {
+		self.Timestamp = t
+	}
+
+	switch header.Type {
+	case mrt.TABLE_DUMPv2:
+		subType := mrt.MRTSubTypeTableDumpv2(header.SubType)
+		if subType == mrt.PEER_INDEX_TABLE {
+			msg, err := mrt.ParseMRTBody(header, body)
+			if err != nil {
+				return err
+			}
+			self.addPeerIndexTable(msg.Body.(*mrt.PeerIndexTable))
+			return nil
+		}
+		rib, err := decodeRib(subType, body)
+		if err != nil || rib == nil {
+			return err
+		}
+		return self.addRib(rib)
+
+	case mrt.BGP4MP:
+		switch mrt.MRTSubTypeBGP4MP(header.SubType) {
+		case mrt.MESSAGE_LOCAL, mrt.MESSAGE_AS4_LOCAL,
+			mrt.MESSAGE_LOCAL_ADDPATH, mrt.MESSAGE_AS4_LOCAL_ADDPATH:
+			// Messages sent by the collector
+			return nil
+		}
+		msg, err := mrt.ParseMRTBody(header, body)
+		if err != nil {
+			return err
+		}
+		switch m := msg.Body.(type) {
+		case *mrt.BGP4MPStateChange:
+			self.applyStateChange(t, m)
+		case *mrt.BGP4MPMessage:
+			self.applyMessage(t, m)
+		}
+	}
+
+	return nil
+}
+
+/*
+Read a dump from an MRT stream. Malformed records are
+skipped, as collectors are known to produce some.
+*/
+func readDump(r io.Reader) (*Dump, error) {
+	dump := newDump()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), MAX_RECORD_SIZE)
+	scanner.Split(mrt.SplitMrt)
+
+	records := 0
+	failed := 0
+	for scanner.Scan() {
+		records++
+		if err := dump.applyRecord(scanner.Bytes()); err != nil {
+			if failed == 0 {
+				log.Println("MRT: Error while reading record:", err)
+			}
+			failed++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if failed > 0 {
+		log.Println("MRT: Skipped", failed, "of", records, "records")
+	}
+	if records == 0 {
+		return nil, fmt.Errorf("no MRT records found")
+	}
+
+	return dump, nil
+}
+
+// Load the dump from a file
+func loadDump(filename string, modTime time.Time) (*Dump, error) {
+	r, err := openDump(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	dump, err := readDump(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	dump.File = filename
+	dump.ModTime = modTime
+
+	return dump, nil
+}
+
+// Make an api route from a rib entry
+func (self *Peer) route(key string, entry *RibEntry, now time.Time) *api.Route {
+	return &api.Route{
+		Id:          key,
+		NeighbourId: self.Id,
+
+		Network: entry.Network,
+		Gateway: entry.Bgp.NextHop,
+		Bgp:     entry.Bgp,
+		Age:     now.Sub(entry.Originated),
+		Type:    []string{"BGP"},
+
+		Details: api.Details{
+			"path_id":    entry.PathId,
+			"originated": entry.Originated,
+		},
+	}
+}
+
+// Get all routes of the peer. The age of the
+// routes is relative to the time of the dump.
+func (self *Peer) routes(now time.Time) api.Routes {
+	routes := make(api.Routes, 0, len(self.Rib))
+	for key, entry := range self.Rib {
+		routes = append(routes, self.route(key, entry, now))
+	}
+	return routes
+}
+
+// Make an api neighbour from the peer
+func (self *Peer) neighbour(rsId string, now time.Time) *api.Neighbour {
+	state := "down"
+	if self.Up {
+		state = "up"
+	}
+
+	var uptime time.Duration
+	if !self.Since.IsZero() {
+		uptime = now.Sub(self.Since)
+	}
+
+	return &api.Neighbour{
+		Id: self.Id,
+
+		Address:     self.Address,
+		Asn:         self.Asn,
+		State:       state,
+		Description: fmt.Sprintf("AS%d", self.Asn),
+
+		RoutesReceived: len(self.Rib),
+		RoutesAccepted: len(self.Rib),
+
+		Uptime: uptime,
+
+		RouteServerId: rsId,
+
+		Details: map[string]interface{}{
+			"bgp_id": self.BgpId,
+		},
+	}
+}
@@ -0,0 +1,310 @@
+package mrt
+
+import (
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/osrg/gobgp/pkg/packet/bgp"
+	"github.com/osrg/gobgp/pkg/packet/mrt"
+)
+
+const TEST_DUMP_TIME = 1500000000
+
+func testAttributes(asn uint32) []bgp.PathAttributeInterface {
+	return []bgp.PathAttributeInterface{
+		bgp.NewPathAttributeOrigin(bgp.BGP_ORIGIN_ATTR_TYPE_IGP),
+		bgp.NewPathAttributeAsPath([]bgp.AsPathParamInterface{
+			bgp.NewAs4PathParam(bgp.BGP_ASPATH_ATTR_TYPE_SEQ,
+				[]uint32{asn, 4200000000}),
+		}),
+	}
+}
+
+// Make the MP_REACH_NLRI attribute as found in RIB entries,
+// holding only the next hop.
+func testMpReachAbbreviated(nexthop string) bgp.PathAttributeInterface {
+	value := append([]byte{16}, net.ParseIP(nexthop).To16()...)
+	return bgp.NewPathAttributeUnknown(
+		bgp.BGP_ATTR_FLAG_OPTIONAL, bgp.BGP_ATTR_TYPE_MP_REACH_NLRI, value)
+}
+
+func testRecord(t *testing.T, ts uint32, typ mrt.MRTType, subType mrt.MRTSubTyper, body mrt.Body) []byte {
+	msg, err := mrt.NewMRTMessage(ts, typ, subType, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := msg.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// Make a TABLE_DUMP_V2 dump with three peers,
+// the last one without any routes.
+func testTableDump(t *testing.T) []byte {
+	peers := []*mrt.Peer{
+		mrt.NewPeer("192.0.2.1", "192.0.2.1", 65001, true),
+		mrt.NewPeer("192.0.2.2", "2001:db8::2", 65002, true),
+		mrt.NewPeer("192.0.2.3", "192.0.2.3", 65003, true),
+	}
+
+	v4Attrs := func(asn uint32) []bgp.PathAttributeInterface {
+		return append(testAttributes(asn),
+			bgp.NewPathAttributeNextHop("192.0.2.1"))
+	}
+	v6Attrs := append(testAttributes(65002),
+		testMpReachAbbreviated("2001:db8::2"))
+
+	data := testRecord(t, TEST_DUMP_TIME, mrt.TABLE_DUMPv2, mrt.PEER_INDEX_TABLE,
+		mrt.NewPeerIndexTable("192.0.2.254", "rs1", peers))
+	data = append(data, testRecord(t, TEST_DUMP_TIME, mrt.TABLE_DUMPv2, mrt.RIB_IPV4_UNICAST,
+		mrt.NewRib(0, bgp.NewIPAddrPrefix(24, "10.1.0.0"), []*mrt.RibEntry{
+			mrt.NewRibEntry(0, TEST_DUMP_TIME-3600, 0, v4Attrs(65001), false),
+			mrt.NewRibEntry(1, TEST_DUMP_TIME-60, 0, v4Attrs(65002), false),
+		}))...)
+	data = append(data, testRecord(t, TEST_DUMP_TIME, mrt.TABLE_DUMPv2, mrt.RIB_IPV6_UNICAST,
+		mrt.NewRib(1, bgp.NewIPv6AddrPrefix(48, "2001:db8:1::"), []*mrt.RibEntry{
+			mrt.NewRibEntry(1, TEST_DUMP_TIME-60, 0, v6Attrs, false),
+		}))...)
+
+	return data
+}
+
+func writeDump(t *testing.T, filename string, data []byte) {
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if filepath.Ext(filename) == ".gz" {
+		w := gzip.NewWriter(f)
+		defer w.Close()
+		if _, err := w.Write(data); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	if _, err := f.Write(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTableDump(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mrt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeDump(t, filepath.Join(dir, "bview.gz"), testTableDump(t))
+	source := NewMRT(Config{Id: "rs1", File: dir})
+
+	status, err := source.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Status.RouterId != "192.0.2.254" ||
+		status.Status.ServerTime.Unix() != TEST_DUMP_TIME {
+		t.Error("Unexpected status:", status.Status)
+	}
+
+	neighbours, err := source.Neighbours()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(neighbours.Neighbours) != 3 {
+		t.Fatal("Expected 3 neighbors, got:", len(neighbours.Neighbours))
+	}
+	for _, n := range neighbours.Neighbours {
+		switch n.Id {
+		case "192.0.2.1":
+			if n.State != "up" || n.RoutesReceived != 1 {
+				t.Error("Unexpected neighbor:", n)
+			}
+		case "2001:db8::2":
+			if n.Asn != 65002 || n.State != "up" || n.RoutesReceived != 2 {
+				t.Error("Unexpected neighbor:", n)
+			}
+		case "192.0.2.3":
+			if n.State != "down" || n.RoutesReceived != 0 {
+				t.Error("Unexpected neighbor:", n)
+			}
+		default:
+			t.Error("Unexpected neighbor:", n.Id)
+		}
+	}
+
+	routes, err := source.Routes("2001:db8::2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes.Imported) != 2 {
+		t.Fatal("Expected 2 routes, got:", len(routes.Imported))
+	}
+	for _, r := range routes.Imported {
+		if r.Network == "2001:db8:1::/48" && r.Gateway != "2001:db8::2" {
+			t.Error("Unexpected gateway:", r.Gateway)
+		}
+		if r.Age != time.Minute {
+			t.Error("Expected age to be relative to the dump:", r.Age)
+		}
+		if len(r.Bgp.AsPath) != 2 || r.Bgp.AsPath[1] != 4200000000 {
+			t.Error("Unexpected as path:", r.Bgp.AsPath)
+		}
+	}
+
+	all, err := source.AllRoutes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all.Imported) != 3 {
+		t.Error("Expected 3 routes in total, got:", len(all.Imported))
+	}
+}
+
+func TestReplayUpdates(t *testing.T) {
+	update := func(withdraw, announce string) []byte {
+		var withdrawn, nlri []*bgp.IPAddrPrefix
+		if withdraw != "" {
+			withdrawn = []*bgp.IPAddrPrefix{bgp.NewIPAddrPrefix(24, withdraw)}
+		}
+		attrs := []bgp.PathAttributeInterface{}
+		if announce != "" {
+			nlri = []*bgp.IPAddrPrefix{bgp.NewIPAddrPrefix(24, announce)}
+			attrs = append(testAttributes(65001),
+				bgp.NewPathAttributeNextHop("192.0.2.1"))
+		}
+		return testRecord(t, TEST_DUMP_TIME, mrt.BGP4MP, mrt.MESSAGE_AS4,
+			mrt.NewBGP4MPMessage(65001, 65000, 0, "192.0.2.1", "192.0.2.254", true,
+				bgp.NewBGPUpdateMessage(withdrawn, attrs, nlri)))
+	}
+	stateChange := func(peer string, state mrt.BGPState) []byte {
+		return testRecord(t, TEST_DUMP_TIME-10, mrt.BGP4MP, mrt.STATE_CHANGE_AS4,
+			mrt.NewBGP4MPStateChange(65001, 65000, 0, peer, "192.0.2.254", true,
+				mrt.OPENCONFIRM, state))
+	}
+
+	data := stateChange("192.0.2.1", mrt.ESTABLISHED)
+	data = append(data, update("", "10.1.0.0")...)
+	data = append(data, update("", "10.2.0.0")...)
+	data = append(data, update("10.1.0.0", "")...)
+	data = append(data, stateChange("192.0.2.2", mrt.IDLE)...)
+
+	dir, err := ioutil.TempDir("", "mrt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "updates")
+	writeDump(t, filename, data)
+
+	source := NewMRT(Config{Id: "rs1", File: filename})
+	status, err := source.NeighboursStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status.Neighbours) != 2 {
+		t.Fatal("Expected 2 neighbors, got:", len(status.Neighbours))
+	}
+	if status.Neighbours[0].State != "up" || status.Neighbours[1].State != "down" {
+		t.Error("Unexpected neighbor status:", status.Neighbours)
+	}
+
+	routes, err := source.Routes("192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes.Imported) != 1 || routes.Imported[0].Network != "10.2.0.0/24" {
+		t.Error("Expected only 10.2.0.0/24 to remain, got:", routes.Imported)
+	}
+}
+
+func TestReloadDump(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mrt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	older := filepath.Join(dir, "bview.1")
+	newer := filepath.Join(dir, "bview.2")
+
+	writeDump(t, older, testTableDump(t))
+	os.Chtimes(older, time.Now().Add(-time.Hour), time.Now().Add(-time.Hour))
+
+	source := NewMRT(Config{Id: "rs1", File: dir})
+	dump, err := source.getDump()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dump.File != older {
+		t.Error("Expected", older, "to be loaded, got:", dump.File)
+	}
+
+	// A newer dump appears, but the reload
+	// interval has not passed yet.
+	writeDump(t, newer, testTableDump(t))
+	dump, _ = source.getDump()
+	if dump.File != older {
+		t.Error("Expected dump not to be reloaded yet")
+	}
+
+	source.checkedAt = time.Time{}
+	dump, _ = source.getDump()
+	if dump.File != newer {
+		t.Error("Expected", newer, "to be loaded, got:", dump.File)
+	}
+}
+
+func TestDumpCanceled(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mrt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeDump(t, filepath.Join(dir, "bview.1"), testTableDump(t))
+
+	source := NewMRT(Config{Id: "rs1", File: dir})
+
+	// The dump is still loading
+	source.Lock()
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = source.StatusContext(ctx)
+	if err != context.DeadlineExceeded {
+		t.Error("Expected deadline to be exceeded, got:", err)
+	}
+	source.Unlock()
+
+	status, err := source.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Status.Backend != "mrt" {
+		t.Error("Unexpected status:", status.Status)
+	}
+}
+
+func TestExpandMpReach(t *testing.T) {
+	attr, _ := testMpReachAbbreviated("2001:db8::1").Serialize()
+	attrs, err := decodePathAttributes(bgp.AFI_IP6, bgp.SAFI_UNICAST, attr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mpReach, ok := attrs[0].(*bgp.PathAttributeMpReachNLRI)
+	if !ok {
+		t.Fatal("Expected MP_REACH_NLRI, got:", attrs[0])
+	}
+	if mpReach.Nexthop.String() != "2001:db8::1" {
+		t.Error("Unexpected next hop:", mpReach.Nexthop)
+	}
+}
@@ -0,0 +1,76 @@
+package mrt
+
+import (
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Find the dump to load: If the path is a directory,
+// this is the most recently modified file in it.
+func findDump(path string) (string, time.Time, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if !info.IsDir() {
+		return path, info.ModTime(), nil
+	}
+
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	var latest os.FileInfo
+	for _, f := range files {
+		if !f.Mode().IsRegular() || strings.HasPrefix(f.Name(), ".") {
+			continue
+		}
+		if latest == nil || f.ModTime().After(latest.ModTime()) {
+			latest = f
+		}
+	}
+	if latest == nil {
+		return "", time.Time{}, fmt.Errorf("no dump found in %s", path)
+	}
+
+	return filepath.Join(path, latest.Name()), latest.ModTime(), nil
+}
+
+type dumpReader struct {
+	io.Reader
+	file *os.File
+}
+
+func (self *dumpReader) Close() error {
+	return self.file.Close()
+}
+
+// Open a dump, decompressing it if required
+func openDump(filename string) (io.ReadCloser, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	switch filepath.Ext(filename) {
+	case ".gz":
+		r, err := gzip.NewReader(file)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		return &dumpReader{Reader: r, file: file}, nil
+	case ".bz2":
+		return &dumpReader{Reader: bzip2.NewReader(file), file: file}, nil
+	}
+
+	return file, nil
+}
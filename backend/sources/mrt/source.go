@@ -0,0 +1,315 @@
+package mrt
+
+import (
+	"context"
+	"log"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alice-lg/alice-lg/backend/api"
+	"github.com/alice-lg/alice-lg/backend/sources"
+)
+
+const (
+	DEFAULT_RELOAD_INTERVAL = 300
+)
+
+/*
+MRT is a source serving the state read from an MRT dump,
+for example an archived RIB of a route server. The dump is
+replaced as soon as a newer one appears.
+*/
+type MRT struct {
+	config Config
+
+	dump      *Dump
+	checkedAt time.Time
+	sync.Mutex
+}
+
+func NewMRT(config Config) *MRT {
+	if config.ReloadInterval == 0 {
+		config.ReloadInterval = DEFAULT_RELOAD_INTERVAL
+	}
+
+	return &MRT{
+		config: config,
+	}
+}
+
+/*
+Get the current dump. When the reload interval has passed,
+we check for a newer dump and load it. If loading fails, the
+previous dump is served.
+*/
+func (self *MRT) getDump() (*Dump, error) {
+	self.Lock()
+	defer self.Unlock()
+
+	interval := time.Duration(self.config.ReloadInterval) * time.Second
+	if self.dump != nil && time.Since(self.checkedAt) < interval {
+		return self.dump, nil
+	}
+	self.checkedAt = time.Now()
+
+	filename, modTime, err := findDump(self.config.File)
+	if err != nil {
+		if self.dump != nil {
+			log.Println("MRT: Could not find dump:", err)
+			return self.dump, nil
+		}
+		return nil, err
+	}
+
+	if self.dump != nil &&
+		self.dump.File == filename &&
+		!modTime.After(self.dump.ModTime) {
+		return self.dump, nil // Nothing changed
+	}
+
+	log.Println("MRT: Loading dump", filename, "for", self.config.Name)
+	dump, err := loadDump(filename, modTime)
+	if err != nil {
+		if self.dump != nil {
+			log.Println("MRT: Could not load dump:", err)
+			return self.dump, nil
+		}
+		return nil, err
+	}
+	self.dump = dump
+
+	return dump, nil
+}
+
+/*
+Get the current dump for a request. Loading a dump is
+shared by all requests and finishes in the background,
+only the waiting request returns when the context is done.
+*/
+func (self *MRT) getDumpContext(ctx context.Context) (*Dump, error) {
+	type result struct {
+		dump *Dump
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		dump, err := self.getDump()
+		done <- result{dump: dump, err: err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.dump, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Make api status for responses
+func (self *MRT) makeApiStatus(dump *Dump) api.ApiStatus {
+	now := time.Now().UTC()
+	return api.ApiStatus{
+		Version:         "mrt",
+		ResultFromCache: false,
+		Ttl:             now.Add(time.Duration(self.config.ReloadInterval) * time.Second),
+		CacheStatus: api.CacheStatus{
+			CachedAt: dump.ModTime.UTC(),
+			OrigTtl:  self.config.ReloadInterval,
+		},
+	}
+}
+
+// Get a peer by neighbor id
+func (self *MRT) getPeer(dump *Dump, neighborId string) (*Peer, error) {
+	peer, ok := dump.Peers[neighborId]
+	if !ok {
+		return nil, &sources.NotFoundError{Resource: "neighbor", Id: neighborId}
+	}
+	return peer, nil
+}
+
+// The dump is not cached but replaced on reload
+func (self *MRT) ExpireCaches() int {
+	return 0
+}
+
+// A dump only has the accepted routes
+func (self *MRT) Capabilities() api.SourceCapabilities {
+	return api.SourceCapabilities{
+		RoutesReceived:    true,
+		RoutesFiltered:    false,
+		RoutesNotExported: false,
+		Status:            true,
+		LiveLookup:        false,
+		NeighboursStatus:  true,
+	}
+}
+
+func (self *MRT) StatusContext(ctx context.Context) (*api.StatusResponse, error) {
+	dump, err := self.getDumpContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	message := "MRT dump " + filepath.Base(dump.File)
+	if dump.ViewName != "" {
+		message += " (" + dump.ViewName + ")"
+	}
+
+	response := &api.StatusResponse{
+		Api: self.makeApiStatus(dump),
+		Status: api.Status{
+			ServerTime:   dump.Timestamp,
+			LastReboot:   dump.Timestamp,
+			LastReconfig: dump.Timestamp,
+			Message:      message,
+			RouterId:     dump.CollectorId,
+			Backend:      "mrt",
+		},
+	}
+
+	return response, nil
+}
+
+func (self *MRT) NeighboursContext(ctx context.Context) (*api.NeighboursResponse, error) {
+	dump, err := self.getDumpContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	neighbours := make(api.Neighbours, 0, len(dump.Peers))
+	for _, peer := range dump.Peers {
+		neighbours = append(neighbours, peer.neighbour(self.config.Id, dump.Timestamp))
+	}
+	sort.Sort(neighbours)
+
+	response := &api.NeighboursResponse{
+		Api:        self.makeApiStatus(dump),
+		Neighbours: neighbours,
+	}
+
+	return response, nil
+}
+
+// Get the neighbor status at the time of the dump
+func (self *MRT) NeighboursStatusContext(ctx context.Context) (*api.NeighboursStatusResponse, error) {
+	dump, err := self.getDumpContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := make(api.NeighboursStatus, 0, len(dump.Peers))
+	for _, peer := range dump.Peers {
+		n := peer.neighbour(self.config.Id, dump.Timestamp)
+		status = append(status, &api.NeighbourStatus{
+			Id:    n.Id,
+			State: n.State,
+			Since: n.Uptime,
+		})
+	}
+	sort.Sort(status)
+
+	response := &api.NeighboursStatusResponse{
+		Api:        self.makeApiStatus(dump),
+		Neighbours: status,
+	}
+
+	return response, nil
+}
+
+// Get the routes of the neighbor. A dump holds
+// only the accepted routes.
+func (self *MRT) RoutesContext(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
+	dump, err := self.getDumpContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	peer, err := self.getPeer(dump, neighborId)
+	if err != nil {
+		return nil, err
+	}
+
+	imported := peer.routes(dump.Timestamp)
+	sort.Sort(imported)
+
+	response := &api.RoutesResponse{
+		Api:         self.makeApiStatus(dump),
+		Imported:    imported,
+		Filtered:    api.Routes{},
+		NotExported: api.Routes{},
+	}
+
+	return response, nil
+}
+
+// Get all received routes
+func (self *MRT) RoutesReceivedContext(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
+	routes, err := self.RoutesContext(ctx, neighborId)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &api.RoutesResponse{
+		Api:      routes.Api,
+		Imported: routes.Imported,
+	}
+
+	return response, nil
+}
+
+// Filtered routes are not part of a dump
+func (self *MRT) RoutesFilteredContext(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
+	routes, err := self.RoutesContext(ctx, neighborId)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &api.RoutesResponse{
+		Api:      routes.Api,
+		Filtered: routes.Filtered,
+	}
+
+	return response, nil
+}
+
+// Not exported routes are not part of a dump
+func (self *MRT) RoutesNotExportedContext(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
+	routes, err := self.RoutesContext(ctx, neighborId)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &api.RoutesResponse{
+		Api:         routes.Api,
+		NotExported: routes.NotExported,
+	}
+
+	return response, nil
+}
+
+// Get the routes of all peers for the global search
+func (self *MRT) AllRoutesContext(ctx context.Context) (*api.RoutesResponse, error) {
+	dump, err := self.getDumpContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	imported := api.Routes{}
+	for _, peer := range dump.Peers {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		imported = append(imported, peer.routes(dump.Timestamp)...)
+	}
+
+	response := &api.RoutesResponse{
+		Api:      self.makeApiStatus(dump),
+		Imported: imported,
+		Filtered: api.Routes{},
+	}
+
+	return response, nil
+}
@@ -0,0 +1,66 @@
+package openbgpd
+
+// Http client for the openbgpd state server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type ClientResponse map[string]interface{}
+
+type Client struct {
+	Api string
+
+	http *http.Client
+}
+
+func NewClient(api string, timeout time.Duration) *Client {
+	client := &Client{
+		Api: strings.TrimSuffix(api, "/"),
+		http: &http.Client{
+			Timeout: timeout,
+		},
+	}
+	return client
+}
+
+// Make API request, decode the bgpctl json output
+// and return the map or an error
+func (self *Client) GetJson(endpoint string) (ClientResponse, error) {
+	return self.GetJsonContext(context.Background(), endpoint)
+}
+
+// Make API request, which is canceled with the context
+func (self *Client) GetJsonContext(
+	ctx context.Context,
+	endpoint string,
+) (ClientResponse, error) {
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodGet, self.Api+endpoint, nil)
+	if err != nil {
+		return ClientResponse{}, err
+	}
+	res, err := self.http.Do(req)
+	if err != nil {
+		return ClientResponse{}, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return ClientResponse{}, fmt.Errorf(
+			"Unexpected response from state server: %s", res.Status)
+	}
+
+	result := make(ClientResponse)
+	err = json.NewDecoder(res.Body).Decode(&result)
+	if err != nil {
+		return ClientResponse{}, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,48 @@
+package openbgpd
+
+import (
+	"github.com/alice-lg/alice-lg/backend/sources"
+
+	"github.com/go-ini/ini"
+)
+
+type Config struct {
+	Id   string
+	Name string
+
+	// Api is the base url of the openbgpd state server,
+	// which serves the JSON output of bgpctl.
+	Api string `ini:"api"`
+
+	// Timeout in seconds for requests to the state server
+	Timeout int `ini:"timeout"`
+
+	// CacheTtl is the time in seconds a response is
+	// considered to be valid.
+	CacheTtl int `ini:"cache_ttl"`
+}
+
+func init() {
+	sources.Register(&sources.Backend{
+		Name:      "openbgpd",
+		Configure: configure,
+		New: func(config interface{}) sources.Source {
+			return NewOpenBGPD(config.(Config))
+		},
+	})
+}
+
+// Parse the config section of an OpenBGPD source
+func configure(
+	base sources.Definition,
+	section *ini.Section,
+) ([]*sources.Definition, error) {
+	c := Config{
+		Id:   base.Id,
+		Name: base.Name,
+	}
+	section.MapTo(&c)
+
+	base.Config = c
+	return []*sources.Definition{&base}, nil
+}
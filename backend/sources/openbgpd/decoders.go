@@ -0,0 +1,284 @@
+package openbgpd
+
+// Decoders for the JSON output of bgpctl
+
+import (
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alice-lg/alice-lg/backend/api"
+)
+
+// Assert string, provide default
+func mustString(value interface{}, fallback string) string {
+	sval, ok := value.(string)
+	if !ok {
+		return fallback
+	}
+	return sval
+}
+
+// Get an int from a json number or a numeric string.
+// bgpctl encodes some numbers (e.g. the remote_as) as strings.
+func mustInt(value interface{}, fallback int) int {
+	switch v := value.(type) {
+	case float64:
+		return int(v)
+	case string:
+		ival, err := strconv.Atoi(v)
+		if err != nil {
+			return fallback
+		}
+		return ival
+	}
+	return fallback
+}
+
+func mustBool(value interface{}, fallback bool) bool {
+	val, ok := value.(bool)
+	if !ok {
+		return fallback
+	}
+	return val
+}
+
+// Assert nested object, returns an empty map when
+// the value is missing
+func mustMap(value interface{}) map[string]interface{} {
+	mval, ok := value.(map[string]interface{})
+	if !ok {
+		return map[string]interface{}{}
+	}
+	return mval
+}
+
+// Assert list of objects
+func mustMapList(value interface{}) []map[string]interface{} {
+	list := []map[string]interface{}{}
+	ldata, ok := value.([]interface{})
+	if !ok {
+		return list
+	}
+	for _, e := range ldata {
+		m, ok := e.(map[string]interface{})
+		if ok {
+			list = append(list, m)
+		}
+	}
+	return list
+}
+
+// Assert list of strings
+func mustStringList(value interface{}) []string {
+	list := []string{}
+	ldata, ok := value.([]interface{})
+	if !ok {
+		return list
+	}
+	for _, e := range ldata {
+		s, ok := e.(string)
+		if ok {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// Map the bgpctl session state to the up / down
+// state used by alice
+func decodeState(state string) string {
+	if strings.ToLower(state) == "established" {
+		return "up"
+	}
+	return "down"
+}
+
+// Decode a single neighbor from the bgpctl show neighbor output
+func decodeNeighbor(rsId string, data map[string]interface{}) *api.Neighbour {
+	stats := mustMap(data["stats"])
+	prefixes := mustMap(stats["prefixes"])
+
+	received := mustInt(prefixes["received"], 0)
+	uptime := time.Duration(mustInt(data["last_updown_sec"], 0)) * time.Second
+
+	neighbor := &api.Neighbour{
+		Id: mustString(data["remote_addr"], "unknown"),
+
+		Address:     mustString(data["remote_addr"], "error"),
+		Asn:         mustInt(data["remote_as"], 0),
+		State:       decodeState(mustString(data["state"], "unknown")),
+		Description: mustString(data["description"], "no description"),
+
+		RoutesReceived: received,
+		RoutesAccepted: received,
+		RoutesExported: mustInt(prefixes["sent"], 0),
+
+		Uptime:    uptime,
+		LastError: mustString(data["last_error"], ""),
+
+		RouteServerId: rsId,
+
+		Details: data,
+	}
+
+	return neighbor
+}
+
+// Decode the neighbors response
+func decodeNeighbors(rsId string, res ClientResponse) api.Neighbours {
+	neighbors := api.Neighbours{}
+	for _, data := range mustMapList(res["neighbors"]) {
+		neighbors = append(neighbors, decodeNeighbor(rsId, data))
+	}
+
+	sort.Sort(neighbors)
+
+	return neighbors
+}
+
+// Decode the neighbors summary into a status list
+func decodeNeighborsStatus(res ClientResponse) api.NeighboursStatus {
+	status := api.NeighboursStatus{}
+	for _, data := range mustMapList(res["neighbors"]) {
+		since := time.Duration(mustInt(data["last_updown_sec"], 0)) * time.Second
+		status = append(status, &api.NeighbourStatus{
+			Id:    mustString(data["remote_addr"], "unknown"),
+			State: decodeState(mustString(data["state"], "unknown")),
+			Since: since,
+		})
+	}
+
+	sort.Sort(status)
+
+	return status
+}
+
+// Decode an as path like "65001 65002 {65003 65004}".
+// AS sets are flattened.
+func decodeAsPath(path string) []int {
+	asPath := []int{}
+	path = strings.NewReplacer("{", " ", "}", " ", ",", " ").Replace(path)
+	for _, token := range strings.Fields(path) {
+		asn, err := strconv.Atoi(token)
+		if err != nil {
+			continue
+		}
+		asPath = append(asPath, asn)
+	}
+	return asPath
+}
+
+// Decode communities in the form of "65000:23" or "1:2:3"
+func decodeCommunities(value interface{}) api.Communities {
+	communities := api.Communities{}
+	for _, c := range mustStringList(value) {
+		community := api.Community{}
+		for _, part := range strings.Split(c, ":") {
+			val, err := strconv.Atoi(part)
+			if err != nil {
+				log.Println("Ignoring malformed community:", c)
+				community = nil
+				break
+			}
+			community = append(community, val)
+		}
+		if community != nil {
+			communities = append(communities, community)
+		}
+	}
+	return communities
+}
+
+// Decode extended communities in the form of "rt 65000:23"
+func decodeExtCommunities(value interface{}) api.ExtCommunities {
+	communities := api.ExtCommunities{}
+	for _, c := range mustStringList(value) {
+		tokens := strings.Fields(c)
+		if len(tokens) != 2 {
+			log.Println("Ignoring malformed ext community:", c)
+			continue
+		}
+		values := strings.SplitN(tokens[1], ":", 2)
+		if len(values) != 2 {
+			log.Println("Ignoring malformed ext community:", c)
+			continue
+		}
+		communities = append(communities, api.ExtCommunity{
+			tokens[0], values[0], values[1],
+		})
+	}
+	return communities
+}
+
+// Decode a rib entry into a route
+func decodeRoute(data map[string]interface{}) *api.Route {
+	neighbor := mustMap(data["neighbor"])
+	nextHop := mustString(data["exit_nexthop"], "unknown")
+	age := time.Duration(mustInt(data["last_update_sec"], 0)) * time.Second
+
+	bgp := api.BgpInfo{
+		Origin:           mustString(data["origin"], "unknown"),
+		AsPath:           decodeAsPath(mustString(data["aspath"], "")),
+		NextHop:          nextHop,
+		LocalPref:        mustInt(data["localpref"], 0),
+		Med:              mustInt(data["metric"], 0),
+		Communities:      decodeCommunities(data["communities"]),
+		LargeCommunities: decodeCommunities(data["large_communities"]),
+		ExtCommunities:   decodeExtCommunities(data["extended_communities"]),
+	}
+
+	route := &api.Route{
+		Id:          mustString(data["prefix"], "unknown"),
+		NeighbourId: mustString(neighbor["remote_addr"], "unknown neighbour"),
+
+		Network:   mustString(data["prefix"], "unknown net"),
+		Interface: "unknown",
+		Gateway:   nextHop,
+		Metric:    mustInt(data["metric"], 0),
+		Primary:   mustBool(data["best"], false),
+		Age:       age,
+		Type:      []string{"BGP"},
+		Bgp:       bgp,
+
+		Details: data,
+	}
+
+	return route
+}
+
+// Decode the rib response and split the routes into
+// accepted and filtered routes.
+func decodeRoutes(res ClientResponse) (api.Routes, api.Routes) {
+	imported := api.Routes{}
+	filtered := api.Routes{}
+
+	for _, data := range mustMapList(res["rib"]) {
+		route := decodeRoute(data)
+		if mustBool(data["filtered"], false) {
+			filtered = append(filtered, route)
+		} else {
+			imported = append(imported, route)
+		}
+	}
+
+	// Sort routes for deterministic ordering
+	sort.Sort(imported)
+	sort.Sort(filtered)
+
+	return imported, filtered
+}
+
+// Decode the state server status
+func decodeStatus(res ClientResponse) api.Status {
+	serverTime, _ := time.Parse(time.RFC3339, mustString(res["server_time"], ""))
+	return api.Status{
+		ServerTime: serverTime.UTC(),
+		Backend:    "openbgpd",
+		Version:    mustString(res["version"], "unknown"),
+		Message:    mustString(res["message"], "unknown"),
+		RouterId:   mustString(res["router_id"], "unknown"),
+	}
+}
@@ -0,0 +1,102 @@
+package openbgpd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+)
+
+// Load testdata from file
+func loadTestResponse(filename string) (ClientResponse, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return ClientResponse{}, err
+	}
+	result := make(ClientResponse)
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
+
+func TestDecodeNeighbors(t *testing.T) {
+	res, err := loadTestResponse("testdata/neighbors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	neighbors := decodeNeighbors("rs1", res)
+	if len(neighbors) != 2 {
+		t.Fatal("Expected 2 neighbors, got:", len(neighbors))
+	}
+
+	n := neighbors[0]
+	if n.Asn != 65001 {
+		t.Error("Expected ASN 65001, got:", n.Asn)
+	}
+	if n.Id != "192.0.2.1" || n.Address != "192.0.2.1" {
+		t.Error("Unexpected neighbor id / address:", n.Id, n.Address)
+	}
+	if n.State != "up" {
+		t.Error("Expected state up, got:", n.State)
+	}
+	if n.RoutesReceived != 3 || n.RoutesExported != 42 {
+		t.Error("Unexpected route counts:", n.RoutesReceived, n.RoutesExported)
+	}
+	if n.RouteServerId != "rs1" {
+		t.Error("Expected routeserver id rs1, got:", n.RouteServerId)
+	}
+
+	n = neighbors[1]
+	if n.State != "down" {
+		t.Error("Expected state down, got:", n.State)
+	}
+	if n.LastError != "Hold timer expired" {
+		t.Error("Unexpected last error:", n.LastError)
+	}
+}
+
+func TestDecodeRoutes(t *testing.T) {
+	res, err := loadTestResponse("testdata/rib.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imported, filtered := decodeRoutes(res)
+	if len(imported) != 1 {
+		t.Fatal("Expected 1 imported route, got:", len(imported))
+	}
+	if len(filtered) != 1 {
+		t.Fatal("Expected 1 filtered route, got:", len(filtered))
+	}
+
+	r := imported[0]
+	if r.Network != "198.51.100.0/24" {
+		t.Error("Unexpected network:", r.Network)
+	}
+	if r.NeighbourId != "192.0.2.1" {
+		t.Error("Unexpected neighbor id:", r.NeighbourId)
+	}
+	if len(r.Bgp.AsPath) != 2 || r.Bgp.AsPath[1] != 65010 {
+		t.Error("Unexpected as path:", r.Bgp.AsPath)
+	}
+	if len(r.Bgp.Communities) != 2 || r.Bgp.Communities[1].String() != "65001:23" {
+		t.Error("Unexpected communities:", r.Bgp.Communities)
+	}
+	if len(r.Bgp.LargeCommunities) != 1 {
+		t.Error("Unexpected large communities:", r.Bgp.LargeCommunities)
+	}
+	if len(r.Bgp.ExtCommunities) != 1 ||
+		r.Bgp.ExtCommunities[0].String() != "rt:65000:100" {
+		t.Error("Unexpected ext communities:", r.Bgp.ExtCommunities)
+	}
+	if r.Bgp.Med != 10 || r.Bgp.LocalPref != 100 {
+		t.Error("Unexpected med / local pref:", r.Bgp.Med, r.Bgp.LocalPref)
+	}
+	if !r.Primary {
+		t.Error("Expected route to be primary")
+	}
+
+	// AS sets are flattened
+	if len(filtered[0].Bgp.AsPath) != 3 {
+		t.Error("Unexpected as path:", filtered[0].Bgp.AsPath)
+	}
+}
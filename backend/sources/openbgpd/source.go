@@ -0,0 +1,331 @@
+package openbgpd
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/alice-lg/alice-lg/backend/api"
+	"github.com/alice-lg/alice-lg/backend/caches"
+)
+
+const (
+	// Endpoints of the openbgpd state server
+	ENDPOINT_STATUS            = "/v1/status"
+	ENDPOINT_NEIGHBORS         = "/v1/bgpd/show/neighbor"
+	ENDPOINT_NEIGHBORS_SUMMARY = "/v1/bgpd/show/summary"
+	ENDPOINT_RIB               = "/v1/bgpd/show/rib/detail"
+	ENDPOINT_RIB_IN            = "/v1/bgpd/show/rib/in/detail"
+	ENDPOINT_RIB_IN_NEIGHBOR   = "/v1/bgpd/show/rib/in/neighbor/%s/detail"
+	ENDPOINT_RIB_OUT_NEIGHBOR  = "/v1/bgpd/show/rib/out/neighbor/%s/detail"
+	DEFAULT_TIMEOUT            = 30
+	DEFAULT_CACHE_TTL          = 300
+)
+
+type OpenBGPD struct {
+	config Config
+	client *Client
+
+	// Caches: Neighbors
+	neighborsCache *caches.NeighborsCache
+
+	// Caches: Routes
+	routesRequiredCache    *caches.RoutesCache
+	routesNotExportedCache *caches.RoutesCache
+}
+
+func NewOpenBGPD(config Config) *OpenBGPD {
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = DEFAULT_TIMEOUT
+	}
+	if config.CacheTtl == 0 {
+		config.CacheTtl = DEFAULT_CACHE_TTL
+	}
+
+	client := NewClient(config.Api, time.Duration(timeout)*time.Second)
+
+	// Cache settings:
+	// TODO: Maybe read from config file
+	neighborsCacheDisable := false
+
+	routesCacheDisabled := false
+	routesCacheMaxSize := 128
+
+	// Initialize caches
+	neighborsCache := caches.NewNeighborsCache(neighborsCacheDisable)
+	routesRequiredCache := caches.NewRoutesCache(
+		routesCacheDisabled, routesCacheMaxSize)
+	routesNotExportedCache := caches.NewRoutesCache(
+		routesCacheDisabled, routesCacheMaxSize)
+
+	return &OpenBGPD{
+		config: config,
+		client: client,
+
+		neighborsCache: neighborsCache,
+
+		routesRequiredCache:    routesRequiredCache,
+		routesNotExportedCache: routesNotExportedCache,
+	}
+}
+
+// Make api status for responses. As the state server
+// does not provide any caching information, the ttl
+// is derived from the configured cache ttl.
+func (self *OpenBGPD) makeApiStatus() api.ApiStatus {
+	now := time.Now().UTC()
+	return api.ApiStatus{
+		Version:         "openbgpd",
+		ResultFromCache: false,
+		Ttl:             now.Add(time.Duration(self.config.CacheTtl) * time.Second),
+		CacheStatus: api.CacheStatus{
+			CachedAt: now,
+			OrigTtl:  self.config.CacheTtl,
+		},
+	}
+}
+
+// Get the path of an endpoint for a neighbor
+func neighborEndpoint(endpoint string, neighborId string) string {
+	return fmt.Sprintf(endpoint, url.PathEscape(neighborId))
+}
+
+func (self *OpenBGPD) ExpireCaches() int {
+	count := self.routesRequiredCache.Expire()
+	count += self.routesNotExportedCache.Expire()
+
+	return count
+}
+
+func (self *OpenBGPD) Capabilities() api.SourceCapabilities {
+	return api.SourceCapabilities{
+		RoutesReceived:    true,
+		RoutesFiltered:    true,
+		RoutesNotExported: true,
+		Status:            true,
+		LiveLookup:        false,
+		NeighboursStatus:  true,
+	}
+}
+
+func (self *OpenBGPD) StatusContext(ctx context.Context) (*api.StatusResponse, error) {
+	res, err := self.client.GetJsonContext(ctx, ENDPOINT_STATUS)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &api.StatusResponse{
+		Api:    self.makeApiStatus(),
+		Status: decodeStatus(res),
+	}
+
+	return response, nil
+}
+
+func (self *OpenBGPD) NeighboursContext(ctx context.Context) (*api.NeighboursResponse, error) {
+	// Check if we hit the cache
+	response := self.neighborsCache.Get()
+	if response != nil {
+		return response, nil
+	}
+
+	res, err := self.client.GetJsonContext(ctx, ENDPOINT_NEIGHBORS)
+	if err != nil {
+		return nil, err
+	}
+
+	response = &api.NeighboursResponse{
+		Api:        self.makeApiStatus(),
+		Neighbours: decodeNeighbors(self.config.Id, res),
+	}
+
+	// Cache result
+	self.neighborsCache.Set(response)
+
+	return response, nil
+}
+
+// Get live neighbor status
+func (self *OpenBGPD) NeighboursStatusContext(ctx context.Context) (*api.NeighboursStatusResponse, error) {
+	res, err := self.client.GetJsonContext(ctx, ENDPOINT_NEIGHBORS_SUMMARY)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &api.NeighboursStatusResponse{
+		Api:        self.makeApiStatus(),
+		Neighbours: decodeNeighborsStatus(res),
+	}
+
+	return response, nil
+}
+
+/*
+fetchRequiredRoutes gets the Adj-RIB-In of the neighbor
+and splits it into accepted and filtered routes.
+*/
+func (self *OpenBGPD) fetchRequiredRoutes(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
+	// Check if we have a cache hit
+	response := self.routesRequiredCache.Get(neighborId)
+	if response != nil {
+		return response, nil
+	}
+
+	res, err := self.client.GetJsonContext(ctx,
+		neighborEndpoint(ENDPOINT_RIB_IN_NEIGHBOR, neighborId))
+	if err != nil {
+		return nil, err
+	}
+
+	imported, filtered := decodeRoutes(res)
+
+	response = &api.RoutesResponse{
+		Api:      self.makeApiStatus(),
+		Imported: imported,
+		Filtered: filtered,
+	}
+
+	// Cache result
+	self.routesRequiredCache.Set(neighborId, response)
+
+	return response, nil
+}
+
+/*
+fetchNotExportedRoutes calculates the routes not exported
+to a neighbor: These are all best routes in the Loc-RIB,
+which are not present in the Adj-RIB-Out of the neighbor.
+*/
+func (self *OpenBGPD) fetchNotExportedRoutes(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
+	// Check if we have a cache hit
+	response := self.routesNotExportedCache.Get(neighborId)
+	if response != nil {
+		return response, nil
+	}
+
+	ribRes, err := self.client.GetJsonContext(ctx, ENDPOINT_RIB)
+	if err != nil {
+		return nil, err
+	}
+
+	outRes, err := self.client.GetJsonContext(ctx,
+		neighborEndpoint(ENDPOINT_RIB_OUT_NEIGHBOR, neighborId))
+	if err != nil {
+		return nil, err
+	}
+
+	rib, _ := decodeRoutes(ribRes)
+	exported, _ := decodeRoutes(outRes)
+
+	exportedKeys := make(map[string]bool, len(exported))
+	for _, route := range exported {
+		exportedKeys[route.Network] = true
+	}
+
+	notExported := api.Routes{}
+	for _, route := range rib {
+		// Routes learned from the neighbor are never
+		// sent back to it.
+		if route.NeighbourId == neighborId || !route.Primary {
+			continue
+		}
+		if exportedKeys[route.Network] {
+			continue
+		}
+		notExported = append(notExported, route)
+	}
+
+	// Sort routes for deterministic ordering
+	sort.Sort(notExported)
+
+	response = &api.RoutesResponse{
+		Api:         self.makeApiStatus(),
+		NotExported: notExported,
+	}
+
+	// Cache result
+	self.routesNotExportedCache.Set(neighborId, response)
+
+	return response, nil
+}
+
+// Get filtered, accepted and not exported routes
+func (self *OpenBGPD) RoutesContext(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
+	required, err := self.fetchRequiredRoutes(ctx, neighborId)
+	if err != nil {
+		return nil, err
+	}
+
+	notExported, err := self.fetchNotExportedRoutes(ctx, neighborId)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &api.RoutesResponse{
+		Api:         required.Api,
+		Imported:    required.Imported,
+		Filtered:    required.Filtered,
+		NotExported: notExported.NotExported,
+	}
+
+	return response, nil
+}
+
+// Get all received routes
+func (self *OpenBGPD) RoutesReceivedContext(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
+	routes, err := self.fetchRequiredRoutes(ctx, neighborId)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &api.RoutesResponse{
+		Api:      routes.Api,
+		Imported: routes.Imported,
+	}
+
+	return response, nil
+}
+
+// Get all filtered routes
+func (self *OpenBGPD) RoutesFilteredContext(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
+	routes, err := self.fetchRequiredRoutes(ctx, neighborId)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &api.RoutesResponse{
+		Api:      routes.Api,
+		Filtered: routes.Filtered,
+	}
+
+	return response, nil
+}
+
+// Get all not exported routes
+func (self *OpenBGPD) RoutesNotExportedContext(ctx context.Context, neighborId string) (*api.RoutesResponse, error) {
+	return self.fetchNotExportedRoutes(ctx, neighborId)
+}
+
+/*
+AllRoutes returns the Adj-RIB-In of all neighbors, which
+is used to build up the local store for searching.
+*/
+func (self *OpenBGPD) AllRoutesContext(ctx context.Context) (*api.RoutesResponse, error) {
+	res, err := self.client.GetJsonContext(ctx, ENDPOINT_RIB_IN)
+	if err != nil {
+		return nil, err
+	}
+
+	imported, filtered := decodeRoutes(res)
+
+	response := &api.RoutesResponse{
+		Api:      self.makeApiStatus(),
+		Imported: imported,
+		Filtered: filtered,
+	}
+
+	return response, nil
+}
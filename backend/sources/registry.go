@@ -0,0 +1,115 @@
+package sources
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-ini/ini"
+)
+
+/*
+Source backends:
+
+Each backend registers itself when its package is
+initialized, usually with
+
+    func init() {
+        sources.Register(&sources.Backend{...})
+    }
+
+The backend of a source is selected by the name of its
+config section, e.g. [source.rs1.birdwatcher] is a source
+of the birdwatcher backend.
+
+Backends are compiled in by importing their package,
+e.g. out-of-tree backends with a blank import.
+*/
+
+// A Definition describes a source, made from its config
+type Definition struct {
+	Id         string
+	Name       string
+	Group      string
+	Blackholes []string
+
+	// LiveLookup is set when prefix lookups are answered
+	// by the source itself instead of the routes store.
+	LiveLookup bool
+
+	// Config is the configuration of the backend,
+	// passed to its constructor.
+	Config interface{}
+}
+
+// A Backend makes sources
+type Backend struct {
+	// The name of the backend, e.g. birdwatcher
+	Name string
+
+	// Configure parses the config section of a source. The
+	// base definition is made from the source section.
+	// Usually a section configures a single source, however
+	// a backend might provide more, e.g. the VRFs of a GoBGP
+	// daemon.
+	Configure func(base Definition, section *ini.Section) ([]*Definition, error)
+
+	// New makes a source from the config of a definition
+	New func(config interface{}) Source
+
+	// Eager backends make their sources when Alice starts
+	// instead of on the first request, e.g. to accept
+	// BMP sessions.
+	Eager bool
+}
+
+var (
+	backends     = make(map[string]*Backend)
+	backendsLock sync.RWMutex
+)
+
+// Register a backend. Registering a name twice is an error.
+func Register(backend *Backend) {
+	if backend == nil || backend.Name == "" {
+		panic("sources: register backend without a name")
+	}
+	if backend.Configure == nil || backend.New == nil {
+		panic("sources: register incomplete backend " + backend.Name)
+	}
+
+	backendsLock.Lock()
+	defer backendsLock.Unlock()
+
+	if _, ok := backends[backend.Name]; ok {
+		panic("sources: register backend twice: " + backend.Name)
+	}
+	backends[backend.Name] = backend
+}
+
+// Get the names of the registered backends
+func Backends() []string {
+	backendsLock.RLock()
+	defer backendsLock.RUnlock()
+
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get a registered backend by name
+func GetBackend(name string) (*Backend, error) {
+	backendsLock.RLock()
+	backend, ok := backends[name]
+	backendsLock.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf(
+			"unknown backend '%s', available backends are: %s",
+			name, strings.Join(Backends(), ", "))
+	}
+	return backend, nil
+}
@@ -0,0 +1,63 @@
+package sources
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-ini/ini"
+)
+
+func testBackend(name string) *Backend {
+	return &Backend{
+		Name: name,
+		Configure: func(base Definition, section *ini.Section) ([]*Definition, error) {
+			base.Config = section.Key("api").String()
+			return []*Definition{&base}, nil
+		},
+		New: func(config interface{}) Source {
+			return &blockingSource{}
+		},
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register(testBackend("test_registered"))
+
+	backend, err := GetBackend("test_registered")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backend.Name != "test_registered" {
+		t.Error("Unexpected backend:", backend.Name)
+	}
+
+	found := false
+	for _, name := range Backends() {
+		found = found || name == "test_registered"
+	}
+	if !found {
+		t.Error("Expected backend in:", Backends())
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	Register(testBackend("test_twice"))
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected registering a backend twice to panic")
+		}
+	}()
+	Register(testBackend("test_twice"))
+}
+
+func TestGetUnknownBackend(t *testing.T) {
+	Register(testBackend("test_known"))
+
+	_, err := GetBackend("unknown")
+	if err == nil {
+		t.Fatal("Expected an error for an unknown backend")
+	}
+	if !strings.Contains(err.Error(), "test_known") {
+		t.Error("Expected the registered backends in the error, got:", err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 type Source interface {
 	ExpireCaches() int
+	Capabilities() api.SourceCapabilities
 	Status() (*api.StatusResponse, error)
 	Neighbours() (*api.NeighboursResponse, error)
 	NeighboursStatus() (*api.NeighboursStatusResponse, error)
@@ -15,3 +16,9 @@ type Source interface {
 	RoutesNotExported(neighbourId string) (*api.RoutesResponse, error)
 	AllRoutes() (*api.RoutesResponse, error)
 }
+
+// A PrefixLookupSource can look up a prefix on the route
+// server directly, instead of searching the routes store.
+type PrefixLookupSource interface {
+	LookupPrefix(prefix string) (*api.RoutesLookupResponse, error)
+}
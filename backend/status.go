@@ -1,5 +1,9 @@
 package main
 
+import (
+	"github.com/alice-lg/alice-lg/backend/api"
+)
+
 var version = "unknown"
 
 // Gather application status information
@@ -7,6 +11,9 @@ type AppStatus struct {
 	Version    string               `json:"version"`
 	Routes     RoutesStoreStats     `json:"routes"`
 	Neighbours NeighboursStoreStats `json:"neighbours"`
+
+	// The health of the sources by id
+	Sources map[string]api.SourceHealth `json:"sources"`
 }
 
 // Get application status, perform health checks
@@ -22,10 +29,20 @@ func NewAppStatus() (*AppStatus, error) {
 		neighboursStatus = AliceNeighboursStore.Stats()
 	}
 
+	sourcesHealth := make(map[string]api.SourceHealth)
+	if AliceConfig != nil {
+		for _, source := range AliceConfig.Sources {
+			if health := source.getHealth(); health != nil {
+				sourcesHealth[source.Id] = *health
+			}
+		}
+	}
+
 	status := &AppStatus{
 		Version:    version,
 		Routes:     routesStatus,
 		Neighbours: neighboursStatus,
+		Sources:    sourcesHealth,
 	}
 	return status, nil
 }
@@ -15,6 +15,10 @@ type StoreStatus struct {
 	LastRefresh time.Time
 	LastError   error
 	State       int
+
+	// Parts of the source, e.g. tables, which failed
+	// while the remaining result was stored
+	Failed map[string]error
 }
 
 // Helper: stateToString
@@ -18,6 +18,8 @@ type RouteServerRoutesStats struct {
 
 	State     string    `json:"state"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	FailedTables map[string]string `json:"failed_tables,omitempty"`
 }
 
 type RoutesStoreStats struct {
@@ -43,6 +45,9 @@ func (stats RoutesStoreStats) Log() {
 			rs.Routes.Imported,
 			"Filtered:",
 			rs.Routes.Filtered)
+		if len(rs.FailedTables) > 0 {
+			log.Println("        Failed Tables:", len(rs.FailedTables))
+		}
 	}
 }
 
@@ -53,6 +58,8 @@ type RouteServerNeighboursStats struct {
 	State      string    `json:"state"`
 	Neighbours int       `json:"neighbours"`
 	UpdatedAt  time.Time `json:"updated_at"`
+
+	FailedTables map[string]string `json:"failed_tables,omitempty"`
 }
 
 type NeighboursStoreStats struct {
@@ -2,6 +2,7 @@ package main
 
 // Some helper functions
 import (
+	"net"
 	"regexp"
 	"strconv"
 	"strings"
@@ -52,6 +53,17 @@ func MaybePrefix(s string) bool {
 	return false
 }
 
+/*
+ Check if something is a complete prefix or address,
+ which can be looked up by the sources directly
+*/
+func IsPrefixOrAddress(s string) bool {
+	if _, _, err := net.ParseCIDR(s); err == nil {
+		return true
+	}
+	return net.ParseIP(s) != nil
+}
+
 /*
  Since havin ints as keys in json is
  acutally undefined behaviour, we keep these interally
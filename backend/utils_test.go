@@ -36,6 +36,28 @@ func TestMaybePrefix(t *testing.T) {
 	}
 }
 
+func TestIsPrefixOrAddress(t *testing.T) {
+	expected := []struct {
+		string
+		bool
+	}{
+		{"10.0.0.0/8", true},
+		{"10.23.42.1", true},
+		{"2001:db8::/32", true},
+		{"2001:db8::1", true},
+		{"10.0", false},
+		{"10.23.", false},
+		{"2001:", false},
+		{"AS2342", false},
+	}
+
+	for _, e := range expected {
+		if IsPrefixOrAddress(e.string) != e.bool {
+			t.Error("Expected", e.string, "to be a prefix or address:", e.bool)
+		}
+	}
+}
+
 func TestTrimmedStringList(t *testing.T) {
 	l := TrimmedStringList("foo, bar   , dreiundzwanzig,")
 
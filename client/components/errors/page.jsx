@@ -35,6 +35,14 @@ class ErrorsPage extends React.Component {
     // Find affected routeserver
     let rs = null;
     const errorInfo = infoFromError(this.props.error);
+
+    // Views not supported by a route server are not errors:
+    // the routes page uses the capabilities of the route server
+    // to show them as not available instead.
+    if (errorInfo && errorInfo.tag == "NOT_SUPPORTED") {
+      return null;
+    }
+
     if (errorInfo) {
       const rsId = errorInfo.routeserver_id; 
       if (rsId !== null) {
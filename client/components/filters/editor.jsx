@@ -14,12 +14,14 @@ import {FILTER_GROUP_SOURCES,
         FILTER_GROUP_ASNS,
         FILTER_GROUP_COMMUNITIES,
         FILTER_GROUP_EXT_COMMUNITIES,
-        FILTER_GROUP_LARGE_COMMUNITIES}
+        FILTER_GROUP_LARGE_COMMUNITIES,
+        FILTER_GROUP_FAMILIES}
   from './groups'
 
 import {RouteserversSelect,
         PeersFilterSelect,
-        CommunitiesSelect}
+        CommunitiesSelect,
+        FamiliesSelect}
  from './widgets'
 
 /*
@@ -91,6 +93,11 @@ class FiltersEditor extends React.Component {
                            onRemove={(group, value) => this.removeFilter(group, value)}
                            available={this.props.availableCommunities}
                            applied={this.props.appliedCommunities} />
+
+        <FamiliesSelect onChange={(value) => this.addFilter(FILTER_GROUP_FAMILIES, value)}
+                        onRemove={(value) => this.removeFilter(FILTER_GROUP_FAMILIES, value)}
+                        available={this.props.availableFamilies}
+                        applied={this.props.appliedFamilies} />
       </div>
     );
   }
@@ -121,6 +128,9 @@ export default connect(
       ext:         props.filtersApplied[FILTER_GROUP_EXT_COMMUNITIES].filters,
       large:       props.filtersApplied[FILTER_GROUP_LARGE_COMMUNITIES].filters,
     },
+
+    availableFamilies: props.filtersAvailable[FILTER_GROUP_FAMILIES].filters,
+    appliedFamilies:   props.filtersApplied[FILTER_GROUP_FAMILIES].filters,
   })
 )(FiltersEditor);
 
@@ -5,6 +5,7 @@ import {
   FILTER_GROUP_COMMUNITIES,
   FILTER_GROUP_EXT_COMMUNITIES,
   FILTER_GROUP_LARGE_COMMUNITIES,
+  FILTER_GROUP_FAMILIES,
 } from './groups'
 
 
@@ -65,6 +66,14 @@ export function decodeFiltersLargeCommunities(params) {
   return communities.map((c) => _makeFilter(_decodeCommunity(c)));
 }
 
+export function decodeFiltersFamilies(params) {
+  if (!params.families) {
+    return []; // No params available
+  }
+  const families = params.families.split(",");
+  return families.map((family) => _makeFilter(family));
+}
+
 
 export function encodeGroupInt(group) {
   if (!group.filters.length) {
@@ -90,6 +99,7 @@ export function filtersUrlEncode(filters) {
   encoded += encodeGroupCommunities(filters[FILTER_GROUP_COMMUNITIES]);
   encoded += encodeGroupCommunities(filters[FILTER_GROUP_EXT_COMMUNITIES]);
   encoded += encodeGroupCommunities(filters[FILTER_GROUP_LARGE_COMMUNITIES]);
+  encoded += encodeGroupInt(filters[FILTER_GROUP_FAMILIES]);
 
   return encoded;
 }
@@ -4,12 +4,14 @@ export const FILTER_KEY_ASNS = "asns"
 export const FILTER_KEY_COMMUNITIES = "communities"
 export const FILTER_KEY_EXT_COMMUNITIES = "ext_communities"
 export const FILTER_KEY_LARGE_COMMUNITIES = "large_communities"
+export const FILTER_KEY_FAMILIES = "families"
 
 export const FILTER_GROUP_SOURCES = 0
 export const FILTER_GROUP_ASNS = 1
 export const FILTER_GROUP_COMMUNITIES = 2
 export const FILTER_GROUP_EXT_COMMUNITIES = 3
 export const FILTER_GROUP_LARGE_COMMUNITIES = 4
+export const FILTER_GROUP_FAMILIES = 5
 
 
 export function filtersEqual(a, b) {
@@ -26,6 +28,9 @@ export function filtersEqual(a, b) {
           b[FILTER_GROUP_EXT_COMMUNITIES].filters.length) &&
 
          (a[FILTER_GROUP_LARGE_COMMUNITIES].filters.length ===
-          b[FILTER_GROUP_LARGE_COMMUNITIES].filters.length);
+          b[FILTER_GROUP_LARGE_COMMUNITIES].filters.length) &&
+
+         (a[FILTER_GROUP_FAMILIES].filters.length ===
+          b[FILTER_GROUP_FAMILIES].filters.length);
 }
 
@@ -5,14 +5,16 @@ import {FILTER_GROUP_SOURCES,
         FILTER_GROUP_ASNS,
         FILTER_GROUP_COMMUNITIES,
         FILTER_GROUP_EXT_COMMUNITIES,
-        FILTER_GROUP_LARGE_COMMUNITIES}
+        FILTER_GROUP_LARGE_COMMUNITIES,
+        FILTER_GROUP_FAMILIES}
   from './groups'
 
 import {decodeFiltersSources,
         decodeFiltersAsns,
         decodeFiltersCommunities,
         decodeFiltersExtCommunities,
-        decodeFiltersLargeCommunities}
+        decodeFiltersLargeCommunities,
+        decodeFiltersFamilies}
   from 'components/filters/encoding'
 
 export const initializeFilterState = () => ([
@@ -21,6 +23,7 @@ export const initializeFilterState = () => ([
   {"key": "communities", "filters": []},
   {"key": "ext_communities", "filters": []},
   {"key": "large_communities", "filters": []},
+  {"key": "families", "filters": []},
 ]);
 
 export function cloneFilters(filters) {
@@ -30,6 +33,7 @@ export function cloneFilters(filters) {
     Object.assign({}, filters[FILTER_GROUP_COMMUNITIES]),
     Object.assign({}, filters[FILTER_GROUP_EXT_COMMUNITIES]),
     Object.assign({}, filters[FILTER_GROUP_LARGE_COMMUNITIES]),
+    Object.assign({}, filters[FILTER_GROUP_FAMILIES]),
   ];
 
   nextFilters[FILTER_GROUP_SOURCES].filters =
@@ -47,6 +51,9 @@ export function cloneFilters(filters) {
   nextFilters[FILTER_GROUP_LARGE_COMMUNITIES].filters =
     [...nextFilters[FILTER_GROUP_LARGE_COMMUNITIES].filters];
 
+  nextFilters[FILTER_GROUP_FAMILIES].filters =
+    [...nextFilters[FILTER_GROUP_FAMILIES].filters];
+
   return nextFilters;
 }
 
@@ -61,6 +68,7 @@ export function decodeFiltersApplied(params) {
   groups[FILTER_GROUP_COMMUNITIES].filters =       decodeFiltersCommunities(params);
   groups[FILTER_GROUP_EXT_COMMUNITIES].filters =   decodeFiltersExtCommunities(params);
   groups[FILTER_GROUP_LARGE_COMMUNITIES].filters = decodeFiltersLargeCommunities(params);
+  groups[FILTER_GROUP_FAMILIES].filters =          decodeFiltersFamilies(params);
 
   return groups;
 }
@@ -76,6 +84,7 @@ function _mergeFilters(a, b) {
   setCmp[FILTER_GROUP_COMMUNITIES] = cmpFilterCommunity;
   setCmp[FILTER_GROUP_EXT_COMMUNITIES] = cmpFilterCommunity;
   setCmp[FILTER_GROUP_LARGE_COMMUNITIES] = cmpFilterCommunity;
+  setCmp[FILTER_GROUP_FAMILIES] = cmpFilterValue;
 
   for (const i in groups) {
     groups[i].filters = mergeFilterSet(setCmp[i], a[i].filters, b[i].filters);
@@ -168,6 +168,72 @@ class _PeersFilterSelect extends React.Component {
 export const PeersFilterSelect = withTitle("Neighbor")(_PeersFilterSelect);
 
 
+class _FamiliesSelect extends React.Component {
+  render() {
+    // Nothing to do if we don't have filters
+    if (this.props.available.length == 0 &&
+        this.props.applied.length == 0) {
+      return null;
+    }
+
+    // Sort filters available
+    const sortedFiltersAvailable = this.props.available.sort((a, b) => {
+      return a.value.localeCompare(b.value);
+    });
+
+    // For now we allow only one applied
+    const appliedFilter = this.props.applied[0] || {value: undefined};
+
+    if (appliedFilter.value !== undefined) {
+      // Just render this, with a button for removal
+      return (
+        <table className="select-ctrl">
+          <tbody>
+            <tr>
+              <td className="select-container">
+                {appliedFilter.value}
+              </td>
+              <td>
+                <button className="btn btn-remove"
+                        onClick={() => this.props.onRemove(appliedFilter.value)}>
+                  <i className="fa fa-times" />
+                </button>
+              </td>
+            </tr>
+          </tbody>
+        </table>
+      );
+    }
+
+    // Build options
+    const optionsAvailable = sortedFiltersAvailable.map((filter) => {
+      return <option key={filter.value} value={filter.value}>
+          {filter.value} ({filter.cardinality})
+        </option>;
+    });
+
+    return (
+      <table className="select-ctrl">
+        <tbody>
+          <tr>
+            <td className="select-container">
+              <select className="form-control"
+                      onChange={(e) => this.props.onChange(e.target.value)}
+                      value={appliedFilter.value}>
+                <option value="none" className="options-title">Show only routes of family...</option>
+                {optionsAvailable}
+              </select>
+            </td>
+          </tr>
+        </tbody>
+      </table>
+    );
+  }
+}
+
+export const FamiliesSelect = withTitle("Address Family")(_FamiliesSelect);
+
+
 class __CommunitiesSelect extends React.Component {
   propagateChange(value) {
     // Decode value
@@ -56,6 +56,25 @@ class BgpAttributesModal extends React.Component {
         <Body>
           <table className="table table-nolines">
            <tbody>
+            {attrs.family &&
+                <tr>
+                  <th>Address Family:</th><td>{attrs.family}</td>
+                </tr>}
+            {attrs.route_distinguisher &&
+                <tr>
+                  <th>Route Distinguisher:</th><td>{attrs.route_distinguisher}</td>
+                </tr>}
+            {attrs.labels && attrs.labels.length > 0 &&
+                <tr>
+                  <th>Labels:</th><td>{attrs.labels.join(' ')}</td>
+                </tr>}
+            {attrs.flowspec && attrs.flowspec.length > 0 &&
+                <tr>
+                  <th>FlowSpec:</th>
+                  <td>
+                    {attrs.flowspec.map((rule, i) => <div key={i}>{rule.type}: {rule.value}</div>)}
+                  </td>
+                </tr>}
             <tr>
               <th>Origin:</th><td>{attrs.bgp.origin}</td>
             </tr>
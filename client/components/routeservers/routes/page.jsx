@@ -30,7 +30,8 @@ import BgpAttributesModal
 
 import RoutesLoadingIndicator from './loading-indicator'
 
-import {filterableColumnsText} from './utils'
+import {filterableColumnsText,
+        routesSupported} from './utils'
 
 import FiltersEditor from 'components/filters/editor'
 import {mergeFilters} from 'components/filters/state'
@@ -243,6 +244,7 @@ export default connect(
     const rsId = props.params.routeserverId;
     const neighbors = state.routeservers.protocols[rsId];
     const neighbor = _.findWhere(neighbors, {id: protocolId});
+    const routeserver = state.routeservers.byId[rsId];
 
     // Find related peers. Peers belonging to the same AS.
     let localRelatedPeers = [];
@@ -254,17 +256,20 @@ export default connect(
     const received = {
       loading:      state.routes.receivedLoading,
       totalResults: state.routes.receivedTotalResults,
-      apiStatus:    state.routes.receivedApiStatus
+      apiStatus:    state.routes.receivedApiStatus,
+      supported:    routesSupported(routeserver, ROUTES_RECEIVED)
     };
     const filtered = {
       loading:      state.routes.filteredLoading,
       totalResults: state.routes.filteredTotalResults,
-      apiStatus:    state.routes.filteredApiStatus
+      apiStatus:    state.routes.filteredApiStatus,
+      supported:    routesSupported(routeserver, ROUTES_FILTERED)
     };
     const notExported = {
       loading:      state.routes.notExportedLoading,
       totalResults: state.routes.notExportedTotalResults,
-      apiStatus:    state.routes.notExportedApiStatus
+      apiStatus:    state.routes.notExportedApiStatus,
+      supported:    routesSupported(routeserver, ROUTES_NOT_EXPORTED)
     };
     const anyLoading = state.routes.receivedLoading ||
                      state.routes.filteredLoading ||
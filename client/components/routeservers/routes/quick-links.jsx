@@ -24,7 +24,8 @@ const QuickLinks = function(props) {
   let showNotExported = (!props.routes.notExported.loading &&
                           props.routes.notExported.totalResults > 0);
   let excludeNotExported = props.excludeNotExported || false;
-  if (props.loadNotExportedOnDemand && !excludeNotExported) {
+  if (props.loadNotExportedOnDemand && !excludeNotExported &&
+      props.routes.notExported.supported !== false) {
     // Show the link when nothing else is loading anymore
     showNotExported = !isLoading;
   }
@@ -45,6 +45,10 @@ export const ColNetwork = function(props) {
       <span className="route-network" onClick={props.onClick}>
         {props.route.network} 
       </span>
+      {props.route.route_distinguisher &&
+        <span className="route-distinguisher">
+          RD {props.route.route_distinguisher}
+        </span>}
       <FilterReason route={props.route} />
       <NoexportReason route={props.route} />
     </td>
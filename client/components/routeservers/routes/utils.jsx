@@ -2,6 +2,10 @@
 import {humanizedJoin} from 'components/utils/text'
 import {intersect, resolve} from 'components/utils/lists'
 
+import {ROUTES_RECEIVED,
+        ROUTES_FILTERED,
+        ROUTES_NOT_EXPORTED} from './actions'
+
 const filterable = [
   "gateway", "network"
 ];
@@ -14,4 +18,19 @@ export function filterableColumnsText(columns, order) {
   return humanizedJoin(filterableColumns(columns, order), "or");
 }
 
-
+/*
+ * Check if the routes view is supported by the route server.
+ * Without capabilities, e.g. while the route servers are
+ * loading, all views are assumed to be supported.
+ */
+export function routesSupported(routeserver, type) {
+  if (!routeserver || !routeserver.capabilities) {
+    return true;
+  }
+  const capability = {
+    [ROUTES_RECEIVED]:     "routes_received",
+    [ROUTES_FILTERED]:     "routes_filtered",
+    [ROUTES_NOT_EXPORTED]: "routes_not_exported",
+  }[type];
+  return routeserver.capabilities[capability] !== false;
+}
@@ -13,6 +13,7 @@ import {fetchRoutesReceived,
         fetchRoutesNotExported} from './actions'
 
 import {makeLinkProps} from './urls'
+import {routesSupported} from './utils'
 
 import {filtersEqual} from 'components/filters/groups'
 import {mergeFilters} from 'components/filters/state'
@@ -74,6 +75,11 @@ class RoutesView extends React.Component {
 
     // Make request
 
+    // Views not supported by the route server are not requested
+    if (!params.supported) {
+      return;
+    }
+
     // Handle special case, when on demand loading is enabled,
     // we defer this dispatch, until an user interaction.
     if (!params.loadRoutes) {
@@ -97,6 +103,7 @@ class RoutesView extends React.Component {
         params.page != nextParams.page || // Pagination
         !filtersEqual(this.props.filtersApplied, props.filtersApplied) || // Filters
         params.loadRoutes != nextParams.loadRoutes || // Defered loading
+        params.supported != nextParams.supported || // Capabilities loaded
         props.protocolId != this.props.protocolId // Switch related peers
         ) {
           return true;
@@ -139,6 +146,10 @@ class RoutesView extends React.Component {
       [ROUTES_NOT_EXPORTED]: "routes-not-exported",
     }[type];
 
+    if (!state.supported) {
+      return this.renderNotSupported();
+    }
+
     if (!state.loadRoutes) {
       // In case it was not yet requested, render a trigger
       // and defer routesFetching until a user interaction has
@@ -182,6 +193,28 @@ class RoutesView extends React.Component {
     );
   }
 
+  renderNotSupported() {
+    const type = this.props.type;
+    const name = {
+      [ROUTES_RECEIVED]:     "routes-received",
+      [ROUTES_FILTERED]:     "routes-filtered",
+      [ROUTES_NOT_EXPORTED]: "routes-not-exported",
+    }[type];
+
+    return (
+      <div className={`card routes-view ${name}`}>
+        <div className="row">
+          <div className="col-md-6">
+            <RoutesHeader type={type} />
+          </div>
+        </div>
+        <p className="help">
+          These routes are not available from this route server.
+        </p>
+      </div>
+    );
+  }
+
   renderLoadTrigger() {
     const type = this.props.type;
     const state = this.props.routes[type];
@@ -234,7 +267,13 @@ class RoutesView extends React.Component {
 }
 
 export default connect(
-  (state) => {
+  (state, props) => {
+    const routeserver = state.routeservers.byId[props.routeserverId];
+    const supported = {
+      [ROUTES_RECEIVED]:     routesSupported(routeserver, ROUTES_RECEIVED),
+      [ROUTES_FILTERED]:     routesSupported(routeserver, ROUTES_FILTERED),
+      [ROUTES_NOT_EXPORTED]: routesSupported(routeserver, ROUTES_NOT_EXPORTED),
+    };
     const received = {
       routes:       state.routes.received,
       requested:    state.routes.receivedRequested,
@@ -243,6 +282,7 @@ export default connect(
       pageSize:     state.routes.receivedPageSize,
       totalPages:   state.routes.receivedTotalPages,
       totalResults: state.routes.receivedTotalResults,
+      supported:    supported[ROUTES_RECEIVED],
       loadRoutes:   true,
     };
     const filtered = {
@@ -253,6 +293,7 @@ export default connect(
       pageSize:     state.routes.filteredPageSize,
       totalPages:   state.routes.filteredTotalPages,
       totalResults: state.routes.filteredTotalResults,
+      supported:    supported[ROUTES_FILTERED],
       loadRoutes:   true,
     };
     const notExported = {
@@ -263,14 +304,18 @@ export default connect(
       pageSize:     state.routes.notExportedPageSize,
       totalPages:   state.routes.notExportedTotalPages,
       totalResults: state.routes.notExportedTotalResults,
+      supported:    supported[ROUTES_NOT_EXPORTED],
 
       loadRoutes:    state.routes.loadNotExported ||
                      !state.config.noexport_load_on_demand,
 
-      otherLoaded:  state.routes.receivedRequested &&
-                    !state.routes.receivedLoading  &&
-                    state.routes.filteredRequested &&
-                    !state.routes.filteredLoading
+      // Unsupported views are never requested
+      otherLoaded:  (!supported[ROUTES_RECEIVED] ||
+                     (state.routes.receivedRequested &&
+                      !state.routes.receivedLoading)) &&
+                    (!supported[ROUTES_FILTERED] ||
+                     (state.routes.filteredRequested &&
+                      !state.routes.filteredLoading))
     };
     const filtersApplied = mergeFilters(
         state.routes.receivedFiltersApplied,